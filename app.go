@@ -7,18 +7,31 @@ import (
 	"os"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 	"videoarchiver/backend/daemonsignal"
+	"videoarchiver/backend/domains/backup"
 	"videoarchiver/backend/domains/closeconfirm"
 	"videoarchiver/backend/domains/config"
+	"videoarchiver/backend/domains/corruptionscan"
 	"videoarchiver/backend/domains/db"
 	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/duplicates"
 	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/fingerprint"
+	"videoarchiver/backend/domains/hashmigration"
+	"videoarchiver/backend/domains/integrity"
 	"videoarchiver/backend/domains/lockfile"
 	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/managedconfig"
+	"videoarchiver/backend/domains/musictag"
 	"videoarchiver/backend/domains/playlist"
+	"videoarchiver/backend/domains/playlistfile"
+	"videoarchiver/backend/domains/postprocess"
 	"videoarchiver/backend/domains/runner"
 	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/toolupdate"
+	"videoarchiver/backend/domains/transfer"
 	"videoarchiver/backend/domains/utils"
 	"videoarchiver/backend/domains/ytdlp"
 
@@ -53,12 +66,30 @@ type App struct {
 	DownloadDB          *download.DownloadDB
 	DownloadService     *download.DownloadService
 	FileRegistryService *fileregistry.FileRegistryService
+	FingerprintService  *fingerprint.FingerprintService
+	IntegrityService    *integrity.IntegrityService
+	DuplicatesService   *duplicates.DuplicatesService
+	CorruptionScan      *corruptionscan.CorruptionScanService
+	PostProcessService  *postprocess.PostProcessService
+	MusicTagService     *musictag.MusicTagService
+	PlaylistFiles       *playlistfile.PlaylistFileService
+	TransferService     *transfer.TransferService
+	BackupService       *backup.BackupService
+	ManagedConfig       *managedconfig.ManagedConfigService
+	HashMigration       *hashmigration.HashMigrationService
+	ToolUpdateService   *toolupdate.ToolUpdateService
 	LogService          *logging.LogService
 	CloseConfirmService *closeconfirm.CloseConfirmService
 	StartupProgress     string
 	isDaemonRunning     bool
 	mode                string
 	confirmCloseEnabled bool
+	containerMode       bool               // Daemon in the foreground without a UI to accept the legal disclaimer
+	acceptDisclaimer    bool               // Legal disclaimer accepted on the command line
+	registrationCancel  context.CancelFunc // Cancels the running directory registration, nil if none
+	registrationMutex   sync.Mutex
+	corruptionCancel    context.CancelFunc // Cancels the running corruption scan, nil if none
+	corruptionMutex     sync.Mutex
 }
 
 // NewApp creates a new App application struct
@@ -114,59 +145,8 @@ func (a *App) startup(ctx context.Context) {
 		}()
 	}
 
-	// Create configuration service FIRST
-	configService, err := config.NewConfigService()
-	if err != nil {
-		a.HandleFatalError("Failed to create configuration service: " + err.Error())
-	}
-	a.ConfigService = configService
-
-	// LogService already initialized early in startup function
-
-	// Create database service using configuration
-	dbService, err := db.NewDatabaseService(configService, a.LogService)
-	if err != nil {
-		a.HandleFatalError("Failed to create database service: " + err.Error())
-	}
-	a.DB = dbService
-
-	// Create SettingsService using dbService
-	a.SettingsService = settings.NewSettingsService(dbService, a.LogService)
-
-	// Create DaemonTrigger service
-	a.DaemonSignalService = daemonsignal.NewDaemonSignalService(a.SettingsService)
-
-	// Create PlaylistDB using dbService
-	a.PlaylistDB = playlist.NewPlaylistDB(dbService)
-	a.PlaylistService = playlist.NewPlaylistService(a.PlaylistDB, a.DaemonSignalService, a.LogService)
-
-	// Create DownloadService using dbService
-	a.DownloadDB = download.NewDownloadDB(dbService)
-	a.FileRegistryService = fileregistry.NewFileRegistryService(dbService)
-	a.DownloadService = download.NewDownloadService(
-		ctx,
-		a.SettingsService,
-		a.DownloadDB,
-		a.FileRegistryService,
-		a.DaemonSignalService,
-		a.LogService,
-	)
-
-	// Init utils with context
-	a.Utils = utils.NewUtils(ctx)
-
-	// Initialize CloseConfirmService with context
-	a.CloseConfirmService = closeconfirm.NewCloseConfirmService(ctx, a.LogService)
-
-	// Apply database migrations (AFTER setting up DB)
-	a.StartupProgress = "Applying database updates..."
-	db := dbService.GetDB()
-	dbmigrator.SetDatabaseType(dbmigrator.SQLite)
-	<-dbmigrator.MigrateUpCh(
-		db,
-		migrationFS,
-		"migrations",
-	)
+	// Create services and apply database migrations
+	a.initCore(ctx)
 
 	// For UI mode, wait for legal disclaimer acceptance before installing dependencies
 	if a.WailsEnabled {
@@ -214,6 +194,12 @@ func (a *App) startup(ctx context.Context) {
 			}
 		}()
 
+		if a.acceptDisclaimer {
+			if err := a.SettingsService.SetPreparsed("legal_disclaimer_accepted", "true"); err != nil {
+				a.HandleFatalError("Failed to accept legal disclaimer: " + err.Error())
+			}
+		}
+
 		a.StartupProgress = "Waiting for legal disclaimer acceptance..."
 		for {
 			accepted, err := a.GetLegalDisclaimerAccepted()
@@ -226,6 +212,10 @@ func (a *App) startup(ctx context.Context) {
 				a.LogService.Info("Legal disclaimer accepted, proceeding with dependency installation")
 				break
 			}
+			// Nothing can accept it later without a UI
+			if a.containerMode {
+				a.HandleFatalError("The legal disclaimer has not been accepted. Read the Legal Notice in the README and set " + acceptDisclaimerEnv + "=true to accept it.")
+			}
 			a.LogService.Info("Waiting for legal disclaimer acceptance before proceeding...")
 			time.Sleep(5 * time.Second)
 		}
@@ -233,6 +223,9 @@ func (a *App) startup(ctx context.Context) {
 
 	// ✅ Install ytdlp in background channel (after legal disclaimer is accepted)
 	a.LogService.Info("Starting dependency installation after legal disclaimer acceptance")
+	if err := ytdlp.ConfigureToolSource(a.SettingsService); err != nil {
+		a.HandleFatalError("Invalid tool settings: " + err.Error())
+	}
 	ytdlpUpdateChan := make(chan error)
 	go func() {
 		defer close(ytdlpUpdateChan)
@@ -257,9 +250,13 @@ func (a *App) startup(ctx context.Context) {
 	}()
 
 	// Install/update ytdlp/ffmpeg
-	err = <-ytdlpUpdateChan
+	err := <-ytdlpUpdateChan
 	if err != nil {
-		a.HandleFatalError("Failed to install ytdlp: " + err.Error())
+		// Without network access updates fail, the installed binaries still work
+		if checkErr := ytdlp.CheckTools(); checkErr != nil {
+			a.HandleFatalError("Failed to install ytdlp: " + err.Error())
+		}
+		a.LogService.Warn(fmt.Sprintf("Failed to update dependencies, continuing with the installed versions: %v", err))
 	}
 	ytdlpUpdateDone = true
 
@@ -297,6 +294,167 @@ func (a *App) startup(ctx context.Context) {
 	}
 }
 
+// initCore creates the configuration, database and domain services and applies migrations.
+// Shared by every startup mode, including CLI commands that exit when done.
+func (a *App) initCore(ctx context.Context) {
+	// Create configuration service FIRST
+	configService, err := config.NewConfigService()
+	if err != nil {
+		a.HandleFatalError("Failed to create configuration service: " + err.Error())
+	}
+	a.ConfigService = configService
+
+	// LogService must already be initialized by the caller
+
+	// Create database service using configuration
+	dbService, err := db.NewDatabaseService(configService, a.LogService)
+	if err != nil {
+		a.HandleFatalError("Failed to create database service: " + err.Error())
+	}
+	a.DB = dbService
+
+	// Create SettingsService using dbService
+	a.SettingsService = settings.NewSettingsService(dbService, a.LogService)
+
+	// Create DaemonTrigger service
+	a.DaemonSignalService = daemonsignal.NewDaemonSignalService(a.SettingsService)
+
+	// Create PlaylistDB using dbService
+	a.PlaylistDB = playlist.NewPlaylistDB(dbService)
+	a.PlaylistService = playlist.NewPlaylistService(a.PlaylistDB, a.DaemonSignalService, a.LogService)
+
+	// Create DownloadService using dbService
+	a.DownloadDB = download.NewDownloadDB(dbService)
+	a.FingerprintService = fingerprint.NewFingerprintService(
+		fingerprint.NewFingerprintDB(dbService),
+		a.SettingsService,
+		a.LogService,
+	)
+	a.FileRegistryService = fileregistry.NewFileRegistryService(dbService, a.SettingsService, a.FingerprintService)
+	a.PostProcessService = postprocess.NewPostProcessService(postprocess.NewPostProcessDB(dbService), a.LogService)
+	a.MusicTagService = musictag.NewMusicTagService(a.SettingsService, a.LogService)
+	a.DownloadService = download.NewDownloadService(
+		ctx,
+		a.SettingsService,
+		a.DownloadDB,
+		a.FileRegistryService,
+		a.FingerprintService,
+		a.PostProcessService,
+		a.MusicTagService,
+		a.DaemonSignalService,
+		a.LogService,
+	)
+
+	// Create PlaylistFileService to mirror source playlists as M3U8 and XSPF files
+	a.PlaylistFiles = playlistfile.NewPlaylistFileService(a.SettingsService, a.DownloadDB, a.LogService)
+
+	// Create ManagedConfigService to reconcile playlists and settings against the declarative config file
+	a.ManagedConfig = managedconfig.NewManagedConfigService(
+		managedconfig.NewManagedConfigDB(dbService),
+		configService,
+		a.SettingsService,
+		a.PlaylistDB,
+		a.PostProcessService,
+		a.DaemonSignalService,
+		a.LogService,
+	)
+
+	// Create TransferService to export and import playlists and settings
+	a.TransferService = transfer.NewTransferService(
+		a.SettingsService,
+		a.PlaylistDB,
+		a.PostProcessService,
+		a.DaemonSignalService,
+		a.ManagedConfig,
+		a.LogService,
+	)
+
+	// Create BackupService for database backups and history exports
+	a.BackupService = backup.NewBackupService(
+		dbService,
+		configService,
+		a.SettingsService,
+		a.DownloadDB,
+		a.FileRegistryService,
+		a.LogService,
+	)
+
+	// Create IntegrityService for archive verification
+	a.IntegrityService = integrity.NewIntegrityService(
+		integrity.NewIntegrityDB(dbService),
+		a.SettingsService,
+		a.DownloadDB,
+		a.DownloadService,
+		a.FileRegistryService,
+		a.LogService,
+	)
+
+	// Create DuplicatesService for duplicate reports and reconciliation
+	a.DuplicatesService = duplicates.NewDuplicatesService(
+		duplicates.NewDuplicatesDB(dbService),
+		a.SettingsService,
+		a.DownloadDB,
+		a.FileRegistryService,
+		a.FingerprintService,
+		a.LogService,
+	)
+
+	// Create CorruptionScanService for bulk corruption checks of existing files
+	a.CorruptionScan = corruptionscan.NewCorruptionScanService(
+		corruptionscan.NewCorruptionScanDB(dbService),
+		a.SettingsService,
+		a.DownloadDB,
+		a.DownloadService,
+		a.LogService,
+	)
+
+	// Create HashMigrationService to rehash rows after the hash algorithm changes
+	a.HashMigration = hashmigration.NewHashMigrationService(
+		a.SettingsService,
+		a.DownloadDB,
+		a.FileRegistryService,
+		a.LogService,
+	)
+
+	// Create ToolUpdateService to update yt-dlp while the daemon runs
+	a.ToolUpdateService = toolupdate.NewToolUpdateService(
+		toolupdate.NewToolUpdateDB(dbService),
+		a.SettingsService,
+		a.DownloadDB,
+		a.DaemonSignalService,
+		a.LogService,
+	)
+
+	// Init utils with context
+	a.Utils = utils.NewUtils(ctx)
+
+	// Initialize CloseConfirmService with context
+	a.CloseConfirmService = closeconfirm.NewCloseConfirmService(ctx, a.LogService)
+
+	// Apply database migrations (AFTER setting up DB)
+	a.StartupProgress = "Applying database updates..."
+	db := dbService.GetDB()
+	dbmigrator.SetDatabaseType(dbmigrator.SQLite)
+	<-dbmigrator.MigrateUpCh(
+		db,
+		migrationFS,
+		"migrations",
+	)
+
+	// Settings added to the schema after the last migration get their default
+	if err := a.SettingsService.EnsureDefaults(); err != nil {
+		a.HandleFatalError("Failed to initialize settings: " + err.Error())
+	}
+	if err := a.SettingsService.ValidateOverrides(); err != nil {
+		a.HandleFatalError("Invalid settings override: " + err.Error())
+	}
+	for _, definition := range settings.GetSchema() {
+		if override, ok := a.SettingsService.GetSettingOverride(definition.Key); ok {
+			a.LogService.Info(fmt.Sprintf("Setting %s is overridden by %s", definition.Key, override.Source))
+		}
+	}
+}
+
 // handleUILocking handles locking for UI mode (slave)
 func (a *App) handleUILocking() error {
 	a.LogService.Info("Starting handleUILocking check...")
@@ -425,6 +583,9 @@ func (a *App) GetDownloadsDirectory() (string, error) {
 }
 
 func (a *App) UpdatePlaylistDirectory(id int, newDirectory string) error {
+	if err := a.ManagedConfig.CheckPlaylistEditable(id); err != nil {
+		return err
+	}
 	return a.PlaylistService.TryUpdatePlaylistDirectory(id, newDirectory)
 }
 
@@ -433,6 +594,9 @@ func (a *App) ValidateAndAddPlaylist(url, directory, format string) error {
 }
 
 func (a *App) DeletePlaylist(id int) error {
+	if err := a.ManagedConfig.CheckPlaylistEditable(id); err != nil {
+		return err
+	}
 	return a.PlaylistService.TryDeletePlaylist(id)
 }
 
@@ -444,7 +608,18 @@ func (a *App) GetSettingString(key string) (string, error) {
 	return a.SettingsService.GetSettingString(key)
 }
 
+// GetSettingsSchema returns every setting with its type, default and allowed values, in display order
+func (a *App) GetSettingsSchema() []settings.SettingDefinition {
+	return settings.GetSchema()
+}
+
 func (a *App) SetSettingPreparsed(key string, value string) error {
+	if override, ok := a.SettingsService.GetSettingOverride(key); ok {
+		return fmt.Errorf("setting %s is overridden by %s", key, override.Source)
+	}
+	if err := a.ManagedConfig.CheckSettingEditable(key); err != nil {
+		return err
+	}
 	err := a.SettingsService.SetPreparsed(key, value)
 	if err != nil {
 		return errors.Wrap(err, "failed to set setting")
@@ -526,43 +701,40 @@ func (a *App) StartDaemon() error {
 
 	a.LogService.Info("Starting daemon process...")
 
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %v", err)
+	}
+
 	switch goruntime.GOOS {
 	case "windows":
-		exePath, err := os.Executable()
-		if err != nil {
-			return fmt.Errorf("failed to get executable path: %v", err)
-		}
-
 		err = runner.StartDetachedWithFlags(exePath, 0x00000200|0x00000008, "--mode", "daemon")
 		if err != nil {
 			return fmt.Errorf("failed to start daemon: %v", err)
 		}
 
-		// Wait a moment to check if process started successfully
-		time.Sleep(500 * time.Millisecond)
-		if !a.IsDaemonRunning() {
-			return fmt.Errorf("daemon process failed to start")
-		}
-		a.LogService.Info("Daemon process started successfully")
-
 	case "linux":
-		err := runner.RunAndWait("systemctl", "start", LinuxServiceName)
+		// Without the systemd user service, such as in a container, the daemon is started directly
+		if err := runner.RunAndWait("systemctl", "--user", "start", LinuxServiceName); err == nil {
+			a.LogService.Info("Daemon started through systemd")
+			a.isDaemonRunning = true
+			return nil
+		}
+		err = runner.StartDetached(exePath, "--mode", "daemon")
 		if err != nil {
-			// Fallback to direct execution if service not installed
-			err = runner.StartDetached(os.Args[0], "--daemon")
-			if err != nil {
-				return fmt.Errorf("failed to start daemon: %v", err)
-			}
-			time.Sleep(500 * time.Millisecond)
-			if !a.IsDaemonRunning() {
-				return fmt.Errorf("daemon process failed to start")
-			}
-			a.LogService.Info("Daemon process started successfully via direct execution")
+			return fmt.Errorf("failed to start daemon: %v", err)
 		}
 	default:
 		return fmt.Errorf("unsupported operating system: %s", goruntime.GOOS)
 	}
 
+	// Wait a moment to check if process started successfully
+	time.Sleep(500 * time.Millisecond)
+	if !a.IsDaemonRunning() {
+		return fmt.Errorf("daemon process failed to start")
+	}
+	a.LogService.Info("Daemon process started successfully")
+
 	a.isDaemonRunning = true
 	return nil
 }
@@ -574,48 +746,28 @@ func (a *App) StopDaemon() error {
 
 	switch goruntime.GOOS {
 	case "windows":
-		selfPid := os.Getpid()
-		processes, err := process.Processes()
-		if err != nil {
-			return fmt.Errorf("failed to list processes: %v", err)
-		}
-
-		selfExe, err := os.Executable()
-		if err != nil {
-			return fmt.Errorf("failed to get executable path: %v", err)
-		}
-		selfExe = strings.ToLower(selfExe)
-
-		for _, p := range processes {
-			if int32(selfPid) == p.Pid {
-				continue
-			}
-
-			exe, err := p.Exe()
-			if err != nil {
-				continue
-			}
-
-			cmdline, _ := p.Cmdline()
-			exe = strings.ToLower(exe)
+	case "linux":
+		// Stop the systemd user service if there is one, then any daemon started directly
+		_ = runner.RunAndWait("systemctl", "--user", "stop", LinuxServiceName)
+	default:
+		return fmt.Errorf("unsupported operating system: %s", goruntime.GOOS)
+	}
 
-			// Only kill processes running in daemon mode
-			if exe == selfExe && strings.Contains(cmdline, "--mode daemon") {
-				if err := p.Kill(); err != nil {
-					return fmt.Errorf("failed to kill process %d: %v", p.Pid, err)
-				}
-				a.LogService.Info(fmt.Sprintf("Killed daemon process: PID=%d", p.Pid))
-			}
+	processes, err := findDaemonProcesses()
+	if err != nil {
+		return err
+	}
+	for _, p := range processes {
+		// Windows has no SIGTERM, elsewhere the daemon finishes its current download and exits
+		if goruntime.GOOS == "windows" {
+			err = p.Kill()
+		} else {
+			err = p.Terminate()
 		}
-
-	case "linux":
-		err := runner.RunAndWait("systemctl", "stop", LinuxServiceName)
 		if err != nil {
-			// Fallback to finding and killing the process
-			runner.RunAndWait("pkill", "-f", os.Args[0])
+			return fmt.Errorf("failed to stop process %d: %v", p.Pid, err)
 		}
-	default:
-		return fmt.Errorf("unsupported operating system: %s", goruntime.GOOS)
+		a.LogService.Info(fmt.Sprintf("Stopped daemon process: PID=%d", p.Pid))
 	}
 
 	a.isDaemonRunning = false
@@ -649,52 +801,53 @@ func (a *App) GetUILogLinesWithLevel(lines int, minLevel string) ([]string, erro
 }
 
 func (a *App) IsDaemonRunning() bool {
-	switch goruntime.GOOS {
-	case "windows":
-		selfPid := os.Getpid()
-		processes, err := process.Processes()
-		if err != nil {
-			return false
+	if goruntime.GOOS == "linux" {
+		if err := runner.RunAndWait("systemctl", "--user", "is-active", LinuxServiceName); err == nil {
+			a.isDaemonRunning = true
+			return true
 		}
+	}
 
-		selfExe, err := os.Executable()
-		if err != nil {
-			return false
-		}
-		selfExe = strings.ToLower(selfExe)
+	// Daemons started directly, or anywhere systemd is not available
+	processes, err := findDaemonProcesses()
+	a.isDaemonRunning = err == nil && len(processes) > 0
+	return a.isDaemonRunning
+}
 
-		for _, p := range processes {
-			if int32(selfPid) == p.Pid {
-				continue
-			}
+// findDaemonProcesses returns other processes of this executable running in daemon mode
+func findDaemonProcesses() ([]*process.Process, error) {
+	selfPid := os.Getpid()
+	processes, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %v", err)
+	}
 
-			exe, err := p.Exe()
-			if err != nil {
-				continue
-			}
+	selfExe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executable path: %v", err)
+	}
+	selfExe = strings.ToLower(selfExe)
 
-			cmdline, _ := p.Cmdline()
-			exe = strings.ToLower(exe)
+	daemons := make([]*process.Process, 0)
+	for _, p := range processes {
+		if int32(selfPid) == p.Pid {
+			continue
+		}
 
-			// Check if it's our executable AND it's running in daemon mode
-			if exe == selfExe && strings.Contains(cmdline, "--mode daemon") {
-				return true
-			}
+		exe, err := p.Exe()
+		if err != nil {
+			continue
 		}
-		return false
 
-	case "linux":
-		err := runner.RunAndWait("systemctl", "is-active", LinuxServiceName)
-		if err == nil {
-			a.isDaemonRunning = true
-			return true
+		cmdline, _ := p.Cmdline()
+		exe = strings.ToLower(exe)
+
+		// Check if it's our executable AND it's running in daemon mode
+		if exe == selfExe && strings.Contains(cmdline, "--mode daemon") {
+			daemons = append(daemons, p)
 		}
 	}
-
-	// Check for running process in case of direct execution
-	// This is a simplified check and might need improvement
-	a.isDaemonRunning = false
-	return false
+	return daemons, nil
 }
 
 func (a *App) GetLegalDisclaimerAccepted() (bool, error) {
@@ -760,13 +913,30 @@ func (a *App) GetRegisteredFilesCountWithSearch(searchQuery string) (int, error)
 	return a.FileRegistryService.GetCountWithSearch(searchQuery)
 }
 
-// RegisterDirectory registers all files in a directory for duplicate detection with progress reporting
+// RegisterDirectory registers all files in a directory for duplicate detection with progress reporting.
+// Files unchanged since a previous registration are skipped.
 func (a *App) RegisterDirectory(directoryPath string) error {
 	a.LogService.Info(fmt.Sprintf("RegisterDirectory called with path: '%s' (length: %d)", directoryPath, len(directoryPath)))
 
 	// If Wails is enabled, emit progress events in background
 	if a.WailsEnabled {
+		ctx, cancel := context.WithCancel(a.ctx)
+		a.registrationMutex.Lock()
+		if a.registrationCancel != nil {
+			a.registrationMutex.Unlock()
+			cancel()
+			return fmt.Errorf("a directory registration is already running")
+		}
+		a.registrationCancel = cancel
+		a.registrationMutex.Unlock()
+
 		go func() {
+			defer func() {
+				a.registrationMutex.Lock()
+				a.registrationCancel = nil
+				a.registrationMutex.Unlock()
+				cancel()
+			}()
 			a.SetConfirmCloseEnabled(true) // Enable close confirmation during long operation
 			defer a.SetConfirmCloseEnabled(false)
 			// Create progress callback for UI mode
@@ -778,8 +948,11 @@ func (a *App) RegisterDirectory(directoryPath string) error {
 			}
 
 			// All validation and processing happens in the service with consistent error handling
-			err := a.FileRegistryService.RegisterDirectoryWithProgress(directoryPath, a.LogService, progressCallback)
-			if err != nil {
+			result, err := a.FileRegistryService.ScanDirectory(ctx, directoryPath, a.LogService, progressCallback)
+			if errors.Is(err, context.Canceled) {
+				a.LogService.Info("Directory registration cancelled")
+				runtime.EventsEmit(a.ctx, "file-registration-cancelled", result)
+			} else if err != nil {
 				a.LogService.Error(fmt.Sprintf("Directory registration failed: %v", err))
 				// Emit error completion event
 				runtime.EventsEmit(a.ctx, "file-registration-error", map[string]interface{}{
@@ -787,18 +960,35 @@ func (a *App) RegisterDirectory(directoryPath string) error {
 				})
 			} else {
 				// Only emit success completion if no error
-				runtime.EventsEmit(a.ctx, "file-registration-complete")
+				runtime.EventsEmit(a.ctx, "file-registration-complete", result)
 			}
 			a.LogService.Info("Directory registration process completed")
 		}()
 	} else {
 		// Direct execution for non-UI mode (no progress callback needed)
-		return a.FileRegistryService.RegisterDirectoryWithProgress(directoryPath, a.LogService, nil)
+		_, err := a.FileRegistryService.ScanDirectory(context.Background(), directoryPath, a.LogService, nil)
+		return err
 	}
 
 	return nil
 }
 
+// CancelDirectoryRegistration stops a running directory registration.
+// Files registered so far are kept, registering the directory again resumes where it stopped.
+func (a *App) CancelDirectoryRegistration() {
+	a.registrationMutex.Lock()
+	defer a.registrationMutex.Unlock()
+	if a.registrationCancel != nil {
+		a.LogService.Info("Cancelling directory registration")
+		a.registrationCancel()
+	}
+}
+
+// GetRecentRegistryScans returns the most recent directory registration scans with their counts
+func (a *App) GetRecentRegistryScans(limit int) ([]fileregistry.RegistryScan, error) {
+	return a.FileRegistryService.GetRecentScans(limit)
+}
+
 // ClearAllRegisteredFiles removes all registered files from the database
 func (a *App) ClearAllRegisteredFiles() error {
 	a.LogService.Info("Clearing all registered files")
@@ -809,3 +999,277 @@ func (a *App) ClearAllRegisteredFiles() error {
 func (a *App) GetVersion() string {
 	return GetVersionInfo()
 }
+
+// RunIntegrityCheck verifies all archived and registered files with progress reporting
+func (a *App) RunIntegrityCheck() error {
+	a.LogService.Info("RunIntegrityCheck called")
+
+	// If Wails is enabled, emit progress events in background
+	if a.WailsEnabled {
+		go func() {
+			a.SetConfirmCloseEnabled(true) // Enable close confirmation during long operation
+			defer a.SetConfirmCloseEnabled(false)
+			progressCallback := func(percent int, message string) {
+				runtime.EventsEmit(a.ctx, "integrity-check-progress", map[string]interface{}{
+					"percent": percent,
+					"message": message,
+				})
+			}
+
+			run, err := a.IntegrityService.RunVerification(a.ctx, nil, progressCallback)
+			if err != nil {
+				a.LogService.Error(fmt.Sprintf("Integrity check failed: %v", err))
+				runtime.EventsEmit(a.ctx, "integrity-check-error", map[string]interface{}{
+					"error": err.Error(),
+				})
+			} else {
+				runtime.EventsEmit(a.ctx, "integrity-check-complete", run)
+			}
+		}()
+	} else {
+		_, err := a.IntegrityService.RunVerification(context.Background(), nil, nil)
+		return err
+	}
+
+	return nil
+}
+
+// GetLatestIntegrityRun returns the most recent integrity verification run, or null if none ran yet
+func (a *App) GetLatestIntegrityRun() (*integrity.IntegrityRun, error) {
+	return a.IntegrityService.GetLatestRun()
+}
+
+// GetIntegrityIssues returns a paginated list of issues found during an integrity run
+func (a *App) GetIntegrityIssues(runId int, offset int, limit int) ([]integrity.IntegrityIssue, error) {
+	return a.IntegrityService.GetIssuesForRun(runId, offset, limit)
+}
+
+// RequeueIntegrityIssue marks the download behind an integrity issue for download again
+func (a *App) RequeueIntegrityIssue(issueId int) error {
+	return a.IntegrityService.RequeueIssue(issueId)
+}
+
+// GetProbableDuplicates returns a paginated list of downloads flagged as probable duplicates by fingerprinting
+func (a *App) GetProbableDuplicates(offset int, limit int) ([]fingerprint.ProbableDuplicate, error) {
+	return a.FingerprintService.GetProbableDuplicates(offset, limit)
+}
+
+// ResolveProbableDuplicate stores the review outcome of a probable duplicate (pending, confirmed or dismissed)
+func (a *App) ResolveProbableDuplicate(id int, status string) error {
+	return a.FingerprintService.ResolveProbableDuplicate(id, status)
+}
+
+// GetDuplicateReport groups all archived and registered files by content hash, video ID and fingerprint
+func (a *App) GetDuplicateReport() (*duplicates.DuplicateReport, error) {
+	return a.DuplicatesService.BuildReport(a.ctx)
+}
+
+// ReconcileDuplicates keeps one file of each group and hardlinks, quarantines or deletes the others.
+// With dry run set nothing is changed, the returned actions show what would happen.
+func (a *App) ReconcileDuplicates(request duplicates.ReconcileRequest) ([]duplicates.DuplicateAction, error) {
+	return a.DuplicatesService.Reconcile(a.ctx, request)
+}
+
+// GetDuplicateActions returns a page of the duplicate reconciliation audit log
+func (a *App) GetDuplicateActions(offset int, limit int) ([]duplicates.DuplicateAction, error) {
+	return a.DuplicatesService.GetActions(offset, limit)
+}
+
+// StartCorruptionScanDirectory checks every media file under a directory for corruption
+func (a *App) StartCorruptionScanDirectory(directoryPath string) error {
+	a.LogService.Info(fmt.Sprintf("StartCorruptionScanDirectory called with path: %s", directoryPath))
+	return a.runCorruptionScan(func(ctx context.Context, progressCallback corruptionscan.ProgressCallback) (*corruptionscan.CorruptionScan, error) {
+		return a.CorruptionScan.ScanDirectory(ctx, directoryPath, progressCallback)
+	})
+}
+
+// StartCorruptionScanPlaylist checks the archived downloads of a playlist for corruption
+func (a *App) StartCorruptionScanPlaylist(playlistId int) error {
+	a.LogService.Info(fmt.Sprintf("StartCorruptionScanPlaylist called for playlist %d", playlistId))
+	return a.runCorruptionScan(func(ctx context.Context, progressCallback corruptionscan.ProgressCallback) (*corruptionscan.CorruptionScan, error) {
+		return a.CorruptionScan.ScanPlaylist(ctx, playlistId, progressCallback)
+	})
+}
+
+// runCorruptionScan runs a corruption scan, in the background with progress events when Wails is enabled
+func (a *App) runCorruptionScan(scan func(ctx context.Context, progressCallback corruptionscan.ProgressCallback) (*corruptionscan.CorruptionScan, error)) error {
+	if !a.WailsEnabled {
+		_, err := scan(context.Background(), nil)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.corruptionMutex.Lock()
+	if a.corruptionCancel != nil {
+		a.corruptionMutex.Unlock()
+		cancel()
+		return fmt.Errorf("a corruption scan is already running")
+	}
+	a.corruptionCancel = cancel
+	a.corruptionMutex.Unlock()
+
+	go func() {
+		defer func() {
+			a.corruptionMutex.Lock()
+			a.corruptionCancel = nil
+			a.corruptionMutex.Unlock()
+			cancel()
+		}()
+		progressCallback := func(percent int, message string) {
+			runtime.EventsEmit(a.ctx, "corruption-scan-progress", map[string]interface{}{
+				"percent": percent,
+				"message": message,
+			})
+		}
+
+		result, err := scan(ctx, progressCallback)
+		if errors.Is(err, context.Canceled) {
+			a.LogService.Info("Corruption scan cancelled")
+			runtime.EventsEmit(a.ctx, "corruption-scan-cancelled", result)
+		} else if err != nil {
+			a.LogService.Error(fmt.Sprintf("Corruption scan failed: %v", err))
+			runtime.EventsEmit(a.ctx, "corruption-scan-error", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			runtime.EventsEmit(a.ctx, "corruption-scan-complete", result)
+		}
+	}()
+	return nil
+}
+
+// CancelCorruptionScan stops a running corruption scan, files checked so far keep their results
+func (a *App) CancelCorruptionScan() {
+	a.corruptionMutex.Lock()
+	defer a.corruptionMutex.Unlock()
+	if a.corruptionCancel != nil {
+		a.LogService.Info("Cancelling corruption scan")
+		a.corruptionCancel()
+	}
+}
+
+// GetRecentCorruptionScans returns the most recent corruption scans
+func (a *App) GetRecentCorruptionScans(limit int) ([]corruptionscan.CorruptionScan, error) {
+	return a.CorruptionScan.GetRecentScans(limit)
+}
+
+// GetCorruptionScanResults returns a paginated list of corrupt files found during a scan
+func (a *App) GetCorruptionScanResults(scanId int, offset int, limit int) ([]corruptionscan.CorruptionResult, error) {
+	return a.CorruptionScan.GetResultsForScan(scanId, offset, limit)
+}
+
+// RequeueCorruptFile removes a corrupt archived file and marks its download for download again
+func (a *App) RequeueCorruptFile(resultId int) error {
+	return a.CorruptionScan.RequeueResult(resultId)
+}
+
+// GetPostProcessSteps returns the post-processing pipeline of a playlist
+func (a *App) GetPostProcessSteps(playlistId int) ([]postprocess.PostProcessStep, error) {
+	return a.PostProcessService.GetSteps(playlistId)
+}
+
+// SetPostProcessSteps replaces the post-processing pipeline of a playlist, steps run in the given order
+func (a *App) SetPostProcessSteps(playlistId int, steps []postprocess.PostProcessStep) error {
+	if err := a.ManagedConfig.CheckPlaylistEditable(playlistId); err != nil {
+		return err
+	}
+	return a.PostProcessService.SetSteps(playlistId, steps)
+}
+
+// GetPostProcessStepDefaults returns the options of every kind of post-processing step with their defaults
+func (a *App) GetPostProcessStepDefaults() map[string]map[string]string {
+	return a.PostProcessService.GetStepDefaults()
+}
+
+// GetPostProcessResults returns the post-processing step results recorded for a download
+func (a *App) GetPostProcessResults(downloadId int) ([]postprocess.PostProcessResult, error) {
+	return a.PostProcessService.GetResultsForDownload(downloadId)
+}
+
+// PreviewMusicTags returns the tags the configured patterns find in a title and description
+func (a *App) PreviewMusicTags(title, description string) (*musictag.Tags, error) {
+	return a.MusicTagService.PreviewTags(title, description)
+}
+
+// GetWatchedRoots returns the registered directories kept up to date by the daemon
+func (a *App) GetWatchedRoots() ([]fileregistry.WatchedRoot, error) {
+	return a.FileRegistryService.GetWatchedRoots()
+}
+
+// AddWatchedRoot registers all files in a directory and keeps it up to date, same as RegisterDirectory
+func (a *App) AddWatchedRoot(directoryPath string) error {
+	return a.RegisterDirectory(directoryPath)
+}
+
+// GetDefaultScanFilter returns the filter newly added watched roots start with
+func (a *App) GetDefaultScanFilter() fileregistry.ScanFilter {
+	return fileregistry.DefaultScanFilter()
+}
+
+// UpdateWatchedRootFilter changes which files are registered under a watched root.
+// The daemon applies the new filter on its next reconciliation, files it excludes are removed from the registry.
+func (a *App) UpdateWatchedRootFilter(rootId int, filter fileregistry.ScanFilter) error {
+	a.LogService.Info(fmt.Sprintf("Updating scan filter of watched root %d", rootId))
+	return a.FileRegistryService.UpdateWatchedRootFilter(rootId, filter)
+}
+
+// RemoveWatchedRoot stops keeping a directory up to date, optionally removing its files from the registry
+func (a *App) RemoveWatchedRoot(rootId int, removeFiles bool) error {
+	a.LogService.Info(fmt.Sprintf("Removing watched root %d (remove files: %t)", rootId, removeFiles))
+	return a.FileRegistryService.RemoveWatchedRoot(rootId, removeFiles)
+}
+
+// ExportConfiguration exports active playlists, their post-processing steps and settings to a JSON or YAML file
+func (a *App) ExportConfiguration(path string) error {
+	return a.TransferService.ExportToFile(path, GetVersionInfo())
+}
+
+// ImportConfiguration imports playlists and settings from a JSON or YAML export file
+func (a *App) ImportConfiguration(path string, options transfer.ImportOptions) (*transfer.ImportReport, error) {
+	return a.TransferService.ImportFromFile(path, options)
+}
+
+// CreateBackup takes a database backup now, old backups beyond the configured number are removed
+func (a *App) CreateBackup() (*backup.BackupFile, error) {
+	return a.BackupService.CreateBackup(a.ctx)
+}
+
+// GetBackups returns the database backups, newest first
+func (a *App) GetBackups() ([]backup.BackupFile, error) {
+	return a.BackupService.GetBackups()
+}
+
+// ExportDownloadHistory exports the download history with the same status filters as the history page to a CSV or JSON file
+func (a *App) ExportDownloadHistory(path string, showSuccess, showFailed, showDuplicate bool) (int, error) {
+	return a.BackupService.ExportDownloadHistory(path, showSuccess, showFailed, showDuplicate)
+}
+
+// ExportFileRegistry exports the file registry to a CSV or JSON file
+func (a *App) ExportFileRegistry(path string) (int, error) {
+	return a.BackupService.ExportFileRegistry(path)
+}
+
+// GetConfigSyncStatus returns the latest sync of the declarative config file with the drift it found
+func (a *App) GetConfigSyncStatus() (*managedconfig.SyncStatus, error) {
+	return a.ManagedConfig.GetStatus()
+}
+
+// GetManagedPlaylistIds returns the IDs of playlists managed by the declarative config file, these cannot be edited in the UI
+func (a *App) GetManagedPlaylistIds() ([]int, error) {
+	return a.ManagedConfig.GetManagedPlaylistIds()
+}
+
+// GetInstalledToolVersions returns the installed yt-dlp, ffmpeg and ffprobe versions and the releases they were installed from
+func (a *App) GetInstalledToolVersions() ([]ytdlp.ToolVersion, error) {
+	return ytdlp.GetInstalledToolVersions(a.SettingsService)
+}
+
+// GetInstalledPlugins returns the yt-dlp plugins installed from the ytdlp_plugins setting
+func (a *App) GetInstalledPlugins() ([]ytdlp.Plugin, error) {
+	return ytdlp.GetInstalledPlugins()
+}
+
+// GetToolUpdateHistory returns the most recent yt-dlp update checks of the daemon
+func (a *App) GetToolUpdateHistory(limit int) ([]toolupdate.ToolUpdate, error) {
+	return a.ToolUpdateService.GetHistory(limit)
+}
@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+)
+
+var historyColumns = []string{
+	"id", "playlist_id", "url", "status", "format", "output_filename", "full_path", "hash", "hash_algorithm",
+	"last_attempt", "attempt_count", "fail_message", "extractor", "video_id",
+}
+
+var registryColumns = []string{
+	"id", "filename", "file_path", "hash", "hash_algorithm", "registered_at",
+	"known_url", "extractor", "video_id", "title", "artist", "duration",
+}
+
+// FormatFromPath returns the export format for a file name, CSV for .csv files and JSON otherwise
+func FormatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSON
+}
+
+// ExportDownloadHistory writes the downloads matching the history filters to a CSV or JSON file.
+// Returns the number of exported downloads.
+func (b *BackupService) ExportDownloadHistory(path string, showSuccess, showFailed, showDuplicate bool) (int, error) {
+	downloads, err := b.downloadDB.GetDownloadHistory(showSuccess, showFailed, showDuplicate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get download history: %w", err)
+	}
+
+	records := make([]historyRecord, 0, len(downloads))
+	rows := make([][]string, 0, len(downloads))
+	for _, dl := range downloads {
+		record := newHistoryRecord(dl)
+		records = append(records, record)
+		rows = append(rows, record.values())
+	}
+	if err := writeRecords(path, records, historyColumns, rows); err != nil {
+		return 0, err
+	}
+	b.logService.Info(fmt.Sprintf("Exported %d downloads to %s", len(records), path))
+	return len(records), nil
+}
+
+// ExportFileRegistry writes every registered file to a CSV or JSON file.
+// Returns the number of exported files.
+func (b *BackupService) ExportFileRegistry(path string) (int, error) {
+	files, err := b.fileRegistryService.GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get registered files: %w", err)
+	}
+
+	records := make([]registryRecord, 0, len(files))
+	rows := make([][]string, 0, len(files))
+	for _, file := range files {
+		record := newRegistryRecord(file)
+		records = append(records, record)
+		rows = append(rows, record.values())
+	}
+	if err := writeRecords(path, records, registryColumns, rows); err != nil {
+		return 0, err
+	}
+	b.logService.Info(fmt.Sprintf("Exported %d registered files to %s", len(records), path))
+	return len(records), nil
+}
+
+func newHistoryRecord(dl download.Download) historyRecord {
+	record := historyRecord{
+		ID:             dl.ID,
+		PlaylistID:     dl.PlaylistID,
+		URL:            dl.Url,
+		Status:         download.StatusName(dl.Status),
+		Format:         dl.FormatDownloaded,
+		OutputFilename: dl.OutputFilename.String,
+		FullPath:       dl.FullPath.String,
+		Hash:           dl.MD5.String,
+		HashAlgorithm:  dl.HashAlgorithm,
+		AttemptCount:   dl.AttemptCount,
+		FailMessage:    dl.FailMessage.String,
+		Extractor:      dl.Extractor.String,
+		VideoID:        dl.VideoID.String,
+	}
+	if dl.LastAttempt > 0 {
+		record.LastAttempt = formatTime(dl.LastAttempt)
+	}
+	return record
+}
+
+func (r historyRecord) values() []string {
+	return []string{
+		strconv.Itoa(r.ID), strconv.Itoa(r.PlaylistID), r.URL, r.Status, r.Format, r.OutputFilename, r.FullPath,
+		r.Hash, r.HashAlgorithm, r.LastAttempt, strconv.Itoa(r.AttemptCount), r.FailMessage, r.Extractor, r.VideoID,
+	}
+}
+
+func newRegistryRecord(file fileregistry.RegisteredFile) registryRecord {
+	return registryRecord{
+		ID:            file.ID,
+		Filename:      file.Filename,
+		FilePath:      file.FilePath,
+		Hash:          file.MD5,
+		HashAlgorithm: file.HashAlgorithm,
+		RegisteredAt:  formatTime(file.RegisteredAt),
+		KnownURL:      file.KnownUrl.String,
+		Extractor:     file.Extractor.String,
+		VideoID:       file.VideoID.String,
+		Title:         file.Title.String,
+		Artist:        file.Artist.String,
+		Duration:      file.Duration.Float64,
+	}
+}
+
+func (r registryRecord) values() []string {
+	duration := ""
+	if r.Duration > 0 {
+		duration = strconv.FormatFloat(r.Duration, 'f', -1, 64)
+	}
+	return []string{
+		strconv.Itoa(r.ID), r.Filename, r.FilePath, r.Hash, r.HashAlgorithm, r.RegisteredAt,
+		r.KnownURL, r.Extractor, r.VideoID, r.Title, r.Artist, duration,
+	}
+}
+
+// writeRecords writes the records as a JSON array, or their rows as CSV with a header row, depending on the file extension
+func writeRecords(path string, records interface{}, columns []string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	switch FormatFromPath(path) {
+	case FormatCSV:
+		writer := csv.NewWriter(file)
+		writer.Write(columns)
+		writer.WriteAll(rows)
+		err = writer.Error()
+	default:
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(records)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return nil
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,56 @@
+package backup
+
+// Backups are named after the UTC time they were taken, so they sort by name
+const (
+	backupPrefix     = "db-"
+	backupSuffix     = ".sqlite"
+	backupTimeLayout = "20060102T150405Z"
+)
+
+// Export formats, chosen by file extension
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// BackupFile is a database backup in the backup directory
+type BackupFile struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"created_at"` // Unix timestamp
+}
+
+// historyRecord is a download history row as exported for auditing
+type historyRecord struct {
+	ID             int    `json:"id"`
+	PlaylistID     int    `json:"playlist_id"`
+	URL            string `json:"url"`
+	Status         string `json:"status"`
+	Format         string `json:"format"`
+	OutputFilename string `json:"output_filename"`
+	FullPath       string `json:"full_path"`
+	Hash           string `json:"hash"`
+	HashAlgorithm  string `json:"hash_algorithm"`
+	LastAttempt    string `json:"last_attempt"` // RFC 3339, empty if never attempted
+	AttemptCount   int    `json:"attempt_count"`
+	FailMessage    string `json:"fail_message"`
+	Extractor      string `json:"extractor"`
+	VideoID        string `json:"video_id"`
+}
+
+// registryRecord is a file registry row as exported for auditing
+type registryRecord struct {
+	ID            int     `json:"id"`
+	Filename      string  `json:"filename"`
+	FilePath      string  `json:"file_path"`
+	Hash          string  `json:"hash"`
+	HashAlgorithm string  `json:"hash_algorithm"`
+	RegisteredAt  string  `json:"registered_at"` // RFC 3339
+	KnownURL      string  `json:"known_url"`
+	Extractor     string  `json:"extractor"`
+	VideoID       string  `json:"video_id"`
+	Title         string  `json:"title"`
+	Artist        string  `json:"artist"`
+	Duration      float64 `json:"duration"` // Seconds, 0 if unknown
+}
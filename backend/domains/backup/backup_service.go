@@ -0,0 +1,312 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"videoarchiver/backend/domains/config"
+	"videoarchiver/backend/domains/db"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/settings"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+type BackupService struct {
+	db                  *sql.DB
+	configService       *config.ConfigService
+	settingsService     *settings.SettingsService
+	downloadDB          *download.DownloadDB
+	fileRegistryService *fileregistry.FileRegistryService
+	logService          LogServiceInterface
+}
+
+func NewBackupService(
+	dbService *db.DatabaseService,
+	configService *config.ConfigService,
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	fileRegistryService *fileregistry.FileRegistryService,
+	logService LogServiceInterface,
+) *BackupService {
+	return &BackupService{
+		db:                  dbService.GetDB(),
+		configService:       configService,
+		settingsService:     settingsService,
+		downloadDB:          downloadDB,
+		fileRegistryService: fileRegistryService,
+		logService:          logService,
+	}
+}
+
+// GetBackupDirectory returns the configured backup directory,
+// or a backups directory next to the database if none is configured
+func (b *BackupService) GetBackupDirectory() (string, error) {
+	directory, err := b.settingsService.GetSettingString("backup_directory")
+	if err != nil {
+		return "", fmt.Errorf("failed to get backup_directory setting: %w", err)
+	}
+	if strings.TrimSpace(directory) != "" {
+		return directory, nil
+	}
+	dbPath, err := b.configService.GetDatabasePath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get database path: %w", err)
+	}
+	return filepath.Join(filepath.Dir(dbPath), "backups"), nil
+}
+
+// IsBackupDue returns true if scheduled backups are enabled and the newest backup is older than the interval
+func (b *BackupService) IsBackupDue() (bool, error) {
+	enabled, err := b.settingsService.GetSettingBool("backup_enabled")
+	if err != nil {
+		return false, fmt.Errorf("failed to get backup_enabled setting: %w", err)
+	}
+	if !enabled {
+		return false, nil
+	}
+	intervalHours, err := b.getIntSetting("backup_interval_hours", 1)
+	if err != nil {
+		return false, err
+	}
+
+	backups, err := b.GetBackups()
+	if err != nil {
+		return false, err
+	}
+	if len(backups) == 0 {
+		return true, nil
+	}
+	newest := time.Unix(backups[0].CreatedAt, 0)
+	return time.Since(newest) >= time.Duration(intervalHours)*time.Hour, nil
+}
+
+// CreateBackup takes an online backup of the database with VACUUM INTO, verifies it
+// and removes the oldest backups beyond the configured number to keep
+func (b *BackupService) CreateBackup(ctx context.Context) (*BackupFile, error) {
+	directory, err := b.GetBackupDirectory()
+	if err != nil {
+		return nil, err
+	}
+	keepCount, err := b.getIntSetting("backup_keep_count", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	backup, err := createBackup(ctx, b.db, directory, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	b.logService.Info(fmt.Sprintf("Created database backup %s (%d bytes)", backup.Path, backup.Size))
+
+	removed, err := rotateBackups(directory, keepCount)
+	if err != nil {
+		b.logService.Warn(fmt.Sprintf("Failed to remove old database backups: %v", err))
+	}
+	for _, path := range removed {
+		b.logService.Info(fmt.Sprintf("Removed old database backup %s", path))
+	}
+	return backup, nil
+}
+
+// GetBackups lists the backups in the backup directory, newest first
+func (b *BackupService) GetBackups() ([]BackupFile, error) {
+	directory, err := b.GetBackupDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return listBackups(directory)
+}
+
+// RestoreDatabase replaces the database file with a verified backup.
+// The current database is kept next to it with a .before-restore suffix, which is returned.
+// Must only be called while no process has the database open.
+func RestoreDatabase(backupPath, dbPath string) (string, error) {
+	if err := verifyDatabase(backupPath); err != nil {
+		return "", fmt.Errorf("backup %s cannot be restored: %w", backupPath, err)
+	}
+
+	restoringPath := dbPath + ".restoring"
+	if err := copyFile(backupPath, restoringPath); err != nil {
+		os.Remove(restoringPath)
+		return "", fmt.Errorf("failed to copy backup: %w", err)
+	}
+
+	// Journal files belong to the current database, a leftover journal would be applied to the restored one
+	keptPath := dbPath + ".before-restore-" + time.Now().UTC().Format(backupTimeLayout)
+	var moved []string
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			continue
+		}
+		if err := os.Rename(dbPath+suffix, keptPath+suffix); err != nil {
+			for _, movedSuffix := range moved {
+				os.Rename(keptPath+movedSuffix, dbPath+movedSuffix)
+			}
+			os.Remove(restoringPath)
+			return "", fmt.Errorf("failed to move current database aside: %w", err)
+		}
+		moved = append(moved, suffix)
+	}
+
+	if err := os.Rename(restoringPath, dbPath); err != nil {
+		return "", fmt.Errorf("failed to restore backup, the previous database is at %s: %w", keptPath, err)
+	}
+	return keptPath, nil
+}
+
+func createBackup(ctx context.Context, database *sql.DB, directory string, now time.Time) (*BackupFile, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := backupPrefix + now.UTC().Format(backupTimeLayout) + backupSuffix
+	path := filepath.Join(directory, name)
+	// VACUUM INTO refuses to overwrite, and a partial file must never look like a finished backup
+	partialPath := path + ".partial"
+	os.Remove(partialPath)
+
+	if _, err := database.ExecContext(ctx, "VACUUM INTO ?", partialPath); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to back up database: %w", err)
+	}
+	if err := verifyDatabase(partialPath); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("database backup failed verification: %w", err)
+	}
+	if err := os.Rename(partialPath, path); err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to finish database backup: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupFile{Name: name, Path: path, Size: info.Size(), CreatedAt: now.Unix()}, nil
+}
+
+// verifyDatabase checks that a file is an intact videoarchiver database
+func verifyDatabase(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	database, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	var result string
+	if err := database.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("database is damaged: %s", result)
+	}
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM settings").Scan(&count); err != nil {
+		return fmt.Errorf("not a videoarchiver database: %w", err)
+	}
+	return nil
+}
+
+// listBackups returns the finished backups in a directory, newest first
+func listBackups(directory string) ([]BackupFile, error) {
+	entries, err := os.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return []BackupFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	backups := make([]BackupFile, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		createdAt, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupFile{
+			Name:      name,
+			Path:      filepath.Join(directory, name),
+			Size:      info.Size(),
+			CreatedAt: createdAt.Unix(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt > backups[j].CreatedAt })
+	return backups, nil
+}
+
+// rotateBackups removes all but the newest keepCount backups, returning the removed paths
+func rotateBackups(directory string, keepCount int) ([]string, error) {
+	backups, err := listBackups(directory)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for i := keepCount; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backups[i].Path)
+	}
+	return removed, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (b *BackupService) getIntSetting(key string, minimum int) (int, error) {
+	value, err := b.settingsService.GetSettingString(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s setting: %w", key, err)
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || parsed < minimum {
+		return 0, fmt.Errorf("invalid %s setting: %s", key, value)
+	}
+	return parsed, nil
+}
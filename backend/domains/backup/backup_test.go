@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTestDatabase(t *testing.T, path, value string) *sql.DB {
+	t.Helper()
+	database, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	_, err = database.Exec(`CREATE TABLE settings (setting_key TEXT PRIMARY KEY, setting_value TEXT);
+		INSERT INTO settings VALUES ('marker', ?)`, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func readMarker(t *testing.T, path string) string {
+	t.Helper()
+	database, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	var value string
+	if err := database.QueryRow("SELECT setting_value FROM settings WHERE setting_key = 'marker'").Scan(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestCreateAndRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	database := createTestDatabase(t, filepath.Join(dir, "db.sqlite"), "live")
+	backupDir := filepath.Join(dir, "backups")
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if _, err := createBackup(context.Background(), database, backupDir, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// Unfinished and unrelated files are never listed or rotated
+	os.WriteFile(filepath.Join(backupDir, "db-20260101T150000Z.sqlite.partial"), nil, 0644)
+	os.WriteFile(filepath.Join(backupDir, "notes.txt"), nil, 0644)
+
+	removed, err := rotateBackups(backupDir, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 backups removed, got %v", removed)
+	}
+
+	backups, err := listBackups(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Name != "db-20260101T150000Z.sqlite" || backups[1].Name != "db-20260101T140000Z.sqlite" {
+		t.Fatalf("Expected the two newest backups, got %+v", backups)
+	}
+	if readMarker(t, backups[0].Path) != "live" {
+		t.Error("Expected the backup to contain the database content")
+	}
+}
+
+func TestRestoreDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "db.sqlite")
+	backupPath := filepath.Join(dir, "backup.sqlite")
+	createTestDatabase(t, backupPath, "backup").Close()
+	createTestDatabase(t, dbPath, "current").Close()
+	// A leftover journal of the current database must not be applied to the restored one
+	os.WriteFile(dbPath+"-journal", []byte("journal"), 0644)
+
+	keptPath, err := RestoreDatabase(backupPath, dbPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if readMarker(t, dbPath) != "backup" {
+		t.Error("Expected the database to be replaced by the backup")
+	}
+	if _, err := os.Stat(keptPath + "-journal"); err != nil {
+		t.Error("Expected the journal to be kept with the previous database")
+	}
+	if readMarker(t, keptPath) != "current" {
+		t.Error("Expected the previous database to be kept")
+	}
+
+	notADatabase := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notADatabase, []byte(strings.Repeat("not a database ", 100)), 0644)
+	if _, err := RestoreDatabase(notADatabase, dbPath); err == nil {
+		t.Error("Expected an invalid backup to be rejected")
+	}
+	if readMarker(t, dbPath) != "backup" {
+		t.Error("Expected a rejected restore to leave the database alone")
+	}
+}
+
+func TestWriteRecordsCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.csv")
+	record := historyRecord{ID: 3, URL: "https://example.com/v", Status: "failed_give_up", FailMessage: "HTTP 403, \"Forbidden\"", AttemptCount: 5}
+	if err := writeRecords(path, []historyRecord{record}, historyColumns, [][]string{record.values()}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join(historyColumns, ",") + "\n" +
+		`3,0,https://example.com/v,failed_give_up,,,,,,,5,"HTTP 403, ""Forbidden""",,` + "\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected CSV:\n%s", data)
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"videoarchiver/backend/domains/overrides"
 	"videoarchiver/backend/domains/pathing"
 )
 
 // Config represents the application configuration
 type Config struct {
-	DatabasePath string `json:"database_path"`
+	DatabasePath      string `json:"database_path"`
+	ManagedConfigPath string `json:"managed_config_path"` // Optional declarative YAML or TOML file the daemon reconciles against
 }
 
 // ConfigService handles loading and saving configuration
@@ -22,7 +25,7 @@ type ConfigService struct {
 
 // NewConfigService creates a new configuration service
 func NewConfigService() (*ConfigService, error) {
-	configPath, err := pathing.GetWorkingFile("config.json")
+	configPath, err := pathing.GetFile(pathing.DirConfig, "config.json")
 	if err != nil {
 		return nil, err
 	}
@@ -36,6 +39,10 @@ func NewConfigService() (*ConfigService, error) {
 		return nil, err
 	}
 
+	if err := service.validateFlagOverrides(); err != nil {
+		return nil, err
+	}
+
 	return service, nil
 }
 
@@ -46,17 +53,64 @@ func (c *ConfigService) GetConfig() *Config {
 
 // GetDatabasePath returns the configured database path
 func (c *ConfigService) GetDatabasePath() (string, error) {
-	if c.config.DatabasePath == "" {
+	databasePath := c.getValue("database_path", c.config.DatabasePath)
+	if databasePath == "" {
 		// Return default path if not configured
 		return getDefaultDatabasePath()
 	}
 
 	// If the configured path is relative, make it absolute using GetWorkingFile
-	if !filepath.IsAbs(c.config.DatabasePath) {
-		return pathing.GetWorkingFile(c.config.DatabasePath)
+	if !filepath.IsAbs(databasePath) {
+		return pathing.GetWorkingFile(databasePath)
 	}
 
-	return c.config.DatabasePath, nil
+	return databasePath, nil
+}
+
+// GetManagedConfigPath returns the declarative config file path, empty if none is configured
+func (c *ConfigService) GetManagedConfigPath() (string, error) {
+	managedConfigPath := c.getValue("managed_config_path", c.config.ManagedConfigPath)
+	if managedConfigPath == "" || filepath.IsAbs(managedConfigPath) {
+		return managedConfigPath, nil
+	}
+	return pathing.GetWorkingFile(managedConfigPath)
+}
+
+// GetConfigOverride returns the flag or environment variable overriding a config field, if any
+func (c *ConfigService) GetConfigOverride(key string) (overrides.Override, bool) {
+	return overrides.LookupConfig(key)
+}
+
+// getValue applies overrides to a value from config.json: flag > environment > config.json
+func (c *ConfigService) getValue(key, stored string) string {
+	if override, ok := overrides.LookupConfig(key); ok {
+		return override.Value
+	}
+	return stored
+}
+
+// validateFlagOverrides rejects --config flags for fields config.json does not have
+func (c *ConfigService) validateFlagOverrides() error {
+	keys := make([]string, 0, len(overrides.GetConfigFlags()))
+	for key := range overrides.GetConfigFlags() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	configType := reflect.TypeOf(c.config).Elem()
+	for _, key := range keys {
+		found := false
+		for i := 0; i < configType.NumField(); i++ {
+			if configType.Field(i).Tag.Get("json") == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown config field in --config flag: %s", key)
+		}
+	}
+	return nil
 }
 
 // loadConfig loads configuration from file or creates default if it doesn't exist
@@ -139,6 +193,9 @@ func (c *ConfigService) GetConfigString(key string) (string, error) {
 			if !fieldValue.IsValid() {
 				return "", fmt.Errorf("field %s not found", key)
 			}
+			if override, ok := overrides.LookupConfig(key); ok {
+				return override.Value, nil
+			}
 			return fmt.Sprintf("%v", fieldValue.Interface()), nil
 		}
 	}
@@ -148,6 +205,10 @@ func (c *ConfigService) GetConfigString(key string) (string, error) {
 
 // SetConfigString sets a config field value from a string
 func (c *ConfigService) SetConfigString(key string, value string) error {
+	if override, ok := overrides.LookupConfig(key); ok {
+		return fmt.Errorf("config field %s is overridden by %s", key, override.Source)
+	}
+
 	// Use reflection to set field value in config struct
 	configValue := reflect.ValueOf(c.config).Elem()
 	configType := reflect.TypeOf(c.config).Elem()
@@ -3,6 +3,8 @@ package config
 import (
 	"path/filepath"
 	"testing"
+	"videoarchiver/backend/domains/overrides"
+	"videoarchiver/backend/domains/pathing"
 )
 
 func TestConfigServiceBasic(t *testing.T) {
@@ -32,3 +34,46 @@ func TestConfigServiceBasic(t *testing.T) {
 		t.Errorf("Expected database path to end with 'db.sqlite', got: %s", filepath.Base(dbPath))
 	}
 }
+
+func TestConfigServiceOverrides(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv(pathing.DataDirEnv, dataDir)
+
+	configSvc, err := NewConfigService()
+	if err != nil {
+		t.Fatalf("Failed to create config service: %v", err)
+	}
+
+	// The relocated working directory holds config.json and the default database
+	dbPath, err := configSvc.GetDatabasePath()
+	if err != nil {
+		t.Fatalf("Failed to get database path: %v", err)
+	}
+	if dbPath != filepath.Join(dataDir, "db.sqlite") {
+		t.Errorf("Expected database in the data directory, got: %s", dbPath)
+	}
+
+	// Environment overrides config.json, flags override the environment
+	t.Setenv(overrides.EnvName("database_path"), "env.sqlite")
+	if dbPath, _ := configSvc.GetDatabasePath(); dbPath != filepath.Join(dataDir, "env.sqlite") {
+		t.Errorf("Expected environment override, got: %s", dbPath)
+	}
+	overrides.SetConfigFlags(map[string]string{"database_path": "flag.sqlite"})
+	defer overrides.SetConfigFlags(map[string]string{})
+	if value, _ := configSvc.GetConfigString("database_path"); value != "flag.sqlite" {
+		t.Errorf("Expected flag override, got: %s", value)
+	}
+
+	// Overridden fields cannot be changed, config.json keeps its value
+	if err := configSvc.SetConfigString("database_path", "other.sqlite"); err == nil {
+		t.Error("Expected an error setting an overridden field")
+	}
+	if configSvc.GetConfig().DatabasePath != filepath.Join(dataDir, "db.sqlite") {
+		t.Errorf("Expected config.json to keep its value, got: %s", configSvc.GetConfig().DatabasePath)
+	}
+
+	overrides.SetConfigFlags(map[string]string{"no_such_field": "x"})
+	if _, err := NewConfigService(); err == nil {
+		t.Error("Expected an error for an unknown --config field")
+	}
+}
@@ -0,0 +1,138 @@
+package corruptionscan
+
+import (
+	"database/sql"
+	"time"
+	"videoarchiver/backend/domains/db"
+)
+
+type CorruptionScanDB struct {
+	db *sql.DB
+}
+
+func NewCorruptionScanDB(dbService *db.DatabaseService) *CorruptionScanDB {
+	return &CorruptionScanDB{db: dbService.GetDB()}
+}
+
+// CreateScan inserts a new running scan and returns its ID
+func (c *CorruptionScanDB) CreateScan(targetKind, targetPath string, playlistId sql.NullInt64, filesTotal int) (int, error) {
+	res, err := c.db.Exec(
+		"INSERT INTO corruption_scans (target_kind, target_path, playlist_id, status, started_at, files_total) VALUES (?, ?, ?, ?, ?, ?)",
+		targetKind, targetPath, playlistId, ScanRunning, time.Now().Unix(), filesTotal,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// FinishScan stores the final status and counters of a scan
+func (c *CorruptionScanDB) FinishScan(scanId int, status string, filesChecked, corruptFound int) error {
+	_, err := c.db.Exec(
+		"UPDATE corruption_scans SET status = ?, finished_at = ?, files_checked = ?, corrupt_found = ? WHERE id = ?",
+		status, time.Now().Unix(), filesChecked, corruptFound, scanId,
+	)
+	return err
+}
+
+// InsertResult records a corrupt file found during a scan
+func (c *CorruptionScanDB) InsertResult(result *CorruptionResult) error {
+	res, err := c.db.Exec(
+		"INSERT INTO corruption_results (scan_id, file_path, download_id, detail, requeued, detected_at) VALUES (?, ?, ?, ?, ?, ?)",
+		result.ScanID, result.FilePath, result.DownloadID, result.Detail, result.Requeued, result.DetectedAt,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	result.ID = int(id)
+	return err
+}
+
+// GetScan returns a single scan by ID
+func (c *CorruptionScanDB) GetScan(scanId int) (*CorruptionScan, error) {
+	var scan CorruptionScan
+	err := c.db.QueryRow(
+		`SELECT id, target_kind, target_path, playlist_id, status, started_at, finished_at, files_total, files_checked, corrupt_found
+		 FROM corruption_scans WHERE id = ?`,
+		scanId,
+	).Scan(
+		&scan.ID, &scan.TargetKind, &scan.TargetPath, &scan.PlaylistID, &scan.Status, &scan.StartedAt,
+		&scan.FinishedAt, &scan.FilesTotal, &scan.FilesChecked, &scan.CorruptFound,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// GetRecentScans returns the most recent scans, newest first
+func (c *CorruptionScanDB) GetRecentScans(limit int) ([]CorruptionScan, error) {
+	rows, err := c.db.Query(
+		`SELECT id, target_kind, target_path, playlist_id, status, started_at, finished_at, files_total, files_checked, corrupt_found
+		 FROM corruption_scans ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scans := make([]CorruptionScan, 0)
+	for rows.Next() {
+		var scan CorruptionScan
+		err := rows.Scan(
+			&scan.ID, &scan.TargetKind, &scan.TargetPath, &scan.PlaylistID, &scan.Status, &scan.StartedAt,
+			&scan.FinishedAt, &scan.FilesTotal, &scan.FilesChecked, &scan.CorruptFound,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
+
+// GetResultsForScan returns a page of corrupt files found during a scan
+func (c *CorruptionScanDB) GetResultsForScan(scanId, offset, limit int) ([]CorruptionResult, error) {
+	rows, err := c.db.Query(
+		`SELECT id, scan_id, file_path, download_id, detail, requeued, detected_at
+		 FROM corruption_results WHERE scan_id = ? ORDER BY id ASC LIMIT ? OFFSET ?`,
+		scanId, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]CorruptionResult, 0)
+	for rows.Next() {
+		var result CorruptionResult
+		err := rows.Scan(&result.ID, &result.ScanID, &result.FilePath, &result.DownloadID, &result.Detail, &result.Requeued, &result.DetectedAt)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetResult returns a single result by ID
+func (c *CorruptionScanDB) GetResult(resultId int) (*CorruptionResult, error) {
+	var result CorruptionResult
+	err := c.db.QueryRow(
+		"SELECT id, scan_id, file_path, download_id, detail, requeued, detected_at FROM corruption_results WHERE id = ?",
+		resultId,
+	).Scan(&result.ID, &result.ScanID, &result.FilePath, &result.DownloadID, &result.Detail, &result.Requeued, &result.DetectedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MarkRequeued flags a result as requeued for download
+func (c *CorruptionScanDB) MarkRequeued(resultId int) error {
+	_, err := c.db.Exec("UPDATE corruption_results SET requeued = 1 WHERE id = ?", resultId)
+	return err
+}
@@ -0,0 +1,42 @@
+package corruptionscan
+
+import "database/sql"
+
+// What a corruption scan checks
+const (
+	TargetDirectory = "directory" // Every media file under a directory
+	TargetPlaylist  = "playlist"  // The archived downloads of a playlist
+)
+
+// Statuses of a corruption scan
+const (
+	ScanRunning   = "running"
+	ScanCompleted = "completed"
+	ScanCancelled = "cancelled"
+	ScanFailed    = "failed"
+)
+
+// CorruptionScan is a single run of the bulk corruption scan
+type CorruptionScan struct {
+	ID           int           `json:"id" db:"id"`
+	TargetKind   string        `json:"target_kind" db:"target_kind"`
+	TargetPath   string        `json:"target_path" db:"target_path"`
+	PlaylistID   sql.NullInt64 `json:"playlist_id,omitempty" db:"playlist_id"`
+	Status       string        `json:"status" db:"status"`
+	StartedAt    int64         `json:"started_at" db:"started_at"`
+	FinishedAt   sql.NullInt64 `json:"finished_at,omitempty" db:"finished_at"`
+	FilesTotal   int           `json:"files_total" db:"files_total"`
+	FilesChecked int           `json:"files_checked" db:"files_checked"`
+	CorruptFound int           `json:"corrupt_found" db:"corrupt_found"`
+}
+
+// CorruptionResult is a corrupt file found during a scan
+type CorruptionResult struct {
+	ID         int           `json:"id" db:"id"`
+	ScanID     int           `json:"scan_id" db:"scan_id"`
+	FilePath   string        `json:"file_path" db:"file_path"`
+	DownloadID sql.NullInt64 `json:"download_id,omitempty" db:"download_id"` // Set if the file is an archived download
+	Detail     string        `json:"detail" db:"detail"`
+	Requeued   bool          `json:"requeued" db:"requeued"`
+	DetectedAt int64         `json:"detected_at" db:"detected_at"`
+}
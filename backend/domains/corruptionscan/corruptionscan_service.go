@@ -0,0 +1,346 @@
+package corruptionscan
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// ProgressCallback defines the signature for progress reporting callbacks
+type ProgressCallback func(percent int, message string)
+
+const (
+	// Bounds of the corruption_scan_concurrency setting, every check runs its own ffmpeg process
+	minConcurrency = 1
+	maxConcurrency = 16
+)
+
+type CorruptionScanService struct {
+	scanDB          *CorruptionScanDB
+	settingsService *settings.SettingsService
+	downloadDB      *download.DownloadDB
+	downloadService *download.DownloadService
+	logService      LogServiceInterface
+
+	// Checks a single file without modifying it, returns an error describing the corruption
+	checkFile func(filePath string) error
+}
+
+func NewCorruptionScanService(
+	scanDB *CorruptionScanDB,
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	downloadService *download.DownloadService,
+	logService LogServiceInterface,
+) *CorruptionScanService {
+	s := &CorruptionScanService{
+		scanDB:          scanDB,
+		settingsService: settingsService,
+		downloadDB:      downloadDB,
+		downloadService: downloadService,
+		logService:      logService,
+	}
+	s.checkFile = s.detectCorruption
+	return s
+}
+
+// detectCorruption checks a file with the configured tolerated classes, without repairing it
+func (s *CorruptionScanService) detectCorruption(filePath string) error {
+	config, err := download.LoadCorruptionCheckConfig(s.settingsService)
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Invalid corruption check settings: %v", err))
+	}
+	config.RepairStrategies = nil
+
+	report, err := download.InspectFile(filePath, config)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// scanTarget is a single file to check
+type scanTarget struct {
+	path       string
+	downloadId sql.NullInt64
+}
+
+// checkResult is the outcome of checking a single file
+type checkResult struct {
+	target  scanTarget
+	err     error
+	missing bool
+}
+
+// ScanDirectory checks every media file under a directory for corruption.
+// Files that are archived downloads can be requeued from the results.
+func (s *CorruptionScanService) ScanDirectory(ctx context.Context, directoryPath string, progressCallback ProgressCallback) (*CorruptionScan, error) {
+	directoryPath = strings.TrimSpace(directoryPath)
+	if directoryPath == "" {
+		return nil, fmt.Errorf("directory path is empty")
+	}
+	if info, err := os.Stat(directoryPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("directory does not exist: %s", directoryPath)
+	}
+	if progressCallback != nil {
+		progressCallback(0, "Collecting media files...")
+	}
+
+	downloadIds, err := s.getDownloadIdsByPath()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]scanTarget, 0)
+	err = filepath.Walk(directoryPath, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			s.logService.Warn(fmt.Sprintf("Error accessing path %s: %v", path, err))
+			return nil // Continue walking, don't fail on individual file errors
+		}
+		if !info.IsDir() && fileutils.IsMediaFile(path) {
+			target := scanTarget{path: path}
+			if id, ok := downloadIds[filepath.Clean(path)]; ok {
+				target.downloadId = sql.NullInt64{Int64: int64(id), Valid: true}
+			}
+			targets = append(targets, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.runScan(ctx, TargetDirectory, directoryPath, sql.NullInt64{}, targets, progressCallback)
+}
+
+// ScanPlaylist checks the archived downloads of a playlist for corruption
+func (s *CorruptionScanService) ScanPlaylist(ctx context.Context, playlistId int, progressCallback ProgressCallback) (*CorruptionScan, error) {
+	if progressCallback != nil {
+		progressCallback(0, "Collecting archived files...")
+	}
+
+	downloads, err := s.downloadDB.GetSuccessfulDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get successful downloads: %w", err)
+	}
+	targets := make([]scanTarget, 0)
+	targetPath := ""
+	for _, dl := range downloads {
+		if dl.PlaylistID != playlistId || !dl.FullPath.Valid {
+			continue
+		}
+		targetPath = dl.SaveDirectory.String
+		targets = append(targets, scanTarget{
+			path:       dl.FullPath.String,
+			downloadId: sql.NullInt64{Int64: int64(dl.ID), Valid: true},
+		})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("playlist %d has no archived files", playlistId)
+	}
+
+	return s.runScan(ctx, TargetPlaylist, targetPath, sql.NullInt64{Int64: int64(playlistId), Valid: true}, targets, progressCallback)
+}
+
+// RequeueResult removes a corrupt archived file and marks its download for manual retry
+func (s *CorruptionScanService) RequeueResult(resultId int) error {
+	result, err := s.scanDB.GetResult(resultId)
+	if err != nil {
+		return fmt.Errorf("failed to get corruption result: %w", err)
+	}
+	if !result.DownloadID.Valid {
+		return fmt.Errorf("only archived downloads can be requeued")
+	}
+
+	// Remove the broken file so the download is not stored next to it under a new name
+	if err := os.Remove(result.FilePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file before requeue: %w", err)
+	}
+
+	if err := s.downloadService.SetManualRetry(int(result.DownloadID.Int64)); err != nil {
+		return err
+	}
+	return s.scanDB.MarkRequeued(resultId)
+}
+
+// GetRecentScans returns the most recent corruption scans
+func (s *CorruptionScanService) GetRecentScans(limit int) ([]CorruptionScan, error) {
+	return s.scanDB.GetRecentScans(limit)
+}
+
+// GetResultsForScan returns a page of corrupt files found during a scan
+func (s *CorruptionScanService) GetResultsForScan(scanId, offset, limit int) ([]CorruptionResult, error) {
+	return s.scanDB.GetResultsForScan(scanId, offset, limit)
+}
+
+// runScan checks the targets with a bounded number of ffmpeg processes and records corrupt files.
+// Cancelling the context lets running checks finish and stores the scan as cancelled.
+func (s *CorruptionScanService) runScan(
+	ctx context.Context,
+	targetKind string,
+	targetPath string,
+	playlistId sql.NullInt64,
+	targets []scanTarget,
+	progressCallback ProgressCallback,
+) (*CorruptionScan, error) {
+	// Without ffmpeg every file would be reported as corrupt
+	if _, err := ytdlp.GetFfmpegPath(); err != nil {
+		return nil, fmt.Errorf("failed to get ffmpeg path: %w", err)
+	}
+
+	scanId, err := s.scanDB.CreateScan(targetKind, targetPath, playlistId, len(targets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create corruption scan: %w", err)
+	}
+	concurrency := s.getConcurrency()
+	s.logService.Info(fmt.Sprintf("Starting corruption scan %d of %s: %d files, %d at a time", scanId, targetPath, len(targets), concurrency))
+
+	filesChecked := 0
+	corruptFound := 0
+	for result := range s.checkTargets(ctx, targets, concurrency) {
+		filesChecked++
+		if progressCallback != nil {
+			progressPercent := int(float64(filesChecked) / float64(len(targets)) * 100)
+			progressCallback(progressPercent, fmt.Sprintf("Checked file %d of %d: %s", filesChecked, len(targets), filepath.Base(result.target.path)))
+		}
+
+		if result.missing {
+			s.logService.Warn(fmt.Sprintf("Skipping missing file during corruption scan: %s", result.target.path))
+			continue
+		}
+		if result.err == nil {
+			continue
+		}
+
+		corruptFound++
+		s.logService.Warn(fmt.Sprintf("Corrupt file found: %s: %v", result.target.path, result.err))
+		corruptResult := &CorruptionResult{
+			ScanID:     scanId,
+			FilePath:   result.target.path,
+			DownloadID: result.target.downloadId,
+			Detail:     result.err.Error(),
+			DetectedAt: time.Now().Unix(),
+		}
+		if err := s.scanDB.InsertResult(corruptResult); err != nil {
+			s.logService.Error(fmt.Sprintf("Failed to record corrupt file %s: %v", result.target.path, err))
+		}
+	}
+
+	status := ScanCompleted
+	if ctx.Err() != nil {
+		status = ScanCancelled
+	}
+	if err := s.scanDB.FinishScan(scanId, status, filesChecked, corruptFound); err != nil {
+		return nil, fmt.Errorf("failed to finish corruption scan: %w", err)
+	}
+	s.logService.Info(fmt.Sprintf("Corruption scan %d %s: %d files checked, %d corrupt", scanId, status, filesChecked, corruptFound))
+
+	scan, err := s.scanDB.GetScan(scanId)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return scan, ctx.Err()
+	}
+	if progressCallback != nil {
+		progressCallback(100, fmt.Sprintf("Corruption scan completed: %d files checked, %d corrupt", filesChecked, corruptFound))
+	}
+	return scan, nil
+}
+
+// checkTargets checks files with a bounded number of workers.
+// The returned channel is closed once all files are checked or the context is cancelled.
+func (s *CorruptionScanService) checkTargets(ctx context.Context, targets []scanTarget, concurrency int) <-chan checkResult {
+	jobs := make(chan scanTarget)
+	results := make(chan checkResult, concurrency)
+
+	go func() {
+		defer close(jobs)
+		for _, target := range targets {
+			select {
+			case jobs <- target:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				if _, err := os.Stat(target.path); err != nil {
+					results <- checkResult{target: target, missing: true}
+					continue
+				}
+				results <- checkResult{target: target, err: s.checkFile(target.path)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// getDownloadIdsByPath maps the file path of every archived download to its ID
+func (s *CorruptionScanService) getDownloadIdsByPath() (map[string]int, error) {
+	downloads, err := s.downloadDB.GetSuccessfulDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get successful downloads: %w", err)
+	}
+	downloadIds := make(map[string]int, len(downloads))
+	for _, dl := range downloads {
+		if dl.FullPath.Valid {
+			downloadIds[filepath.Clean(dl.FullPath.String)] = dl.ID
+		}
+	}
+	return downloadIds, nil
+}
+
+// getConcurrency reads corruption_scan_concurrency, clamped to a sane range
+func (s *CorruptionScanService) getConcurrency() int {
+	concurrencyStr, err := s.settingsService.GetSettingString("corruption_scan_concurrency")
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Failed to get corruption_scan_concurrency setting: %v", err))
+		return minConcurrency
+	}
+	concurrency, err := strconv.Atoi(concurrencyStr)
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Invalid corruption_scan_concurrency setting: %s", concurrencyStr))
+		return minConcurrency
+	}
+	if concurrency < minConcurrency {
+		return minConcurrency
+	}
+	if concurrency > maxConcurrency {
+		return maxConcurrency
+	}
+	return concurrency
+}
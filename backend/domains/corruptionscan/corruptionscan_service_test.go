@@ -0,0 +1,91 @@
+package corruptionscan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckTargetsBoundsConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	targets := make([]scanTarget, 0)
+	for _, name := range []string{"a.mp4", "b.mp4", "c.mp4", "d.mp4", "broken.mp4"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, scanTarget{path: path})
+	}
+	targets = append(targets, scanTarget{path: filepath.Join(dir, "missing.mp4")})
+
+	var running, maxRunning int32
+	service := &CorruptionScanService{
+		checkFile: func(filePath string) error {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				observed := atomic.LoadInt32(&maxRunning)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			if filepath.Base(filePath) == "broken.mp4" {
+				return errors.New("corrupt")
+			}
+			return nil
+		},
+	}
+
+	checked, corrupt, missing := 0, 0, 0
+	for result := range service.checkTargets(context.Background(), targets, 2) {
+		checked++
+		if result.missing {
+			missing++
+		} else if result.err != nil {
+			corrupt++
+		}
+	}
+
+	if checked != len(targets) {
+		t.Errorf("Expected %d results, got %d", len(targets), checked)
+	}
+	if corrupt != 1 || missing != 1 {
+		t.Errorf("Expected 1 corrupt and 1 missing file, got %d and %d", corrupt, missing)
+	}
+	if maxRunning > 2 {
+		t.Errorf("Expected at most 2 concurrent checks, got %d", maxRunning)
+	}
+}
+
+func TestCheckTargetsStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.mp4")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targets := make([]scanTarget, 100)
+	for i := range targets {
+		targets[i] = scanTarget{path: path}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &CorruptionScanService{
+		checkFile: func(filePath string) error {
+			cancel()
+			return nil
+		},
+	}
+
+	checked := 0
+	for range service.checkTargets(ctx, targets, 1) {
+		checked++
+	}
+	if checked >= len(targets) {
+		t.Errorf("Expected the scan to stop early, checked %d files", checked)
+	}
+}
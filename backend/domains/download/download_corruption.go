@@ -0,0 +1,519 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"videoarchiver/backend/domains/runner"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+// Message shown for corrupt downloads, the most common cause is missing cookies
+const corruptionMessage = "file was corrupted. possibly soundcloud premium content but cookies not set up."
+
+// Kinds of problems ffmpeg reports while decoding a file
+const (
+	ClassTimestamps = "timestamps" // Non-monotonic or missing timestamps, usually repairable
+	ClassDecode     = "decode"     // Damaged frames or packets
+	ClassTruncated  = "truncated"  // File ends early
+	ClassIO         = "io"         // Read errors
+	ClassUnreadable = "unreadable" // File cannot be opened as media at all
+	ClassUnknown    = "unknown"    // Any other error ffmpeg reports
+)
+
+// How much an issue counts towards a file being corrupt
+const (
+	SeverityTolerated = "tolerated" // Listed in corruption_tolerated_classes
+	SeverityWarning   = "warning"   // Only corrupt when a configured repair fails
+	SeverityError     = "error"
+	SeverityFatal     = "fatal"
+)
+
+// Defaults of the corruption check settings
+const (
+	defaultRepairStrategies  = "remux,reencode_cfr"
+	defaultMaxRepairAttempts = 2
+)
+
+// CorruptionIssue is a single problem reported by ffmpeg
+type CorruptionIssue struct {
+	Class     string  `json:"class"`
+	Severity  string  `json:"severity"`
+	Stream    int     `json:"stream"`    // -1 if ffmpeg did not name a stream
+	Timestamp float64 `json:"timestamp"` // Decode position in seconds, -1 if unknown
+	Component string  `json:"component"` // Demuxer or decoder that reported it, such as h264
+	Message   string  `json:"message"`
+}
+
+// RepairAttempt records the outcome of a single repair strategy
+type RepairAttempt struct {
+	Strategy string `json:"strategy"`
+	Success  bool   `json:"success"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// CorruptionReport is the result of checking a file with ffmpeg
+type CorruptionReport struct {
+	FilePath       string            `json:"file_path"`
+	ExitCode       int               `json:"exit_code"`
+	Issues         []CorruptionIssue `json:"issues"`
+	Corrupt        bool              `json:"corrupt"`
+	RepairAttempts []RepairAttempt   `json:"repair_attempts"`
+	RepairedWith   string            `json:"repaired_with,omitempty"` // Strategy that replaced the file, if any
+}
+
+// Err returns an error describing the corruption, or nil if the file is intact
+func (r *CorruptionReport) Err() error {
+	if !r.Corrupt {
+		return nil
+	}
+	summary := r.Summary()
+	if summary == "" {
+		return errors.New(corruptionMessage)
+	}
+	return fmt.Errorf("%s (%s)", corruptionMessage, summary)
+}
+
+// Summary describes the most severe issue and how many others were found
+func (r *CorruptionReport) Summary() string {
+	worst := -1
+	for i, issue := range r.Issues {
+		if worst == -1 || severityRank(issue.Severity) > severityRank(r.Issues[worst].Severity) {
+			worst = i
+		}
+	}
+	if worst == -1 {
+		return ""
+	}
+
+	issue := r.Issues[worst]
+	summary := issue.Class
+	if issue.Stream >= 0 {
+		summary += fmt.Sprintf(" in stream %d", issue.Stream)
+	}
+	if issue.Timestamp >= 0 {
+		summary += fmt.Sprintf(" at %.2fs", issue.Timestamp)
+	}
+	summary += ": " + issue.Message
+	if len(r.Issues) > 1 {
+		summary += fmt.Sprintf(" (+%d more)", len(r.Issues)-1)
+	}
+	return summary
+}
+
+// hasSeverity reports whether any issue is at least as severe as the given severity
+func (r *CorruptionReport) hasSeverity(severity string) bool {
+	for _, issue := range r.Issues {
+		if severityRank(issue.Severity) >= severityRank(severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// unresolvedClasses returns the classes of issues that are not tolerated
+func (r *CorruptionReport) unresolvedClasses() map[string]bool {
+	classes := make(map[string]bool)
+	for _, issue := range r.Issues {
+		if issue.Severity != SeverityTolerated {
+			classes[issue.Class] = true
+		}
+	}
+	return classes
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityWarning:
+		return 1
+	case SeverityError:
+		return 2
+	case SeverityFatal:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// RepairStrategy rewrites a file to fix the issues of some classes.
+// Strategies are tried in the configured order and the first output that checks clean replaces the file.
+type RepairStrategy interface {
+	Name() string
+	Repairs(class string) bool
+	Repair(ffmpegPath, inputPath, outputPath string) error
+}
+
+// remuxRepair copies the streams into a new container with regenerated timestamps, without re-encoding
+type remuxRepair struct{}
+
+func (remuxRepair) Name() string { return "remux" }
+
+func (remuxRepair) Repairs(class string) bool { return class == ClassTimestamps }
+
+func (remuxRepair) Repair(ffmpegPath, inputPath, outputPath string) error {
+	return runRepair(ffmpegPath, "-v", "error", "-fflags", "+genpts", "-i", inputPath, "-map", "0", "-c", "copy", "-y", outputPath)
+}
+
+// cfrReencodeRepair re-encodes the file with a constant frame rate, slow but fixes broken video timestamps
+type cfrReencodeRepair struct{}
+
+func (cfrReencodeRepair) Name() string { return "reencode_cfr" }
+
+func (cfrReencodeRepair) Repairs(class string) bool { return class == ClassTimestamps }
+
+func (cfrReencodeRepair) Repair(ffmpegPath, inputPath, outputPath string) error {
+	return runRepair(ffmpegPath, "-v", "error", "-i", inputPath, "-vsync", "cfr", "-y", outputPath)
+}
+
+func runRepair(ffmpegPath string, args ...string) error {
+	output, err := runner.RunCombinedOutput(ffmpegPath, args...)
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// repairStrategies holds every strategy that can be named in corruption_repair_strategies
+var repairStrategies = map[string]RepairStrategy{
+	"remux":        remuxRepair{},
+	"reencode_cfr": cfrReencodeRepair{},
+}
+
+// RegisterRepairStrategy makes a strategy available to corruption_repair_strategies
+func RegisterRepairStrategy(strategy RepairStrategy) {
+	repairStrategies[strategy.Name()] = strategy
+}
+
+// CorruptionCheckConfig controls what counts as corruption and how it is repaired
+type CorruptionCheckConfig struct {
+	ToleratedClasses  []string         // Issues of these classes never make a file corrupt
+	RepairStrategies  []RepairStrategy // Tried in order, empty to never modify the file
+	MaxRepairAttempts int              // Upper bound on repair runs per check
+}
+
+// DefaultCorruptionCheckConfig returns the configuration used when settings are not available
+func DefaultCorruptionCheckConfig() CorruptionCheckConfig {
+	strategies, _ := parseRepairStrategies(defaultRepairStrategies)
+	return CorruptionCheckConfig{
+		RepairStrategies:  strategies,
+		MaxRepairAttempts: defaultMaxRepairAttempts,
+	}
+}
+
+// LoadCorruptionCheckConfig reads the corruption check settings.
+// Invalid values fall back to their default and are reported in the returned error.
+func LoadCorruptionCheckConfig(settingsService *settings.SettingsService) (CorruptionCheckConfig, error) {
+	config := DefaultCorruptionCheckConfig()
+	var errs []error
+
+	if tolerated, err := settingsService.GetSettingString("corruption_tolerated_classes"); err != nil {
+		errs = append(errs, fmt.Errorf("failed to get corruption_tolerated_classes setting: %w", err))
+	} else {
+		config.ToleratedClasses = splitSettingList(tolerated)
+	}
+
+	if strategyNames, err := settingsService.GetSettingString("corruption_repair_strategies"); err != nil {
+		errs = append(errs, fmt.Errorf("failed to get corruption_repair_strategies setting: %w", err))
+	} else if strategies, err := parseRepairStrategies(strategyNames); err != nil {
+		errs = append(errs, err)
+	} else {
+		config.RepairStrategies = strategies
+	}
+
+	if attemptsStr, err := settingsService.GetSettingString("corruption_max_repair_attempts"); err != nil {
+		errs = append(errs, fmt.Errorf("failed to get corruption_max_repair_attempts setting: %w", err))
+	} else if attempts, err := strconv.Atoi(strings.TrimSpace(attemptsStr)); err != nil || attempts < 0 {
+		errs = append(errs, fmt.Errorf("invalid corruption_max_repair_attempts setting: %s", attemptsStr))
+	} else {
+		config.MaxRepairAttempts = attempts
+	}
+
+	return config, errors.Join(errs...)
+}
+
+func parseRepairStrategies(value string) ([]RepairStrategy, error) {
+	strategies := make([]RepairStrategy, 0)
+	for _, name := range splitSettingList(value) {
+		strategy, ok := repairStrategies[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown corruption repair strategy: %s", name)
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
+
+// splitSettingList splits a comma separated setting into lowercase entries
+func splitSettingList(value string) []string {
+	entries := make([]string, 0)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// CheckFileCorruption checks if a downloaded file is corrupted using ffmpeg, repairing timestamp issues.
+// Returns error if file is corrupted or if ffmpeg check fails
+func CheckFileCorruption(filePath string) error {
+	report, err := InspectFile(filePath, DefaultCorruptionCheckConfig())
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// DetectFileCorruption checks a file for corruption without modifying it.
+// Timestamp issues that CheckFileCorruption repairs are not reported as corruption.
+func DetectFileCorruption(filePath string) error {
+	config := DefaultCorruptionCheckConfig()
+	config.RepairStrategies = nil
+	report, err := InspectFile(filePath, config)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// InspectFile decodes a file with ffmpeg and reports every issue found.
+// Repairable issues are fixed with the configured strategies, replacing the file with the repaired copy.
+// The returned error is only set when the check itself could not run.
+func InspectFile(filePath string, config CorruptionCheckConfig) (*CorruptionReport, error) {
+	ffmpegPath, err := ytdlp.GetFfmpegPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ffmpeg path: %w", err)
+	}
+	return inspectFile(ffmpegPath, filePath, config)
+}
+
+func inspectFile(ffmpegPath, filePath string, config CorruptionCheckConfig) (*CorruptionReport, error) {
+	report, err := runCorruptionCheck(ffmpegPath, filePath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Repairs only make sense when the repairable issues are all that is wrong
+	attempts := make([]RepairAttempt, 0)
+	if !report.hasSeverity(SeverityError) {
+		for _, strategy := range config.RepairStrategies {
+			if len(attempts) >= config.MaxRepairAttempts {
+				break
+			}
+			if !repairsAny(strategy, report.unresolvedClasses()) {
+				continue
+			}
+
+			attempt, repaired := tryRepair(ffmpegPath, filePath, strategy, config)
+			attempts = append(attempts, attempt)
+			if repaired != nil {
+				report = repaired
+				report.RepairedWith = strategy.Name()
+				break
+			}
+		}
+	}
+	report.RepairAttempts = attempts
+
+	// Warnings only count when a repair was wanted and did not get rid of them
+	report.Corrupt = report.hasSeverity(SeverityError) ||
+		(len(config.RepairStrategies) > 0 && report.hasSeverity(SeverityWarning))
+	return report, nil
+}
+
+func repairsAny(strategy RepairStrategy, classes map[string]bool) bool {
+	for class := range classes {
+		if strategy.Repairs(class) {
+			return true
+		}
+	}
+	return false
+}
+
+// tryRepair writes a repaired copy next to the file and replaces the file if the copy checks clean
+func tryRepair(ffmpegPath, filePath string, strategy RepairStrategy, config CorruptionCheckConfig) (RepairAttempt, *CorruptionReport) {
+	attempt := RepairAttempt{Strategy: strategy.Name()}
+
+	// Keep the extension so ffmpeg picks the same container
+	extension := filepath.Ext(filePath)
+	if extension == "" {
+		extension = ".mp4"
+	}
+	repairedPath := filePath + ".repair" + extension
+	defer os.Remove(repairedPath)
+
+	if err := strategy.Repair(ffmpegPath, filePath, repairedPath); err != nil {
+		attempt.Detail = err.Error()
+		return attempt, nil
+	}
+	if info, err := os.Stat(repairedPath); err != nil || info.Size() == 0 {
+		attempt.Detail = "repair produced no output"
+		return attempt, nil
+	}
+
+	repaired, err := runCorruptionCheck(ffmpegPath, repairedPath, config)
+	if err != nil {
+		attempt.Detail = err.Error()
+		return attempt, nil
+	}
+	if repaired.hasSeverity(SeverityWarning) {
+		attempt.Detail = "repaired file still has issues: " + repaired.Summary()
+		return attempt, nil
+	}
+
+	// Rename replaces the original in one step, a failure leaves the original in place
+	if err := os.Rename(repairedPath, filePath); err != nil {
+		attempt.Detail = fmt.Sprintf("failed to replace file with repaired version: %v", err)
+		return attempt, nil
+	}
+
+	attempt.Success = true
+	repaired.FilePath = filePath
+	return attempt, repaired
+}
+
+// runCorruptionCheck decodes the whole file once and classifies what ffmpeg reports
+func runCorruptionCheck(ffmpegPath, filePath string, config CorruptionCheckConfig) (*CorruptionReport, error) {
+	// Progress goes to stderr as well so every error line can be placed at the last reported position
+	output, err := runner.RunCombinedOutput(ffmpegPath, "-nostdin", "-v", "error", "-progress", "pipe:2", "-i", filePath, "-f", "null", "-")
+
+	report := &CorruptionReport{
+		FilePath: filePath,
+		Issues:   parseFfmpegIssues(string(output), config.ToleratedClasses),
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to run ffmpeg: %w", err)
+		}
+		report.ExitCode = exitErr.ExitCode()
+
+		// ffmpeg gave up on the file, which is never tolerated
+		report.Issues = append(report.Issues, CorruptionIssue{
+			Class:     ClassUnreadable,
+			Severity:  SeverityFatal,
+			Stream:    -1,
+			Timestamp: -1,
+			Message:   fmt.Sprintf("ffmpeg exited with code %d", report.ExitCode),
+		})
+	}
+	return report, nil
+}
+
+// corruptionRule assigns a class to ffmpeg messages containing any of its patterns
+type corruptionRule struct {
+	class    string
+	severity string
+	patterns []string
+}
+
+// Checked in order, decoding errors come before unreadable since they share "invalid data found"
+var corruptionRules = []corruptionRule{
+	{ClassTimestamps, SeverityWarning, []string{
+		"non monotonically increasing dts", "non monotonous dts", "invalid dts", "invalid pts",
+		"pts has no value", "timestamps are unset",
+	}},
+	{ClassDecode, SeverityError, []string{
+		"error while decoding", "decode_slice_header error", "concealing", "packet corrupt",
+		"corrupt decoded frame", "corrupt input packet", "invalid nal unit", "header missing",
+		"no frame!", "error decoding", "damaged", "missing picture", "error submitting packet to decoder",
+		"invalid frame", "overread", "bytestream",
+	}},
+	{ClassTruncated, SeverityError, []string{
+		"partial file", "truncat", "end of file", "input buffer exhausted", "incomplete frame", "premature",
+	}},
+	{ClassIO, SeverityError, []string{
+		"read error", "i/o error", "error reading",
+	}},
+	{ClassUnreadable, SeverityFatal, []string{
+		"no such file or directory", "permission denied", "moov atom not found", "ebml header parsing failed",
+		"could not find codec parameters", "invalid data found when processing input",
+	}},
+}
+
+var (
+	progressLinePattern = regexp.MustCompile(`^[a-z0-9_]+=\S*$`)
+	componentPattern    = regexp.MustCompile(`^\[([^\s\]@]+)\s*@\s*[^\]]*\]\s*`)
+	streamPattern       = regexp.MustCompile(`(?i)stream\s*(?:#\d+:|=\s*)?(\d+)`)
+)
+
+// parseFfmpegIssues turns ffmpeg error output with interleaved progress lines into issues
+func parseFfmpegIssues(output string, toleratedClasses []string) []CorruptionIssue {
+	tolerated := make(map[string]bool, len(toleratedClasses))
+	for _, class := range toleratedClasses {
+		tolerated[class] = true
+	}
+
+	issues := make([]CorruptionIssue, 0)
+	timestamp := -1.0
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if progressLinePattern.MatchString(line) {
+			if position, ok := parseProgressPosition(line); ok {
+				timestamp = position
+			}
+			continue
+		}
+
+		issue := classifyFfmpegLine(line)
+		issue.Timestamp = timestamp
+		if tolerated[issue.Class] {
+			issue.Severity = SeverityTolerated
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// parseProgressPosition reads the decode position from an out_time_us progress line
+func parseProgressPosition(line string) (float64, bool) {
+	value, found := strings.CutPrefix(line, "out_time_us=")
+	if !found {
+		return 0, false
+	}
+	microseconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || microseconds < 0 {
+		return 0, false
+	}
+	return float64(microseconds) / 1e6, true
+}
+
+// classifyFfmpegLine matches a single ffmpeg error line against the corruption rules
+func classifyFfmpegLine(line string) CorruptionIssue {
+	issue := CorruptionIssue{
+		Class:     ClassUnknown,
+		Severity:  SeverityError,
+		Stream:    -1,
+		Timestamp: -1,
+		Message:   line,
+	}
+	if match := componentPattern.FindStringSubmatch(line); match != nil {
+		issue.Component = match[1]
+	}
+	if match := streamPattern.FindStringSubmatch(line); match != nil {
+		if stream, err := strconv.Atoi(match[1]); err == nil {
+			issue.Stream = stream
+		}
+	}
+
+	lineLower := strings.ToLower(line)
+	for _, rule := range corruptionRules {
+		for _, pattern := range rule.patterns {
+			if strings.Contains(lineLower, pattern) {
+				issue.Class = rule.class
+				issue.Severity = rule.severity
+				return issue
+			}
+		}
+	}
+	return issue
+}
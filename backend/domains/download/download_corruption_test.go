@@ -0,0 +1,235 @@
+package download
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseFfmpegIssues(t *testing.T) {
+	output := `frame=10
+out_time_us=400000
+progress=continue
+[h264 @ 0x55d5c0] error while decoding MB 12 34, bytestream -5
+[mp4 @ 0x55d5c1] Application provided invalid, non monotonically increasing dts to muxer in stream 0: 1234 >= 1200
+out_time_us=1500000
+Error while decoding stream #0:1: Invalid data found when processing input
+[mov,mp4,m4a,3gp,3g2,mj2 @ 0x55d5c2] stream 1, offset 0x1234: partial file
+something else entirely
+progress=end`
+
+	issues := parseFfmpegIssues(output, []string{ClassTimestamps})
+	expected := []CorruptionIssue{
+		{Class: ClassDecode, Severity: SeverityError, Stream: -1, Timestamp: 0.4, Component: "h264"},
+		{Class: ClassTimestamps, Severity: SeverityTolerated, Stream: 0, Timestamp: 0.4, Component: "mp4"},
+		{Class: ClassDecode, Severity: SeverityError, Stream: 1, Timestamp: 1.5},
+		{Class: ClassTruncated, Severity: SeverityError, Stream: 1, Timestamp: 1.5, Component: "mov,mp4,m4a,3gp,3g2,mj2"},
+		{Class: ClassUnknown, Severity: SeverityError, Stream: -1, Timestamp: 1.5},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for i, want := range expected {
+		got := issues[i]
+		if got.Class != want.Class || got.Severity != want.Severity || got.Stream != want.Stream ||
+			got.Timestamp != want.Timestamp || got.Component != want.Component {
+			t.Errorf("Issue %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestCorruptionReportSummary(t *testing.T) {
+	report := &CorruptionReport{Issues: []CorruptionIssue{
+		{Class: ClassTimestamps, Severity: SeverityWarning, Stream: 0, Timestamp: -1, Message: "dts"},
+		{Class: ClassDecode, Severity: SeverityError, Stream: 1, Timestamp: 2.5, Message: "bad frame"},
+	}}
+	if report.Err() != nil {
+		t.Error("Expected no error for a report that is not corrupt")
+	}
+
+	report.Corrupt = true
+	if summary := report.Summary(); summary != "decode in stream 1 at 2.50s: bad frame (+1 more)" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+	if report.Err() == nil {
+		t.Error("Expected an error for a corrupt report")
+	}
+}
+
+func TestParseRepairStrategies(t *testing.T) {
+	strategies, err := parseRepairStrategies(" Remux , reencode_cfr,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(strategies) != 2 || strategies[0].Name() != "remux" || strategies[1].Name() != "reencode_cfr" {
+		t.Errorf("Unexpected strategies: %+v", strategies)
+	}
+
+	if strategies, err := parseRepairStrategies(""); err != nil || len(strategies) != 0 {
+		t.Errorf("Expected no strategies for an empty setting, got %+v (%v)", strategies, err)
+	}
+	if _, err := parseRepairStrategies("remux,transcode"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+// generateSample encodes a short test video with ffmpeg, skipping the test when ffmpeg is not installed
+func generateSample(t *testing.T, name string) (string, string) {
+	t.Helper()
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+
+	samplePath := filepath.Join(t.TempDir(), name)
+	output, err := exec.Command(ffmpegPath, "-v", "error",
+		"-f", "lavfi", "-i", "testsrc=duration=2:size=160x120:rate=25",
+		"-f", "lavfi", "-i", "sine=duration=2",
+		"-c:v", "mpeg4", "-c:a", "aac", "-shortest", "-y", samplePath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to generate sample: %v: %s", err, output)
+	}
+	return ffmpegPath, samplePath
+}
+
+func TestInspectFileCleanSample(t *testing.T) {
+	ffmpegPath, samplePath := generateSample(t, "clean.mp4")
+
+	report, err := inspectFile(ffmpegPath, samplePath, DefaultCorruptionCheckConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Corrupt || len(report.Issues) != 0 {
+		t.Errorf("Expected a clean report, got %+v", report)
+	}
+	if len(report.RepairAttempts) != 0 {
+		t.Errorf("Expected no repair attempts, got %+v", report.RepairAttempts)
+	}
+}
+
+func TestInspectFileTruncatedSample(t *testing.T) {
+	ffmpegPath, samplePath := generateSample(t, "truncated.mp4")
+
+	// The index of an mp4 is written at the end, cutting the file in half makes it unreadable
+	content, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(samplePath, content[:len(content)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := inspectFile(ffmpegPath, samplePath, DefaultCorruptionCheckConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.Corrupt || !report.hasSeverity(SeverityFatal) {
+		t.Errorf("Expected a fatal issue for a truncated file, got %+v", report)
+	}
+	if len(report.RepairAttempts) != 0 {
+		t.Errorf("Expected no repair attempts for an unreadable file, got %+v", report.RepairAttempts)
+	}
+
+	after, err := os.ReadFile(samplePath)
+	if err != nil || len(after) != len(content)/2 {
+		t.Error("Expected the corrupt file to be left untouched")
+	}
+}
+
+// timestampIssueFfmpeg wraps ffmpeg so every check of a file whose name ends with the suffix also reports
+// a repairable timestamp issue. The repairs themselves run the real ffmpeg.
+func timestampIssueFfmpeg(t *testing.T, ffmpegPath, suffix string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("ffmpeg wrapper is a shell script")
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+case "$*" in
+*"%s -f null"*) echo "[mp4 @ 0x0] Application provided invalid, non monotonically increasing dts to muxer in stream 0: 1024 >= 512" >&2 ;;
+esac
+exec "%s" "$@"
+`, suffix, ffmpegPath)
+	wrapperPath := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(wrapperPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return wrapperPath
+}
+
+func TestInspectFileRepairsSample(t *testing.T) {
+	for _, strategy := range []RepairStrategy{remuxRepair{}, cfrReencodeRepair{}} {
+		t.Run(strategy.Name(), func(t *testing.T) {
+			ffmpegPath, samplePath := generateSample(t, "timestamps.mp4")
+			// Only the original reports the issue, the repaired copy checks clean
+			wrapperPath := timestampIssueFfmpeg(t, ffmpegPath, "/timestamps.mp4")
+
+			config := CorruptionCheckConfig{RepairStrategies: []RepairStrategy{strategy}, MaxRepairAttempts: 2}
+			report, err := inspectFile(wrapperPath, samplePath, config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if report.Corrupt || report.RepairedWith != strategy.Name() {
+				t.Fatalf("Expected the file to be repaired with %s, got %+v", strategy.Name(), report)
+			}
+			if len(report.RepairAttempts) != 1 || !report.RepairAttempts[0].Success {
+				t.Errorf("Expected one successful repair attempt, got %+v", report.RepairAttempts)
+			}
+			// The report describes the re-inspected repaired file, not the original
+			if report.FilePath != samplePath || len(report.Issues) != 0 {
+				t.Errorf("Expected the report of the repaired file, got %+v", report)
+			}
+			if _, err := os.Stat(samplePath + ".repair.mp4"); !os.IsNotExist(err) {
+				t.Error("Expected the repaired copy to be moved into place")
+			}
+
+			// The replaced file is still a readable video
+			check, err := inspectFile(ffmpegPath, samplePath, CorruptionCheckConfig{})
+			if err != nil || check.Corrupt || len(check.Issues) != 0 {
+				t.Errorf("Expected the repaired file to check clean, got %+v (%v)", check, err)
+			}
+		})
+	}
+}
+
+func TestInspectFileBoundsRepairAttempts(t *testing.T) {
+	ffmpegPath, samplePath := generateSample(t, "timestamps.mp4")
+	// The repaired copies report the issue as well, so no repair succeeds
+	wrapperPath := timestampIssueFfmpeg(t, ffmpegPath, ".mp4")
+	original, err := os.ReadFile(samplePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strategies := []RepairStrategy{remuxRepair{}, cfrReencodeRepair{}, remuxRepair{}}
+	for _, maxAttempts := range []int{0, 1, 2} {
+		config := CorruptionCheckConfig{RepairStrategies: strategies, MaxRepairAttempts: maxAttempts}
+		report, err := inspectFile(wrapperPath, samplePath, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(report.RepairAttempts) != maxAttempts {
+			t.Errorf("Expected %d repair attempts, got %+v", maxAttempts, report.RepairAttempts)
+		}
+		for _, attempt := range report.RepairAttempts {
+			if attempt.Success || !strings.Contains(attempt.Detail, "still has issues") {
+				t.Errorf("Expected the re-inspection to reject the repair, got %+v", attempt)
+			}
+		}
+		if !report.Corrupt || report.RepairedWith != "" {
+			t.Errorf("Expected an unrepaired corrupt file, got %+v", report)
+		}
+	}
+
+	after, err := os.ReadFile(samplePath)
+	if err != nil || !bytes.Equal(after, original) {
+		t.Error("Expected the file to be left untouched by failed repairs")
+	}
+	if _, err := os.Stat(samplePath + ".repair.mp4"); !os.IsNotExist(err) {
+		t.Error("Expected the repaired copies to be removed")
+	}
+}
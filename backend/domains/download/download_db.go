@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 	"videoarchiver/backend/domains/db"
+	"videoarchiver/backend/domains/fileutils"
 )
 
 type DownloadDB struct {
@@ -19,8 +20,8 @@ func NewDownloadDB(dbService *db.DatabaseService) *DownloadDB {
 
 func (d *DownloadDB) GetAllDownloads(limit int) ([]Download, error) {
 	rows, err := d.db.Query(`SELECT 
-		id, playlist_id, url, status, format_downloaded, md5, output_filename, 
-		last_attempt, fail_message, attempt_count, NULL as save_directory
+		id, playlist_id, url, status, format_downloaded, md5, hash_algorithm, output_filename, 
+		last_attempt, fail_message, attempt_count, extractor, video_id, NULL as save_directory
 		FROM downloads ORDER BY last_attempt DESC LIMIT ?`, limit)
 	if err != nil {
 		return nil, err
@@ -31,8 +32,8 @@ func (d *DownloadDB) GetAllDownloads(limit int) ([]Download, error) {
 
 func (d *DownloadDB) GetDownloadsForPlaylist(playlistId int) ([]Download, error) {
 	rows, err := d.db.Query(`SELECT 
-		id, playlist_id, url, status, format_downloaded, md5, output_filename, 
-		last_attempt, fail_message, attempt_count, NULL as save_directory
+		id, playlist_id, url, status, format_downloaded, md5, hash_algorithm, output_filename, 
+		last_attempt, fail_message, attempt_count, extractor, video_id, NULL as save_directory
 		FROM downloads WHERE playlist_id = ?`, playlistId)
 	if err != nil {
 		return nil, err
@@ -41,7 +42,71 @@ func (d *DownloadDB) GetDownloadsForPlaylist(playlistId int) ([]Download, error)
 	return d.scanRows(rows)
 }
 
+// GetSuccessfulDownloads returns all downloads that should have a file in the archive
+func (d *DownloadDB) GetSuccessfulDownloads() ([]Download, error) {
+	rows, err := d.db.Query(`SELECT
+		d.id, d.playlist_id, d.url, d.status, d.format_downloaded, d.md5, d.hash_algorithm, d.output_filename,
+		d.last_attempt, d.fail_message, d.attempt_count, d.extractor, d.video_id, p.save_directory
+		FROM downloads d
+		LEFT JOIN playlists p ON d.playlist_id = p.id
+		WHERE d.status IN (?, ?)
+		ORDER BY d.id ASC`,
+		StSuccess, StSuccessPlaylistRemoved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return d.scanRows(rows)
+}
+
+// GetFailuresSince returns downloads that failed at or after a unix timestamp and are not queued for a manual retry
+func (d *DownloadDB) GetFailuresSince(since int64) ([]Download, error) {
+	rows, err := d.db.Query(`SELECT
+		id, playlist_id, url, status, format_downloaded, md5, hash_algorithm, output_filename,
+		last_attempt, fail_message, attempt_count, extractor, video_id, NULL as save_directory
+		FROM downloads WHERE status IN (?, ?) AND last_attempt >= ?
+		ORDER BY last_attempt ASC`,
+		StFailedAutoRetry, StFailedGiveUp, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return d.scanRows(rows)
+}
+
 func (d *DownloadDB) GetDownloadHistoryPage(offset, limit int, showSuccess, showFailed, showDuplicate bool) ([]Download, error) {
+	query, args := historyQuery(showSuccess, showFailed, showDuplicate)
+	if query == "" {
+		// Return empty if no filters selected
+		return []Download{}, nil
+	}
+
+	rows, err := d.db.Query(query+" LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return d.scanRows(rows)
+}
+
+// GetDownloadHistory returns every download matching the history filters, newest first
+func (d *DownloadDB) GetDownloadHistory(showSuccess, showFailed, showDuplicate bool) ([]Download, error) {
+	query, args := historyQuery(showSuccess, showFailed, showDuplicate)
+	if query == "" {
+		return []Download{}, nil
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return d.scanRows(rows)
+}
+
+// historyQuery builds the download history query for the selected status filters.
+// Returns an empty query if no filters are selected.
+func historyQuery(showSuccess, showFailed, showDuplicate bool) (string, []interface{}) {
 	var statuses []int
 	if showSuccess {
 		statuses = append(statuses, StSuccess, StSuccessPlaylistRemoved)
@@ -52,35 +117,24 @@ func (d *DownloadDB) GetDownloadHistoryPage(offset, limit int, showSuccess, show
 	if showDuplicate {
 		statuses = append(statuses, StSuccessDuplicate)
 	}
-
-	// Return empty if no filters selected
 	if len(statuses) == 0 {
-		return []Download{}, nil
+		return "", nil
 	}
 
 	query := `SELECT 
-		d.id, d.playlist_id, d.url, d.status, d.format_downloaded, d.md5, d.output_filename, 
-		d.last_attempt, d.fail_message, d.attempt_count, p.save_directory
+		d.id, d.playlist_id, d.url, d.status, d.format_downloaded, d.md5, d.hash_algorithm, d.output_filename, 
+		d.last_attempt, d.fail_message, d.attempt_count, d.extractor, d.video_id, p.save_directory
 		FROM downloads d 
 		LEFT JOIN playlists p ON d.playlist_id = p.id 
 		WHERE d.status IN (` + strings.Repeat("?,", len(statuses)-1) + `?) 
-		ORDER BY d.last_attempt DESC 
-		LIMIT ? OFFSET ?`
+		ORDER BY d.last_attempt DESC`
 
 	// Convert statuses to interface{} for query args
-	args := make([]interface{}, len(statuses)+2)
+	args := make([]interface{}, len(statuses))
 	for i, status := range statuses {
 		args[i] = status
 	}
-	args[len(statuses)] = limit
-	args[len(statuses)+1] = offset
-
-	rows, err := d.db.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	return d.scanRows(rows)
+	return query, args
 }
 
 func (d *DownloadDB) scanRows(rows *sql.Rows) ([]Download, error) {
@@ -89,8 +143,9 @@ func (d *DownloadDB) scanRows(rows *sql.Rows) ([]Download, error) {
 		var download Download
 		err := rows.Scan(
 			&download.ID, &download.PlaylistID, &download.Url,
-			&download.Status, &download.FormatDownloaded, &download.MD5, &download.OutputFilename,
-			&download.LastAttempt, &download.FailMessage, &download.AttemptCount, &download.SaveDirectory,
+			&download.Status, &download.FormatDownloaded, &download.MD5, &download.HashAlgorithm, &download.OutputFilename,
+			&download.LastAttempt, &download.FailMessage, &download.AttemptCount,
+			&download.Extractor, &download.VideoID, &download.SaveDirectory,
 		)
 		if err != nil {
 			return nil, err
@@ -113,10 +168,12 @@ func (d *DownloadDB) scanRows(rows *sql.Rows) ([]Download, error) {
 func (d *Download) SetSuccess(
 	dlDB *DownloadDB,
 	outputFilename string,
-	md5 string,
+	fileHash string,
+	hashAlgorithm fileutils.HashAlgorithm,
 ) error {
 	d.Status = StSuccess
-	d.MD5 = sql.NullString{String: md5, Valid: true}
+	d.MD5 = sql.NullString{String: fileHash, Valid: fileHash != ""} // Empty if the file could not be hashed
+	d.HashAlgorithm = string(hashAlgorithm)
 	d.OutputFilename = sql.NullString{String: outputFilename, Valid: true}
 	d.FailMessage = sql.NullString{String: "", Valid: false}
 	d.AttemptCount += 1
@@ -132,10 +189,11 @@ func (d *Download) SetSuccess(
 }
 
 func (d *Download) SetSuccessDuplicate(
-	dlDB *DownloadDB, outputFilename string, md5 string,
+	dlDB *DownloadDB, outputFilename string, fileHash string, hashAlgorithm fileutils.HashAlgorithm,
 ) error {
 	d.Status = StSuccessDuplicate
-	d.MD5 = sql.NullString{String: md5, Valid: true}
+	d.MD5 = sql.NullString{String: fileHash, Valid: fileHash != ""} // Empty if the matched file has no hash
+	d.HashAlgorithm = string(hashAlgorithm)
 	d.OutputFilename = sql.NullString{String: outputFilename, Valid: true}
 	d.FailMessage = sql.NullString{String: "", Valid: false}
 	d.AttemptCount += 1
@@ -187,6 +245,19 @@ func (d *DownloadDB) SetManualRetry(downloadId int) error {
 	return err
 }
 
+// MarkDuplicate marks a successful download whose file was removed in favour of an identical file elsewhere.
+// The download is no longer verified and is not downloaded again.
+func (d *DownloadDB) MarkDuplicate(downloadId int) error {
+	_, err := d.db.Exec(
+		"UPDATE downloads SET status = ? WHERE id = ? AND status IN (?, ?)",
+		StSuccessDuplicate,
+		downloadId,
+		StSuccess,
+		StSuccessPlaylistRemoved,
+	)
+	return err
+}
+
 func (d *DownloadDB) RegisterAllFailedForRetryManual() error {
 	_, err := d.db.Exec(
 		"UPDATE downloads SET status = ? WHERE status = ?",
@@ -197,43 +268,138 @@ func (d *DownloadDB) RegisterAllFailedForRetryManual() error {
 }
 
 func (d *Download) insertDownload(dlDB *DownloadDB) error {
-	_, err := dlDB.db.Exec(
-		`INSERT INTO downloads (playlist_id, url, status, format_downloaded, md5, output_filename, last_attempt, fail_message, attempt_count)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		d.PlaylistID, d.Url, d.Status, d.FormatDownloaded, d.MD5, d.OutputFilename, d.LastAttempt, d.FailMessage, d.AttemptCount,
+	result, err := dlDB.db.Exec(
+		`INSERT INTO downloads (playlist_id, url, status, format_downloaded, md5, hash_algorithm, output_filename, last_attempt, fail_message, attempt_count, extractor, video_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.PlaylistID, d.Url, d.Status, d.FormatDownloaded, d.MD5, d.HashAlgorithm, d.OutputFilename, d.LastAttempt, d.FailMessage, d.AttemptCount, d.Extractor, d.VideoID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	d.ID = int(id)
+	return nil
 }
 
 func (d *Download) updateDownload(dlDB *DownloadDB) error {
 	_, err := dlDB.db.Exec(
-		`UPDATE downloads SET playlist_id = ?, url = ?, status = ?, format_downloaded = ?, md5 = ?, output_filename = ?, last_attempt = ?, fail_message = ?, attempt_count = ? WHERE id = ?`,
-		d.PlaylistID, d.Url, d.Status, d.FormatDownloaded, d.MD5, d.OutputFilename, d.LastAttempt, d.FailMessage, d.AttemptCount, d.ID)
+		`UPDATE downloads SET playlist_id = ?, url = ?, status = ?, format_downloaded = ?, md5 = ?, hash_algorithm = ?, output_filename = ?, last_attempt = ?, fail_message = ?, attempt_count = ?, extractor = ?, video_id = ? WHERE id = ?`,
+		d.PlaylistID, d.Url, d.Status, d.FormatDownloaded, d.MD5, d.HashAlgorithm, d.OutputFilename, d.LastAttempt, d.FailMessage, d.AttemptCount, d.Extractor, d.VideoID, d.ID)
 	return err
 }
 
-// CheckForDuplicateInDownloads checks if any existing download has the same MD5
+// CheckForDuplicateInDownloads checks if any existing download has the same content hash.
+// The file is hashed with every algorithm still present in the table, so duplicates are
+// found while older rows are being migrated to a different algorithm.
 // Returns: exists (bool), id (int), error
-func (d *DownloadDB) CheckForDuplicateInDownloads(fileMD5 string, ignoredOwnId int) (bool, int, error) {
-	var id int
+func (d *DownloadDB) CheckForDuplicateInDownloads(hasher *fileutils.FileHasher, ignoredOwnId int) (bool, int, error) {
+	algorithms, err := d.getHashAlgorithmsInUse()
+	if err != nil {
+		return false, 0, err
+	}
+
+	for _, algorithm := range algorithms {
+		fileHash, err := hasher.Hash(algorithm)
+		if err != nil {
+			return false, 0, err
+		}
+
+		// Query downloads table for matching hash
+		var id int
+		err = d.db.QueryRow(
+			"SELECT id FROM downloads WHERE hash_algorithm = ? AND md5 = ? AND id != ? LIMIT 1",
+			algorithm, fileHash, ignoredOwnId,
+		).Scan(&id)
+		if err == nil {
+			// Duplicate found
+			return true, id, nil
+		}
+		if err != sql.ErrNoRows {
+			// Actual error occurred
+			return false, 0, err
+		}
+	}
+
+	// No duplicate found
+	return false, 0, nil
+}
+
+// FindByVideoIdentity returns a downloaded item with the same extractor, video ID and format, or nil if none exists.
+// Used to skip known duplicates before downloading.
+func (d *DownloadDB) FindByVideoIdentity(extractor, videoId, format string, ignoredOwnId int) (*Download, error) {
+	rows, err := d.db.Query(`SELECT
+		d.id, d.playlist_id, d.url, d.status, d.format_downloaded, d.md5, d.hash_algorithm, d.output_filename,
+		d.last_attempt, d.fail_message, d.attempt_count, d.extractor, d.video_id, p.save_directory
+		FROM downloads d
+		LEFT JOIN playlists p ON d.playlist_id = p.id
+		WHERE d.extractor = ? AND d.video_id = ? AND d.format_downloaded = ? AND d.id != ?
+		AND d.md5 IS NOT NULL AND d.status IN (?, ?, ?)
+		ORDER BY d.id ASC LIMIT 1`,
+		extractor, videoId, format, ignoredOwnId, StSuccess, StSuccessPlaylistRemoved, StSuccessDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	// Query downloads table for matching MD5
-	err := d.db.QueryRow(
-		"SELECT id FROM downloads WHERE md5 = ? AND id != ? LIMIT 1",
-		fileMD5, ignoredOwnId,
-	).Scan(&id)
+	downloads, err := d.scanRows(rows)
+	if err != nil || len(downloads) == 0 {
+		return nil, err
+	}
+	return &downloads[0], nil
+}
 
+// getHashAlgorithmsInUse returns every hash algorithm used by stored download hashes
+func (d *DownloadDB) getHashAlgorithmsInUse() ([]fileutils.HashAlgorithm, error) {
+	rows, err := d.db.Query("SELECT DISTINCT hash_algorithm FROM downloads WHERE md5 IS NOT NULL")
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// No duplicate found
-			return false, 0, nil
+		return nil, err
+	}
+	defer rows.Close()
+
+	algorithms := make([]fileutils.HashAlgorithm, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
 		}
-		// Actual error occurred
-		return false, 0, err
+		algorithm, err := fileutils.ParseHashAlgorithm(name)
+		if err != nil {
+			continue
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
+}
+
+// GetDownloadsWithOtherHashAlgorithm returns successful downloads hashed with a different algorithm,
+// starting after the given ID. Used by the background rehash migration.
+func (d *DownloadDB) GetDownloadsWithOtherHashAlgorithm(algorithm fileutils.HashAlgorithm, afterId, limit int) ([]Download, error) {
+	rows, err := d.db.Query(`SELECT
+		d.id, d.playlist_id, d.url, d.status, d.format_downloaded, d.md5, d.hash_algorithm, d.output_filename,
+		d.last_attempt, d.fail_message, d.attempt_count, d.extractor, d.video_id, p.save_directory
+		FROM downloads d
+		LEFT JOIN playlists p ON d.playlist_id = p.id
+		WHERE d.hash_algorithm != ? AND d.md5 IS NOT NULL AND d.status IN (?, ?) AND d.id > ?
+		ORDER BY d.id ASC LIMIT ?`,
+		algorithm, StSuccess, StSuccessPlaylistRemoved, afterId, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+	return d.scanRows(rows)
+}
 
-	// Duplicate found
-	return true, id, nil
+// UpdateHash replaces the stored hash of a download
+func (d *DownloadDB) UpdateHash(downloadId int, fileHash string, algorithm fileutils.HashAlgorithm) error {
+	_, err := d.db.Exec(
+		"UPDATE downloads SET md5 = ?, hash_algorithm = ? WHERE id = ?",
+		fileHash, algorithm, downloadId,
+	)
+	return err
 }
 
 // Removes excessive error message parts
@@ -2,6 +2,8 @@ package download
 
 import (
 	"database/sql"
+	"fmt"
+	"videoarchiver/backend/domains/fileutils"
 )
 
 const (
@@ -15,13 +17,17 @@ type Download struct {
 	Url              string         `json:"url" db:"url"`
 	Status           Status         `json:"status" db:"status"`
 	FormatDownloaded string         `json:"format_downloaded" db:"format_downloaded"`
-	MD5              sql.NullString `json:"md5,omitempty" db:"md5"`
+	MD5              sql.NullString `json:"md5,omitempty" db:"md5"` // Content hash, calculated with HashAlgorithm
+	HashAlgorithm    string         `json:"hash_algorithm" db:"hash_algorithm"`
 	OutputFilename   sql.NullString `json:"output_filename,omitempty" db:"output_filename"`
 	LastAttempt      int64          `json:"last_attempt" db:"last_attempt"`
 	FailMessage      sql.NullString `json:"fail_message,omitempty" db:"fail_message"`
 	AttemptCount     int            `json:"attempt_count" db:"attempt_count"`
+	Extractor        sql.NullString `json:"extractor,omitempty" db:"extractor"` // Normalized yt-dlp extractor key
+	VideoID          sql.NullString `json:"video_id,omitempty" db:"video_id"`   // Video ID on the extractor's site
 	SaveDirectory    sql.NullString `json:"save_directory,omitempty" db:"save_directory"`
 	FullPath         sql.NullString `json:"full_path,omitempty" db:"full_path"`
+	PlaylistIndex    int            `json:"-" db:"-"` // 1-based position in the playlist when known, not stored
 }
 
 // Creates new instance of Download without an ID or attempt info
@@ -36,13 +42,25 @@ func NewDownload(
 		Status:           StUndownloaded,
 		FormatDownloaded: formatDownloaded,
 		MD5:              sql.NullString{String: "", Valid: false},
+		HashAlgorithm:    string(fileutils.HashMD5),
 		OutputFilename:   sql.NullString{String: "", Valid: false},
 		LastAttempt:      0,
 		FailMessage:      sql.NullString{String: "", Valid: false},
 		AttemptCount:     0,
+		Extractor:        sql.NullString{String: "", Valid: false},
+		VideoID:          sql.NullString{String: "", Valid: false},
 	}
 }
 
+// SetVideoIdentity stores the extractor and video ID of the download if both are known
+func (d *Download) SetVideoIdentity(extractor, videoId string) {
+	if extractor == "" || videoId == "" {
+		return
+	}
+	d.Extractor = sql.NullString{String: extractor, Valid: true}
+	d.VideoID = sql.NullString{String: videoId, Valid: true}
+}
+
 type Status int
 
 const (
@@ -55,3 +73,23 @@ const (
 	StFailedPlaylistRemoved  = 6
 	StSuccessDuplicate       = 7
 )
+
+// Names of the statuses, used in exports read outside the app
+var statusNames = map[Status]string{
+	StUndownloaded:           "undownloaded",
+	StSuccess:                "success",
+	StFailedAutoRetry:        "failed_auto_retry",
+	StFailedManualRetry:      "failed_manual_retry",
+	StFailedGiveUp:           "failed_give_up",
+	StSuccessPlaylistRemoved: "success_playlist_removed",
+	StFailedPlaylistRemoved:  "failed_playlist_removed",
+	StSuccessDuplicate:       "success_duplicate",
+}
+
+// StatusName returns a readable name for a status
+func StatusName(status Status) string {
+	if name, ok := statusNames[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", status)
+}
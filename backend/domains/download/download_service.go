@@ -2,7 +2,6 @@ package download
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,8 +11,10 @@ import (
 	"videoarchiver/backend/daemonsignal"
 	"videoarchiver/backend/domains/fileregistry"
 	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/fingerprint"
+	"videoarchiver/backend/domains/musictag"
 	"videoarchiver/backend/domains/playlist"
-	"videoarchiver/backend/domains/runner"
+	"videoarchiver/backend/domains/postprocess"
 	"videoarchiver/backend/domains/settings"
 	"videoarchiver/backend/domains/ytdlp"
 
@@ -34,6 +35,9 @@ type DownloadService struct {
 	settingsService     *settings.SettingsService
 	downloadDB          *DownloadDB
 	fileRegistryService *fileregistry.FileRegistryService
+	fingerprintService  *fingerprint.FingerprintService
+	postProcessService  *postprocess.PostProcessService
+	musicTagService     *musictag.MusicTagService
 	daemonSignalService *daemonsignal.DaemonSignalService
 	logService          LogServiceInterface
 }
@@ -48,6 +52,9 @@ func NewDownloadService(
 	settingsService *settings.SettingsService,
 	downloadDB *DownloadDB,
 	fileRegistryService *fileregistry.FileRegistryService,
+	fingerprintService *fingerprint.FingerprintService,
+	postProcessService *postprocess.PostProcessService,
+	musicTagService *musictag.MusicTagService,
 	daemonSignalService *daemonsignal.DaemonSignalService,
 	logService LogServiceInterface,
 ) *DownloadService {
@@ -56,6 +63,9 @@ func NewDownloadService(
 		settingsService:     settingsService,
 		downloadDB:          downloadDB,
 		fileRegistryService: fileRegistryService,
+		fingerprintService:  fingerprintService,
+		postProcessService:  postProcessService,
+		musicTagService:     musicTagService,
 		daemonSignalService: daemonSignalService,
 		logService:          logService,
 	}
@@ -69,12 +79,32 @@ type DownloadResult struct {
 	FinalFullPath  string
 	VideoTitle     string
 	Format         string
-	MD5            string
+	Hash           string
+	HashAlgorithm  fileutils.HashAlgorithm
+	Hasher         *fileutils.FileHasher // Caches hashes of the downloaded file for duplicate checks
+	Extractor      string                // Normalized extractor key reported by yt-dlp, empty if unknown
+	VideoID        string                // Video ID reported by yt-dlp, empty if unknown
+	InfoJSON       string                // yt-dlp's JSON output for the item, used for tagging
 }
 
 // ArchiveDownloadFile used by daemon and automated operations. Handles errors and logging.
 // Handles duplicates, downloads table, error logging.
 func (d *DownloadService) ArchiveDownloadFile(dl *Download, pl *playlist.Playlist) {
+	// Check settings if we need to filter duplicates
+	allowDuplicates, err := d.settingsService.GetSettingBool("allow_duplicates")
+	if err != nil {
+		// should never happen
+		d.logService.Fatal(fmt.Sprintf("Failed to get allow_duplicates setting for %s: %v", dl.Url, err))
+		return
+	}
+
+	// Skip known duplicates by video ID before spending bandwidth on the download
+	if !allowDuplicates {
+		if isDup := d.handleVideoIdentityDuplicate(dl); isDup {
+			return
+		}
+	}
+
 	// Download file
 	d.logService.Info(fmt.Sprintf("Downloading new item: %s", dl.Url))
 	dlR, err := d.DownloadFile(dl.Url, pl.SaveDirectory, pl.OutputFormat)
@@ -84,40 +114,35 @@ func (d *DownloadService) ArchiveDownloadFile(dl *Download, pl *playlist.Playlis
 		return
 	}
 
-	// Check settings if we need to filter duplicates
-	allowDuplicates, err := d.settingsService.GetSettingBool("allow_duplicates")
-	if err != nil {
-		// should never happen
-		d.logService.Fatal(fmt.Sprintf("Failed to get allow_duplicates setting for %s: %v", dl.Url, err))
-		return
-	}
+	// Record the video identity reported by yt-dlp, it may be missing from the playlist entry
+	dl.SetVideoIdentity(dlR.Extractor, dlR.VideoID)
 
 	if !allowDuplicates {
 		// Handle duplicate in downloads table
-		isDup, existingId, err := d.HasDownloadsDuplicate(dlR.MD5, dl.ID)
+		isDup, existingId, err := d.HasDownloadsDuplicate(dlR.Hasher, dl.ID)
 		if err != nil {
 			d.logService.Error(fmt.Sprintf("Failed to check for duplicate in downloads table for %s: %v", dl.Url, err))
 			dl.SetFail(d.downloadDB, fmt.Sprintf("failed to check for duplicate in downloads table: %v", err))
 			return
 		}
 		if isDup {
-			d.logService.Info(fmt.Sprintf("Duplicate download detected in downloads table for %s (MD5: %s), skipping download. Existing ID: %d", dl.Url, dlR.MD5, existingId))
-			if err := dl.SetSuccessDuplicate(d.downloadDB, dlR.FinalFileName, dlR.MD5); err != nil {
+			d.logService.Info(fmt.Sprintf("Duplicate download detected in downloads table for %s (%s: %s), skipping download. Existing ID: %d", dl.Url, dlR.HashAlgorithm, dlR.Hash, existingId))
+			if err := dl.SetSuccessDuplicate(d.downloadDB, dlR.FinalFileName, dlR.Hash, dlR.HashAlgorithm); err != nil {
 				d.logService.Error(fmt.Sprintf("Failed to mark download as duplicate for %s: %v", dl.Url, err))
 			}
 			return
 		}
 
 		// Handle duplicate in file registry
-		isDup, err = d.HasFileRegistryDuplicate(dlR.MD5, dl.Url, dl.FormatDownloaded)
+		isDup, err = d.HasFileRegistryDuplicate(dlR.Hasher, dl.Url, dl.FormatDownloaded)
 		if err != nil {
 			d.logService.Error(fmt.Sprintf("Failed to check for duplicate in file registry for %s: %v", dl.Url, err))
 			dl.SetFail(d.downloadDB, fmt.Sprintf("failed to check for duplicate in file registry: %v", err))
 			return
 		}
 		if isDup {
-			d.logService.Info(fmt.Sprintf("Duplicate download detected in file registry for %s (MD5: %s), skipping download.", dl.Url, dlR.MD5))
-			if err := dl.SetSuccessDuplicate(d.downloadDB, dlR.FinalFileName, dlR.MD5); err != nil {
+			d.logService.Info(fmt.Sprintf("Duplicate download detected in file registry for %s (%s: %s), skipping download.", dl.Url, dlR.HashAlgorithm, dlR.Hash))
+			if err := dl.SetSuccessDuplicate(d.downloadDB, dlR.FinalFileName, dlR.Hash, dlR.HashAlgorithm); err != nil {
 				d.logService.Error(fmt.Sprintf("Failed to mark download as duplicate for %s: %v", dl.Url, err))
 			}
 			return
@@ -126,7 +151,10 @@ func (d *DownloadService) ArchiveDownloadFile(dl *Download, pl *playlist.Playlis
 
 	// Check file for corruption before moving to final location
 	d.logService.Info(fmt.Sprintf("Checking file integrity for %s", dl.Url))
-	err = CheckFileCorruption(dlR.TempFilePath)
+	report, err := InspectFile(dlR.TempFilePath, d.corruptionCheckConfig())
+	if err == nil {
+		err = report.Err()
+	}
 	if err != nil {
 		d.logService.Error(fmt.Sprintf("File corruption detected for %s: %v", dl.Url, err))
 		dl.SetFail(d.downloadDB, fmt.Sprintf("file corruption detected: %v", err))
@@ -135,6 +163,37 @@ func (d *DownloadService) ArchiveDownloadFile(dl *Download, pl *playlist.Playlis
 		return
 	}
 
+	// A repair rewrites the file, so the stored hash has to describe the repaired content
+	if report.RepairedWith != "" {
+		d.logService.Info(fmt.Sprintf("Repaired %s with %s", dl.Url, report.RepairedWith))
+		if err := dlR.Rehash(); err != nil {
+			d.logService.Error(fmt.Sprintf("Failed to hash repaired file for %s: %v", dl.Url, err))
+			dl.SetFail(d.downloadDB, fmt.Sprintf("failed to hash repaired file: %v", err))
+			os.Remove(dlR.TempFilePath)
+			return
+		}
+	}
+
+	// Replace yt-dlp's tags of mp3 downloads, a failure keeps the file with the tags it has
+	musicTagged := false
+	if strings.EqualFold(dlR.Format, "mp3") && d.musicTagService.IsEnabled() {
+		info := musictag.SourceInfoFromYtdlp(dlR.InfoJSON)
+		info.URL = dl.Url
+		info.PlaylistName = pl.Name
+		info.PlaylistIndex = dl.PlaylistIndex
+		if _, err := d.musicTagService.TagFile(dlR.TempFilePath, info); err != nil {
+			d.logService.Warn(fmt.Sprintf("Failed to tag %s: %v", dl.Url, err))
+		} else {
+			if err := dlR.Rehash(); err != nil {
+				d.logService.Error(fmt.Sprintf("Failed to hash tagged file for %s: %v", dl.Url, err))
+				dl.SetFail(d.downloadDB, fmt.Sprintf("failed to hash tagged file: %v", err))
+				os.Remove(dlR.TempFilePath)
+				return
+			}
+			musicTagged = true
+		}
+	}
+
 	// Move to final location
 	err = dlR.MoveToFinalLocation(pl.SaveDirectory)
 	if err != nil {
@@ -143,11 +202,86 @@ func (d *DownloadService) ArchiveDownloadFile(dl *Download, pl *playlist.Playlis
 		return
 	}
 
+	// Run the playlist's post-processing steps, a failed step never fails the download
+	modified := d.postProcessService.Run(d.ctx, postprocess.StepInput{
+		DownloadID:   dl.ID,
+		PlaylistID:   pl.ID,
+		PlaylistName: pl.Name,
+		FilePath:     dlR.FinalFullPath,
+		URL:          dl.Url,
+		Title:        dlR.VideoTitle,
+		Format:       dlR.Format,
+		MusicTagged:  musicTagged,
+	})
+	if modified {
+		hash, err := fileutils.CalculateHash(dlR.FinalFullPath, dlR.HashAlgorithm)
+		if err != nil {
+			// The hash of the download no longer describes the file, storing it would fail every integrity check
+			d.logService.Error(fmt.Sprintf("Failed to hash post-processed file for %s, it is stored without a hash: %v", dl.Url, err))
+		}
+		dlR.Hash = hash
+	}
+
 	// Mark download as success
-	if err := dl.SetSuccess(d.downloadDB, dlR.FinalFileName, dlR.MD5); err != nil {
+	if err := dl.SetSuccess(d.downloadDB, dlR.FinalFileName, dlR.Hash, dlR.HashAlgorithm); err != nil {
 		d.logService.Error(fmt.Sprintf("Failed to mark download as success for %s: %v", dl.Url, err))
 		return
 	}
+
+	// Fingerprint the archived file. Similar files are only flagged for review, never skipped,
+	// since re-uploads and different cuts of the same content may be wanted.
+	if d.fingerprintService.IsEnabled() {
+		matches, err := d.fingerprintService.ProcessDownload(dl.ID, dlR.FinalFullPath)
+		if err != nil {
+			d.logService.Warn(fmt.Sprintf("Failed to fingerprint %s: %v", dl.Url, err))
+			return
+		}
+		for _, match := range matches {
+			d.logService.Info(fmt.Sprintf("Probable duplicate of %s detected for %s (similarity %.2f)", match.Fingerprint.FilePath, dl.Url, match.Similarity))
+		}
+	}
+}
+
+// handleVideoIdentityDuplicate checks the downloads table and file registry for an item with the same
+// extractor, video ID and format. Duplicates are marked as such using the existing file's name and hash.
+// Returns true if the download was handled as a duplicate.
+func (d *DownloadService) handleVideoIdentityDuplicate(dl *Download) bool {
+	if !dl.Extractor.Valid || !dl.VideoID.Valid {
+		return false
+	}
+
+	existing, err := d.downloadDB.FindByVideoIdentity(dl.Extractor.String, dl.VideoID.String, dl.FormatDownloaded, dl.ID)
+	if err != nil {
+		// Not fatal, the hash based check after downloading still applies
+		d.logService.Warn(fmt.Sprintf("Failed to check downloads table for video ID of %s: %v", dl.Url, err))
+		return false
+	}
+	if existing != nil {
+		d.logService.Info(fmt.Sprintf("Duplicate video ID %s:%s detected in downloads table for %s, skipping download. Existing ID: %d",
+			dl.Extractor.String, dl.VideoID.String, dl.Url, existing.ID))
+		algorithm, _ := fileutils.ParseHashAlgorithm(existing.HashAlgorithm)
+		if err := dl.SetSuccessDuplicate(d.downloadDB, existing.OutputFilename.String, existing.MD5.String, algorithm); err != nil {
+			d.logService.Error(fmt.Sprintf("Failed to mark download as duplicate for %s: %v", dl.Url, err))
+		}
+		return true
+	}
+
+	registered, err := d.fileRegistryService.FindByVideoIdentity(dl.Extractor.String, dl.VideoID.String, dl.FormatDownloaded)
+	if err != nil {
+		d.logService.Warn(fmt.Sprintf("Failed to check file registry for video ID of %s: %v", dl.Url, err))
+		return false
+	}
+	if registered != nil {
+		d.logService.Info(fmt.Sprintf("Duplicate video ID %s:%s detected in file registry for %s, skipping download. Existing file: %s",
+			dl.Extractor.String, dl.VideoID.String, dl.Url, registered.FilePath))
+		algorithm, _ := fileutils.ParseHashAlgorithm(registered.HashAlgorithm)
+		if err := dl.SetSuccessDuplicate(d.downloadDB, registered.Filename, registered.MD5, algorithm); err != nil {
+			d.logService.Error(fmt.Sprintf("Failed to mark download as duplicate for %s: %v", dl.Url, err))
+		}
+		return true
+	}
+
+	return false
 }
 
 // Download file to a temporary location. No duplicate handling here.
@@ -169,10 +303,16 @@ func (d *DownloadService) DownloadFile(url, directory, format string) (*Download
 		return nil, fmt.Errorf("download service: failed to get title: %w", err)
 	}
 
-	// Calculate MD5 of the downloaded temp file
-	fileMD5, err := CalculateMD5(tmpFile)
+	// Extractor and video ID are optional, not every extractor reports them
+	extractor, _ := ytdlp.GetString(outputString, "extractor_key")
+	videoId, _ := ytdlp.GetString(outputString, "id")
+
+	// Calculate content hash of the downloaded temp file
+	hashAlgorithm := fileutils.GetConfiguredHashAlgorithm(d.settingsService)
+	hasher := fileutils.NewFileHasher(tmpFile)
+	fileHash, err := hasher.Hash(hashAlgorithm)
 	if err != nil {
-		return nil, fmt.Errorf("download service: failed to calculate MD5: %w", err)
+		return nil, fmt.Errorf("download service: failed to calculate %s hash: %w", hashAlgorithm, err)
 	}
 
 	// Decide available filename, handling duplicate filenames.
@@ -195,10 +335,26 @@ func (d *DownloadService) DownloadFile(url, directory, format string) (*Download
 		FinalFullPath:  finalPath,
 		VideoTitle:     videoTitle,
 		Format:         format,
-		MD5:            fileMD5,
+		Hash:           fileHash,
+		HashAlgorithm:  hashAlgorithm,
+		Hasher:         hasher,
+		Extractor:      ytdlp.NormalizeExtractor(extractor),
+		VideoID:        videoId,
+		InfoJSON:       outputString,
 	}, nil
 }
 
+// Rehash recalculates the hash of the temp file after it was rewritten
+func (dlR *DownloadResult) Rehash() error {
+	dlR.Hasher = fileutils.NewFileHasher(dlR.TempFilePath)
+	hash, err := dlR.Hasher.Hash(dlR.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+	dlR.Hash = hash
+	return nil
+}
+
 // MoveToFinalLocation moves the downloaded file to its final location.
 // Returns final path and error (if any)
 func (dlR *DownloadResult) MoveToFinalLocation(finalDir string) error {
@@ -216,12 +372,12 @@ func CalculateMD5(path string) (string, error) {
 	return fileutils.CalculateMD5(path)
 }
 
-func (d *DownloadService) HasFileRegistryDuplicate(fileMD5 string, youtubeUrl string, fileFormat string) (bool, error) {
-	return d.fileRegistryService.CheckForDuplicateInFileRegistry(fileMD5, youtubeUrl, fileFormat)
+func (d *DownloadService) HasFileRegistryDuplicate(hasher *fileutils.FileHasher, videoUrl string, fileFormat string) (bool, error) {
+	return d.fileRegistryService.CheckForDuplicateInFileRegistry(hasher, videoUrl, fileFormat)
 }
 
-func (d *DownloadService) HasDownloadsDuplicate(fileMD5 string, ignoredOwnId int) (bool, int, error) {
-	return d.downloadDB.CheckForDuplicateInDownloads(fileMD5, ignoredOwnId)
+func (d *DownloadService) HasDownloadsDuplicate(hasher *fileutils.FileHasher, ignoredOwnId int) (bool, int, error) {
+	return d.downloadDB.CheckForDuplicateInDownloads(hasher, ignoredOwnId)
 }
 
 func fileExists(path string) bool {
@@ -229,72 +385,13 @@ func fileExists(path string) bool {
 	return !os.IsNotExist(err)
 }
 
-// CheckFileCorruption checks if a downloaded file is corrupted using ffmpeg
-// Returns error if file is corrupted or if ffmpeg check fails
-func CheckFileCorruption(filePath string) error {
-	corruptionMessage := "file was corrupted. possibly soundcloud premium content but cookies not set up."
-	// Get ffmpeg path
-	ffmpegPath, err := ytdlp.GetFfmpegPath()
+// corruptionCheckConfig reads the corruption check settings, falling back to defaults for invalid values
+func (d *DownloadService) corruptionCheckConfig() CorruptionCheckConfig {
+	config, err := LoadCorruptionCheckConfig(d.settingsService)
 	if err != nil {
-		return fmt.Errorf("failed to get ffmpeg path: %w", err)
-	}
-	// Run ffmpeg corruption check: ffmpeg -v error -i <file> -f null -
-	// This will return non-zero exit code if corrupted
-	resultBytes, err := runner.RunCombinedOutput(ffmpegPath, "-v", "error", "-i", filePath, "-f", "null", "-")
-	resultString := strings.TrimSpace(string(resultBytes))
-	// If runner returned an error, ffmpeg exited non-zero (corruption detected)
-	if err != nil {
-		return errors.New(corruptionMessage)
-	}
-	// ffmpeg succeeded (exit code 0) but check stderr for warnings
-	if resultString != "" {
-		resultLower := strings.ToLower(resultString)
-
-		// specific common but non-corrupt video-only issue related to timestamps
-		// Create fixed version of the video and re-check for corruption
-		if strings.Contains(resultLower, "invalid, non monotonically increasing dts to muxer in stream") {
-			// Create temp file path for fixed version - USE .mp4 EXTENSION
-			fixedPath := filePath + ".fixed.mp4"
-
-			// Fix timestamps with re-encode using constant frame rate
-			_, fixErr := runner.RunCombinedOutput(ffmpegPath, "-i", filePath, "-vsync", "cfr", "-y", fixedPath)
-
-			if fixErr != nil {
-				// Cleanup and fail
-				os.Remove(fixedPath)
-				return errors.New(corruptionMessage)
-			}
-
-			// Check if fixed file exists and has content
-			if info, err := os.Stat(fixedPath); err != nil || info.Size() == 0 {
-				os.Remove(fixedPath)
-				return errors.New(corruptionMessage)
-			}
-
-			// Replace original with fixed version
-			if err := os.Remove(filePath); err != nil {
-				os.Remove(fixedPath) // Cleanup temp file
-				return fmt.Errorf("failed to remove original file: %w", err)
-			}
-			if err := os.Rename(fixedPath, filePath); err != nil {
-				return fmt.Errorf("failed to replace file with fixed version: %w", err)
-			}
-
-			// Recheck the fixed file
-			return CheckFileCorruption(filePath)
-		} else { // Handle other corruption indicators
-			failParts := []string{
-				"error", "corrupt", "invalid", "broken", "truncated",
-				"damaged", "failed", "missing", "decode error",
-				"header damaged", "no frame", "unexpected"}
-			for _, part := range failParts {
-				if strings.Contains(resultLower, part) {
-					return errors.New(corruptionMessage)
-				}
-			}
-		}
+		d.logService.Warn(fmt.Sprintf("Invalid corruption check settings: %v", err))
 	}
-	return nil
+	return config
 }
 
 func (d *DownloadService) SetManualRetry(downloadId int) error {
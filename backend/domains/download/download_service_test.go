@@ -1,9 +1,13 @@
 package download
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
 	"testing"
+	"videoarchiver/backend/domains/fileutils"
+
+	"github.com/NotCoffee418/dbmigrator"
 )
 
 func TestCheckFileCorruption(t *testing.T) {
@@ -34,3 +38,41 @@ func TestCheckFileCorruption(t *testing.T) {
 		t.Error("Expected corruption check to fail on non-existent file, but it passed")
 	}
 }
+
+func TestSetSuccessWithoutHash(t *testing.T) {
+	database, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	dbmigrator.SetDatabaseType(dbmigrator.SQLite)
+	<-dbmigrator.MigrateUpCh(database, os.DirFS("../../.."), "migrations")
+	if _, err := database.Exec(`INSERT INTO playlists (id, name, url, output_format, save_directory) VALUES (1, 'test', 'https://example.com', 'mp4', '/tmp')`); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file that could not be hashed after post-processing has no hash rather than a stale one
+	dl := &Download{PlaylistID: 1, Url: "https://example.com/video", FormatDownloaded: "mp4"}
+	if err := dl.SetSuccess(&DownloadDB{db: database}, "video.mp4", "", fileutils.HashMD5); err != nil {
+		t.Fatal(err)
+	}
+	var hash sql.NullString
+	if err := database.QueryRow("SELECT md5 FROM downloads WHERE id = ?", dl.ID).Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if hash.Valid {
+		t.Errorf("Expected no hash to be stored, got %q", hash.String)
+	}
+
+	// A duplicate of a file without a hash has no hash either
+	duplicate := &Download{PlaylistID: 1, Url: "https://example.com/other", FormatDownloaded: "mp4"}
+	if err := duplicate.SetSuccessDuplicate(&DownloadDB{db: database}, "video.mp4", "", fileutils.HashMD5); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.QueryRow("SELECT md5 FROM downloads WHERE id = ?", duplicate.ID).Scan(&hash); err != nil {
+		t.Fatal(err)
+	}
+	if hash.Valid {
+		t.Errorf("Expected no hash to be stored for the duplicate, got %q", hash.String)
+	}
+}
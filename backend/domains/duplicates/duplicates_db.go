@@ -0,0 +1,60 @@
+package duplicates
+
+import (
+	"database/sql"
+	"videoarchiver/backend/domains/db"
+)
+
+type DuplicatesDB struct {
+	db *sql.DB
+}
+
+func NewDuplicatesDB(dbService *db.DatabaseService) *DuplicatesDB {
+	return &DuplicatesDB{db: dbService.GetDB()}
+}
+
+// InsertAction records a reconciliation action in the audit log
+func (d *DuplicatesDB) InsertAction(action *DuplicateAction) error {
+	res, err := d.db.Exec(
+		`INSERT INTO duplicate_actions (batch_id, performed_at, dry_run, group_kind, group_key, action, keep_strategy,
+		 kept_path, file_path, destination_path, file_size, status, detail)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		action.BatchID, action.PerformedAt, action.DryRun, action.GroupKind, action.GroupKey, action.Action, action.KeepStrategy,
+		action.KeptPath, action.FilePath, action.DestinationPath, action.FileSize, action.Status, action.Detail,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	action.ID = int(id)
+	return err
+}
+
+// GetActions returns a page of the audit log, newest first
+func (d *DuplicatesDB) GetActions(offset, limit int) ([]DuplicateAction, error) {
+	rows, err := d.db.Query(
+		`SELECT id, batch_id, performed_at, dry_run, group_kind, group_key, action, keep_strategy,
+		 kept_path, file_path, destination_path, file_size, status, detail
+		 FROM duplicate_actions ORDER BY id DESC LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := make([]DuplicateAction, 0)
+	for rows.Next() {
+		var action DuplicateAction
+		err := rows.Scan(
+			&action.ID, &action.BatchID, &action.PerformedAt, &action.DryRun, &action.GroupKind, &action.GroupKey,
+			&action.Action, &action.KeepStrategy, &action.KeptPath, &action.FilePath, &action.DestinationPath,
+			&action.FileSize, &action.Status, &action.Detail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
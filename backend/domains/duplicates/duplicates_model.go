@@ -0,0 +1,87 @@
+package duplicates
+
+import "database/sql"
+
+// How the files in a duplicate group were found to be duplicates
+const (
+	GroupHash        = "hash"        // Identical content
+	GroupVideoID     = "video_id"    // Same video on the same site, in the same format
+	GroupFingerprint = "fingerprint" // Similar audio or video, such as re-encodes
+)
+
+// Which file of a group is kept during reconciliation
+const (
+	KeepNewest  = "newest"
+	KeepOldest  = "oldest"
+	KeepLargest = "largest"
+)
+
+// What happens to the files that are not kept
+const (
+	ActionHardlink   = "hardlink"   // Replace with a hardlink to the kept file, only for identical content
+	ActionQuarantine = "quarantine" // Move to the quarantine directory
+	ActionDelete     = "delete"
+)
+
+// Outcome of a single reconciliation action
+const (
+	StatusPlanned = "planned" // Dry run
+	StatusDone    = "done"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// DuplicateFile is a file on disk that belongs to a duplicate group.
+// A path can be known both as a download and as a registered file.
+type DuplicateFile struct {
+	Path        string `json:"path"`
+	Size        int64  `json:"size"` // -1 if the file no longer exists
+	ModifiedAt  int64  `json:"modified_at"`
+	DownloadIDs []int  `json:"download_ids"`
+	RegistryIDs []int  `json:"registry_ids"`
+	LinkedTo    string `json:"linked_to,omitempty"` // Earlier file in the group that is the same file on disk
+}
+
+// DuplicateGroup is a set of files that are duplicates of each other
+type DuplicateGroup struct {
+	Kind            string          `json:"kind"`
+	Key             string          `json:"key"`
+	Label           string          `json:"label"`
+	Similarity      float64         `json:"similarity,omitempty"` // Only set for fingerprint groups
+	Files           []DuplicateFile `json:"files"`
+	TotalSize       int64           `json:"total_size"`
+	ReclaimableSize int64           `json:"reclaimable_size"` // Freed by keeping only the largest file
+}
+
+// DuplicateReport lists every duplicate group in the archive and the file registry
+type DuplicateReport struct {
+	GeneratedAt     int64            `json:"generated_at"`
+	Groups          []DuplicateGroup `json:"groups"`
+	ReclaimableSize int64            `json:"reclaimable_size"`
+}
+
+// ReconcileRequest describes how to resolve a set of duplicate groups
+type ReconcileRequest struct {
+	Groups []DuplicateGroup `json:"groups"`
+	Keep   string           `json:"keep"`
+	Action string           `json:"action"`
+	DryRun bool             `json:"dry_run"`
+}
+
+// DuplicateAction is an audit log entry for a single file handled during reconciliation
+type DuplicateAction struct {
+	ID              int            `json:"id" db:"id"`
+	BatchID         string         `json:"batch_id" db:"batch_id"`
+	PerformedAt     int64          `json:"performed_at" db:"performed_at"`
+	DryRun          bool           `json:"dry_run" db:"dry_run"`
+	GroupKind       string         `json:"group_kind" db:"group_kind"`
+	GroupKey        string         `json:"group_key" db:"group_key"`
+	Action          string         `json:"action" db:"action"`
+	KeepStrategy    string         `json:"keep_strategy" db:"keep_strategy"`
+	KeptPath        string         `json:"kept_path" db:"kept_path"`
+	FilePath        string         `json:"file_path" db:"file_path"`
+	DestinationPath sql.NullString `json:"destination_path,omitempty" db:"destination_path"`
+	FileSize        int64          `json:"file_size" db:"file_size"`
+	Status          string         `json:"status" db:"status"`
+	Detail          sql.NullString `json:"detail,omitempty" db:"detail"`
+}
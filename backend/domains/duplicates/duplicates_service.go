@@ -0,0 +1,632 @@
+package duplicates
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/fingerprint"
+	"videoarchiver/backend/domains/pathing"
+	"videoarchiver/backend/domains/settings"
+
+	cp "github.com/otiai10/copy"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// Amount of registry entries loaded per query while collecting files
+const registryPageSize = 500
+
+type DuplicatesService struct {
+	duplicatesDB        *DuplicatesDB
+	settingsService     *settings.SettingsService
+	downloadDB          *download.DownloadDB
+	fileRegistryService *fileregistry.FileRegistryService
+	fingerprintService  *fingerprint.FingerprintService
+	logService          LogServiceInterface
+}
+
+func NewDuplicatesService(
+	duplicatesDB *DuplicatesDB,
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	fileRegistryService *fileregistry.FileRegistryService,
+	fingerprintService *fingerprint.FingerprintService,
+	logService LogServiceInterface,
+) *DuplicatesService {
+	return &DuplicatesService{
+		duplicatesDB:        duplicatesDB,
+		settingsService:     settingsService,
+		downloadDB:          downloadDB,
+		fileRegistryService: fileRegistryService,
+		fingerprintService:  fingerprintService,
+		logService:          logService,
+	}
+}
+
+// libraryEntry is a single download or registered file
+type libraryEntry struct {
+	source        string
+	sourceId      int
+	path          string
+	hash          string
+	hashAlgorithm string
+	extractor     string
+	videoId       string
+}
+
+// BuildReport groups every download and registered file by content hash, video ID and fingerprint.
+// Only groups with at least two distinct files on disk are reported, largest reclaimable size first.
+func (s *DuplicatesService) BuildReport(ctx context.Context) (*DuplicateReport, error) {
+	groups, err := s.buildGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DuplicateReport{GeneratedAt: time.Now().Unix(), Groups: make([]DuplicateGroup, 0)}
+	for i := range groups {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if describeFiles(&groups[i]) < 2 {
+			continue
+		}
+		report.Groups = append(report.Groups, groups[i])
+		report.ReclaimableSize += groups[i].ReclaimableSize
+	}
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return report.Groups[i].ReclaimableSize > report.Groups[j].ReclaimableSize
+	})
+
+	s.logService.Info(fmt.Sprintf("Duplicate report: %d groups, %d bytes reclaimable", len(report.Groups), report.ReclaimableSize))
+	return report, nil
+}
+
+// buildGroups groups every download and registered file by content hash, video ID and fingerprint
+func (s *DuplicatesService) buildGroups() ([]DuplicateGroup, error) {
+	entries, err := s.collectEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupEntries(entries, GroupHash, hashGroupKey)
+	groups = append(groups, groupEntries(entries, GroupVideoID, videoIdGroupKey)...)
+
+	fingerprintGroups, err := s.fingerprintGroups(entries)
+	if err != nil {
+		return nil, err
+	}
+	return append(groups, fingerprintGroups...), nil
+}
+
+// Reconcile keeps one file of each group and hardlinks, quarantines or deletes the others.
+// Every handled file is recorded in the audit log, a dry run only records what would happen.
+func (s *DuplicatesService) Reconcile(ctx context.Context, request ReconcileRequest) ([]DuplicateAction, error) {
+	switch request.Keep {
+	case KeepNewest, KeepOldest, KeepLargest:
+	default:
+		return nil, fmt.Errorf("invalid keep strategy: %s", request.Keep)
+	}
+	switch request.Action {
+	case ActionHardlink:
+		for _, group := range request.Groups {
+			if group.Kind != GroupHash {
+				return nil, fmt.Errorf("hardlinks can only replace files with identical content")
+			}
+		}
+	case ActionQuarantine, ActionDelete:
+	default:
+		return nil, fmt.Errorf("invalid duplicate action: %s", request.Action)
+	}
+
+	batchId := time.Now().Format("20060102-150405")
+	quarantineDir := ""
+	if request.Action == ActionQuarantine {
+		quarantineRoot, err := s.getQuarantineRoot()
+		if err != nil {
+			return nil, err
+		}
+		quarantineDir = filepath.Join(quarantineRoot, batchId)
+		if !request.DryRun {
+			if err := os.MkdirAll(quarantineDir, os.ModePerm); err != nil {
+				return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+			}
+		}
+	}
+	s.logService.Info(fmt.Sprintf("Reconciling %d duplicate groups (batch %s, keep %s, %s, dry run: %t)",
+		len(request.Groups), batchId, request.Keep, request.Action, request.DryRun))
+
+	// The request comes from the UI, only files the database still groups together are touched
+	currentGroups, err := s.buildGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]DuplicateAction, 0)
+	for _, requested := range request.Groups {
+		if ctx.Err() != nil {
+			return actions, ctx.Err()
+		}
+
+		group, rejected := verifyGroup(requested, currentGroups)
+		for _, path := range rejected {
+			s.logService.Warn(fmt.Sprintf("Not reconciling %s, it is not part of duplicate group %s", path, requested.Key))
+			action := DuplicateAction{
+				BatchID:      batchId,
+				PerformedAt:  time.Now().Unix(),
+				DryRun:       request.DryRun,
+				GroupKind:    requested.Kind,
+				GroupKey:     requested.Key,
+				Action:       request.Action,
+				KeepStrategy: request.Keep,
+				FilePath:     path,
+				FileSize:     -1,
+				Status:       StatusSkipped,
+				Detail:       sql.NullString{String: "not part of this duplicate group in the current report", Valid: true},
+			}
+			if err := s.duplicatesDB.InsertAction(&action); err != nil {
+				s.logService.Error(fmt.Sprintf("Failed to record duplicate action for %s: %v", path, err))
+			}
+			actions = append(actions, action)
+		}
+
+		// The report may be outdated, look at the files as they are now
+		if describeFiles(&group) < 2 {
+			s.logService.Info(fmt.Sprintf("Skipping duplicate group %s, less than two files remain", group.Key))
+			continue
+		}
+		existing := make([]DuplicateFile, 0, len(group.Files))
+		for _, file := range group.Files {
+			if file.Size >= 0 {
+				existing = append(existing, file)
+			}
+		}
+		kept := chooseKeep(existing, request.Keep)
+
+		for _, file := range existing {
+			if file.Path == kept.Path {
+				continue
+			}
+			action := DuplicateAction{
+				BatchID:      batchId,
+				PerformedAt:  time.Now().Unix(),
+				DryRun:       request.DryRun,
+				GroupKind:    group.Kind,
+				GroupKey:     group.Key,
+				Action:       request.Action,
+				KeepStrategy: request.Keep,
+				KeptPath:     kept.Path,
+				FilePath:     file.Path,
+				FileSize:     file.Size,
+			}
+			if request.Action == ActionQuarantine {
+				destination := filepath.Join(quarantineDir, fmt.Sprintf("%d_%s", len(actions)+1, filepath.Base(file.Path)))
+				action.DestinationPath = sql.NullString{String: destination, Valid: true}
+			}
+
+			s.reconcileFile(group, kept, file, &action)
+			if err := s.duplicatesDB.InsertAction(&action); err != nil {
+				s.logService.Error(fmt.Sprintf("Failed to record duplicate action for %s: %v", file.Path, err))
+			}
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// verifyGroup looks up a requested group among the current groups by kind and key.
+// Returns the current group limited to the requested files, with the paths and database IDs
+// the server knows, and the requested paths that are not part of it.
+func verifyGroup(requested DuplicateGroup, currentGroups []DuplicateGroup) (DuplicateGroup, []string) {
+	var current *DuplicateGroup
+	for i := range currentGroups {
+		if currentGroups[i].Kind == requested.Kind && currentGroups[i].Key == requested.Key {
+			current = &currentGroups[i]
+			break
+		}
+	}
+
+	rejected := make([]string, 0)
+	if current == nil {
+		for _, file := range requested.Files {
+			rejected = append(rejected, file.Path)
+		}
+		return DuplicateGroup{Kind: requested.Kind, Key: requested.Key}, rejected
+	}
+
+	known := make(map[string]DuplicateFile, len(current.Files))
+	for _, file := range current.Files {
+		known[file.Path] = file
+	}
+	verified := *current
+	verified.Files = make([]DuplicateFile, 0, len(requested.Files))
+	for _, file := range requested.Files {
+		if currentFile, ok := known[file.Path]; ok {
+			verified.Files = append(verified.Files, currentFile)
+			delete(known, file.Path)
+		} else {
+			rejected = append(rejected, file.Path)
+		}
+	}
+	return verified, rejected
+}
+
+// GetActions returns a page of the reconciliation audit log
+func (s *DuplicatesService) GetActions(offset, limit int) ([]DuplicateAction, error) {
+	return s.duplicatesDB.GetActions(offset, limit)
+}
+
+// reconcileFile handles a single file that is not kept and stores the outcome on the action
+func (s *DuplicatesService) reconcileFile(group DuplicateGroup, kept, file DuplicateFile, action *DuplicateAction) {
+	setOutcome := func(status string, detail string) {
+		action.Status = status
+		action.Detail = sql.NullString{String: detail, Valid: detail != ""}
+	}
+
+	if file.LinkedTo != "" || isSameFile(kept.Path, file.Path) {
+		setOutcome(StatusSkipped, "already the same file on disk as the kept file")
+		return
+	}
+
+	// Only touch identical files if they are still identical
+	if group.Kind == GroupHash {
+		identical, err := haveSameContent(group.Key, kept.Path, file.Path)
+		if err != nil {
+			setOutcome(StatusFailed, err.Error())
+			return
+		}
+		if !identical {
+			setOutcome(StatusSkipped, "content changed since the report was created")
+			return
+		}
+	}
+
+	if action.DryRun {
+		setOutcome(StatusPlanned, "")
+		return
+	}
+
+	var err error
+	switch action.Action {
+	case ActionHardlink:
+		err = replaceWithHardlink(kept.Path, file.Path)
+	case ActionQuarantine:
+		err = moveFile(file.Path, action.DestinationPath.String)
+	case ActionDelete:
+		err = os.Remove(file.Path)
+	}
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Failed to %s duplicate %s: %v", action.Action, file.Path, err))
+		setOutcome(StatusFailed, err.Error())
+		return
+	}
+	s.logService.Info(fmt.Sprintf("Duplicate %s: %s (kept %s)", action.Action, file.Path, kept.Path))
+
+	detail := ""
+	if action.Action != ActionHardlink {
+		if err := s.forgetFile(file); err != nil {
+			s.logService.Warn(fmt.Sprintf("Failed to update database for removed duplicate %s: %v", file.Path, err))
+			detail = err.Error()
+		}
+	}
+	setOutcome(StatusDone, detail)
+}
+
+// forgetFile updates the database after a file was removed from its location.
+// Downloads are marked as duplicates so they are neither verified nor downloaded again.
+func (s *DuplicatesService) forgetFile(file DuplicateFile) error {
+	for _, downloadId := range file.DownloadIDs {
+		if err := s.downloadDB.MarkDuplicate(downloadId); err != nil {
+			return fmt.Errorf("failed to mark download %d as duplicate: %w", downloadId, err)
+		}
+		if err := s.fingerprintService.RemoveDownload(downloadId); err != nil {
+			return fmt.Errorf("failed to remove fingerprint of download %d: %w", downloadId, err)
+		}
+	}
+	if len(file.RegistryIDs) > 0 {
+		if _, err := s.fileRegistryService.RemovePath(file.Path); err != nil {
+			return fmt.Errorf("failed to remove registered file: %w", err)
+		}
+	}
+	return nil
+}
+
+// getQuarantineRoot returns duplicate_quarantine_directory, or a directory in the working directory if it is not set
+func (s *DuplicatesService) getQuarantineRoot() (string, error) {
+	quarantineDir, err := s.settingsService.GetSettingString("duplicate_quarantine_directory")
+	if err != nil {
+		return "", fmt.Errorf("failed to get duplicate_quarantine_directory setting: %w", err)
+	}
+	if quarantineDir = strings.TrimSpace(quarantineDir); quarantineDir != "" {
+		return quarantineDir, nil
+	}
+	return pathing.GetWorkingDir("quarantine")
+}
+
+// collectEntries gathers every successful download and registered file
+func (s *DuplicatesService) collectEntries() ([]libraryEntry, error) {
+	entries := make([]libraryEntry, 0)
+
+	downloads, err := s.downloadDB.GetSuccessfulDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get successful downloads: %w", err)
+	}
+	for _, dl := range downloads {
+		if !dl.FullPath.Valid {
+			continue
+		}
+		entries = append(entries, libraryEntry{
+			source:        fingerprint.SourceDownload,
+			sourceId:      dl.ID,
+			path:          dl.FullPath.String,
+			hash:          dl.MD5.String,
+			hashAlgorithm: dl.HashAlgorithm,
+			extractor:     dl.Extractor.String,
+			videoId:       dl.VideoID.String,
+		})
+	}
+
+	for offset := 0; ; offset += registryPageSize {
+		files, err := s.fileRegistryService.GetAllPaginated(offset, registryPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get registered files: %w", err)
+		}
+		for _, file := range files {
+			entries = append(entries, libraryEntry{
+				source:        fingerprint.SourceFileRegistry,
+				sourceId:      file.ID,
+				path:          file.FilePath,
+				hash:          file.MD5,
+				hashAlgorithm: file.HashAlgorithm,
+				extractor:     file.Extractor.String,
+				videoId:       file.VideoID.String,
+			})
+		}
+		if len(files) < registryPageSize {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// fingerprintGroups turns groups of similar fingerprints into duplicate groups.
+// Groups of files with identical content are left out, they are already reported by hash.
+func (s *DuplicatesService) fingerprintGroups(entries []libraryEntry) ([]DuplicateGroup, error) {
+	similarGroups, err := s.fingerprintService.FindSimilarGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to group fingerprints: %w", err)
+	}
+
+	bySource := make(map[string]libraryEntry, len(entries))
+	for _, entry := range entries {
+		bySource[fmt.Sprintf("%s:%d", entry.source, entry.sourceId)] = entry
+	}
+
+	groups := make([]DuplicateGroup, 0, len(similarGroups))
+	for _, similar := range similarGroups {
+		members := make([]libraryEntry, 0, len(similar.Fingerprints))
+		hashKeys := make(map[string]bool)
+		lowestId := similar.Fingerprints[0].ID
+		for _, fp := range similar.Fingerprints {
+			entry, exists := bySource[fmt.Sprintf("%s:%d", fp.Source, fp.SourceID)]
+			if !exists {
+				continue // Fingerprint of a file that is no longer successful or registered
+			}
+			members = append(members, entry)
+			key, _, _ := hashGroupKey(entry)
+			hashKeys[key] = true
+			if fp.ID < lowestId {
+				lowestId = fp.ID
+			}
+		}
+		if len(hashKeys) < 2 {
+			continue
+		}
+
+		memberGroups := groupEntries(members, GroupFingerprint, func(entry libraryEntry) (string, string, bool) {
+			return fmt.Sprintf("%d", lowestId), fmt.Sprintf("Similar media (%.0f%%)", similar.Similarity*100), true
+		})
+		for i := range memberGroups {
+			memberGroups[i].Similarity = similar.Similarity
+		}
+		groups = append(groups, memberGroups...)
+	}
+	return groups, nil
+}
+
+// hashGroupKey groups entries by hash algorithm and content hash
+func hashGroupKey(entry libraryEntry) (string, string, bool) {
+	if entry.hash == "" {
+		return "", "", false
+	}
+	return entry.hashAlgorithm + ":" + entry.hash, fmt.Sprintf("Identical content (%s %s)", entry.hashAlgorithm, entry.hash), true
+}
+
+// videoIdGroupKey groups entries by extractor, video ID and file extension.
+// Different formats of the same video are intentional and not grouped.
+func videoIdGroupKey(entry libraryEntry) (string, string, bool) {
+	if entry.extractor == "" || entry.videoId == "" {
+		return "", "", false
+	}
+	ext := strings.ToLower(filepath.Ext(entry.path))
+	key := entry.extractor + ":" + entry.videoId + ":" + ext
+	return key, fmt.Sprintf("Same video (%s %s, %s)", entry.extractor, entry.videoId, ext), true
+}
+
+// groupEntries groups entries by key. Entries with the same path are merged into one file.
+// Groups with fewer than two paths are omitted, groups are returned in order of first appearance.
+func groupEntries(entries []libraryEntry, kind string, keyFunc func(entry libraryEntry) (key string, label string, ok bool)) []DuplicateGroup {
+	byKey := make(map[string]*DuplicateGroup)
+	keys := make([]string, 0)
+	for _, entry := range entries {
+		key, label, ok := keyFunc(entry)
+		if !ok {
+			continue
+		}
+		group, exists := byKey[key]
+		if !exists {
+			group = &DuplicateGroup{Kind: kind, Key: key, Label: label}
+			byKey[key] = group
+			keys = append(keys, key)
+		}
+
+		path := filepath.Clean(entry.path)
+		var file *DuplicateFile
+		for i := range group.Files {
+			if group.Files[i].Path == path {
+				file = &group.Files[i]
+				break
+			}
+		}
+		if file == nil {
+			group.Files = append(group.Files, DuplicateFile{Path: path, DownloadIDs: []int{}, RegistryIDs: []int{}})
+			file = &group.Files[len(group.Files)-1]
+		}
+		if entry.source == fingerprint.SourceDownload {
+			file.DownloadIDs = append(file.DownloadIDs, entry.sourceId)
+		} else {
+			file.RegistryIDs = append(file.RegistryIDs, entry.sourceId)
+		}
+	}
+
+	groups := make([]DuplicateGroup, 0)
+	for _, key := range keys {
+		if len(byKey[key].Files) >= 2 {
+			groups = append(groups, *byKey[key])
+		}
+	}
+	return groups
+}
+
+// describeFiles reads size and modification time of every file in a group and calculates the group sizes.
+// Missing files get a size of -1. Returns the amount of distinct files on disk.
+func describeFiles(group *DuplicateGroup) int {
+	infos := make([]os.FileInfo, len(group.Files))
+	distinct := make([]int64, 0, len(group.Files))
+	for i := range group.Files {
+		file := &group.Files[i]
+		file.LinkedTo = ""
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			file.Size = -1
+			file.ModifiedAt = 0
+			continue
+		}
+		infos[i] = info
+		file.Size = info.Size()
+		file.ModifiedAt = info.ModTime().Unix()
+
+		for j := 0; j < i; j++ {
+			if infos[j] != nil && os.SameFile(infos[j], info) {
+				file.LinkedTo = group.Files[j].Path
+				break
+			}
+		}
+		if file.LinkedTo == "" {
+			distinct = append(distinct, file.Size)
+		}
+	}
+
+	group.TotalSize = 0
+	largest := int64(0)
+	for _, size := range distinct {
+		group.TotalSize += size
+		if size > largest {
+			largest = size
+		}
+	}
+	group.ReclaimableSize = group.TotalSize - largest
+	return len(distinct)
+}
+
+// chooseKeep picks the file to keep, ties go to the first file
+func chooseKeep(files []DuplicateFile, keep string) DuplicateFile {
+	kept := files[0]
+	for _, file := range files[1:] {
+		switch keep {
+		case KeepNewest:
+			if file.ModifiedAt > kept.ModifiedAt {
+				kept = file
+			}
+		case KeepOldest:
+			if file.ModifiedAt < kept.ModifiedAt {
+				kept = file
+			}
+		case KeepLargest:
+			if file.Size > kept.Size {
+				kept = file
+			}
+		}
+	}
+	return kept
+}
+
+// haveSameContent re-hashes two files with the algorithm of a hash group key
+func haveSameContent(groupKey, pathA, pathB string) (bool, error) {
+	algorithmName, _, _ := strings.Cut(groupKey, ":")
+	algorithm, err := fileutils.ParseHashAlgorithm(algorithmName)
+	if err != nil {
+		return false, err
+	}
+	hashA, err := fileutils.CalculateHash(pathA, algorithm)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash %s: %w", pathA, err)
+	}
+	hashB, err := fileutils.CalculateHash(pathB, algorithm)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash %s: %w", pathB, err)
+	}
+	return hashA == hashB, nil
+}
+
+func isSameFile(pathA, pathB string) bool {
+	infoA, err := os.Stat(pathA)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(pathB)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// replaceWithHardlink replaces target with a hardlink to source.
+// The link is created next to the target first so the target is never lost if linking fails.
+func replaceWithHardlink(source, target string) error {
+	tempPath := target + ".videoarchiver-link"
+	if err := os.Link(source, tempPath); err != nil {
+		return fmt.Errorf("failed to create hardlink, files must be on the same drive: %w", err)
+	}
+	if err := os.Rename(tempPath, target); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to replace file with hardlink: %w", err)
+	}
+	return nil
+}
+
+// moveFile moves a file, copying it when the destination is on a different drive
+func moveFile(source, destination string) error {
+	if err := os.Rename(source, destination); err == nil {
+		return nil
+	}
+	if err := cp.Copy(source, destination); err != nil {
+		return fmt.Errorf("failed to copy file to quarantine: %w", err)
+	}
+	return os.Remove(source)
+}
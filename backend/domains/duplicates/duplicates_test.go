@@ -0,0 +1,152 @@
+package duplicates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"videoarchiver/backend/domains/fingerprint"
+)
+
+func TestGroupEntries(t *testing.T) {
+	entries := []libraryEntry{
+		{source: fingerprint.SourceDownload, sourceId: 1, path: "/music/a.mp3", hash: "aaa", hashAlgorithm: "md5"},
+		// Same file known as a download and registered file is not a duplicate of itself
+		{source: fingerprint.SourceFileRegistry, sourceId: 7, path: "/music/a.mp3", hash: "aaa", hashAlgorithm: "md5"},
+		{source: fingerprint.SourceFileRegistry, sourceId: 8, path: "/backup/a.mp3", hash: "aaa", hashAlgorithm: "md5"},
+		{source: fingerprint.SourceFileRegistry, sourceId: 9, path: "/backup/b.mp3", hash: "bbb", hashAlgorithm: "md5"},
+		{source: fingerprint.SourceFileRegistry, sourceId: 10, path: "/other/b.mp3", hash: "bbb", hashAlgorithm: "xxh3"},
+	}
+
+	groups := groupEntries(entries, GroupHash, hashGroupKey)
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 group, got %d", len(groups))
+	}
+	group := groups[0]
+	if group.Key != "md5:aaa" || len(group.Files) != 2 {
+		t.Fatalf("Unexpected group: %+v", group)
+	}
+	first := group.Files[0]
+	if len(first.DownloadIDs) != 1 || first.DownloadIDs[0] != 1 || len(first.RegistryIDs) != 1 || first.RegistryIDs[0] != 7 {
+		t.Errorf("Expected download 1 and registry entry 7 merged into one file, got %+v", first)
+	}
+}
+
+func TestVideoIdGroupKeySeparatesFormats(t *testing.T) {
+	entries := []libraryEntry{
+		{source: fingerprint.SourceDownload, sourceId: 1, path: "/audio/song.mp3", extractor: "youtube", videoId: "dQw4w9WgXcQ"},
+		{source: fingerprint.SourceDownload, sourceId: 2, path: "/video/song.mp4", extractor: "youtube", videoId: "dQw4w9WgXcQ"},
+		{source: fingerprint.SourceFileRegistry, sourceId: 3, path: "/old/song.MP3", extractor: "youtube", videoId: "dQw4w9WgXcQ"},
+	}
+
+	groups := groupEntries(entries, GroupVideoID, videoIdGroupKey)
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 group, got %d", len(groups))
+	}
+	if groups[0].Key != "youtube:dQw4w9WgXcQ:.mp3" {
+		t.Errorf("Unexpected key: %s", groups[0].Key)
+	}
+}
+
+func TestVerifyGroup(t *testing.T) {
+	current := []DuplicateGroup{
+		{Kind: GroupVideoID, Key: "youtube:abc:.mp3", Files: []DuplicateFile{
+			{Path: "/music/a.mp3", DownloadIDs: []int{1}},
+			{Path: "/backup/a.mp3", RegistryIDs: []int{7}},
+		}},
+	}
+
+	// IDs and kind come from the server, paths outside the group are rejected
+	requested := DuplicateGroup{Kind: GroupVideoID, Key: "youtube:abc:.mp3", Files: []DuplicateFile{
+		{Path: "/music/a.mp3", DownloadIDs: []int{99}},
+		{Path: "/home/user/important.doc"},
+	}}
+	group, rejected := verifyGroup(requested, current)
+	if len(group.Files) != 1 || group.Files[0].Path != "/music/a.mp3" || group.Files[0].DownloadIDs[0] != 1 {
+		t.Errorf("Expected only the known file with its stored IDs, got %+v", group.Files)
+	}
+	if len(rejected) != 1 || rejected[0] != "/home/user/important.doc" {
+		t.Errorf("Expected the unknown path to be rejected, got %v", rejected)
+	}
+
+	// A group cannot be passed off as identical content to allow hardlinks
+	requested.Kind = GroupHash
+	group, rejected = verifyGroup(requested, current)
+	if len(group.Files) != 0 || len(rejected) != 2 {
+		t.Errorf("Expected a group of another kind to be rejected, got %+v, %v", group.Files, rejected)
+	}
+}
+
+func TestDescribeFilesAndChooseKeep(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "older.mp4")
+	newer := filepath.Join(dir, "newer.mp4")
+	linked := filepath.Join(dir, "linked.mp4")
+	if err := os.WriteFile(older, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newer, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(older, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceWithHardlinkOrCreate(older, linked); err != nil {
+		t.Skipf("Hardlinks not supported: %v", err)
+	}
+
+	group := DuplicateGroup{Files: []DuplicateFile{
+		{Path: older}, {Path: newer}, {Path: linked}, {Path: filepath.Join(dir, "missing.mp4")},
+	}}
+	if distinct := describeFiles(&group); distinct != 2 {
+		t.Fatalf("Expected 2 distinct files, got %d", distinct)
+	}
+	if group.Files[2].LinkedTo != older {
+		t.Errorf("Expected linked file to refer to %s, got %q", older, group.Files[2].LinkedTo)
+	}
+	if group.Files[3].Size != -1 {
+		t.Errorf("Expected missing file to have size -1, got %d", group.Files[3].Size)
+	}
+	if group.ReclaimableSize != int64(len("content")) {
+		t.Errorf("Expected %d reclaimable bytes, got %d", len("content"), group.ReclaimableSize)
+	}
+
+	existing := group.Files[:3]
+	if kept := chooseKeep(existing, KeepNewest); kept.Path != newer {
+		t.Errorf("Expected newest to keep %s, got %s", newer, kept.Path)
+	}
+	if kept := chooseKeep(existing, KeepOldest); kept.Path != older {
+		t.Errorf("Expected oldest to keep %s, got %s", older, kept.Path)
+	}
+}
+
+func TestReplaceWithHardlink(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.mp4")
+	target := filepath.Join(dir, "target.mp4")
+	if err := os.WriteFile(source, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := replaceWithHardlink(source, target); err != nil {
+		t.Skipf("Hardlinks not supported: %v", err)
+	}
+	if !isSameFile(source, target) {
+		t.Error("Expected target to be a hardlink to source")
+	}
+	if _, err := os.Stat(target + ".videoarchiver-link"); !os.IsNotExist(err) {
+		t.Error("Expected temporary link to be removed")
+	}
+}
+
+// replaceWithHardlinkOrCreate links target to source, creating target first if needed
+func replaceWithHardlinkOrCreate(source, target string) error {
+	if err := os.WriteFile(target, nil, 0644); err != nil {
+		return err
+	}
+	return replaceWithHardlink(source, target)
+}
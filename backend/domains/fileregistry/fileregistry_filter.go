@@ -0,0 +1,278 @@
+package fileregistry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"videoarchiver/backend/domains/fileutils"
+)
+
+// Patterns ignored by default: partial downloads, temporary files and system junk
+var defaultIgnorePatterns = []string{
+	"*.part", "*.ytdl", "*.tmp", "*.temp",
+	"Thumbs.db", "desktop.ini", ".DS_Store",
+	"$RECYCLE.BIN", "System Volume Information", "@eaDir",
+}
+
+// ScanFilter decides which files under a watched root are registered
+type ScanFilter struct {
+	// Lowercase extensions with a leading dot. Empty includes files of every extension.
+	IncludeExtensions []string `json:"include_extensions"`
+	// Glob patterns as understood by filepath.Match, matched case-insensitively.
+	// Patterns without a slash match any file or directory name, patterns with a slash
+	// match the path relative to the root where ** matches any number of directories.
+	IgnorePatterns []string `json:"ignore_patterns"`
+	MinFileSize    int64    `json:"min_file_size"` // Bytes
+	IncludeHidden  bool     `json:"include_hidden"`
+	FollowSymlinks bool     `json:"follow_symlinks"`
+}
+
+// DefaultScanFilter returns the filter used for newly added roots: media files only, without temporary files
+func DefaultScanFilter() ScanFilter {
+	return ScanFilter{
+		IncludeExtensions: append([]string{}, fileutils.MediaExtensions...),
+		IgnorePatterns:    append([]string{}, defaultIgnorePatterns...),
+	}
+}
+
+// Normalize validates the filter and returns it with cleaned up extensions and patterns
+func (s ScanFilter) Normalize() (ScanFilter, error) {
+	normalized := ScanFilter{
+		IncludeExtensions: make([]string, 0, len(s.IncludeExtensions)),
+		IgnorePatterns:    make([]string, 0, len(s.IgnorePatterns)),
+		MinFileSize:       s.MinFileSize,
+		IncludeHidden:     s.IncludeHidden,
+		FollowSymlinks:    s.FollowSymlinks,
+	}
+	if normalized.MinFileSize < 0 {
+		return normalized, fmt.Errorf("minimum file size can't be negative")
+	}
+
+	seen := make(map[string]bool)
+	for _, ext := range s.IncludeExtensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		ext = strings.TrimPrefix(ext, "*")
+		if ext == "" || ext == "." {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if strings.ContainsAny(ext, `/\`) {
+			return normalized, fmt.Errorf("invalid extension: %s", ext)
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			normalized.IncludeExtensions = append(normalized.IncludeExtensions, ext)
+		}
+	}
+
+	for _, pattern := range s.IgnorePatterns {
+		pattern = strings.Trim(strings.TrimSpace(strings.ReplaceAll(pattern, `\`, "/")), "/")
+		if pattern == "" {
+			continue
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return normalized, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+			}
+		}
+		normalized.IgnorePatterns = append(normalized.IgnorePatterns, pattern)
+	}
+	return normalized, nil
+}
+
+// excludesDir checks if a directory and everything below it is skipped.
+// relPath is relative to the root, info may be nil if the directory could not be read.
+func (s *ScanFilter) excludesDir(relPath string, info os.FileInfo) bool {
+	if !s.IncludeHidden && isHidden(filepath.Base(relPath), info) {
+		return true
+	}
+	return s.isIgnored(relPath)
+}
+
+// includesFile checks if a file is registered.
+// relPath is relative to the root, info may be nil for files that no longer exist.
+func (s *ScanFilter) includesFile(relPath string, info os.FileInfo) bool {
+	if len(s.IncludeExtensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(relPath))
+		included := false
+		for _, includeExt := range s.IncludeExtensions {
+			if ext == includeExt {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if info != nil && info.Size() < s.MinFileSize {
+		return false
+	}
+	if !s.IncludeHidden && isHidden(filepath.Base(relPath), info) {
+		return false
+	}
+	return !s.isIgnored(relPath)
+}
+
+// includesPath checks a file and every directory between it and the root.
+// Used for single paths outside of a walk, such as watcher events and registry lookups.
+func (s *ScanFilter) includesPath(rootPath, path string) bool {
+	relPath, err := filepath.Rel(rootPath, path)
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+		return false
+	}
+
+	segments := strings.Split(relPath, string(os.PathSeparator))
+	for i := 1; i < len(segments); i++ {
+		dirRelPath := filepath.Join(segments[:i]...)
+		info, err := os.Lstat(filepath.Join(rootPath, dirRelPath))
+		if err != nil {
+			info = nil
+		} else if info.Mode()&os.ModeSymlink != 0 && !s.FollowSymlinks {
+			return false
+		}
+		if s.excludesDir(dirRelPath, info) {
+			return false
+		}
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		info = nil
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		if !s.FollowSymlinks {
+			return false
+		}
+		if info, err = os.Stat(path); err != nil {
+			info = nil
+		}
+	}
+	return s.includesFile(relPath, info)
+}
+
+// isIgnored checks the path, and each directory it is in, against the ignore patterns
+func (s *ScanFilter) isIgnored(relPath string) bool {
+	if len(s.IgnorePatterns) == 0 {
+		return false
+	}
+	segments := strings.Split(strings.ToLower(filepath.ToSlash(relPath)), "/")
+	for _, pattern := range s.IgnorePatterns {
+		patternSegments := strings.Split(strings.ToLower(pattern), "/")
+		if len(patternSegments) == 1 {
+			for _, segment := range segments {
+				if matched, _ := filepath.Match(patternSegments[0], segment); matched {
+					return true
+				}
+			}
+			continue
+		}
+		// A matching directory excludes everything inside it
+		for i := 1; i <= len(segments); i++ {
+			if matchSegments(patternSegments, segments[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, ** matches zero or more segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// walkRoot calls visit for every directory and file under a directory of a root that pass the root's filter.
+// Excluded directories are not descended into. visit receives a non-nil error for paths that can't be read.
+func walkRoot(ctx context.Context, root WatchedRoot, directoryPath string, visit func(path string, info os.FileInfo, err error)) error {
+	info, err := os.Stat(directoryPath)
+	if err != nil {
+		return err
+	}
+	walker := &rootWalker{ctx: ctx, rootPath: root.Path, filter: root.Filter, visit: visit, visited: make(map[string]bool)}
+	return walker.walkDir(directoryPath, info)
+}
+
+type rootWalker struct {
+	ctx      context.Context
+	rootPath string
+	filter   ScanFilter
+	visit    func(path string, info os.FileInfo, err error)
+	visited  map[string]bool // Resolved directories, followed symlinks may loop back to a parent
+}
+
+func (w *rootWalker) walkDir(dirPath string, info os.FileInfo) error {
+	if w.ctx.Err() != nil {
+		return w.ctx.Err()
+	}
+	if w.filter.FollowSymlinks {
+		if resolved, err := filepath.EvalSymlinks(dirPath); err == nil {
+			if w.visited[resolved] {
+				return nil
+			}
+			w.visited[resolved] = true
+		}
+	}
+	w.visit(dirPath, info, nil)
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		w.visit(dirPath, nil, err)
+		return nil // Continue walking, don't fail on individual directory errors
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dirPath, entry.Name())
+		entryInfo, err := entry.Info()
+		if err != nil {
+			w.visit(path, nil, err)
+			continue
+		}
+		if entryInfo.Mode()&os.ModeSymlink != 0 {
+			if !w.filter.FollowSymlinks {
+				continue
+			}
+			if entryInfo, err = os.Stat(path); err != nil {
+				w.visit(path, nil, err)
+				continue
+			}
+		}
+
+		relPath, err := filepath.Rel(w.rootPath, path)
+		if err != nil {
+			continue
+		}
+		if entryInfo.IsDir() {
+			if w.filter.excludesDir(relPath, entryInfo) {
+				continue
+			}
+			if err := w.walkDir(path, entryInfo); err != nil {
+				return err
+			}
+			continue
+		}
+		if entryInfo.Mode().IsRegular() && w.filter.includesFile(relPath, entryInfo) {
+			w.visit(path, entryInfo, nil)
+		}
+	}
+	return nil
+}
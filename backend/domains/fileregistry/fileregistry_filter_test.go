@@ -0,0 +1,146 @@
+package fileregistry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestScanFilterNormalize(t *testing.T) {
+	filter, err := ScanFilter{
+		IncludeExtensions: []string{"MP4", ".mkv", "*.webm", " ", ".mp4"},
+		IgnorePatterns:    []string{" *.part ", `Temp\Old`, "", "/Downloads/"},
+	}.Normalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedExtensions := []string{".mp4", ".mkv", ".webm"}
+	if len(filter.IncludeExtensions) != len(expectedExtensions) {
+		t.Fatalf("expected extensions %v, got %v", expectedExtensions, filter.IncludeExtensions)
+	}
+	for i, ext := range expectedExtensions {
+		if filter.IncludeExtensions[i] != ext {
+			t.Errorf("expected extension %q at %d, got %q", ext, i, filter.IncludeExtensions[i])
+		}
+	}
+
+	expectedPatterns := []string{"*.part", "Temp/Old", "Downloads"}
+	if len(filter.IgnorePatterns) != len(expectedPatterns) {
+		t.Fatalf("expected patterns %v, got %v", expectedPatterns, filter.IgnorePatterns)
+	}
+	for i, pattern := range expectedPatterns {
+		if filter.IgnorePatterns[i] != pattern {
+			t.Errorf("expected pattern %q at %d, got %q", pattern, i, filter.IgnorePatterns[i])
+		}
+	}
+
+	if _, err := (ScanFilter{IgnorePatterns: []string{"[abc"}}).Normalize(); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+	if _, err := (ScanFilter{MinFileSize: -1}).Normalize(); err == nil {
+		t.Error("expected error for negative minimum size")
+	}
+}
+
+func TestScanFilterIncludesFile(t *testing.T) {
+	filter := DefaultScanFilter()
+	filter.IgnorePatterns = append(filter.IgnorePatterns, "Music/**/Drafts", "*sample*")
+
+	tests := []struct {
+		relPath  string
+		expected bool
+	}{
+		{relPath: "video.mp4", expected: true},
+		{relPath: filepath.Join("Shows", "Episode.MKV"), expected: true},
+		{relPath: "notes.txt", expected: false},
+		{relPath: "video.mp4.part", expected: false},
+		{relPath: ".hidden.mp4", expected: false},
+		{relPath: filepath.Join("@eaDir", "video.mp4"), expected: false},
+		{relPath: filepath.Join("Music", "Album", "Drafts", "song.mp3"), expected: false},
+		{relPath: filepath.Join("Music", "Drafts", "song.mp3"), expected: false},
+		{relPath: filepath.Join("Other", "Drafts", "song.mp3"), expected: true},
+		{relPath: "SAMPLE clip.mp4", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			if result := filter.includesFile(tt.relPath, nil); result != tt.expected {
+				t.Errorf("includesFile(%q) = %v, expected %v", tt.relPath, result, tt.expected)
+			}
+		})
+	}
+
+	filter.IncludeHidden = true
+	if !filter.includesFile(".hidden.mp4", nil) {
+		t.Error("expected hidden file to be included when IncludeHidden is set")
+	}
+	filter.IncludeExtensions = nil
+	if !filter.includesFile("notes.txt", nil) {
+		t.Error("expected every extension to be included when IncludeExtensions is empty")
+	}
+}
+
+func TestWalkRoot(t *testing.T) {
+	rootPath := t.TempDir()
+	files := map[string]int{
+		"video.mp4":                             2048,
+		"small.mp4":                             10,
+		"notes.txt":                             2048,
+		filepath.Join("Shows", "ep1.mkv"):       2048,
+		filepath.Join(".cache", "clip.mp4"):     2048,
+		filepath.Join("Skip", "ep2.mkv"):        2048,
+		filepath.Join("Shows", "ep3.webm.part"): 2048,
+	}
+	for relPath, size := range files {
+		path := filepath.Join(rootPath, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := WatchedRoot{Path: rootPath, Filter: DefaultScanFilter()}
+	root.Filter.MinFileSize = 1024
+	root.Filter.IgnorePatterns = append(root.Filter.IgnorePatterns, "skip")
+
+	found := make([]string, 0)
+	err := walkRoot(context.Background(), root, rootPath, func(path string, info os.FileInfo, err error) {
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", path, err)
+			return
+		}
+		if !info.IsDir() {
+			relPath, _ := filepath.Rel(rootPath, path)
+			found = append(found, relPath)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(found)
+
+	expected := []string{filepath.Join("Shows", "ep1.mkv"), "video.mp4"}
+	if len(found) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, found)
+	}
+	for i := range expected {
+		if found[i] != expected[i] {
+			t.Errorf("expected %q at %d, got %q", expected[i], i, found[i])
+		}
+	}
+
+	if !root.Filter.includesPath(rootPath, filepath.Join(rootPath, "video.mp4")) {
+		t.Error("expected video.mp4 to be included")
+	}
+	if root.Filter.includesPath(rootPath, filepath.Join(rootPath, "small.mp4")) {
+		t.Error("expected small.mp4 to be excluded by size")
+	}
+	if root.Filter.includesPath(rootPath, filepath.Join(rootPath, "Skip", "ep2.mkv")) {
+		t.Error("expected file in ignored directory to be excluded")
+	}
+}
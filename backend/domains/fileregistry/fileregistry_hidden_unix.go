@@ -0,0 +1,13 @@
+//go:build !windows
+
+package fileregistry
+
+import (
+	"os"
+	"strings"
+)
+
+// isHidden checks for a leading dot in the name
+func isHidden(name string, info os.FileInfo) bool {
+	return strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package fileregistry
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// isHidden checks for the hidden attribute, or a leading dot as used by files copied from other systems
+func isHidden(name string, info os.FileInfo) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	if info == nil {
+		return false
+	}
+	if attributes, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return attributes.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package fileregistry
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"videoarchiver/backend/domains/runner"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+// Container tags that may hold the source URL, in order of preference.
+// yt-dlp writes the webpage URL to purl and comment when embedding metadata.
+var urlTags = []string{"purl", "comment", "description", "synopsis"}
+
+// FileMetadata is the information read from a file's container tags
+type FileMetadata struct {
+	KnownUrl  string
+	Extractor string
+	VideoID   string
+	Title     string
+	Artist    string
+	Duration  float64
+}
+
+// ExtractFileMetadata reads the source URL, title, artist and duration of a file.
+// Container tags are read with ffprobe, files ffprobe can't read fall back to scanning raw bytes for a URL.
+// Source URLs without a video ID in them are resolved with yt-dlp.
+func (f *FileRegistryService) ExtractFileMetadata(filePath string) *FileMetadata {
+	meta, err := probeFileMetadata(filePath)
+	if err != nil || meta.KnownUrl == "" {
+		if meta == nil {
+			meta = &FileMetadata{}
+		}
+		knownUrl, err := ExtractKnownUrlFromBytes(filePath)
+		if err == nil && knownUrl != "" {
+			meta.setKnownUrl(knownUrl)
+		}
+	}
+	meta.resolveIdentity(ytdlp.ResolveVideoURL)
+	return meta
+}
+
+// resolveIdentity looks up the extractor and video ID of a source URL the known sites don't cover,
+// such as other sites yt-dlp supports or SoundCloud URLs that don't contain the ID.
+// A failed lookup leaves the file without an identity, it is retried when the file changes.
+func (m *FileMetadata) resolveIdentity(resolve func(url string) (string, string, error)) {
+	if m.KnownUrl == "" || m.VideoID != "" {
+		return
+	}
+	extractor, videoId, err := resolve(m.KnownUrl)
+	if err != nil {
+		return
+	}
+	m.Extractor = extractor
+	m.VideoID = videoId
+}
+
+// probeFileMetadata reads format and stream tags with ffprobe
+func probeFileMetadata(filePath string) (*FileMetadata, error) {
+	ffprobePath, err := ytdlp.GetFfprobePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ffprobe path: %w", err)
+	}
+	stdout, stderr, err := runner.RunWithOutput(ffprobePath,
+		"-v", "error", "-print_format", "json", "-show_format", "-show_streams", filePath)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w: %s", err, stderr)
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	// Tag names differ in case between containers, Ogg and Opus store tags on the stream
+	tags := make(map[string]string)
+	for _, stream := range probe.Streams {
+		for key, value := range stream.Tags {
+			tags[strings.ToLower(key)] = value
+		}
+	}
+	for key, value := range probe.Format.Tags {
+		tags[strings.ToLower(key)] = value
+	}
+
+	duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+	return metadataFromTags(tags, duration), nil
+}
+
+// metadataFromTags builds FileMetadata from lowercased container tags
+func metadataFromTags(tags map[string]string, duration float64) *FileMetadata {
+	meta := &FileMetadata{
+		Title:    strings.TrimSpace(tags["title"]),
+		Artist:   strings.TrimSpace(tags["artist"]),
+		Duration: duration,
+	}
+	if meta.Artist == "" {
+		meta.Artist = strings.TrimSpace(tags["album_artist"])
+	}
+
+	for _, tag := range urlTags {
+		if recognized := ytdlp.FindVideoURL(tags[tag]); recognized != nil {
+			meta.setRecognizedUrl(recognized)
+			return meta
+		}
+	}
+
+	// Keep an unrecognized purl, it is the webpage URL of a site yt-dlp downloaded from
+	purl := strings.TrimSpace(tags["purl"])
+	if strings.HasPrefix(purl, "http://") || strings.HasPrefix(purl, "https://") {
+		meta.KnownUrl = purl
+	}
+	return meta
+}
+
+func (m *FileMetadata) setKnownUrl(url string) {
+	if recognized := ytdlp.RecognizeVideoURL(url); recognized != nil {
+		m.setRecognizedUrl(recognized)
+		return
+	}
+	m.KnownUrl = url
+}
+
+func (m *FileMetadata) setRecognizedUrl(recognized *ytdlp.VideoURL) {
+	m.KnownUrl = recognized.CanonicalURL
+	if recognized.VideoID != "" {
+		m.Extractor = recognized.Extractor
+		m.VideoID = recognized.VideoID
+	}
+}
+
+// ExtractKnownUrlFromBytes scans the start of a file for a recognized video URL.
+// Used for files ffprobe can't read.
+func ExtractKnownUrlFromBytes(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// Read first 512KB of file (MP4 metadata can be larger and further in)
+	buffer := make([]byte, 524288)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	data := buffer[:n]
+
+	// Look for common metadata tags and extract text around them
+	patterns := []string{
+		"COMM", // ID3v2 Comment
+		"comm", // lowercase variant
+		"TXXX", // ID3v2 User defined text
+		"TIT2", // ID3v2 Title
+		"TALB", // ID3v2 Album
+		"TPE1", // ID3v2 Artist
+		"©cmt", // iTunes comment
+		"desc", // Description
+	}
+
+	for _, pattern := range patterns {
+		if idx := bytes.Index(data, []byte(pattern)); idx != -1 {
+			// Look for a URL in the next 1000 bytes after the tag
+			start := idx
+			end := start + 1000
+			if end > len(data) {
+				end = len(data)
+			}
+
+			if recognized := ytdlp.FindVideoURL(cleanPrintable(data[start:end])); recognized != nil {
+				return recognized.CanonicalURL, nil
+			}
+		}
+	}
+
+	// Fallback: search the entire buffer for a URL
+	if recognized := ytdlp.FindVideoURL(cleanPrintable(data)); recognized != nil {
+		return recognized.CanonicalURL, nil
+	}
+
+	return "", nil
+}
+
+// cleanPrintable keeps only printable ASCII and basic whitespace
+func cleanPrintable(data []byte) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 32 && r <= 126) || r == '\n' || r == '\r' || r == '\t' {
+			return r
+		}
+		return -1
+	}, string(data))
+}
+
+// nullIfEmpty converts an empty string to NULL for storage
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
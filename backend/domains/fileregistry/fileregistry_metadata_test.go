@@ -0,0 +1,36 @@
+package fileregistry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveIdentity(t *testing.T) {
+	resolved := func(url string) (string, string, error) { return "vimeo", "76979871", nil }
+	failed := func(url string) (string, string, error) { return "", "", errors.New("unsupported URL") }
+
+	meta := &FileMetadata{KnownUrl: "https://vimeo.com/76979871"}
+	meta.resolveIdentity(resolved)
+	if meta.Extractor != "vimeo" || meta.VideoID != "76979871" {
+		t.Errorf("resolved identity = %q, %q, want vimeo, 76979871", meta.Extractor, meta.VideoID)
+	}
+
+	meta = &FileMetadata{KnownUrl: "https://example.com/clip"}
+	meta.resolveIdentity(failed)
+	if meta.Extractor != "" || meta.VideoID != "" {
+		t.Errorf("failed lookup set identity %q, %q", meta.Extractor, meta.VideoID)
+	}
+
+	called := false
+	meta = &FileMetadata{KnownUrl: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Extractor: "youtube", VideoID: "dQw4w9WgXcQ"}
+	meta.resolveIdentity(func(url string) (string, string, error) { called = true; return "", "", nil })
+	if called {
+		t.Error("resolver called for a file that already has an identity")
+	}
+
+	meta = &FileMetadata{}
+	meta.resolveIdentity(func(url string) (string, string, error) { called = true; return "", "", nil })
+	if called {
+		t.Error("resolver called for a file without a source URL")
+	}
+}
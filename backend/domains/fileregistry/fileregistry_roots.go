@@ -0,0 +1,257 @@
+package fileregistry
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Columns selected for a WatchedRoot, in the order expected by scanWatchedRoot
+const watchedRootColumns = `id, path, added_at, last_reconciled_at,
+	include_extensions, ignore_patterns, min_file_size, include_hidden, follow_symlinks`
+
+// Filter lists are stored one entry per line
+const filterListSeparator = "\n"
+
+func scanWatchedRoot(row scanner) (*WatchedRoot, error) {
+	var root WatchedRoot
+	var includeExtensions, ignorePatterns string
+	err := row.Scan(
+		&root.ID, &root.Path, &root.AddedAt, &root.LastReconciledAt,
+		&includeExtensions, &ignorePatterns, &root.Filter.MinFileSize, &root.Filter.IncludeHidden, &root.Filter.FollowSymlinks,
+	)
+	if err != nil {
+		return nil, err
+	}
+	root.Filter.IncludeExtensions = splitFilterList(includeExtensions)
+	root.Filter.IgnorePatterns = splitFilterList(ignorePatterns)
+	return &root, nil
+}
+
+func splitFilterList(value string) []string {
+	list := make([]string, 0)
+	for _, entry := range strings.Split(value, filterListSeparator) {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// normalizeRootPath returns the absolute, cleaned form of a directory path
+func normalizeRootPath(directoryPath string) (string, error) {
+	absPath, err := filepath.Abs(strings.TrimSpace(directoryPath))
+	if err != nil {
+		return "", fmt.Errorf("invalid directory path: %w", err)
+	}
+	return filepath.Clean(absPath), nil
+}
+
+// AddWatchedRoot stores a directory as a watched root. Adding an existing root returns the existing root.
+func (f *FileRegistryService) AddWatchedRoot(directoryPath string) (*WatchedRoot, error) {
+	rootPath, err := normalizeRootPath(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(rootPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("directory does not exist: %s", rootPath)
+	}
+
+	filter := DefaultScanFilter()
+	_, err = f.db.Exec(
+		`INSERT INTO watched_roots (path, added_at, include_extensions, ignore_patterns, min_file_size, include_hidden, follow_symlinks)
+		 VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (path) DO NOTHING`,
+		rootPath, time.Now().Unix(),
+		strings.Join(filter.IncludeExtensions, filterListSeparator), strings.Join(filter.IgnorePatterns, filterListSeparator),
+		filter.MinFileSize, filter.IncludeHidden, filter.FollowSymlinks,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add watched root: %w", err)
+	}
+
+	root, err := scanWatchedRoot(f.db.QueryRow("SELECT "+watchedRootColumns+" FROM watched_roots WHERE path = ?", rootPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watched root: %w", err)
+	}
+	return root, nil
+}
+
+// UpdateWatchedRootFilter replaces the scan filter of a root.
+// The root is marked for reconciliation so files the new filter includes or excludes are picked up by the daemon.
+func (f *FileRegistryService) UpdateWatchedRootFilter(rootId int, filter ScanFilter) error {
+	filter, err := filter.Normalize()
+	if err != nil {
+		return err
+	}
+	result, err := f.db.Exec(
+		`UPDATE watched_roots SET include_extensions = ?, ignore_patterns = ?, min_file_size = ?,
+		 include_hidden = ?, follow_symlinks = ?, last_reconciled_at = NULL WHERE id = ?`,
+		strings.Join(filter.IncludeExtensions, filterListSeparator), strings.Join(filter.IgnorePatterns, filterListSeparator),
+		filter.MinFileSize, filter.IncludeHidden, filter.FollowSymlinks, rootId,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update watched root filter: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("watched root %d does not exist", rootId)
+	}
+	return nil
+}
+
+// GetWatchedRoots returns all watched roots
+func (f *FileRegistryService) GetWatchedRoots() ([]WatchedRoot, error) {
+	rows, err := f.db.Query("SELECT " + watchedRootColumns + " FROM watched_roots ORDER BY path ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roots := make([]WatchedRoot, 0)
+	for rows.Next() {
+		root, err := scanWatchedRoot(rows)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, *root)
+	}
+	return roots, nil
+}
+
+// findRoot returns the most specific root containing a path, or nil if the path is not under any root
+func findRoot(roots []WatchedRoot, path string) *WatchedRoot {
+	var found *WatchedRoot
+	for i := range roots {
+		if !isUnderAny(path, []string{roots[i].Path}) {
+			continue
+		}
+		if found == nil || len(roots[i].Path) > len(found.Path) {
+			found = &roots[i]
+		}
+	}
+	return found
+}
+
+// isExcludedByRoot checks if a path is under a root whose filter excludes it.
+// Paths outside of every root are never excluded, they were registered before roots were tracked.
+func isExcludedByRoot(roots []WatchedRoot, path string) bool {
+	root := findRoot(roots, path)
+	return root != nil && !root.Filter.includesPath(root.Path, path)
+}
+
+// RemoveWatchedRoot stops watching a root. Registered files under it are removed if removeFiles is set,
+// otherwise they stay in the registry but are no longer kept up to date.
+func (f *FileRegistryService) RemoveWatchedRoot(rootId int, removeFiles bool) error {
+	var rootPath string
+	err := f.db.QueryRow("SELECT path FROM watched_roots WHERE id = ?", rootId).Scan(&rootPath)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("watched root %d does not exist", rootId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.db.Exec("DELETE FROM watched_roots WHERE id = ?", rootId); err != nil {
+		return fmt.Errorf("failed to remove watched root: %w", err)
+	}
+
+	if removeFiles {
+		if _, err := f.RemovePath(rootPath); err != nil {
+			return fmt.Errorf("failed to remove registered files under root: %w", err)
+		}
+	}
+	return nil
+}
+
+// RemovePath removes a file, or every file under a directory, from the registry.
+// Returns the number of removed entries.
+func (f *FileRegistryService) RemovePath(path string) (int, error) {
+	path = filepath.Clean(path)
+	prefix := path + string(os.PathSeparator)
+	rows, err := f.db.Query(
+		"SELECT id FROM file_registry WHERE file_path = ? OR substr(file_path, 1, ?) = ?",
+		path, utf8.RuneCountInString(prefix), prefix,
+	)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	return len(ids), f.removeIds(ids)
+}
+
+// IsReconcileDue checks if a root should be reconciled based on registry_reconcile_interval_hours.
+// An interval of 0 disables periodic reconciliation.
+func (f *FileRegistryService) IsReconcileDue(root WatchedRoot) (bool, error) {
+	intervalStr, err := f.settingsService.GetSettingString("registry_reconcile_interval_hours")
+	if err != nil {
+		return false, fmt.Errorf("failed to get registry_reconcile_interval_hours setting: %w", err)
+	}
+	intervalHours, err := strconv.Atoi(intervalStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid registry_reconcile_interval_hours setting: %w", err)
+	}
+	if intervalHours <= 0 {
+		return false, nil
+	}
+	if !root.LastReconciledAt.Valid {
+		return true, nil
+	}
+	nextRun := time.Unix(root.LastReconciledAt.Int64, 0).Add(time.Duration(intervalHours) * time.Hour)
+	return time.Now().After(nextRun), nil
+}
+
+// isUnderAny checks if a path equals or is inside any of the given paths
+func isUnderAny(path string, parents []string) bool {
+	for _, parent := range parents {
+		if path == parent || strings.HasPrefix(path, parent+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getIdsForPath returns the IDs of every registry entry for a path, oldest first
+func (f *FileRegistryService) getIdsForPath(filePath string) ([]int, error) {
+	rows, err := f.db.Query("SELECT id FROM file_registry WHERE file_path = ? ORDER BY id ASC", filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// removeIds deletes registry entries and their fingerprints
+func (f *FileRegistryService) removeIds(ids []int) error {
+	for _, id := range ids {
+		if _, err := f.db.Exec("DELETE FROM file_registry WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to remove registered file: %w", err)
+		}
+		if err := f.fingerprintService.RemoveRegisteredFile(id); err != nil {
+			return fmt.Errorf("failed to remove fingerprint: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,638 @@
+package fileregistry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/fingerprint"
+	"videoarchiver/backend/domains/logging"
+)
+
+// ProgressCallback defines the signature for progress reporting callbacks
+type ProgressCallback func(percent int, message string)
+
+const (
+	// Bounds of the registry_scan_workers setting
+	minScanWorkers = 1
+	maxScanWorkers = 32
+
+	// Scan state is persisted every this many processed files
+	scanStateSaveInterval = 50
+
+	// A running scan updates its heartbeat this often, from walking the directory until it finishes
+	scanHeartbeatInterval = 30 * time.Second
+
+	// A running scan that has not updated its heartbeat for this long belonged to a process that stopped
+	scanInterruptedAfter = 5 * time.Minute
+)
+
+// scanOwner identifies this process in registry_scans, its own scans are never resumed as interrupted
+var scanOwner = fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+
+// scanCandidate is a file found on disk during a scan
+type scanCandidate struct {
+	path         string
+	size         int64
+	mtime        int64
+	metadataOnly bool // Registered before size and mtime were stored and not modified since, the hash is kept
+}
+
+// registryState is what the registry knows about a path
+type registryState struct {
+	ids          []int
+	size         sql.NullInt64
+	mtime        sql.NullInt64
+	registeredAt int64
+}
+
+// preparedFile holds everything needed to store a file, calculated without touching the database
+// so the expensive part can run in parallel
+type preparedFile struct {
+	scanCandidate
+	hash          string
+	hashAlgorithm fileutils.HashAlgorithm
+	meta          *FileMetadata
+	fingerprint   *fingerprint.Fingerprint
+	err           error
+}
+
+// ScanDirectory registers all files in a directory and stores it as a watched root.
+// Files whose size and modification time did not change since the previous scan are skipped,
+// the rest is hashed in parallel. Cancelling the context stops the scan, running it again resumes it.
+func (f *FileRegistryService) ScanDirectory(ctx context.Context, directoryPath string, logService *logging.LogService, progressCallback ProgressCallback) (*ScanResult, error) {
+	if progressCallback != nil {
+		progressCallback(0, "Initializing directory registration...")
+	}
+
+	// Trim and validate directory path
+	directoryPath = strings.TrimSpace(directoryPath)
+	logService.Info(fmt.Sprintf("Starting directory registration for: '%s' (length: %d)", directoryPath, len(directoryPath)))
+
+	if directoryPath == "" {
+		return nil, fmt.Errorf("directory path is empty")
+	}
+
+	if _, err := os.Stat(directoryPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("directory does not exist: %s", directoryPath)
+	}
+
+	// Keep the directory as a watched root so the daemon keeps it up to date
+	root, err := f.AddWatchedRoot(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	return f.scanRoot(ctx, *root, logService, progressCallback)
+}
+
+// ReconcileRoot brings the registry in line with the files under a watched root.
+// New files are registered, changed files are re-hashed and entries for files that no longer exist are removed.
+func (f *FileRegistryService) ReconcileRoot(ctx context.Context, root WatchedRoot, logService *logging.LogService) (*ScanResult, error) {
+	// A missing root is likely an unmounted drive, never treat that as every file being deleted
+	if info, err := os.Stat(root.Path); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("watched root is not accessible: %s", root.Path)
+	}
+	return f.scanRoot(ctx, root, logService, nil)
+}
+
+// ResumeInterruptedScans finishes scans that were running when their process stopped.
+// Files registered before the interruption are skipped as unchanged.
+func (f *FileRegistryService) ResumeInterruptedScans(ctx context.Context, logService *logging.LogService) error {
+	scans, err := f.getInterruptedScans()
+	if err != nil {
+		return fmt.Errorf("failed to get interrupted scans: %w", err)
+	}
+
+	for _, scan := range scans {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Another process may have claimed it first, or its owner was only slow to update the heartbeat
+		claimed, err := f.claimInterruptedScan(scan.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		root, err := f.AddWatchedRoot(scan.RootPath)
+		if err != nil {
+			logService.Warn(fmt.Sprintf("Cannot resume registry scan of %s: %v", scan.RootPath, err))
+			continue
+		}
+		logService.Info(fmt.Sprintf("Resuming interrupted registry scan of %s", root.Path))
+		if _, err := f.ReconcileRoot(ctx, *root, logService); err != nil {
+			logService.Error(fmt.Sprintf("Failed to resume registry scan of %s: %v", root.Path, err))
+		}
+	}
+	return nil
+}
+
+// GetRecentScans returns the most recent registry scans
+func (f *FileRegistryService) GetRecentScans(limit int) ([]RegistryScan, error) {
+	return f.queryScans("SELECT "+scanColumns+" FROM registry_scans ORDER BY id DESC LIMIT ?", limit)
+}
+
+// RegisterPath hashes a single file and stores it in the registry.
+// If the path is already registered its hash and metadata are replaced.
+// Returns true if the file was not registered before.
+func (f *FileRegistryService) RegisterPath(filePath string, logService *logging.LogService) (bool, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false, err
+	}
+	ids, err := f.getIdsForPath(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := scanCandidate{path: filePath, size: info.Size(), mtime: info.ModTime().Unix()}
+	prepared := f.prepareFile(candidate, f.GetHashAlgorithm(), f.fingerprintService.IsEnabled(), logService)
+	if prepared.err != nil {
+		return false, prepared.err
+	}
+	if err := f.storePreparedFile(prepared, ids); err != nil {
+		return false, err
+	}
+	return len(ids) == 0, nil
+}
+
+func (f *FileRegistryService) scanRoot(ctx context.Context, root WatchedRoot, logService *logging.LogService, progressCallback ProgressCallback) (*ScanResult, error) {
+	scanId, err := f.createScan(root.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry scan: %w", err)
+	}
+	result := &ScanResult{}
+
+	// The heartbeat keeps other processes from resuming the scan while it runs
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go f.keepScanAlive(ctx, scanId, cancel, logService)
+
+	// Scan directory to find files
+	if progressCallback != nil {
+		progressCallback(10, "Scanning directory structure...")
+	}
+
+	candidates := make([]scanCandidate, 0)
+	inaccessiblePaths := make([]string, 0)
+	err = walkRoot(ctx, root, root.Path, func(path string, info os.FileInfo, err error) {
+		if err != nil {
+			logService.Warn(fmt.Sprintf("Error accessing path %s: %v", path, err))
+			inaccessiblePaths = append(inaccessiblePaths, path)
+			return
+		}
+		if !info.IsDir() {
+			candidates = append(candidates, scanCandidate{path: path, size: info.Size(), mtime: info.ModTime().Unix()})
+		}
+	})
+	if err != nil {
+		return f.stopScan(scanId, result, err)
+	}
+	result.TotalFiles = len(candidates)
+
+	// Skip files that did not change since they were hashed
+	states, err := f.getRegistryStatesUnder(root.Path)
+	if err != nil {
+		return f.stopScan(scanId, result, fmt.Errorf("failed to get registered files under root: %w", err))
+	}
+
+	work := make([]scanCandidate, 0)
+	for _, candidate := range candidates {
+		state, exists := states[candidate.path]
+		delete(states, candidate.path)
+		if !exists {
+			work = append(work, candidate)
+			continue
+		}
+		if state.size.Valid && state.mtime.Valid {
+			if state.size.Int64 == candidate.size && state.mtime.Int64 == candidate.mtime {
+				result.SkippedFiles++
+				continue
+			}
+		} else if candidate.mtime <= state.registeredAt {
+			// Registered before size and mtime were stored and not modified since.
+			// The metadata is read again, older versions did not store tags and IDs of every site.
+			candidate.metadataOnly = true
+		}
+		work = append(work, candidate)
+	}
+	logService.Info(fmt.Sprintf("Found %d files under %s, %d new or changed", len(candidates), root.Path, len(work)))
+
+	// Hash new and changed files in parallel, the database is only written from this goroutine
+	results := f.prepareFiles(ctx, work, logService)
+	processed := 0
+	for prepared := range results {
+		processed++
+		if progressCallback != nil {
+			progressPercent := 20 + int(float64(processed)/float64(len(work))*75)
+			progressCallback(progressPercent, fmt.Sprintf("Processing file %d of %d: %s", processed, len(work), filepath.Base(prepared.path)))
+		}
+
+		if prepared.err != nil {
+			logService.Warn(fmt.Sprintf("Failed to register file %s: %v", prepared.path, prepared.err))
+			result.ErrorCount++
+		} else if prepared.metadataOnly {
+			ids, err := f.getIdsForPath(prepared.path)
+			if err == nil {
+				err = f.updateFileMetadata(ids, prepared)
+			}
+			if err != nil {
+				logService.Warn(fmt.Sprintf("Failed to update file info for %s: %v", prepared.path, err))
+				result.ErrorCount++
+			} else {
+				result.SkippedFiles++
+			}
+		} else {
+			// Looked up at store time, the watcher may have registered the path while it was being hashed
+			ids, err := f.getIdsForPath(prepared.path)
+			if err == nil {
+				err = f.storePreparedFile(prepared, ids)
+			}
+			if err != nil {
+				logService.Warn(fmt.Sprintf("Failed to register file %s: %v", prepared.path, err))
+				result.ErrorCount++
+			} else if len(ids) == 0 {
+				result.NewFiles++
+				logService.Debug(fmt.Sprintf("Registered file: %s (%s: %s)", prepared.path, prepared.hashAlgorithm, prepared.hash))
+			} else {
+				result.UpdatedFiles++
+				logService.Debug(fmt.Sprintf("Re-hashed changed file: %s (%s: %s)", prepared.path, prepared.hashAlgorithm, prepared.hash))
+			}
+		}
+
+		if processed%scanStateSaveInterval == 0 {
+			if err := f.saveScanProgress(scanId, result); err != nil {
+				logService.Warn(fmt.Sprintf("Failed to save registry scan progress: %v", err))
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		logService.Info(fmt.Sprintf("Registry scan of %s cancelled after %d of %d files", root.Path, processed, len(work)))
+		return f.stopScan(scanId, result, ctx.Err())
+	}
+
+	// Anything left was not found on disk or is now excluded by the filter, unless it could not be read
+	for path := range states {
+		if isUnderAny(path, inaccessiblePaths) {
+			continue
+		}
+		removed, err := f.RemovePath(path)
+		if err != nil {
+			logService.Warn(fmt.Sprintf("Failed to remove deleted file %s from registry: %v", path, err))
+			result.ErrorCount++
+			continue
+		}
+		result.RemovedFiles += removed
+	}
+
+	if err := f.finishScan(scanId, ScanCompleted, result); err != nil {
+		return nil, err
+	}
+	if _, err := f.db.Exec("UPDATE watched_roots SET last_reconciled_at = ? WHERE id = ?", time.Now().Unix(), root.ID); err != nil {
+		return nil, fmt.Errorf("failed to update watched root: %w", err)
+	}
+
+	// Final progress update
+	message := fmt.Sprintf("Registration completed: %d new, %d updated, %d unchanged, %d removed",
+		result.NewFiles, result.UpdatedFiles, result.SkippedFiles, result.RemovedFiles)
+	if result.ErrorCount > 0 {
+		message += fmt.Sprintf(", %d errors", result.ErrorCount)
+	}
+	if progressCallback != nil {
+		progressCallback(100, message)
+	}
+	logService.Info(fmt.Sprintf("Directory registration of %s completed: %s", root.Path, message))
+	return result, nil
+}
+
+// prepareFiles hashes files with a bounded number of workers.
+// The returned channel is closed once all files are prepared or the context is cancelled.
+func (f *FileRegistryService) prepareFiles(ctx context.Context, work []scanCandidate, logService *logging.LogService) <-chan *preparedFile {
+	workerCount := f.getScanWorkerCount(logService)
+	hashAlgorithm := f.GetHashAlgorithm()
+	fingerprinting := f.fingerprintService.IsEnabled()
+
+	jobs := make(chan scanCandidate)
+	results := make(chan *preparedFile, workerCount)
+
+	go func() {
+		defer close(jobs)
+		for _, candidate := range work {
+			select {
+			case jobs <- candidate:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for candidate := range jobs {
+				results <- f.prepareFile(candidate, hashAlgorithm, fingerprinting, logService)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// prepareFile calculates the hash, metadata and optionally the fingerprint of a file.
+// Files that only need their metadata refreshed are not hashed.
+func (f *FileRegistryService) prepareFile(candidate scanCandidate, hashAlgorithm fileutils.HashAlgorithm, fingerprinting bool, logService *logging.LogService) *preparedFile {
+	prepared := &preparedFile{scanCandidate: candidate, hashAlgorithm: hashAlgorithm}
+	if candidate.metadataOnly {
+		prepared.meta = f.ExtractFileMetadata(candidate.path)
+		return prepared
+	}
+
+	prepared.hash, prepared.err = fileutils.CalculateHash(candidate.path, hashAlgorithm)
+	if prepared.err != nil {
+		prepared.err = fmt.Errorf("failed to calculate %s hash: %w", hashAlgorithm, prepared.err)
+		return prepared
+	}
+	prepared.meta = f.ExtractFileMetadata(candidate.path)
+
+	// Fingerprint media files so re-encoded or re-uploaded downloads can be detected.
+	// Failures are only logged, the file stays registered by hash.
+	if fingerprinting && fileutils.IsMediaFile(candidate.path) {
+		fp, err := f.fingerprintService.CalculateFingerprint(candidate.path)
+		if err != nil {
+			logService.Warn(fmt.Sprintf("Failed to fingerprint file %s: %v", candidate.path, err))
+		} else {
+			prepared.fingerprint = fp
+		}
+	}
+	return prepared
+}
+
+// storePreparedFile inserts a prepared file, or updates the first of the existing entries for its path
+func (f *FileRegistryService) storePreparedFile(prepared *preparedFile, existingIds []int) error {
+	meta := prepared.meta
+	args := []interface{}{
+		filepath.Base(prepared.path), prepared.hash, prepared.hashAlgorithm, time.Now().Unix(),
+		nullIfEmpty(meta.KnownUrl), nullIfEmpty(meta.Extractor), nullIfEmpty(meta.VideoID),
+		nullIfEmpty(meta.Title), nullIfEmpty(meta.Artist), sql.NullFloat64{Float64: meta.Duration, Valid: meta.Duration > 0},
+		prepared.size, prepared.mtime,
+	}
+
+	var registryId int
+	if len(existingIds) == 0 {
+		result, err := f.db.Exec(
+			`INSERT INTO file_registry (filename, md5, hash_algorithm, registered_at,
+			 known_url, extractor, video_id, title, artist, duration, file_size, file_mtime, file_path)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			append(args, prepared.path)...,
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		registryId = int(id)
+	} else {
+		// Keep the first entry and drop any older entries for the same path
+		registryId = existingIds[0]
+		_, err := f.db.Exec(
+			`UPDATE file_registry SET filename = ?, md5 = ?, hash_algorithm = ?, registered_at = ?,
+			 known_url = ?, extractor = ?, video_id = ?, title = ?, artist = ?, duration = ?, file_size = ?, file_mtime = ?
+			 WHERE id = ?`,
+			append(args, registryId)...,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update registered file: %w", err)
+		}
+		if err := f.removeIds(existingIds[1:]); err != nil {
+			return err
+		}
+	}
+
+	if prepared.fingerprint != nil {
+		if err := f.fingerprintService.SaveRegisteredFile(registryId, prepared.fingerprint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateFileMetadata stores metadata, size and modification time without re-hashing.
+// Values the new metadata lacks are kept.
+func (f *FileRegistryService) updateFileMetadata(ids []int, prepared *preparedFile) error {
+	meta := prepared.meta
+	for _, id := range ids {
+		_, err := f.db.Exec(
+			`UPDATE file_registry SET known_url = COALESCE(?, known_url), extractor = COALESCE(?, extractor),
+			 video_id = COALESCE(?, video_id), title = COALESCE(?, title), artist = COALESCE(?, artist),
+			 duration = COALESCE(?, duration), file_size = ?, file_mtime = ? WHERE id = ?`,
+			nullIfEmpty(meta.KnownUrl), nullIfEmpty(meta.Extractor), nullIfEmpty(meta.VideoID),
+			nullIfEmpty(meta.Title), nullIfEmpty(meta.Artist), sql.NullFloat64{Float64: meta.Duration, Valid: meta.Duration > 0},
+			prepared.size, prepared.mtime, id,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getRegistryStatesUnder maps every registered path under a directory to what the registry knows about it
+func (f *FileRegistryService) getRegistryStatesUnder(directoryPath string) (map[string]*registryState, error) {
+	prefix := filepath.Clean(directoryPath) + string(os.PathSeparator)
+	rows, err := f.db.Query(
+		"SELECT id, file_path, file_size, file_mtime, registered_at FROM file_registry WHERE substr(file_path, 1, ?) = ? ORDER BY id ASC",
+		utf8.RuneCountInString(prefix), prefix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]*registryState)
+	for rows.Next() {
+		var id int
+		var path string
+		var size, mtime sql.NullInt64
+		var registeredAt int64
+		if err := rows.Scan(&id, &path, &size, &mtime, &registeredAt); err != nil {
+			return nil, err
+		}
+		state, exists := states[path]
+		if !exists {
+			state = &registryState{size: size, mtime: mtime, registeredAt: registeredAt}
+			states[path] = state
+		}
+		state.ids = append(state.ids, id)
+	}
+	return states, nil
+}
+
+// getScanWorkerCount reads registry_scan_workers, clamped to a sane range
+func (f *FileRegistryService) getScanWorkerCount(logService *logging.LogService) int {
+	workersStr, err := f.settingsService.GetSettingString("registry_scan_workers")
+	if err != nil {
+		logService.Warn(fmt.Sprintf("Failed to get registry_scan_workers setting: %v", err))
+		return minScanWorkers
+	}
+	workers, err := strconv.Atoi(workersStr)
+	if err != nil {
+		logService.Warn(fmt.Sprintf("Invalid registry_scan_workers setting: %s", workersStr))
+		return minScanWorkers
+	}
+	if workers < minScanWorkers {
+		return minScanWorkers
+	}
+	if workers > maxScanWorkers {
+		return maxScanWorkers
+	}
+	return workers
+}
+
+// Columns selected for a RegistryScan, in the order expected by queryScans
+const scanColumns = `id, root_path, status, started_at, updated_at, finished_at,
+	total_files, new_files, updated_files, skipped_files, removed_files, error_count`
+
+func (f *FileRegistryService) createScan(rootPath string) (int, error) {
+	now := time.Now().Unix()
+	result, err := f.db.Exec(
+		"INSERT INTO registry_scans (root_path, status, started_at, updated_at, owner) VALUES (?, ?, ?, ?, ?)",
+		rootPath, ScanRunning, now, now, scanOwner,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// keepScanAlive updates the heartbeat of a running scan until its context is done.
+// The scan is cancelled when another process marked it as interrupted after missed heartbeats.
+func (f *FileRegistryService) keepScanAlive(ctx context.Context, scanId int, cancel context.CancelFunc, logService *logging.LogService) {
+	ticker := time.NewTicker(scanHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := f.db.Exec(
+			"UPDATE registry_scans SET updated_at = ? WHERE id = ? AND status = ? AND owner = ?",
+			time.Now().Unix(), scanId, ScanRunning, scanOwner,
+		)
+		if err != nil {
+			logService.Warn(fmt.Sprintf("Failed to update registry scan heartbeat: %v", err))
+			continue
+		}
+		if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+			var status string
+			if err := f.db.QueryRow("SELECT status FROM registry_scans WHERE id = ?", scanId).Scan(&status); err == nil && status == ScanInterrupted {
+				logService.Warn(fmt.Sprintf("Registry scan %d was resumed by another process, stopping it here", scanId))
+				cancel()
+			}
+			return
+		}
+	}
+}
+
+// saveScanProgress stores the counters of a running scan
+func (f *FileRegistryService) saveScanProgress(scanId int, result *ScanResult) error {
+	_, err := f.db.Exec(
+		`UPDATE registry_scans SET updated_at = ?, total_files = ?, new_files = ?, updated_files = ?,
+		 skipped_files = ?, removed_files = ?, error_count = ? WHERE id = ?`,
+		time.Now().Unix(), result.TotalFiles, result.NewFiles, result.UpdatedFiles,
+		result.SkippedFiles, result.RemovedFiles, result.ErrorCount, scanId,
+	)
+	return err
+}
+
+// finishScan records the outcome of a running scan, a scan another process resumed keeps its status
+func (f *FileRegistryService) finishScan(scanId int, status string, result *ScanResult) error {
+	now := time.Now().Unix()
+	_, err := f.db.Exec(
+		`UPDATE registry_scans SET status = ?, updated_at = ?, finished_at = ?, total_files = ?, new_files = ?,
+		 updated_files = ?, skipped_files = ?, removed_files = ?, error_count = ? WHERE id = ? AND status = ?`,
+		status, now, now, result.TotalFiles, result.NewFiles,
+		result.UpdatedFiles, result.SkippedFiles, result.RemovedFiles, result.ErrorCount, scanId, ScanRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish registry scan: %w", err)
+	}
+	return nil
+}
+
+// stopScan records a scan that did not complete and returns the partial result with the cause
+func (f *FileRegistryService) stopScan(scanId int, result *ScanResult, cause error) (*ScanResult, error) {
+	status := ScanFailed
+	if cause == context.Canceled || cause == context.DeadlineExceeded {
+		status = ScanCancelled
+	}
+	if err := f.finishScan(scanId, status, result); err != nil {
+		return result, err
+	}
+	return result, cause
+}
+
+// getInterruptedScans returns running scans of other processes that stopped updating their heartbeat
+func (f *FileRegistryService) getInterruptedScans() ([]RegistryScan, error) {
+	return f.queryScans(
+		"SELECT "+scanColumns+" FROM registry_scans WHERE status = ? AND updated_at < ? AND (owner IS NULL OR owner != ?) ORDER BY id ASC",
+		ScanRunning, time.Now().Add(-scanInterruptedAfter).Unix(), scanOwner,
+	)
+}
+
+// claimInterruptedScan marks a scan as interrupted if it is still running without a heartbeat.
+// Returns false if it finished, updated its heartbeat or was claimed by another process in the meantime.
+func (f *FileRegistryService) claimInterruptedScan(scanId int) (bool, error) {
+	now := time.Now().Unix()
+	result, err := f.db.Exec(
+		`UPDATE registry_scans SET status = ?, finished_at = ?
+		 WHERE id = ? AND status = ? AND updated_at < ? AND (owner IS NULL OR owner != ?)`,
+		ScanInterrupted, now, scanId, ScanRunning, time.Now().Add(-scanInterruptedAfter).Unix(), scanOwner,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim interrupted registry scan: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	return claimed > 0, err
+}
+
+func (f *FileRegistryService) queryScans(query string, args ...interface{}) ([]RegistryScan, error) {
+	rows, err := f.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scans := make([]RegistryScan, 0)
+	for rows.Next() {
+		var scan RegistryScan
+		err := rows.Scan(
+			&scan.ID, &scan.RootPath, &scan.Status, &scan.StartedAt, &scan.UpdatedAt, &scan.FinishedAt,
+			&scan.TotalFiles, &scan.NewFiles, &scan.UpdatedFiles, &scan.SkippedFiles, &scan.RemovedFiles, &scan.ErrorCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
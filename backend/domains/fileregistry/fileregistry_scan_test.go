@@ -0,0 +1,119 @@
+package fileregistry
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NotCoffee418/dbmigrator"
+)
+
+func createTestService(t *testing.T) *FileRegistryService {
+	t.Helper()
+	database, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	dbmigrator.SetDatabaseType(dbmigrator.SQLite)
+	<-dbmigrator.MigrateUpCh(database, os.DirFS("../../.."), "migrations")
+	return &FileRegistryService{db: database}
+}
+
+func TestInterruptedScansSkipLiveScans(t *testing.T) {
+	service := createTestService(t)
+	stale := time.Now().Add(-2 * scanInterruptedAfter).Unix()
+
+	ownId, err := service.createScan("/own")
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertScan := func(rootPath, owner string, updatedAt int64) int {
+		result, err := service.db.Exec(
+			"INSERT INTO registry_scans (root_path, status, started_at, updated_at, owner) VALUES (?, ?, ?, ?, ?)",
+			rootPath, ScanRunning, updatedAt, updatedAt, owner,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		return int(id)
+	}
+	// Only a scan of another process without a recent heartbeat was interrupted
+	if _, err := service.db.Exec("UPDATE registry_scans SET updated_at = ? WHERE id = ?", stale, ownId); err != nil {
+		t.Fatal(err)
+	}
+	insertScan("/live", "other", time.Now().Unix())
+	staleId := insertScan("/stale", "other", stale)
+
+	scans, err := service.getInterruptedScans()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scans) != 1 || scans[0].ID != staleId {
+		t.Fatalf("expected only the stale scan of another process, got %+v", scans)
+	}
+
+	if claimed, err := service.claimInterruptedScan(staleId); err != nil || !claimed {
+		t.Fatalf("expected to claim the stale scan, got %v, %v", claimed, err)
+	}
+	if claimed, err := service.claimInterruptedScan(staleId); err != nil || claimed {
+		t.Errorf("expected a scan to be claimed only once, got %v, %v", claimed, err)
+	}
+
+	// The owner of a claimed scan does not overwrite its status when it stops
+	if err := service.finishScan(staleId, ScanCancelled, &ScanResult{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var status string
+	if err := service.db.QueryRow("SELECT status FROM registry_scans WHERE id = ?", staleId).Scan(&status); err != nil || status != ScanInterrupted {
+		t.Errorf("expected the scan to stay interrupted, got %s (%v)", status, err)
+	}
+}
+
+func TestMetadataOnlyKeepsHash(t *testing.T) {
+	service := createTestService(t)
+	filePath := filepath.Join(t.TempDir(), "old.webm")
+	if err := os.WriteFile(filePath, []byte("header https://www.youtube.com/watch?v=dQw4w9WgXcQ trailer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Registered before size, modification time and video IDs were stored
+	_, err := service.db.Exec(
+		"INSERT INTO file_registry (filename, file_path, md5, registered_at, title) VALUES ('old.webm', ?, 'oldhash', ?, 'Old title')",
+		filePath, time.Now().Add(time.Hour).Unix(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := service.getIdsForPath(filePath)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("expected one entry, got %v, %v", ids, err)
+	}
+
+	candidate := scanCandidate{path: filePath, size: 61, mtime: time.Now().Unix(), metadataOnly: true}
+	prepared := service.prepareFile(candidate, "md5", false, nil)
+	if prepared.err != nil || prepared.hash != "" {
+		t.Fatalf("expected metadata without a hash, got %+v", prepared)
+	}
+	if err := service.updateFileMetadata(ids, prepared); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var hash, extractor, videoId, title string
+	var size int64
+	err = service.db.QueryRow("SELECT md5, extractor, video_id, title, file_size FROM file_registry WHERE id = ?", ids[0]).
+		Scan(&hash, &extractor, &videoId, &title, &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "oldhash" || extractor != "youtube" || videoId != "dQw4w9WgXcQ" || size != 61 {
+		t.Errorf("expected the video ID to be filled in and the hash kept, got %s %s %s %d", hash, extractor, videoId, size)
+	}
+	if title != "Old title" {
+		t.Errorf("expected values missing from the new metadata to be kept, got %q", title)
+	}
+}
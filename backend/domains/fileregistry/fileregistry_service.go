@@ -1,97 +1,188 @@
 package fileregistry
 
 import (
-	"bytes"
 	"database/sql"
-	"fmt"
-	"io"
-	"os"
-	"path/filepath"
-	"regexp"
-	"strings"
-	"time"
 	"videoarchiver/backend/domains/db"
 	"videoarchiver/backend/domains/fileutils"
-	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/fingerprint"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
 )
 
+// Columns selected for a RegisteredFile, in the order expected by scanRegisteredFile
+const registryColumns = "id, filename, file_path, md5, hash_algorithm, registered_at, known_url, extractor, video_id, title, artist, duration"
+
 type FileRegistryService struct {
-	db *sql.DB
+	db                 *sql.DB
+	settingsService    *settings.SettingsService
+	fingerprintService *fingerprint.FingerprintService
 }
 
-func NewFileRegistryService(dbService *db.DatabaseService) *FileRegistryService {
-	return &FileRegistryService{db: dbService.GetDB()}
+func NewFileRegistryService(
+	dbService *db.DatabaseService,
+	settingsService *settings.SettingsService,
+	fingerprintService *fingerprint.FingerprintService,
+) *FileRegistryService {
+	return &FileRegistryService{
+		db:                 dbService.GetDB(),
+		settingsService:    settingsService,
+		fingerprintService: fingerprintService,
+	}
 }
 
-// CheckForDuplicateInFileRegistry checks for duplicate files by MD5 hash and optionally by YouTube URL
-func (f *FileRegistryService) CheckForDuplicateInFileRegistry(fileMD5 string, youtubeUrl string, fileFormat string) (bool, error) {
-	var id int
+// GetHashAlgorithm returns the hash algorithm used for newly registered files
+func (f *FileRegistryService) GetHashAlgorithm() fileutils.HashAlgorithm {
+	return fileutils.GetConfiguredHashAlgorithm(f.settingsService)
+}
 
-	// First check by MD5 hash
-	err := f.db.QueryRow(
-		"SELECT 1 FROM file_registry WHERE md5 = ? LIMIT 1",
-		fileMD5,
-	).Scan(&id)
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	if err == nil {
-		// Duplicate found by MD5
-		return true, nil
+func scanRegisteredFile(row scanner) (*RegisteredFile, error) {
+	var file RegisteredFile
+	err := row.Scan(
+		&file.ID, &file.Filename, &file.FilePath, &file.MD5, &file.HashAlgorithm, &file.RegisteredAt,
+		&file.KnownUrl, &file.Extractor, &file.VideoID, &file.Title, &file.Artist, &file.Duration,
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &file, nil
+}
 
-	if err != sql.ErrNoRows {
-		// Actual error occurred
+// CheckForDuplicateInFileRegistry checks for duplicate files by content hash and optionally by known video URL.
+// The file is hashed with every algorithm still present in the registry so duplicates are
+// found while older rows are being migrated to a different algorithm.
+// Registered files excluded by the filter of their watched root are not considered.
+func (f *FileRegistryService) CheckForDuplicateInFileRegistry(hasher *fileutils.FileHasher, videoUrl string, fileFormat string) (bool, error) {
+	roots, err := f.GetWatchedRoots()
+	if err != nil {
 		return false, err
 	}
 
-	// If YouTube URL is provided, also check for URL match
-	if len(youtubeUrl) > 0 && youtubeUrl != "" {
-		err = f.db.QueryRow(
-			"SELECT 1 FROM file_registry WHERE known_url = ? AND file_path LIKE ? LIMIT 1",
-			youtubeUrl,
-			"%"+fileFormat,
-		).Scan(&id)
+	// First check by content hash
+	algorithms, err := f.getHashAlgorithmsInUse()
+	if err != nil {
+		return false, err
+	}
+	for _, algorithm := range algorithms {
+		fileHash, err := hasher.Hash(algorithm)
+		if err != nil {
+			return false, err
+		}
 
-		if err == nil {
-			// Duplicate found by YouTube URL
+		match, err := f.findIncludedPath(roots,
+			"SELECT file_path FROM file_registry WHERE hash_algorithm = ? AND md5 = ?",
+			algorithm, fileHash,
+		)
+		if err != nil {
+			return false, err
+		}
+		if match != "" {
+			// Duplicate found by hash
 			return true, nil
 		}
+	}
 
-		if err != sql.ErrNoRows {
-			// Actual error occurred
+	// If a video URL is provided, also check for URL match.
+	// Known URLs are stored canonicalized so different forms of the same URL match.
+	if len(videoUrl) > 0 && videoUrl != "" {
+		match, err := f.findIncludedPath(roots,
+			"SELECT file_path FROM file_registry WHERE known_url = ? AND file_path LIKE ?",
+			ytdlp.CanonicalizeURL(videoUrl),
+			"%"+fileFormat,
+		)
+		if err != nil {
 			return false, err
 		}
+		if match != "" {
+			// Duplicate found by video URL
+			return true, nil
+		}
 	}
 
 	// No duplicate found
 	return false, nil
 }
 
-// RegisterFile adds a new file to the registry
-func (f *FileRegistryService) RegisterFile(filename, filePath, md5Hash string) error {
-	// Extract YouTube URL from file metadata if available
-	knownUrl, err := f.ExtractKnownYoutubeUrl(filePath)
+// FindByVideoIdentity returns a registered file with the same extractor, video ID and format, or nil if none exists.
+// Registered files excluded by the filter of their watched root are not considered.
+func (f *FileRegistryService) FindByVideoIdentity(extractor, videoId, fileFormat string) (*RegisteredFile, error) {
+	roots, err := f.GetWatchedRoots()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := f.db.Query(
+		"SELECT "+registryColumns+" FROM file_registry WHERE extractor = ? AND video_id = ? AND file_path LIKE ?",
+		extractor, videoId, "%"+fileFormat,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		file, err := scanRegisteredFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		if !isExcludedByRoot(roots, file.FilePath) {
+			return file, nil
+		}
+	}
+	return nil, rows.Err()
+}
+
+// findIncludedPath runs a query selecting file paths and returns the first one not excluded by its root's filter,
+// or an empty string if there is none
+func (f *FileRegistryService) findIncludedPath(roots []WatchedRoot, query string, args ...interface{}) (string, error) {
+	rows, err := f.db.Query(query, args...)
 	if err != nil {
-		// Log the error but don't fail the registration
-		// The error is already handled in ExtractKnownYoutubeUrl by returning empty string for metadata issues
+		return "", err
 	}
+	defer rows.Close()
 
-	// Store NULL if knownUrl is empty, otherwise store the URL
-	var knownUrlPtr *string
-	if knownUrl != "" {
-		knownUrlPtr = &knownUrl
+	for rows.Next() {
+		var filePath string
+		if err := rows.Scan(&filePath); err != nil {
+			return "", err
+		}
+		if !isExcludedByRoot(roots, filePath) {
+			return filePath, nil
+		}
 	}
+	return "", rows.Err()
+}
 
-	_, err = f.db.Exec(
-		"INSERT INTO file_registry (filename, file_path, md5, registered_at, known_url) VALUES (?, ?, ?, ?, ?)",
-		filename, filePath, md5Hash, time.Now().Unix(), knownUrlPtr,
-	)
-	return err
+// getHashAlgorithmsInUse returns every hash algorithm used by registered files
+func (f *FileRegistryService) getHashAlgorithmsInUse() ([]fileutils.HashAlgorithm, error) {
+	rows, err := f.db.Query("SELECT DISTINCT hash_algorithm FROM file_registry")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	algorithms := make([]fileutils.HashAlgorithm, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		algorithm, err := fileutils.ParseHashAlgorithm(name)
+		if err != nil {
+			continue
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
 }
 
 // GetAllPaginated returns a paginated list of registered files
 func (f *FileRegistryService) GetAllPaginated(offset, limit int) ([]RegisteredFile, error) {
 	rows, err := f.db.Query(
-		"SELECT id, filename, file_path, md5, registered_at, known_url FROM file_registry ORDER BY registered_at DESC LIMIT ? OFFSET ?",
+		"SELECT "+registryColumns+" FROM file_registry ORDER BY registered_at DESC LIMIT ? OFFSET ?",
 		limit, offset,
 	)
 	if err != nil {
@@ -101,17 +192,35 @@ func (f *FileRegistryService) GetAllPaginated(offset, limit int) ([]RegisteredFi
 
 	var files []RegisteredFile
 	for rows.Next() {
-		var file RegisteredFile
-		err := rows.Scan(&file.ID, &file.Filename, &file.FilePath, &file.MD5, &file.RegisteredAt, &file.KnownUrl)
+		file, err := scanRegisteredFile(rows)
 		if err != nil {
 			return nil, err
 		}
-		files = append(files, file)
+		files = append(files, *file)
 	}
 
 	return files, nil
 }
 
+// GetAll returns every registered file, newest first
+func (f *FileRegistryService) GetAll() ([]RegisteredFile, error) {
+	rows, err := f.db.Query("SELECT " + registryColumns + " FROM file_registry ORDER BY registered_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]RegisteredFile, 0)
+	for rows.Next() {
+		file, err := scanRegisteredFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, *file)
+	}
+	return files, rows.Err()
+}
+
 // GetAllPaginatedWithSearch returns a paginated list of registered files filtered by search query
 func (f *FileRegistryService) GetAllPaginatedWithSearch(offset, limit int, searchQuery string) ([]RegisteredFile, error) {
 	var rows *sql.Rows
@@ -125,7 +234,7 @@ func (f *FileRegistryService) GetAllPaginatedWithSearch(offset, limit int, searc
 	// Search in filename and file_path using LIKE queries
 	likeQuery := "%" + searchQuery + "%"
 	rows, err = f.db.Query(
-		"SELECT id, filename, file_path, md5, registered_at, known_url FROM file_registry WHERE filename LIKE ? OR file_path LIKE ? ORDER BY registered_at DESC LIMIT ? OFFSET ?",
+		"SELECT "+registryColumns+" FROM file_registry WHERE filename LIKE ? OR file_path LIKE ? ORDER BY registered_at DESC LIMIT ? OFFSET ?",
 		likeQuery, likeQuery, limit, offset,
 	)
 	if err != nil {
@@ -135,12 +244,11 @@ func (f *FileRegistryService) GetAllPaginatedWithSearch(offset, limit int, searc
 
 	var files []RegisteredFile
 	for rows.Next() {
-		var file RegisteredFile
-		err := rows.Scan(&file.ID, &file.Filename, &file.FilePath, &file.MD5, &file.RegisteredAt, &file.KnownUrl)
+		file, err := scanRegisteredFile(rows)
 		if err != nil {
 			return nil, err
 		}
-		files = append(files, file)
+		files = append(files, *file)
 	}
 
 	return files, nil
@@ -171,184 +279,48 @@ func (f *FileRegistryService) GetCountWithSearch(searchQuery string) (int, error
 	return count, err
 }
 
-// ClearAll removes all registered files from the database
+// ClearAll removes all registered files and watched roots from the database.
+// Roots are removed too, otherwise the daemon would register their files again.
 func (f *FileRegistryService) ClearAll() error {
+	if _, err := f.db.Exec("DELETE FROM watched_roots"); err != nil {
+		return err
+	}
+	if _, err := f.db.Exec("DELETE FROM fingerprints WHERE source = ?", fingerprint.SourceFileRegistry); err != nil {
+		return err
+	}
 	_, err := f.db.Exec("DELETE FROM file_registry")
 	return err
 }
 
-// ProgressCallback defines the signature for progress reporting callbacks
-type ProgressCallback func(percent int, message string)
-
-// RegisterDirectoryWithProgress registers all files in a directory with progress reporting
-func (f *FileRegistryService) RegisterDirectoryWithProgress(directoryPath string, logService *logging.LogService, progressCallback ProgressCallback) error {
-	// Step 1: Initialize and validate input
-	if progressCallback != nil {
-		progressCallback(0, "Initializing directory registration...")
-	}
-
-	// Trim and validate directory path
-	directoryPath = strings.TrimSpace(directoryPath)
-	logService.Info(fmt.Sprintf("Starting directory registration for: '%s' (length: %d)", directoryPath, len(directoryPath)))
-
-	if directoryPath == "" {
-		return fmt.Errorf("directory path is empty")
-	}
-
-	if _, err := os.Stat(directoryPath); os.IsNotExist(err) {
-		return fmt.Errorf("directory does not exist: %s", directoryPath)
-	}
-
-	// Step 2: Scan directory to count files
-	if progressCallback != nil {
-		progressCallback(10, "Scanning directory structure...")
-	}
-
-	var allFiles []string
-	err := filepath.Walk(directoryPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			logService.Warn(fmt.Sprintf("Error accessing path %s: %v", path, err))
-			return nil // Continue walking, don't fail on individual file errors
-		}
-
-		if !info.IsDir() {
-			allFiles = append(allFiles, path)
-		}
-		return nil
-	})
-
+// GetFilesWithOtherHashAlgorithm returns registered files hashed with a different algorithm,
+// starting after the given ID. Used by the background rehash migration.
+func (f *FileRegistryService) GetFilesWithOtherHashAlgorithm(algorithm fileutils.HashAlgorithm, afterId, limit int) ([]RegisteredFile, error) {
+	rows, err := f.db.Query(
+		"SELECT "+registryColumns+" FROM file_registry WHERE hash_algorithm != ? AND id > ? ORDER BY id ASC LIMIT ?",
+		algorithm, afterId, limit,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
-	}
-
-	totalFiles := len(allFiles)
-	logService.Info(fmt.Sprintf("Found %d files to register", totalFiles))
-
-	if totalFiles == 0 {
-		if progressCallback != nil {
-			progressCallback(100, "No files found in directory")
-		}
-		return nil
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Step 3: Process files with progress updates
-	registeredCount := 0
-	errorCount := 0
-
-	for i, filePath := range allFiles {
-		// Calculate progress (20% for setup, 80% for file processing)
-		progressPercent := 20 + int(float64(i)/float64(totalFiles)*80)
-
-		if progressCallback != nil {
-			progressCallback(progressPercent, fmt.Sprintf("Processing file %d of %d: %s", i+1, totalFiles, filepath.Base(filePath)))
-		}
-
-		// Calculate MD5 hash
-		md5Hash, err := fileutils.CalculateMD5(filePath)
-		if err != nil {
-			logService.Warn(fmt.Sprintf("Failed to calculate MD5 for %s: %v", filePath, err))
-			errorCount++
-			continue
-		}
-
-		// Register the file
-		filename := filepath.Base(filePath)
-		err = f.RegisterFile(filename, filePath, md5Hash)
+	var files []RegisteredFile
+	for rows.Next() {
+		file, err := scanRegisteredFile(rows)
 		if err != nil {
-			logService.Warn(fmt.Sprintf("Failed to register file %s: %v", filePath, err))
-			errorCount++
-			continue
-		}
-
-		registeredCount++
-		logService.Debug(fmt.Sprintf("Registered file: %s (MD5: %s)", filePath, md5Hash))
-	}
-
-	// Final progress update
-	if progressCallback != nil {
-		var message string
-		if errorCount > 0 {
-			message = fmt.Sprintf("Registration completed: %d files registered, %d errors", registeredCount, errorCount)
-		} else {
-			message = fmt.Sprintf("Registration completed successfully: %d files registered", registeredCount)
+			return nil, err
 		}
-		progressCallback(100, message)
+		files = append(files, *file)
 	}
 
-	logService.Info(fmt.Sprintf("Directory registration completed: %d files registered, %d errors", registeredCount, errorCount))
-	return nil
+	return files, nil
 }
 
-func (f *FileRegistryService) ExtractKnownYoutubeUrl(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	// YouTube regex - just get the clean URL
-	re := regexp.MustCompile(`https?://(?:www\.)?(?:youtube\.com/watch\?v=|youtu\.be/)([\w-]{11})`)
-
-	// Read first 512KB of file (MP4 metadata can be larger and further in)
-	buffer := make([]byte, 524288)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return "", err
-	}
-
-	data := buffer[:n]
-
-	// Look for common metadata tags and extract text around them
-	patterns := []string{
-		"COMM", // ID3v2 Comment
-		"comm", // lowercase variant
-		"TXXX", // ID3v2 User defined text
-		"TIT2", // ID3v2 Title
-		"TALB", // ID3v2 Album
-		"TPE1", // ID3v2 Artist
-		"©cmt", // iTunes comment
-		"desc", // Description
-	}
-
-	for _, pattern := range patterns {
-		if idx := bytes.Index(data, []byte(pattern)); idx != -1 {
-			// Look for YouTube URL in the next 1000 bytes after the tag
-			start := idx
-			end := start + 1000
-			if end > len(data) {
-				end = len(data)
-			}
-
-			// Convert to string and clean it
-			text := string(data[start:end])
-
-			// Clean the text - keep only printable ASCII and basic punctuation
-			cleaned := strings.Map(func(r rune) rune {
-				if (r >= 32 && r <= 126) || r == '\n' || r == '\r' || r == '\t' {
-					return r
-				}
-				return -1
-			}, text)
-
-			if match := re.FindString(cleaned); match != "" {
-				return match, nil
-			}
-		}
-	}
-
-	// Fallback: search the entire buffer for YouTube URLs
-	// Convert to string and clean
-	text := string(data)
-	cleaned := strings.Map(func(r rune) rune {
-		if (r >= 32 && r <= 126) || r == '\n' || r == '\r' || r == '\t' {
-			return r
-		}
-		return -1
-	}, text)
-
-	if match := re.FindString(cleaned); match != "" {
-		return match, nil
-	}
-
-	return "", nil
+// UpdateHash replaces the stored hash of a registered file
+func (f *FileRegistryService) UpdateHash(id int, fileHash string, algorithm fileutils.HashAlgorithm) error {
+	_, err := f.db.Exec(
+		"UPDATE file_registry SET md5 = ?, hash_algorithm = ? WHERE id = ?",
+		fileHash, algorithm, id,
+	)
+	return err
 }
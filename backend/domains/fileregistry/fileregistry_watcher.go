@@ -0,0 +1,271 @@
+package fileregistry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+	"videoarchiver/backend/domains/logging"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// Time a path must be quiet before it is processed, files being copied fire many write events
+	watcherDebounceDelay = 3 * time.Second
+
+	// How often pending paths are checked
+	watcherFlushInterval = time.Second
+
+	// How often the watched roots are reloaded, roots are added by the UI process
+	watcherRootSyncInterval = time.Minute
+
+	// Quiet paths waiting for the worker, further paths stay pending until there is room
+	watcherQueueSize = 256
+)
+
+// watcherJob is a quiet path handed to the worker with the root it was under when it was flushed
+type watcherJob struct {
+	path string
+	root *WatchedRoot // nil if the path is no longer under a watched root
+}
+
+// RegistryWatcher keeps the registry up to date with changes under the watched roots
+type RegistryWatcher struct {
+	registry   *FileRegistryService
+	logService *logging.LogService
+	watcher    *fsnotify.Watcher
+	roots      map[string]WatchedRoot
+	pending    map[string]time.Time // Changed path and time of its last event
+	jobs       chan watcherJob      // Hashing runs in the worker so the event loop never blocks on it
+}
+
+func NewRegistryWatcher(registry *FileRegistryService, logService *logging.LogService) *RegistryWatcher {
+	return &RegistryWatcher{
+		registry:   registry,
+		logService: logService,
+		roots:      make(map[string]WatchedRoot),
+		pending:    make(map[string]time.Time),
+		jobs:       make(chan watcherJob, watcherQueueSize),
+	}
+}
+
+// Run watches the roots until the context is cancelled
+func (w *RegistryWatcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+	w.watcher = watcher
+
+	w.syncRoots()
+
+	var workerDone sync.WaitGroup
+	workerDone.Add(1)
+	go func() {
+		defer workerDone.Done()
+		w.runWorker(ctx)
+	}()
+	defer workerDone.Wait()
+
+	flushTicker := time.NewTicker(watcherFlushInterval)
+	defer flushTicker.Stop()
+	rootTicker := time.NewTicker(watcherRootSyncInterval)
+	defer rootTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.handleEvent(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// Usually an event queue overflow, periodic reconciliation catches anything missed
+			w.logService.Warn(fmt.Sprintf("File watcher error: %v", err))
+		case <-flushTicker.C:
+			w.flushPending()
+		case <-rootTicker.C:
+			w.syncRoots()
+		}
+	}
+}
+
+// syncRoots starts watching new roots, stops watching removed roots and picks up changed filters
+func (w *RegistryWatcher) syncRoots() {
+	roots, err := w.registry.GetWatchedRoots()
+	if err != nil {
+		w.logService.Error(fmt.Sprintf("Failed to get watched roots: %v", err))
+		return
+	}
+
+	current := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		current[root.Path] = true
+		watched, exists := w.roots[root.Path]
+		if exists && reflect.DeepEqual(watched.Filter, root.Filter) {
+			continue
+		}
+		// Directories excluded by an old filter keep their watch, their events are filtered
+		if err := w.addRecursive(root, root.Path); err != nil {
+			w.logService.Warn(fmt.Sprintf("Failed to watch root %s: %v", root.Path, err))
+			continue
+		}
+		w.roots[root.Path] = root
+		if !exists {
+			w.logService.Info(fmt.Sprintf("Watching registry root: %s", root.Path))
+		}
+	}
+
+	for rootPath := range w.roots {
+		if current[rootPath] {
+			continue
+		}
+		for _, watched := range w.watcher.WatchList() {
+			if isUnderAny(watched, []string{rootPath}) {
+				w.watcher.Remove(watched)
+			}
+		}
+		delete(w.roots, rootPath)
+		w.logService.Info(fmt.Sprintf("Stopped watching registry root: %s", rootPath))
+	}
+}
+
+// addRecursive watches a directory and all its subdirectories that pass the root's filter,
+// fsnotify does not watch recursively
+func (w *RegistryWatcher) addRecursive(root WatchedRoot, directoryPath string) error {
+	return walkRoot(context.Background(), root, directoryPath, func(path string, info os.FileInfo, err error) {
+		if err != nil {
+			w.logService.Warn(fmt.Sprintf("Error accessing path %s: %v", path, err))
+			return
+		}
+		if info.IsDir() {
+			if err := w.watcher.Add(path); err != nil {
+				w.logService.Warn(fmt.Sprintf("Failed to watch directory %s: %v", path, err))
+			}
+		}
+	})
+}
+
+// rootFor returns the watched root containing a path, or nil if it is not under any root
+func (w *RegistryWatcher) rootFor(path string) *WatchedRoot {
+	roots := make([]WatchedRoot, 0, len(w.roots))
+	for _, root := range w.roots {
+		roots = append(roots, root)
+	}
+	return findRoot(roots, path)
+}
+
+func (w *RegistryWatcher) handleEvent(event fsnotify.Event) {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+		return
+	}
+
+	// New directories need their own watch, files moved in with them never fire events
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			root := w.rootFor(event.Name)
+			if root == nil {
+				return
+			}
+			if relPath, err := filepath.Rel(root.Path, event.Name); err != nil || root.Filter.excludesDir(relPath, info) {
+				return
+			}
+			walkRoot(context.Background(), *root, event.Name, func(path string, info os.FileInfo, err error) {
+				if err != nil {
+					w.logService.Warn(fmt.Sprintf("Error accessing path %s: %v", path, err))
+					return
+				}
+				if info.IsDir() {
+					if err := w.watcher.Add(path); err != nil {
+						w.logService.Warn(fmt.Sprintf("Failed to watch new directory %s: %v", path, err))
+					}
+					return
+				}
+				w.pending[path] = time.Now()
+			})
+			return
+		}
+	}
+
+	w.pending[event.Name] = time.Now()
+}
+
+// flushPending hands paths that have been quiet for the debounce delay to the worker
+func (w *RegistryWatcher) flushPending() {
+	for path, lastEvent := range w.pending {
+		if time.Since(lastEvent) < watcherDebounceDelay {
+			continue
+		}
+		select {
+		case w.jobs <- watcherJob{path: path, root: w.rootFor(path)}:
+			delete(w.pending, path)
+		default:
+			// The worker is behind, the remaining paths are handed over on a later flush
+			return
+		}
+	}
+}
+
+// runWorker processes flushed paths until the context is cancelled
+func (w *RegistryWatcher) runWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-w.jobs:
+			w.processPath(job)
+		}
+	}
+}
+
+// processPath brings the registry in line with a quiet path
+func (w *RegistryWatcher) processPath(job watcherJob) {
+	path := job.path
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		// Deleted or moved away, path may have been a file or a whole directory
+		removed, err := w.registry.RemovePath(path)
+		if err != nil {
+			w.logService.Warn(fmt.Sprintf("Failed to remove %s from registry: %v", path, err))
+		} else if removed > 0 {
+			w.logService.Debug(fmt.Sprintf("Removed %d registry entries for deleted path: %s", removed, path))
+		}
+		return
+	}
+	if err != nil || info.IsDir() {
+		return
+	}
+
+	// Files the filter excludes are dropped, they may have been renamed to an excluded name
+	root := job.root
+	if root == nil {
+		return
+	}
+	if !root.Filter.includesPath(root.Path, path) {
+		if _, err := w.registry.RemovePath(path); err != nil {
+			w.logService.Warn(fmt.Sprintf("Failed to remove excluded file %s from registry: %v", path, err))
+		}
+		return
+	}
+
+	isNew, err := w.registry.RegisterPath(path, w.logService)
+	if err != nil {
+		w.logService.Warn(fmt.Sprintf("Failed to register changed file %s: %v", path, err))
+		return
+	}
+	if isNew {
+		w.logService.Debug(fmt.Sprintf("Registered new file: %s", path))
+	} else {
+		w.logService.Debug(fmt.Sprintf("Re-hashed changed file: %s", path))
+	}
+}
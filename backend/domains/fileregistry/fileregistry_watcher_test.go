@@ -0,0 +1,36 @@
+package fileregistry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlushPendingNeverBlocks(t *testing.T) {
+	quiet := time.Now().Add(-2 * watcherDebounceDelay)
+	w := &RegistryWatcher{
+		pending: map[string]time.Time{"/a": quiet, "/b": quiet, "/c": quiet, "/recent": time.Now()},
+		jobs:    make(chan watcherJob, 1),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.flushPending()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected flushing to return while the worker is behind")
+	}
+
+	// One quiet path fits in the queue, the others wait for a later flush
+	if len(w.jobs) != 1 {
+		t.Errorf("Expected 1 queued path, got %d", len(w.jobs))
+	}
+	if len(w.pending) != 3 {
+		t.Errorf("Expected 3 paths to stay pending, got %v", w.pending)
+	}
+	if _, ok := w.pending["/recent"]; !ok {
+		t.Error("Expected the recently changed path to stay pending")
+	}
+}
@@ -4,10 +4,55 @@ import "database/sql"
 
 // RegisteredFile represents a file that has been registered for duplicate detection
 type RegisteredFile struct {
-	ID           int            `json:"id" db:"id"`
-	Filename     string         `json:"filename" db:"filename"`
-	FilePath     string         `json:"file_path" db:"file_path"`
-	MD5          string         `json:"md5" db:"md5"`
-	RegisteredAt int64          `json:"registered_at" db:"registered_at"`
-	KnownUrl     sql.NullString `json:"known_url" db:"known_url"`
+	ID            int             `json:"id" db:"id"`
+	Filename      string          `json:"filename" db:"filename"`
+	FilePath      string          `json:"file_path" db:"file_path"`
+	MD5           string          `json:"md5" db:"md5"` // Content hash, calculated with HashAlgorithm
+	HashAlgorithm string          `json:"hash_algorithm" db:"hash_algorithm"`
+	RegisteredAt  int64           `json:"registered_at" db:"registered_at"`
+	KnownUrl      sql.NullString  `json:"known_url" db:"known_url"`
+	Extractor     sql.NullString  `json:"extractor" db:"extractor"`
+	VideoID       sql.NullString  `json:"video_id" db:"video_id"`
+	Title         sql.NullString  `json:"title" db:"title"`
+	Artist        sql.NullString  `json:"artist" db:"artist"`
+	Duration      sql.NullFloat64 `json:"duration" db:"duration"` // Seconds
+}
+
+// WatchedRoot is a registered directory that the daemon keeps in sync with the registry
+type WatchedRoot struct {
+	ID               int           `json:"id" db:"id"`
+	Path             string        `json:"path" db:"path"`
+	AddedAt          int64         `json:"added_at" db:"added_at"`
+	LastReconciledAt sql.NullInt64 `json:"last_reconciled_at" db:"last_reconciled_at"`
+	Filter           ScanFilter    `json:"filter"`
+}
+
+// Statuses of a registry scan
+const (
+	ScanRunning     = "running"
+	ScanCompleted   = "completed"
+	ScanCancelled   = "cancelled"
+	ScanFailed      = "failed"
+	ScanInterrupted = "interrupted" // Process stopped during the scan, resumed by the daemon
+)
+
+// RegistryScan is the persisted state of a directory scan
+type RegistryScan struct {
+	ID         int           `json:"id" db:"id"`
+	RootPath   string        `json:"root_path" db:"root_path"`
+	Status     string        `json:"status" db:"status"`
+	StartedAt  int64         `json:"started_at" db:"started_at"`
+	UpdatedAt  int64         `json:"updated_at" db:"updated_at"`
+	FinishedAt sql.NullInt64 `json:"finished_at" db:"finished_at"`
+	ScanResult
+}
+
+// ScanResult holds the changes made while scanning a directory
+type ScanResult struct {
+	TotalFiles   int `json:"total_files" db:"total_files"`
+	NewFiles     int `json:"new_files" db:"new_files"`
+	UpdatedFiles int `json:"updated_files" db:"updated_files"`
+	SkippedFiles int `json:"skipped_files" db:"skipped_files"` // Unchanged since the previous scan
+	RemovedFiles int `json:"removed_files" db:"removed_files"`
+	ErrorCount   int `json:"error_count" db:"error_count"`
 }
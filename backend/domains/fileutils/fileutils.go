@@ -1,28 +1,14 @@
 package fileutils
 
 import (
-	"crypto/md5"
-	"fmt"
-	"io"
-	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
 
 // CalculateMD5 calculates the MD5 hash of a file
 func CalculateMD5(path string) (string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("failed to calculate MD5: %w", err)
-	}
-
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	return CalculateHash(path, HashMD5)
 }
 
 // sanitizeFilename replaces filesystem-unsafe characters with underscores
@@ -73,3 +59,38 @@ func SanitizeFilename(filename string) string {
 
 	return filename
 }
+
+// VideoExtensions lists the lowercase file extensions treated as video files
+var VideoExtensions = []string{
+	".mp4", ".mkv", ".webm", ".mov", ".avi", ".m4v", ".flv", ".wmv", ".mpg", ".mpeg", ".ts",
+}
+
+// AudioExtensions lists the lowercase file extensions treated as audio-only files
+var AudioExtensions = []string{
+	".mp3", ".m4a", ".aac", ".ogg", ".opus", ".flac", ".wav", ".wma",
+}
+
+// MediaExtensions lists the lowercase file extensions treated as audio or video files
+var MediaExtensions = append(append([]string{}, VideoExtensions...), AudioExtensions...)
+
+// IsMediaFile checks if a file has an audio or video extension
+func IsMediaFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, mediaExt := range MediaExtensions {
+		if ext == mediaExt {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAudioFile checks if a file has an audio-only extension
+func IsAudioFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, audioExt := range AudioExtensions {
+		if ext == audioExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package fileutils
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/zeebo/xxh3"
+)
+
+// HashAlgorithm identifies the algorithm used to calculate a content hash.
+// The algorithm is stored alongside every hash so hashes of different algorithms are never compared.
+type HashAlgorithm string
+
+const (
+	HashMD5    HashAlgorithm = "md5"
+	HashSHA256 HashAlgorithm = "sha256"
+	HashXXH3   HashAlgorithm = "xxh3" // 128-bit xxh3, fast non-cryptographic hash
+
+	// Algorithm used when no valid algorithm is configured
+	DefaultHashAlgorithm = HashXXH3
+)
+
+// ParseHashAlgorithm validates a hash algorithm name
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	switch HashAlgorithm(name) {
+	case HashMD5, HashSHA256, HashXXH3:
+		return HashAlgorithm(name), nil
+	}
+	return "", fmt.Errorf("unsupported hash algorithm: %s", name)
+}
+
+// SettingsChecker interface to check settings without importing the settings package
+type SettingsChecker interface {
+	GetSettingString(key string) (string, error)
+}
+
+// GetConfiguredHashAlgorithm returns the algorithm from the hash_algorithm setting,
+// falling back to DefaultHashAlgorithm if the setting is missing or invalid
+func GetConfiguredHashAlgorithm(settingsChecker SettingsChecker) HashAlgorithm {
+	if settingsChecker == nil {
+		return DefaultHashAlgorithm
+	}
+	name, err := settingsChecker.GetSettingString("hash_algorithm")
+	if err != nil {
+		return DefaultHashAlgorithm
+	}
+	algorithm, err := ParseHashAlgorithm(name)
+	if err != nil {
+		return DefaultHashAlgorithm
+	}
+	return algorithm
+}
+
+func newHash(algorithm HashAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashXXH3:
+		return xxh3.New128(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+}
+
+// CalculateHash calculates the hash of a file using the given algorithm
+func CalculateHash(path string, algorithm HashAlgorithm) (string, error) {
+	hashes, err := CalculateHashes(path, algorithm)
+	if err != nil {
+		return "", err
+	}
+	return hashes[algorithm], nil
+}
+
+// CalculateHashes calculates the hashes of a file for multiple algorithms while reading it only once
+func CalculateHashes(path string, algorithms ...HashAlgorithm) (map[HashAlgorithm]string, error) {
+	hashers := make(map[HashAlgorithm]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		if _, exists := hashers[algorithm]; exists {
+			continue
+		}
+		h, err := newHash(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashers[algorithm] = h
+		writers = append(writers, h)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writers...), file); err != nil {
+		return nil, fmt.Errorf("failed to calculate hash: %w", err)
+	}
+
+	result := make(map[HashAlgorithm]string, len(hashers))
+	for algorithm, h := range hashers {
+		result[algorithm] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return result, nil
+}
+
+// FileHasher lazily calculates and caches the hashes of a single file.
+// Used by duplicate checks that need to compare against hashes of several algorithms.
+type FileHasher struct {
+	path   string
+	hashes map[HashAlgorithm]string
+}
+
+// NewFileHasher creates a FileHasher for the file at path
+func NewFileHasher(path string) *FileHasher {
+	return &FileHasher{
+		path:   path,
+		hashes: make(map[HashAlgorithm]string),
+	}
+}
+
+// Hash returns the hash of the file for the given algorithm, calculating it only once
+func (f *FileHasher) Hash(algorithm HashAlgorithm) (string, error) {
+	if h, exists := f.hashes[algorithm]; exists {
+		return h, nil
+	}
+	h, err := CalculateHash(f.path, algorithm)
+	if err != nil {
+		return "", err
+	}
+	f.hashes[algorithm] = h
+	return h, nil
+}
@@ -0,0 +1,71 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCalculateHash(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "hello.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		algorithm HashAlgorithm
+		expected  string
+	}{
+		{HashMD5, "5d41402abc4b2a76b9719d911017c592"},
+		{HashSHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+	}
+	for _, tt := range tests {
+		result, err := CalculateHash(filePath, tt.algorithm)
+		if err != nil {
+			t.Fatalf("CalculateHash(%s) failed: %v", tt.algorithm, err)
+		}
+		if result != tt.expected {
+			t.Errorf("CalculateHash(%s) = %s, want %s", tt.algorithm, result, tt.expected)
+		}
+	}
+
+	// xxh3 must be 128-bit and match the single-pass multi-hash result
+	xxh3Hash, err := CalculateHash(filePath, HashXXH3)
+	if err != nil {
+		t.Fatalf("CalculateHash(xxh3) failed: %v", err)
+	}
+	if len(xxh3Hash) != 32 {
+		t.Errorf("Expected 128-bit xxh3 hash, got %q", xxh3Hash)
+	}
+	hashes, err := CalculateHashes(filePath, HashMD5, HashXXH3, HashXXH3)
+	if err != nil {
+		t.Fatalf("CalculateHashes failed: %v", err)
+	}
+	if len(hashes) != 2 || hashes[HashXXH3] != xxh3Hash || hashes[HashMD5] != tests[0].expected {
+		t.Errorf("CalculateHashes returned unexpected result: %v", hashes)
+	}
+
+	// FileHasher must keep returning the cached hash even after the file is gone
+	hasher := NewFileHasher(filePath)
+	first, err := hasher.Hash(HashSHA256)
+	if err != nil {
+		t.Fatalf("FileHasher.Hash failed: %v", err)
+	}
+	os.Remove(filePath)
+	second, err := hasher.Hash(HashSHA256)
+	if err != nil || first != second {
+		t.Errorf("Expected cached hash %s, got %s (err: %v)", first, second, err)
+	}
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	for _, name := range []string{"md5", "sha256", "xxh3"} {
+		if _, err := ParseHashAlgorithm(name); err != nil {
+			t.Errorf("Expected %s to be valid, got error: %v", name, err)
+		}
+	}
+	if _, err := ParseHashAlgorithm("crc32"); err == nil {
+		t.Error("Expected unsupported algorithm to be rejected")
+	}
+}
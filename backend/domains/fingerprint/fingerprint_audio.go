@@ -0,0 +1,117 @@
+package fingerprint
+
+import (
+	"encoding/binary"
+	"math"
+	"math/cmplx"
+)
+
+// Audio fingerprints are calculated on mono PCM decoded by ffmpeg.
+// Each frame produces a 32 bit sub-fingerprint describing how the energy
+// of neighbouring frequency bands changes compared to the previous frame.
+// This survives re-encoding, bitrate and loudness changes.
+const (
+	audioSampleRate   = 5512
+	audioFrameSize    = 2048
+	audioHopSize      = 256
+	audioBandCount    = 33
+	audioMinFrequency = 300.0
+	audioMaxFrequency = 2000.0
+	audioMaxSeconds   = 120
+)
+
+// audioFingerprintFromPCM calculates the audio fingerprint of signed 16 bit little endian mono PCM
+func audioFingerprintFromPCM(pcm []byte) []uint32 {
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / 32768.0
+	}
+	return audioFingerprintFromSamples(samples)
+}
+
+func audioFingerprintFromSamples(samples []float64) []uint32 {
+	window := hannWindow(audioFrameSize)
+	bandEdges := audioBandEdges()
+
+	result := make([]uint32, 0, len(samples)/audioHopSize)
+	frame := make([]complex128, audioFrameSize)
+	var previous []float64
+	for start := 0; start+audioFrameSize <= len(samples); start += audioHopSize {
+		for i := 0; i < audioFrameSize; i++ {
+			frame[i] = complex(samples[start+i]*window[i], 0)
+		}
+		fft(frame)
+
+		energies := make([]float64, audioBandCount)
+		for band := 0; band < audioBandCount; band++ {
+			for bin := bandEdges[band]; bin < bandEdges[band+1]; bin++ {
+				magnitude := cmplx.Abs(frame[bin])
+				energies[band] += magnitude * magnitude
+			}
+		}
+
+		if previous != nil {
+			var bits uint32
+			for band := 0; band < audioBandCount-1; band++ {
+				diff := (energies[band] - energies[band+1]) - (previous[band] - previous[band+1])
+				if diff > 0 {
+					bits |= 1 << uint(band)
+				}
+			}
+			result = append(result, bits)
+		}
+		previous = energies
+	}
+	return result
+}
+
+// audioBandEdges returns the FFT bin boundaries of logarithmically spaced bands
+func audioBandEdges() []int {
+	edges := make([]int, audioBandCount+1)
+	ratio := math.Pow(audioMaxFrequency/audioMinFrequency, 1.0/float64(audioBandCount))
+	for i := range edges {
+		frequency := audioMinFrequency * math.Pow(ratio, float64(i))
+		edges[i] = int(frequency * audioFrameSize / audioSampleRate)
+		if i > 0 && edges[i] <= edges[i-1] {
+			edges[i] = edges[i-1] + 1
+		}
+	}
+	return edges
+}
+
+func hannWindow(size int) []float64 {
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(size-1)))
+	}
+	return window
+}
+
+// fft is an in-place iterative radix-2 FFT, len(x) must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for length := 2; length <= n; length <<= 1 {
+		angle := -2 * math.Pi / float64(length)
+		step := complex(math.Cos(angle), math.Sin(angle))
+		for start := 0; start < n; start += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				u := x[start+k]
+				v := x[start+k+length/2] * w
+				x[start+k] = u + v
+				x[start+k+length/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
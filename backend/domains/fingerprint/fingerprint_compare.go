@@ -0,0 +1,175 @@
+package fingerprint
+
+import "math/bits"
+
+const (
+	// Maximum shift in seconds tried when aligning two fingerprints,
+	// handles intros or SponsorBlock segments removed at the start
+	maxAlignmentSeconds = 30
+
+	// Minimum share of the shorter fingerprint that must overlap
+	minOverlapRatio = 0.5
+
+	// Files whose durations differ more than this ratio are never compared
+	maxDurationDifferenceRatio = 0.35
+)
+
+// Similarity calculates how similar two fingerprints are, from 0 (unrelated) to 1 (identical).
+// Audio and video similarity are averaged when both files have both fingerprints.
+func Similarity(a, b *Fingerprint) float64 {
+	if a.Duration > 0 && b.Duration > 0 {
+		longest := a.Duration
+		if b.Duration > longest {
+			longest = b.Duration
+		}
+		difference := a.Duration - b.Duration
+		if difference < 0 {
+			difference = -difference
+		}
+		if difference/longest > maxDurationDifferenceRatio {
+			return 0
+		}
+	}
+
+	total := 0.0
+	count := 0
+	if len(a.Audio) > 0 && len(b.Audio) > 0 {
+		maxOffset := maxAlignmentSeconds * audioSampleRate / audioHopSize
+		total += AudioSimilarity(a.Audio, b.Audio, maxOffset)
+		count++
+	}
+	if len(a.Video) > 0 && len(b.Video) > 0 {
+		maxOffset := maxAlignmentSeconds / videoSampleSeconds
+		total += VideoSimilarity(a.Video, b.Video, maxOffset)
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// AudioSimilarity compares two audio fingerprints at the best alignment within maxOffset frames
+func AudioSimilarity(a, b []uint32, maxOffset int) float64 {
+	return bestAlignment(len(a), len(b), maxOffset, 32, func(i, j int) int {
+		return bits.OnesCount32(a[i] ^ b[j])
+	})
+}
+
+// VideoSimilarity compares two video fingerprints at the best alignment within maxOffset frames
+func VideoSimilarity(a, b []uint64, maxOffset int) float64 {
+	return bestAlignment(len(a), len(b), maxOffset, 64, func(i, j int) int {
+		return bits.OnesCount64(a[i] ^ b[j])
+	})
+}
+
+// bestAlignment tries every offset and returns the highest similarity.
+// Unrelated content differs in about half of the bits, so a bit error rate
+// of 0.5 maps to a similarity of 0 and identical content maps to 1.
+func bestAlignment(lenA, lenB, maxOffset, bitsPerItem int, distance func(i, j int) int) float64 {
+	if lenA == 0 || lenB == 0 {
+		return 0
+	}
+	shortest := lenA
+	if lenB < shortest {
+		shortest = lenB
+	}
+	minOverlap := int(float64(shortest) * minOverlapRatio)
+	if minOverlap < 1 {
+		minOverlap = 1
+	}
+
+	best := 0.0
+	for offset := -maxOffset; offset <= maxOffset; offset++ {
+		// Offset is the index in b that aligns with index 0 in a
+		startA, startB := 0, offset
+		if offset < 0 {
+			startA, startB = -offset, 0
+		}
+		overlap := lenA - startA
+		if lenB-startB < overlap {
+			overlap = lenB - startB
+		}
+		if overlap < minOverlap {
+			continue
+		}
+
+		differentBits := 0
+		for k := 0; k < overlap; k++ {
+			differentBits += distance(startA+k, startB+k)
+		}
+		bitErrorRate := float64(differentBits) / float64(overlap*bitsPerItem)
+		similarity := 1 - bitErrorRate*2
+		if similarity > best {
+			best = similarity
+		}
+	}
+	return best
+}
+
+// ClusterSimilar groups fingerprints that are at or above the similarity threshold, directly or through
+// other members of the group. Fingerprints must be sorted by duration. Groups with a single member are omitted.
+func ClusterSimilar(fingerprints []Fingerprint, threshold float64) []SimilarGroup {
+	parent := make([]int, len(fingerprints))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Lowest similarity of the links that formed each group, keyed by the final group root
+	linkSimilarity := make(map[int]float64)
+	for i := range fingerprints {
+		maxDuration := fingerprints[i].Duration / (1 - maxDurationDifferenceRatio)
+		for j := i + 1; j < len(fingerprints) && fingerprints[j].Duration <= maxDuration; j++ {
+			a, b := &fingerprints[i], &fingerprints[j]
+			if a.Source == b.Source && a.SourceID == b.SourceID {
+				continue
+			}
+			similarity := Similarity(a, b)
+			if similarity < threshold {
+				continue
+			}
+
+			rootA, rootB := find(i), find(j)
+			lowest := similarity
+			for _, root := range []int{rootA, rootB} {
+				if existing, ok := linkSimilarity[root]; ok && existing < lowest {
+					lowest = existing
+				}
+			}
+			delete(linkSimilarity, rootA)
+			delete(linkSimilarity, rootB)
+			parent[rootB] = rootA
+			linkSimilarity[rootA] = lowest
+		}
+	}
+
+	byRoot := make(map[int]*SimilarGroup)
+	roots := make([]int, 0)
+	for i := range fingerprints {
+		root := find(i)
+		similarity, linked := linkSimilarity[root]
+		if !linked {
+			continue
+		}
+		group, exists := byRoot[root]
+		if !exists {
+			group = &SimilarGroup{Similarity: similarity}
+			byRoot[root] = group
+			roots = append(roots, root)
+		}
+		group.Fingerprints = append(group.Fingerprints, fingerprints[i])
+	}
+
+	groups := make([]SimilarGroup, 0, len(roots))
+	for _, root := range roots {
+		groups = append(groups, *byRoot[root])
+	}
+	return groups
+}
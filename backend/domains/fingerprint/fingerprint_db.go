@@ -0,0 +1,183 @@
+package fingerprint
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"time"
+	"videoarchiver/backend/domains/db"
+)
+
+type FingerprintDB struct {
+	db *sql.DB
+}
+
+func NewFingerprintDB(dbService *db.DatabaseService) *FingerprintDB {
+	return &FingerprintDB{db: dbService.GetDB()}
+}
+
+// SaveFingerprint stores the fingerprint of a file, replacing any previous fingerprint for the same source
+func (f *FingerprintDB) SaveFingerprint(fp *Fingerprint) error {
+	fp.CreatedAt = time.Now().Unix()
+	res, err := f.db.Exec(
+		`INSERT INTO fingerprints (source, source_id, file_path, duration, audio_fingerprint, video_fingerprint, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (source, source_id) DO UPDATE SET
+		 file_path = excluded.file_path, duration = excluded.duration,
+		 audio_fingerprint = excluded.audio_fingerprint, video_fingerprint = excluded.video_fingerprint,
+		 created_at = excluded.created_at`,
+		fp.Source, fp.SourceID, fp.FilePath, fp.Duration,
+		encodeAudio(fp.Audio), encodeVideo(fp.Video), fp.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	fp.ID = int(id)
+	return err
+}
+
+// DeleteFingerprint removes the fingerprint of a source item
+func (f *FingerprintDB) DeleteFingerprint(source string, sourceId int) error {
+	_, err := f.db.Exec("DELETE FROM fingerprints WHERE source = ? AND source_id = ?", source, sourceId)
+	return err
+}
+
+// GetCandidates returns stored fingerprints with a duration close enough to be compared.
+// Fingerprints without a known duration are always returned.
+func (f *FingerprintDB) GetCandidates(duration float64) ([]Fingerprint, error) {
+	minDuration := duration * (1 - maxDurationDifferenceRatio)
+	maxDuration := duration / (1 - maxDurationDifferenceRatio)
+	rows, err := f.db.Query(
+		`SELECT id, source, source_id, file_path, duration, audio_fingerprint, video_fingerprint, created_at
+		 FROM fingerprints WHERE duration = 0 OR ? = 0 OR duration BETWEEN ? AND ?`,
+		duration, minDuration, maxDuration,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fingerprints []Fingerprint
+	for rows.Next() {
+		var fp Fingerprint
+		var audio, video []byte
+		err := rows.Scan(&fp.ID, &fp.Source, &fp.SourceID, &fp.FilePath, &fp.Duration, &audio, &video, &fp.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		fp.Audio = decodeAudio(audio)
+		fp.Video = decodeVideo(video)
+		fingerprints = append(fingerprints, fp)
+	}
+	return fingerprints, nil
+}
+
+// GetAllWithDuration returns every stored fingerprint with a known duration, shortest first
+func (f *FingerprintDB) GetAllWithDuration() ([]Fingerprint, error) {
+	rows, err := f.db.Query(
+		`SELECT id, source, source_id, file_path, duration, audio_fingerprint, video_fingerprint, created_at
+		 FROM fingerprints WHERE duration > 0 ORDER BY duration ASC, id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fingerprints []Fingerprint
+	for rows.Next() {
+		var fp Fingerprint
+		var audio, video []byte
+		err := rows.Scan(&fp.ID, &fp.Source, &fp.SourceID, &fp.FilePath, &fp.Duration, &audio, &video, &fp.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		fp.Audio = decodeAudio(audio)
+		fp.Video = decodeVideo(video)
+		fingerprints = append(fingerprints, fp)
+	}
+	return fingerprints, nil
+}
+
+// InsertProbableDuplicate records a probable duplicate for review
+func (f *FingerprintDB) InsertProbableDuplicate(downloadId int, match *Match) error {
+	_, err := f.db.Exec(
+		`INSERT INTO probable_duplicates (download_id, match_source, match_source_id, match_path, similarity, status, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		downloadId, match.Fingerprint.Source, match.Fingerprint.SourceID, match.Fingerprint.FilePath,
+		match.Similarity, ReviewPending, time.Now().Unix(),
+	)
+	return err
+}
+
+// GetProbableDuplicates returns a page of probable duplicates, pending reviews first
+func (f *FingerprintDB) GetProbableDuplicates(offset, limit int) ([]ProbableDuplicate, error) {
+	rows, err := f.db.Query(
+		`SELECT pd.id, pd.download_id, COALESCE(fp.file_path, ''), pd.match_source, pd.match_source_id,
+		 pd.match_path, pd.similarity, pd.status, pd.created_at
+		 FROM probable_duplicates pd
+		 LEFT JOIN fingerprints fp ON fp.source = ? AND fp.source_id = pd.download_id
+		 ORDER BY pd.status = ? DESC, pd.created_at DESC
+		 LIMIT ? OFFSET ?`,
+		SourceDownload, ReviewPending, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicates := make([]ProbableDuplicate, 0)
+	for rows.Next() {
+		var pd ProbableDuplicate
+		err := rows.Scan(&pd.ID, &pd.DownloadID, &pd.DownloadPath, &pd.MatchSource, &pd.MatchSourceID,
+			&pd.MatchPath, &pd.Similarity, &pd.Status, &pd.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, pd)
+	}
+	return duplicates, nil
+}
+
+// SetProbableDuplicateStatus stores the review outcome of a probable duplicate
+func (f *FingerprintDB) SetProbableDuplicateStatus(id int, status string) error {
+	_, err := f.db.Exec("UPDATE probable_duplicates SET status = ? WHERE id = ?", status, id)
+	return err
+}
+
+func encodeAudio(values []uint32) []byte {
+	if len(values) == 0 {
+		return nil
+	}
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], v)
+	}
+	return data
+}
+
+func decodeAudio(data []byte) []uint32 {
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return values
+}
+
+func encodeVideo(values []uint64) []byte {
+	if len(values) == 0 {
+		return nil
+	}
+	data := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[i*8:], v)
+	}
+	return data
+}
+
+func decodeVideo(data []byte) []uint64 {
+	values := make([]uint64, len(data)/8)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return values
+}
@@ -0,0 +1,52 @@
+package fingerprint
+
+// Sources a fingerprint can belong to
+const (
+	SourceDownload     = "download"
+	SourceFileRegistry = "file_registry"
+)
+
+// Review statuses of a probable duplicate
+const (
+	ReviewPending   = "pending"
+	ReviewConfirmed = "confirmed"
+	ReviewDismissed = "dismissed"
+)
+
+// Fingerprint holds the content fingerprints of a single media file.
+// Either fingerprint may be empty when the file has no audio or video stream.
+type Fingerprint struct {
+	ID        int      `json:"id" db:"id"`
+	Source    string   `json:"source" db:"source"`
+	SourceID  int      `json:"source_id" db:"source_id"`
+	FilePath  string   `json:"file_path" db:"file_path"`
+	Duration  float64  `json:"duration" db:"duration"`
+	Audio     []uint32 `json:"-" db:"audio_fingerprint"`
+	Video     []uint64 `json:"-" db:"video_fingerprint"`
+	CreatedAt int64    `json:"created_at" db:"created_at"`
+}
+
+// Match is a stored fingerprint that is similar to a new file
+type Match struct {
+	Fingerprint Fingerprint `json:"fingerprint"`
+	Similarity  float64     `json:"similarity"`
+}
+
+// SimilarGroup is a set of stored fingerprints that are similar to each other
+type SimilarGroup struct {
+	Fingerprints []Fingerprint `json:"fingerprints"`
+	Similarity   float64       `json:"similarity"` // Lowest similarity between two linked members
+}
+
+// ProbableDuplicate is a download that was found to be similar to an existing file, awaiting review
+type ProbableDuplicate struct {
+	ID            int     `json:"id" db:"id"`
+	DownloadID    int     `json:"download_id" db:"download_id"`
+	DownloadPath  string  `json:"download_path" db:"download_path"`
+	MatchSource   string  `json:"match_source" db:"match_source"`
+	MatchSourceID int     `json:"match_source_id" db:"match_source_id"`
+	MatchPath     string  `json:"match_path" db:"match_path"`
+	Similarity    float64 `json:"similarity" db:"similarity"`
+	Status        string  `json:"status" db:"status"`
+	CreatedAt     int64   `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,264 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"videoarchiver/backend/domains/runner"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+type FingerprintService struct {
+	fingerprintDB   *FingerprintDB
+	settingsService *settings.SettingsService
+	logService      LogServiceInterface
+}
+
+func NewFingerprintService(
+	fingerprintDB *FingerprintDB,
+	settingsService *settings.SettingsService,
+	logService LogServiceInterface,
+) *FingerprintService {
+	return &FingerprintService{
+		fingerprintDB:   fingerprintDB,
+		settingsService: settingsService,
+		logService:      logService,
+	}
+}
+
+// IsEnabled checks the fingerprinting_enabled setting
+func (s *FingerprintService) IsEnabled() bool {
+	enabled, err := s.settingsService.GetSettingBool("fingerprinting_enabled")
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Failed to get fingerprinting_enabled setting: %v", err))
+		return false
+	}
+	return enabled
+}
+
+// GetSimilarityThreshold returns the minimum similarity for a probable duplicate
+func (s *FingerprintService) GetSimilarityThreshold() (float64, error) {
+	thresholdStr, err := s.settingsService.GetSettingString("fingerprint_similarity_threshold")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get fingerprint_similarity_threshold setting: %w", err)
+	}
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return 0, fmt.Errorf("invalid fingerprint_similarity_threshold setting: %s", thresholdStr)
+	}
+	return threshold, nil
+}
+
+// CalculateFingerprint decodes a media file with ffmpeg and calculates its audio and video fingerprints
+func (s *FingerprintService) CalculateFingerprint(filePath string) (*Fingerprint, error) {
+	info, err := probeFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.hasAudio && !info.hasVideo {
+		return nil, fmt.Errorf("fingerprint: no audio or video stream in %s", filePath)
+	}
+
+	ffmpegPath, err := ytdlp.GetFfmpegPath()
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: failed to get ffmpeg path: %w", err)
+	}
+
+	fp := &Fingerprint{FilePath: filePath, Duration: info.duration}
+	if info.hasAudio {
+		stdout, stderr, err := runner.RunWithOutput(ffmpegPath,
+			"-v", "error", "-i", filePath,
+			"-t", strconv.Itoa(audioMaxSeconds),
+			"-vn", "-ac", "1", "-ar", strconv.Itoa(audioSampleRate),
+			"-f", "s16le", "-")
+		if err != nil {
+			return nil, fmt.Errorf("fingerprint: failed to decode audio: %w: %s", err, stderr)
+		}
+		fp.Audio = audioFingerprintFromPCM([]byte(stdout))
+	}
+	if info.hasVideo {
+		stdout, stderr, err := runner.RunWithOutput(ffmpegPath,
+			"-v", "error", "-skip_frame", "nokey", "-i", filePath,
+			"-an", "-vf", fmt.Sprintf("fps=1/%d,scale=%d:%d,format=gray", videoSampleSeconds, videoHashWidth, videoHashHeight),
+			"-f", "rawvideo", "-")
+		if err != nil {
+			return nil, fmt.Errorf("fingerprint: failed to decode video frames: %w: %s", err, stderr)
+		}
+		fp.Video = videoFingerprintFromFrames([]byte(stdout))
+	}
+	return fp, nil
+}
+
+// FindMatches returns stored fingerprints at or above the similarity threshold, most similar first.
+// Fingerprints belonging to the same source item are ignored.
+func (s *FingerprintService) FindMatches(fp *Fingerprint, threshold float64) ([]Match, error) {
+	candidates, err := s.fingerprintDB.GetCandidates(fp.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprint candidates: %w", err)
+	}
+
+	matches := make([]Match, 0)
+	for _, candidate := range candidates {
+		if candidate.Source == fp.Source && candidate.SourceID == fp.SourceID {
+			continue
+		}
+		similarity := Similarity(fp, &candidate)
+		if similarity >= threshold {
+			matches = append(matches, Match{Fingerprint: candidate, Similarity: similarity})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Similarity > matches[j].Similarity
+	})
+	return matches, nil
+}
+
+// ProcessDownload fingerprints an archived download, records probable duplicates for review
+// and stores the fingerprint. Returns the probable duplicates found.
+func (s *FingerprintService) ProcessDownload(downloadId int, filePath string) ([]Match, error) {
+	threshold, err := s.GetSimilarityThreshold()
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := s.CalculateFingerprint(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fp.Source = SourceDownload
+	fp.SourceID = downloadId
+
+	matches, err := s.FindMatches(fp, threshold)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		if err := s.fingerprintDB.InsertProbableDuplicate(downloadId, &matches[i]); err != nil {
+			return nil, fmt.Errorf("failed to record probable duplicate: %w", err)
+		}
+	}
+
+	if err := s.fingerprintDB.SaveFingerprint(fp); err != nil {
+		return nil, fmt.Errorf("failed to save fingerprint: %w", err)
+	}
+	return matches, nil
+}
+
+// ProcessRegisteredFile fingerprints a file in the registry so later downloads can be compared against it
+func (s *FingerprintService) ProcessRegisteredFile(registryId int, filePath string) error {
+	fp, err := s.CalculateFingerprint(filePath)
+	if err != nil {
+		return err
+	}
+	return s.SaveRegisteredFile(registryId, fp)
+}
+
+// SaveRegisteredFile stores an already calculated fingerprint for a file in the registry
+func (s *FingerprintService) SaveRegisteredFile(registryId int, fp *Fingerprint) error {
+	fp.Source = SourceFileRegistry
+	fp.SourceID = registryId
+
+	if err := s.fingerprintDB.SaveFingerprint(fp); err != nil {
+		return fmt.Errorf("failed to save fingerprint: %w", err)
+	}
+	return nil
+}
+
+// RemoveRegisteredFile removes the fingerprint of a file that left the registry
+func (s *FingerprintService) RemoveRegisteredFile(registryId int) error {
+	return s.fingerprintDB.DeleteFingerprint(SourceFileRegistry, registryId)
+}
+
+// RemoveDownload removes the fingerprint of a download whose file left the archive
+func (s *FingerprintService) RemoveDownload(downloadId int) error {
+	return s.fingerprintDB.DeleteFingerprint(SourceDownload, downloadId)
+}
+
+// FindSimilarGroups groups every stored fingerprint with the others it is similar to
+func (s *FingerprintService) FindSimilarGroups() ([]SimilarGroup, error) {
+	threshold, err := s.GetSimilarityThreshold()
+	if err != nil {
+		return nil, err
+	}
+	fingerprints, err := s.fingerprintDB.GetAllWithDuration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprints: %w", err)
+	}
+	return ClusterSimilar(fingerprints, threshold), nil
+}
+
+// GetProbableDuplicates returns a page of probable duplicates for review
+func (s *FingerprintService) GetProbableDuplicates(offset, limit int) ([]ProbableDuplicate, error) {
+	return s.fingerprintDB.GetProbableDuplicates(offset, limit)
+}
+
+// ResolveProbableDuplicate stores the review outcome of a probable duplicate
+func (s *FingerprintService) ResolveProbableDuplicate(id int, status string) error {
+	switch status {
+	case ReviewPending, ReviewConfirmed, ReviewDismissed:
+	default:
+		return fmt.Errorf("invalid probable duplicate status: %s", status)
+	}
+	return s.fingerprintDB.SetProbableDuplicateStatus(id, status)
+}
+
+// mediaInfo holds the stream information needed for fingerprinting
+type mediaInfo struct {
+	duration float64
+	hasAudio bool
+	hasVideo bool
+}
+
+// probeFile reads duration and stream types with ffprobe.
+// Cover art is reported as a video stream, it is ignored so audio files only get an audio fingerprint.
+func probeFile(filePath string) (*mediaInfo, error) {
+	ffprobePath, err := ytdlp.GetFfprobePath()
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: failed to get ffprobe path: %w", err)
+	}
+	stdout, stderr, err := runner.RunWithOutput(ffprobePath,
+		"-v", "error", "-print_format", "json", "-show_format", "-show_streams", filePath)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: ffprobe failed: %w: %s", err, stderr)
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType   string `json:"codec_type"`
+			Disposition struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &probe); err != nil {
+		return nil, fmt.Errorf("fingerprint: failed to parse ffprobe output: %w", err)
+	}
+
+	info := &mediaInfo{}
+	info.duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "audio":
+			info.hasAudio = true
+		case "video":
+			if stream.Disposition.AttachedPic == 0 {
+				info.hasVideo = true
+			}
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,128 @@
+package fingerprint
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// synthSignal generates chords of random tones that change every quarter second
+func synthSignal(seconds int, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]float64, seconds*audioSampleRate)
+	frequencies := make([]float64, 12)
+	for i := range samples {
+		if i%(audioSampleRate/4) == 0 {
+			for f := range frequencies {
+				frequencies[f] = 250 + rng.Float64()*1800
+			}
+		}
+		t := float64(i) / audioSampleRate
+		for _, frequency := range frequencies {
+			samples[i] += math.Sin(2*math.Pi*frequency*t) / float64(len(frequencies))
+		}
+	}
+	return samples
+}
+
+func TestAudioSimilarityDetectsReencodedAudio(t *testing.T) {
+	original := synthSignal(20, 1)
+
+	// Quieter copy with noise, like a re-encode at a different bitrate
+	rng := rand.New(rand.NewSource(2))
+	modified := make([]float64, len(original))
+	for i, sample := range original {
+		modified[i] = sample*0.6 + (rng.Float64()-0.5)*0.02
+	}
+
+	a := audioFingerprintFromSamples(original)
+	b := audioFingerprintFromSamples(modified)
+	if similarity := AudioSimilarity(a, b, 0); similarity < 0.8 {
+		t.Errorf("Expected re-encoded audio to be similar, got %.2f", similarity)
+	}
+}
+
+func TestAudioSimilarityAlignsTrimmedStart(t *testing.T) {
+	original := synthSignal(20, 1)
+	trimmed := original[3*audioSampleRate:]
+
+	a := audioFingerprintFromSamples(original)
+	b := audioFingerprintFromSamples(trimmed)
+	maxOffset := maxAlignmentSeconds * audioSampleRate / audioHopSize
+	if similarity := AudioSimilarity(a, b, maxOffset); similarity < 0.8 {
+		t.Errorf("Expected trimmed audio to be similar, got %.2f", similarity)
+	}
+}
+
+func TestAudioSimilarityRejectsDifferentAudio(t *testing.T) {
+	a := audioFingerprintFromSamples(synthSignal(20, 1))
+	b := audioFingerprintFromSamples(synthSignal(20, 3))
+	if similarity := AudioSimilarity(a, b, 0); similarity > 0.5 {
+		t.Errorf("Expected different audio to be dissimilar, got %.2f", similarity)
+	}
+}
+
+func TestDifferenceHash(t *testing.T) {
+	frame := make([]byte, videoFrameByteLength)
+	for y := 0; y < videoHashHeight; y++ {
+		for x := 0; x < videoHashWidth; x++ {
+			// Brightness decreases left to right, every pixel is brighter than its right neighbour
+			frame[y*videoHashWidth+x] = byte(255 - x*20)
+		}
+	}
+	if hash := differenceHash(frame); hash != math.MaxUint64 {
+		t.Errorf("Expected all bits set, got %x", hash)
+	}
+}
+
+func TestSimilaritySkipsDifferentDurations(t *testing.T) {
+	a := &Fingerprint{Duration: 60, Video: []uint64{1, 2, 3}}
+	b := &Fingerprint{Duration: 200, Video: []uint64{1, 2, 3}}
+	if similarity := Similarity(a, b); similarity != 0 {
+		t.Errorf("Expected 0 for very different durations, got %.2f", similarity)
+	}
+
+	b.Duration = 61
+	if similarity := Similarity(a, b); similarity != 1 {
+		t.Errorf("Expected identical fingerprints to match, got %.2f", similarity)
+	}
+}
+
+func TestFingerprintEncoding(t *testing.T) {
+	audio := []uint32{0, 1, math.MaxUint32}
+	decodedAudio := decodeAudio(encodeAudio(audio))
+	video := []uint64{0, 42, math.MaxUint64}
+	decodedVideo := decodeVideo(encodeVideo(video))
+	for i := range audio {
+		if decodedAudio[i] != audio[i] {
+			t.Errorf("Audio value %d: expected %d, got %d", i, audio[i], decodedAudio[i])
+		}
+	}
+	for i := range video {
+		if decodedVideo[i] != video[i] {
+			t.Errorf("Video value %d: expected %d, got %d", i, video[i], decodedVideo[i])
+		}
+	}
+}
+
+func TestClusterSimilar(t *testing.T) {
+	same := []uint64{0xF0F0, 0x0F0F, 0xFF00, 0x00FF}
+	other := []uint64{math.MaxUint64, 0, math.MaxUint64, 0}
+	fingerprints := []Fingerprint{
+		{ID: 1, Source: SourceDownload, SourceID: 1, Duration: 60, Video: same},
+		{ID: 2, Source: SourceDownload, SourceID: 2, Duration: 60, Video: other},
+		{ID: 3, Source: SourceFileRegistry, SourceID: 1, Duration: 62, Video: same},
+		{ID: 4, Source: SourceFileRegistry, SourceID: 2, Duration: 200, Video: same},
+	}
+
+	groups := ClusterSimilar(fingerprints, 0.9)
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 group, got %d", len(groups))
+	}
+	if len(groups[0].Fingerprints) != 2 || groups[0].Fingerprints[0].ID != 1 || groups[0].Fingerprints[1].ID != 3 {
+		t.Errorf("Expected fingerprints 1 and 3 to be grouped, got %+v", groups[0].Fingerprints)
+	}
+	if groups[0].Similarity != 1 {
+		t.Errorf("Expected similarity 1, got %.2f", groups[0].Similarity)
+	}
+}
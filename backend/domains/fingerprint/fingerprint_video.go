@@ -0,0 +1,35 @@
+package fingerprint
+
+// Video fingerprints are difference hashes (dHash) of sampled keyframes.
+// ffmpeg scales every sampled frame down to a 9x8 grayscale image,
+// each bit describes whether a pixel is brighter than its right neighbour.
+const (
+	videoHashWidth       = 9
+	videoHashHeight      = 8
+	videoSampleSeconds   = 5
+	videoFrameByteLength = videoHashWidth * videoHashHeight
+)
+
+// videoFingerprintFromFrames calculates the dHash of every raw 9x8 gray frame
+func videoFingerprintFromFrames(raw []byte) []uint64 {
+	result := make([]uint64, 0, len(raw)/videoFrameByteLength)
+	for start := 0; start+videoFrameByteLength <= len(raw); start += videoFrameByteLength {
+		result = append(result, differenceHash(raw[start:start+videoFrameByteLength]))
+	}
+	return result
+}
+
+func differenceHash(frame []byte) uint64 {
+	var hash uint64
+	bit := 0
+	for y := 0; y < videoHashHeight; y++ {
+		row := frame[y*videoHashWidth : (y+1)*videoHashWidth]
+		for x := 0; x < videoHashWidth-1; x++ {
+			if row[x] > row[x+1] {
+				hash |= 1 << uint(bit)
+			}
+			bit++
+		}
+	}
+	return hash
+}
@@ -0,0 +1,174 @@
+package hashmigration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/settings"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// Amount of rows loaded per query while migrating
+const batchSize = 50
+
+// HashMigrationService rehashes existing downloads and registry rows
+// to the configured hash algorithm in the background.
+type HashMigrationService struct {
+	settingsService     *settings.SettingsService
+	downloadDB          *download.DownloadDB
+	fileRegistryService *fileregistry.FileRegistryService
+	logService          LogServiceInterface
+
+	// Cursors so rows that can't be migrated (missing or modified files) are not retried every batch.
+	// Reset whenever the configured algorithm changes.
+	algorithm          fileutils.HashAlgorithm
+	lastDownloadId     int
+	lastRegistryFileId int
+}
+
+func NewHashMigrationService(
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	fileRegistryService *fileregistry.FileRegistryService,
+	logService LogServiceInterface,
+) *HashMigrationService {
+	return &HashMigrationService{
+		settingsService:     settingsService,
+		downloadDB:          downloadDB,
+		fileRegistryService: fileRegistryService,
+		logService:          logService,
+	}
+}
+
+// MigrateAll rehashes rows until every row uses the configured algorithm,
+// the context is cancelled or shouldStop returns true. Stopping is checked before every file,
+// the next call continues where this one stopped.
+// Returns the amount of rows that were migrated.
+func (h *HashMigrationService) MigrateAll(ctx context.Context, shouldStop func() bool) (int, error) {
+	algorithm := fileutils.GetConfiguredHashAlgorithm(h.settingsService)
+	if algorithm != h.algorithm {
+		h.algorithm = algorithm
+		h.lastDownloadId = 0
+		h.lastRegistryFileId = 0
+	}
+
+	stopped := func() bool {
+		return ctx.Err() != nil || (shouldStop != nil && shouldStop())
+	}
+	migrated := 0
+	for {
+		if stopped() {
+			return migrated, nil
+		}
+
+		downloadsDone, err := h.migrateDownloadsBatch(algorithm, stopped, &migrated)
+		if err != nil {
+			return migrated, err
+		}
+		registryDone, err := h.migrateRegistryBatch(algorithm, stopped, &migrated)
+		if err != nil {
+			return migrated, err
+		}
+
+		if downloadsDone && registryDone {
+			if migrated > 0 {
+				h.logService.Info(fmt.Sprintf("Hash migration to %s completed, %d rows rehashed", algorithm, migrated))
+			}
+			return migrated, nil
+		}
+	}
+}
+
+// migrateDownloadsBatch rehashes one batch of downloads, returns true when no rows are left
+func (h *HashMigrationService) migrateDownloadsBatch(algorithm fileutils.HashAlgorithm, stopped func() bool, migrated *int) (bool, error) {
+	downloads, err := h.downloadDB.GetDownloadsWithOtherHashAlgorithm(algorithm, h.lastDownloadId, batchSize)
+	if err != nil {
+		return false, fmt.Errorf("failed to get downloads for hash migration: %w", err)
+	}
+
+	for _, dl := range downloads {
+		if stopped() {
+			return false, nil
+		}
+		h.lastDownloadId = dl.ID
+		if !dl.FullPath.Valid {
+			continue
+		}
+
+		newHash, ok := h.rehash(dl.FullPath.String, dl.MD5.String, dl.HashAlgorithm, algorithm)
+		if !ok {
+			continue
+		}
+		if err := h.downloadDB.UpdateHash(dl.ID, newHash, algorithm); err != nil {
+			return false, fmt.Errorf("failed to update hash of download %d: %w", dl.ID, err)
+		}
+		*migrated++
+	}
+
+	return len(downloads) < batchSize, nil
+}
+
+// migrateRegistryBatch rehashes one batch of registered files, returns true when no rows are left
+func (h *HashMigrationService) migrateRegistryBatch(algorithm fileutils.HashAlgorithm, stopped func() bool, migrated *int) (bool, error) {
+	files, err := h.fileRegistryService.GetFilesWithOtherHashAlgorithm(algorithm, h.lastRegistryFileId, batchSize)
+	if err != nil {
+		return false, fmt.Errorf("failed to get registered files for hash migration: %w", err)
+	}
+
+	for _, file := range files {
+		if stopped() {
+			return false, nil
+		}
+		h.lastRegistryFileId = file.ID
+
+		newHash, ok := h.rehash(file.FilePath, file.MD5, file.HashAlgorithm, algorithm)
+		if !ok {
+			continue
+		}
+		if err := h.fileRegistryService.UpdateHash(file.ID, newHash, algorithm); err != nil {
+			return false, fmt.Errorf("failed to update hash of registered file %d: %w", file.ID, err)
+		}
+		*migrated++
+	}
+
+	return len(files) < batchSize, nil
+}
+
+// rehash calculates the old and new hash of a file in a single read.
+// The new hash is only returned if the file still matches the stored old hash,
+// so modified files are left for the integrity verification to report.
+func (h *HashMigrationService) rehash(path, oldHash, oldAlgorithmName string, newAlgorithm fileutils.HashAlgorithm) (string, bool) {
+	oldAlgorithm, err := fileutils.ParseHashAlgorithm(oldAlgorithmName)
+	if err != nil {
+		h.logService.Warn(fmt.Sprintf("Skipping hash migration for %s: %v", path, err))
+		return "", false
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		h.logService.Debug(fmt.Sprintf("Skipping hash migration for %s: %v", path, err))
+		return "", false
+	}
+
+	hashes, err := fileutils.CalculateHashes(path, oldAlgorithm, newAlgorithm)
+	if err != nil {
+		h.logService.Warn(fmt.Sprintf("Failed to rehash %s: %v", path, err))
+		return "", false
+	}
+	if hashes[oldAlgorithm] != oldHash {
+		h.logService.Warn(fmt.Sprintf("Skipping hash migration for %s: file no longer matches its stored %s hash", path, oldAlgorithm))
+		return "", false
+	}
+
+	return hashes[newAlgorithm], true
+}
@@ -0,0 +1,140 @@
+package integrity
+
+import (
+	"database/sql"
+	"time"
+	"videoarchiver/backend/domains/db"
+)
+
+type IntegrityDB struct {
+	db *sql.DB
+}
+
+func NewIntegrityDB(dbService *db.DatabaseService) *IntegrityDB {
+	return &IntegrityDB{db: dbService.GetDB()}
+}
+
+// CreateRun inserts a new run and returns its ID
+func (i *IntegrityDB) CreateRun() (int, error) {
+	res, err := i.db.Exec(
+		"INSERT INTO integrity_runs (started_at) VALUES (?)",
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// FinishRun stores the final counters of a run
+func (i *IntegrityDB) FinishRun(runId, filesChecked, issuesFound int) error {
+	_, err := i.db.Exec(
+		"UPDATE integrity_runs SET finished_at = ?, files_checked = ?, issues_found = ? WHERE id = ?",
+		time.Now().Unix(), filesChecked, issuesFound, runId,
+	)
+	return err
+}
+
+// SaveRunProgress stores the counters of an unfinished run and the last target it checked
+func (i *IntegrityDB) SaveRunProgress(runId, filesChecked, issuesFound int, cursorSource string, cursorSourceId int) error {
+	_, err := i.db.Exec(
+		"UPDATE integrity_runs SET files_checked = ?, issues_found = ?, cursor_source = ?, cursor_source_id = ? WHERE id = ?",
+		filesChecked, issuesFound, cursorSource, cursorSourceId, runId,
+	)
+	return err
+}
+
+// GetRunCursor returns the last target an unfinished run checked, empty if it checked none yet
+func (i *IntegrityDB) GetRunCursor(runId int) (string, int, error) {
+	var cursorSource sql.NullString
+	var cursorSourceId sql.NullInt64
+	err := i.db.QueryRow(
+		"SELECT cursor_source, cursor_source_id FROM integrity_runs WHERE id = ?", runId,
+	).Scan(&cursorSource, &cursorSourceId)
+	if err != nil {
+		return "", 0, err
+	}
+	return cursorSource.String, int(cursorSourceId.Int64), nil
+}
+
+// InsertIssue records an issue found during a run
+func (i *IntegrityDB) InsertIssue(issue *IntegrityIssue) error {
+	res, err := i.db.Exec(
+		`INSERT INTO integrity_issues (run_id, source, source_id, file_path, issue, expected_md5, actual_md5, detail, requeued, detected_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		issue.RunID, issue.Source, issue.SourceID, issue.FilePath, issue.Issue,
+		issue.ExpectedMD5, issue.ActualMD5, issue.Detail, issue.Requeued, issue.DetectedAt,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	issue.ID = int(id)
+	return err
+}
+
+// GetLatestRun returns the most recent run, or nil if verification never ran
+func (i *IntegrityDB) GetLatestRun() (*IntegrityRun, error) {
+	var run IntegrityRun
+	err := i.db.QueryRow(
+		"SELECT id, started_at, finished_at, files_checked, issues_found FROM integrity_runs ORDER BY started_at DESC, id DESC LIMIT 1",
+	).Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.FilesChecked, &run.IssuesFound)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetIssuesForRun returns a page of issues found during a run
+func (i *IntegrityDB) GetIssuesForRun(runId, offset, limit int) ([]IntegrityIssue, error) {
+	rows, err := i.db.Query(
+		`SELECT id, run_id, source, source_id, file_path, issue, expected_md5, actual_md5, detail, requeued, detected_at
+		 FROM integrity_issues WHERE run_id = ? ORDER BY id ASC LIMIT ? OFFSET ?`,
+		runId, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := make([]IntegrityIssue, 0)
+	for rows.Next() {
+		var issue IntegrityIssue
+		err := rows.Scan(
+			&issue.ID, &issue.RunID, &issue.Source, &issue.SourceID, &issue.FilePath, &issue.Issue,
+			&issue.ExpectedMD5, &issue.ActualMD5, &issue.Detail, &issue.Requeued, &issue.DetectedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// GetIssue returns a single issue by ID
+func (i *IntegrityDB) GetIssue(issueId int) (*IntegrityIssue, error) {
+	var issue IntegrityIssue
+	err := i.db.QueryRow(
+		`SELECT id, run_id, source, source_id, file_path, issue, expected_md5, actual_md5, detail, requeued, detected_at
+		 FROM integrity_issues WHERE id = ?`,
+		issueId,
+	).Scan(
+		&issue.ID, &issue.RunID, &issue.Source, &issue.SourceID, &issue.FilePath, &issue.Issue,
+		&issue.ExpectedMD5, &issue.ActualMD5, &issue.Detail, &issue.Requeued, &issue.DetectedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// MarkRequeued flags an issue as requeued for download
+func (i *IntegrityDB) MarkRequeued(issueId int) error {
+	_, err := i.db.Exec("UPDATE integrity_issues SET requeued = 1 WHERE id = ?", issueId)
+	return err
+}
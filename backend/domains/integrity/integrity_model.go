@@ -0,0 +1,42 @@
+package integrity
+
+import "database/sql"
+
+// Sources an integrity issue can originate from
+const (
+	SourceDownload     = "download"
+	SourceFileRegistry = "file_registry"
+)
+
+// Issue types detected by the verification job
+const (
+	IssueMissing    = "missing"
+	IssueModified   = "modified"
+	IssueCorrupt    = "corrupt"
+	IssueUnreadable = "unreadable" // The file exists but could not be read, such as permission or I/O errors
+)
+
+// IntegrityRun represents a single execution of the verification job.
+// A run without FinishedAt was paused or interrupted and is resumed by the next verification.
+type IntegrityRun struct {
+	ID           int           `json:"id" db:"id"`
+	StartedAt    int64         `json:"started_at" db:"started_at"`
+	FinishedAt   sql.NullInt64 `json:"finished_at,omitempty" db:"finished_at"`
+	FilesChecked int           `json:"files_checked" db:"files_checked"`
+	IssuesFound  int           `json:"issues_found" db:"issues_found"`
+}
+
+// IntegrityIssue represents a missing, modified or corrupt file found during a run
+type IntegrityIssue struct {
+	ID          int            `json:"id" db:"id"`
+	RunID       int            `json:"run_id" db:"run_id"`
+	Source      string         `json:"source" db:"source"`
+	SourceID    int            `json:"source_id" db:"source_id"`
+	FilePath    string         `json:"file_path" db:"file_path"`
+	Issue       string         `json:"issue" db:"issue"`
+	ExpectedMD5 sql.NullString `json:"expected_md5,omitempty" db:"expected_md5"`
+	ActualMD5   sql.NullString `json:"actual_md5,omitempty" db:"actual_md5"`
+	Detail      sql.NullString `json:"detail,omitempty" db:"detail"`
+	Requeued    bool           `json:"requeued" db:"requeued"`
+	DetectedAt  int64          `json:"detected_at" db:"detected_at"`
+}
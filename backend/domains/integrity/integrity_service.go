@@ -0,0 +1,380 @@
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/settings"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// ProgressCallback defines the signature for progress reporting callbacks
+type ProgressCallback func(percent int, message string)
+
+// Amount of registry entries loaded per query while collecting verification targets
+const registryPageSize = 500
+
+type IntegrityService struct {
+	integrityDB         *IntegrityDB
+	settingsService     *settings.SettingsService
+	downloadDB          *download.DownloadDB
+	downloadService     *download.DownloadService
+	fileRegistryService *fileregistry.FileRegistryService
+	logService          LogServiceInterface
+}
+
+func NewIntegrityService(
+	integrityDB *IntegrityDB,
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	downloadService *download.DownloadService,
+	fileRegistryService *fileregistry.FileRegistryService,
+	logService LogServiceInterface,
+) *IntegrityService {
+	return &IntegrityService{
+		integrityDB:         integrityDB,
+		settingsService:     settingsService,
+		downloadDB:          downloadDB,
+		downloadService:     downloadService,
+		fileRegistryService: fileRegistryService,
+		logService:          logService,
+	}
+}
+
+// verificationTarget is a single file known to the archive that should be verified
+type verificationTarget struct {
+	source        string
+	sourceId      int
+	path          string
+	expectedHash  string
+	hashAlgorithm string
+}
+
+// IsVerificationDue checks if the scheduled verification should run based on the configured interval.
+// An interval of 0 days disables scheduled verification.
+func (s *IntegrityService) IsVerificationDue() (bool, error) {
+	intervalStr, err := s.settingsService.GetSettingString("integrity_check_interval_days")
+	if err != nil {
+		return false, fmt.Errorf("failed to get integrity_check_interval_days setting: %w", err)
+	}
+	intervalDays, err := strconv.Atoi(intervalStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid integrity_check_interval_days setting: %w", err)
+	}
+	if intervalDays <= 0 {
+		return false, nil
+	}
+
+	lastRun, err := s.integrityDB.GetLatestRun()
+	if err != nil {
+		return false, err
+	}
+	// A paused run continues on the next iteration
+	if lastRun == nil || !lastRun.FinishedAt.Valid {
+		return true, nil
+	}
+
+	nextRun := time.Unix(lastRun.StartedAt, 0).Add(time.Duration(intervalDays) * 24 * time.Hour)
+	return time.Now().After(nextRun), nil
+}
+
+// ErrVerificationPaused is returned when a run stops early to be resumed later
+var ErrVerificationPaused = errors.New("integrity verification paused")
+
+// RunVerification walks every successful download and registry entry,
+// confirms the file exists, re-hashes it and optionally checks it for corruption.
+// Any problems are recorded as issues on a new run. A run paused by shouldStop returns
+// ErrVerificationPaused and the next call resumes it after the last file it checked.
+func (s *IntegrityService) RunVerification(ctx context.Context, shouldStop func() bool, progressCallback ProgressCallback) (*IntegrityRun, error) {
+	if progressCallback != nil {
+		progressCallback(0, "Collecting files to verify...")
+	}
+
+	checkCorruption, err := s.settingsService.GetSettingBool("integrity_check_corruption")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integrity_check_corruption setting: %w", err)
+	}
+	requeueMissing, err := s.settingsService.GetSettingBool("integrity_requeue_missing")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get integrity_requeue_missing setting: %w", err)
+	}
+
+	targets, err := s.collectTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	runId, filesChecked, issuesFound, targets, err := s.startOrResumeRun(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, target := range targets {
+		if ctx.Err() != nil {
+			s.logService.Info(fmt.Sprintf("Integrity verification run %d cancelled after %d files", runId, filesChecked))
+			if err := s.integrityDB.FinishRun(runId, filesChecked, issuesFound); err != nil {
+				s.logService.Error(fmt.Sprintf("Failed to finish integrity run %d: %v", runId, err))
+			}
+			return nil, ctx.Err()
+		}
+		if shouldStop != nil && shouldStop() {
+			s.logService.Info(fmt.Sprintf("Integrity verification run %d paused after %d files", runId, filesChecked))
+			return nil, ErrVerificationPaused
+		}
+
+		if progressCallback != nil {
+			progressPercent := 5 + int(float64(i)/float64(len(targets))*95)
+			progressCallback(progressPercent, fmt.Sprintf("Verifying file %d of %d: %s", i+1, len(targets), target.path))
+		}
+
+		issue := s.verifyTarget(target, checkCorruption)
+		filesChecked++
+		if issue != nil {
+			issuesFound++
+			s.recordIssue(runId, issue, requeueMissing)
+		}
+
+		// Keep the position so a paused or interrupted run resumes after this file
+		if err := s.integrityDB.SaveRunProgress(runId, filesChecked, issuesFound, target.source, target.sourceId); err != nil {
+			s.logService.Error(fmt.Sprintf("Failed to save progress of integrity run %d: %v", runId, err))
+		}
+	}
+
+	if err := s.integrityDB.FinishRun(runId, filesChecked, issuesFound); err != nil {
+		return nil, fmt.Errorf("failed to finish integrity run: %w", err)
+	}
+
+	if progressCallback != nil {
+		progressCallback(100, fmt.Sprintf("Verification completed: %d files checked, %d issues found", filesChecked, issuesFound))
+	}
+	s.logService.Info(fmt.Sprintf("Integrity verification run %d completed: %d files checked, %d issues found", runId, filesChecked, issuesFound))
+
+	return s.integrityDB.GetLatestRun()
+}
+
+// startOrResumeRun continues an unfinished run with the targets after its last checked file, or creates a new run
+func (s *IntegrityService) startOrResumeRun(targets []verificationTarget) (int, int, int, []verificationTarget, error) {
+	latest, err := s.integrityDB.GetLatestRun()
+	if err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("failed to get latest integrity run: %w", err)
+	}
+	if latest != nil && !latest.FinishedAt.Valid {
+		cursorSource, cursorSourceId, err := s.integrityDB.GetRunCursor(latest.ID)
+		if err != nil {
+			return 0, 0, 0, nil, fmt.Errorf("failed to get position of integrity run %d: %w", latest.ID, err)
+		}
+		remaining := targetsAfter(targets, cursorSource, cursorSourceId)
+		s.logService.Info(fmt.Sprintf("Resuming integrity verification run %d, %d of %d files left", latest.ID, len(remaining), len(targets)))
+		return latest.ID, latest.FilesChecked, latest.IssuesFound, remaining, nil
+	}
+
+	runId, err := s.integrityDB.CreateRun()
+	if err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("failed to create integrity run: %w", err)
+	}
+	s.logService.Info(fmt.Sprintf("Starting integrity verification run %d for %d files", runId, len(targets)))
+	return runId, 0, 0, targets, nil
+}
+
+// recordIssue stores an issue of a run, requeueing missing archive files if enabled
+func (s *IntegrityService) recordIssue(runId int, issue *IntegrityIssue, requeueMissing bool) {
+	issue.RunID = runId
+	s.logService.Warn(fmt.Sprintf("Integrity issue (%s) for %s %d: %s", issue.Issue, issue.Source, issue.SourceID, issue.FilePath))
+
+	// Requeue missing archive files so the daemon downloads them again
+	if requeueMissing && issue.Issue == IssueMissing && issue.Source == SourceDownload {
+		if err := s.downloadService.SetManualRetry(issue.SourceID); err != nil {
+			s.logService.Error(fmt.Sprintf("Failed to requeue missing download %d: %v", issue.SourceID, err))
+		} else {
+			issue.Requeued = true
+		}
+	}
+
+	if err := s.integrityDB.InsertIssue(issue); err != nil {
+		s.logService.Error(fmt.Sprintf("Failed to record integrity issue for %s: %v", issue.FilePath, err))
+	}
+}
+
+// RequeueIssue marks the download behind an issue for manual retry
+func (s *IntegrityService) RequeueIssue(issueId int) error {
+	issue, err := s.integrityDB.GetIssue(issueId)
+	if err != nil {
+		return fmt.Errorf("failed to get integrity issue: %w", err)
+	}
+	if issue.Source != SourceDownload {
+		return fmt.Errorf("only archived downloads can be requeued")
+	}
+	if issue.Issue == IssueUnreadable {
+		return fmt.Errorf("%s exists but cannot be read, fix its permissions or the disk instead", issue.FilePath)
+	}
+
+	// Remove the broken file so the download is not stored next to it under a new name
+	if issue.Issue == IssueCorrupt || issue.Issue == IssueModified {
+		if err := os.Remove(issue.FilePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove file before requeue: %w", err)
+		}
+	}
+
+	if err := s.downloadService.SetManualRetry(issue.SourceID); err != nil {
+		return err
+	}
+	return s.integrityDB.MarkRequeued(issueId)
+}
+
+// GetLatestRun returns the most recent verification run
+func (s *IntegrityService) GetLatestRun() (*IntegrityRun, error) {
+	return s.integrityDB.GetLatestRun()
+}
+
+// GetIssuesForRun returns a page of issues found during a run
+func (s *IntegrityService) GetIssuesForRun(runId, offset, limit int) ([]IntegrityIssue, error) {
+	return s.integrityDB.GetIssuesForRun(runId, offset, limit)
+}
+
+// collectTargets gathers every file in downloads and the file registry that should be verified
+func (s *IntegrityService) collectTargets() ([]verificationTarget, error) {
+	targets := make([]verificationTarget, 0)
+
+	downloads, err := s.downloadDB.GetSuccessfulDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get successful downloads: %w", err)
+	}
+	for _, dl := range downloads {
+		if !dl.FullPath.Valid {
+			continue
+		}
+		targets = append(targets, verificationTarget{
+			source:        SourceDownload,
+			sourceId:      dl.ID,
+			path:          dl.FullPath.String,
+			expectedHash:  dl.MD5.String,
+			hashAlgorithm: dl.HashAlgorithm,
+		})
+	}
+
+	for offset := 0; ; offset += registryPageSize {
+		files, err := s.fileRegistryService.GetAllPaginated(offset, registryPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get registered files: %w", err)
+		}
+		for _, file := range files {
+			targets = append(targets, verificationTarget{
+				source:        SourceFileRegistry,
+				sourceId:      file.ID,
+				path:          file.FilePath,
+				expectedHash:  file.MD5,
+				hashAlgorithm: file.HashAlgorithm,
+			})
+		}
+		if len(files) < registryPageSize {
+			break
+		}
+	}
+
+	// A fixed order lets a paused run resume after the last file it checked
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[j].after(targets[i].source, targets[i].sourceId)
+	})
+	return targets, nil
+}
+
+// targetsAfter returns the targets after a position in the verification order, all of them without a position
+func targetsAfter(targets []verificationTarget, source string, sourceId int) []verificationTarget {
+	if source == "" {
+		return targets
+	}
+	remaining := make([]verificationTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.after(source, sourceId) {
+			remaining = append(remaining, target)
+		}
+	}
+	return remaining
+}
+
+// after reports whether a target comes after a position in the verification order
+func (t verificationTarget) after(source string, sourceId int) bool {
+	if t.source != source {
+		return t.source > source
+	}
+	return t.sourceId > sourceId
+}
+
+// verifyTarget checks a single file and returns an issue, or nil if the file is intact
+func (s *IntegrityService) verifyTarget(target verificationTarget, checkCorruption bool) *IntegrityIssue {
+	issue := &IntegrityIssue{
+		Source:      target.source,
+		SourceID:    target.sourceId,
+		FilePath:    target.path,
+		ExpectedMD5: sql.NullString{String: target.expectedHash, Valid: target.expectedHash != ""},
+		DetectedAt:  time.Now().Unix(),
+	}
+
+	if _, err := os.Stat(target.path); os.IsNotExist(err) {
+		issue.Issue = IssueMissing
+		return issue
+	}
+	// The file may still be there, it must not be requeued like a missing one
+	readFailed := func(err error) *IntegrityIssue {
+		issue.Issue = IssueUnreadable
+		issue.Detail = sql.NullString{String: err.Error(), Valid: true}
+		return issue
+	}
+
+	// Re-hash with the algorithm the stored hash was calculated with
+	hashAlgorithm, err := fileutils.ParseHashAlgorithm(target.hashAlgorithm)
+	if err != nil {
+		hashAlgorithm = fileutils.HashMD5
+	}
+	actualHash, err := fileutils.CalculateHash(target.path, hashAlgorithm)
+	if err != nil {
+		return readFailed(err)
+	}
+	if target.expectedHash != "" && actualHash != target.expectedHash {
+		issue.Issue = IssueModified
+		issue.ActualMD5 = sql.NullString{String: actualHash, Valid: true}
+		return issue
+	}
+
+	if checkCorruption {
+		report, err := download.InspectFile(target.path, s.corruptionCheckConfig())
+		if err == nil {
+			err = report.Err()
+		}
+		if err != nil {
+			issue.Issue = IssueCorrupt
+			issue.ActualMD5 = sql.NullString{String: actualHash, Valid: true}
+			issue.Detail = sql.NullString{String: err.Error(), Valid: true}
+			return issue
+		}
+	}
+
+	return nil
+}
+
+// corruptionCheckConfig reads the corruption check settings, falling back to defaults for invalid values.
+// Verification never repairs files, a repaired file would no longer match its stored hash.
+func (s *IntegrityService) corruptionCheckConfig() download.CorruptionCheckConfig {
+	config, err := download.LoadCorruptionCheckConfig(s.settingsService)
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Invalid corruption check settings: %v", err))
+	}
+	config.RepairStrategies = nil
+	return config
+}
@@ -0,0 +1,158 @@
+package integrity
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"videoarchiver/backend/domains/config"
+	"videoarchiver/backend/domains/db"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/pathing"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+
+	"github.com/NotCoffee418/dbmigrator"
+)
+
+type testLogger struct{}
+
+func (testLogger) Debug(message string) {}
+func (testLogger) Info(message string)  {}
+func (testLogger) Warn(message string)  {}
+func (testLogger) Error(message string) {}
+func (testLogger) Fatal(message string) {}
+
+type toolSettings map[string]string
+
+func (t toolSettings) GetSettingString(key string) (string, error) {
+	return t[key], nil
+}
+
+// fakeFfmpeg reports a repairable timestamp issue for every file but its repaired copies,
+// and "repairs" a file by writing new content to the output
+const fakeFfmpeg = `#!/bin/sh
+for last; do :; done
+case "$*" in
+*"-f null"*)
+	case "$*" in *.repair.*) ;; *) echo "[mp4 @ 0x0] Application provided invalid, non monotonically increasing dts to muxer in stream 0: 1024 >= 512" >&2 ;; esac ;;
+*) echo repaired > "$last" ;;
+esac
+`
+
+func TestRunVerificationDoesNotRepair(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	t.Setenv(pathing.DataDirEnv, t.TempDir())
+	dir := t.TempDir()
+
+	ffmpegPath := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpegPath, []byte(fakeFfmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ytdlp.ConfigureToolSource(nil) })
+	err := ytdlp.ConfigureToolSource(toolSettings{"tool_source": ytdlp.ToolSourceCustom, "ffmpeg_path": ffmpegPath, "ffprobe_path": ffmpegPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configService, err := config.NewConfigService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbService, err := db.NewDatabaseService(configService, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbmigrator.SetDatabaseType(dbmigrator.SQLite)
+	<-dbmigrator.MigrateUpCh(dbService.GetDB(), os.DirFS("../../.."), "migrations")
+	settingsService := settings.NewSettingsService(dbService, nil)
+	if err := settingsService.EnsureDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"integrity_check_corruption":   "true",
+		"integrity_requeue_missing":    "false",
+		"corruption_repair_strategies": "remux",
+	} {
+		if err := settingsService.SetPreparsed(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	samplePath := filepath.Join(dir, "sample.mp4")
+	if err := os.WriteFile(samplePath, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := fileutils.CalculateHash(samplePath, fileutils.HashMD5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := dbService.GetDB()
+	if _, err := database.Exec(`INSERT INTO playlists (id, name, url, output_format, save_directory) VALUES (1, 'test', 'https://example.com', 'mp4', ?)`, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`INSERT INTO downloads (playlist_id, url, status, format_downloaded, md5, hash_algorithm, output_filename, attempt_count)
+		VALUES (1, 'https://example.com/video', ?, 'mp4', ?, 'md5', 'sample.mp4', 1)`, download.StSuccess, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewIntegrityService(
+		NewIntegrityDB(dbService),
+		settingsService,
+		download.NewDownloadDB(dbService),
+		nil,
+		fileregistry.NewFileRegistryService(dbService, settingsService, nil),
+		testLogger{},
+	)
+	run, err := service.RunVerification(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if run.FilesChecked != 1 || run.IssuesFound != 0 {
+		t.Errorf("Expected 1 intact file, got %+v", run)
+	}
+	if content, err := os.ReadFile(samplePath); err != nil || string(content) != "original" {
+		t.Errorf("Expected the file to be left untouched, got %q (%v)", content, err)
+	}
+}
+
+func TestTargetsAfter(t *testing.T) {
+	targets := []verificationTarget{
+		{source: SourceDownload, sourceId: 3},
+		{source: SourceDownload, sourceId: 9},
+		{source: SourceFileRegistry, sourceId: 1},
+		{source: SourceFileRegistry, sourceId: 4},
+	}
+
+	if remaining := targetsAfter(targets, "", 0); len(remaining) != 4 {
+		t.Errorf("Expected every target without a position, got %+v", remaining)
+	}
+	remaining := targetsAfter(targets, SourceDownload, 9)
+	if len(remaining) != 2 || remaining[0].source != SourceFileRegistry || remaining[0].sourceId != 1 {
+		t.Errorf("Expected the registry targets after the last download, got %+v", remaining)
+	}
+	// A removed target still marks the position
+	if remaining := targetsAfter(targets, SourceFileRegistry, 2); len(remaining) != 1 || remaining[0].sourceId != 4 {
+		t.Errorf("Expected the registry target after id 2, got %+v", remaining)
+	}
+}
+
+func TestVerifyTargetUnreadable(t *testing.T) {
+	service := &IntegrityService{logService: testLogger{}}
+
+	// A directory exists but cannot be hashed, like a file without read permission
+	issue := service.verifyTarget(verificationTarget{source: SourceDownload, sourceId: 1, path: t.TempDir(), expectedHash: "abc"}, false)
+	if issue == nil || issue.Issue != IssueUnreadable || !issue.Detail.Valid {
+		t.Errorf("Expected an unreadable issue, got %+v", issue)
+	}
+
+	issue = service.verifyTarget(verificationTarget{source: SourceDownload, sourceId: 1, path: filepath.Join(t.TempDir(), "gone.mp4")}, false)
+	if issue == nil || issue.Issue != IssueMissing {
+		t.Errorf("Expected a missing issue, got %+v", issue)
+	}
+}
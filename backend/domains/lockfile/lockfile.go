@@ -9,9 +9,9 @@ import (
 
 const lockFileName = ".lock"
 
-// CreateLock creates a lock file in the working directory
+// CreateLock creates a lock file in the runtime directory
 func CreateLock() error {
-	lockPath, err := pathing.GetWorkingFile(lockFileName)
+	lockPath, err := pathing.GetFile(pathing.DirRuntime, lockFileName)
 	if err != nil {
 		return fmt.Errorf("failed to get lock file path: %w", err)
 	}
@@ -34,7 +34,7 @@ func CreateLock() error {
 
 // RemoveLock removes the lock file
 func RemoveLock() error {
-	lockPath, err := pathing.GetWorkingFile(lockFileName)
+	lockPath, err := pathing.GetFile(pathing.DirRuntime, lockFileName)
 	if err != nil {
 		return fmt.Errorf("failed to get lock file path: %w", err)
 	}
@@ -48,7 +48,7 @@ func RemoveLock() error {
 
 // IsLocked checks if a lock file exists and returns true if it exists and is recent
 func IsLocked() (bool, error) {
-	lockPath, err := pathing.GetWorkingFile(lockFileName)
+	lockPath, err := pathing.GetFile(pathing.DirRuntime, lockFileName)
 	if err != nil {
 		return false, fmt.Errorf("failed to get lock file path: %w", err)
 	}
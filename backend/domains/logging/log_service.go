@@ -34,7 +34,7 @@ func NewLogService(mode string) *LogService {
 	}
 
 	// Get proper log file path using pathing system
-	logFilePath, err := pathing.GetWorkingFile(logFileName)
+	logFilePath, err := pathing.GetFile(pathing.DirState, logFileName)
 	if err != nil {
 		// Fallback to stdout only if pathing fails
 		logger.SetOutput(os.Stdout)
@@ -135,7 +135,7 @@ func (l *LogService) Fatal(message string) {
 // GetLogLinesFromFile reads the last N lines from a log file using proper pathing
 func (l *LogService) GetLogLinesFromFile(filename string, lines int) ([]string, error) {
 	// Get proper log file path using pathing system
-	logFilePath, err := pathing.GetWorkingFile(filename)
+	logFilePath, err := pathing.GetFile(pathing.DirState, filename)
 	if err != nil {
 		return []string{fmt.Sprintf("Error getting log file path: %v", err)}, nil
 	}
@@ -201,7 +201,7 @@ func (l *LogService) GetLogLinesFromFileWithLevel(filename string, lines int, mi
 	}
 
 	// Get proper log file path using pathing system
-	logFilePath, err := pathing.GetWorkingFile(filename)
+	logFilePath, err := pathing.GetFile(pathing.DirState, filename)
 	if err != nil {
 		return []string{fmt.Sprintf("Error getting log file path: %v", err)}, nil
 	}
@@ -293,7 +293,7 @@ func (l *LogService) GetLogLinesFromFileWithLevel(filename string, lines int, mi
 // It modifies the original file in place by truncating and rewriting filtered content
 func (l *LogService) ClearLogsOlderThanDays(filename string, days int) error {
 	// Get proper log file path using pathing system
-	logFilePath, err := pathing.GetWorkingFile(filename)
+	logFilePath, err := pathing.GetFile(pathing.DirState, filename)
 	if err != nil {
 		return fmt.Errorf("failed to get log file path: %w", err)
 	}
@@ -22,7 +22,9 @@ func TestClearLogsOlderThanDaysIntegration(t *testing.T) {
 	// Create the directory structure that pathing expects
 	var vaDirPath string
 	if isLinux() {
-		vaDirPath = filepath.Join(tmpDir, ".local", "share", "videoarchiver")
+		// Logs are state, kept in $XDG_STATE_HOME
+		vaDirPath = filepath.Join(tmpDir, ".local", "state", "videoarchiver")
+		t.Setenv("XDG_STATE_HOME", "")
 	} else {
 		vaDirPath = filepath.Join(tmpDir, "videoarchiver")
 	}
@@ -0,0 +1,163 @@
+package managedconfig
+
+import (
+	"database/sql"
+	"videoarchiver/backend/domains/db"
+)
+
+type ManagedConfigDB struct {
+	db *sql.DB
+}
+
+func NewManagedConfigDB(dbService *db.DatabaseService) *ManagedConfigDB {
+	return &ManagedConfigDB{db: dbService.GetDB()}
+}
+
+func (m *ManagedConfigDB) GetManagedPlaylists() (map[int]ManagedPlaylist, error) {
+	rows, err := m.db.Query("SELECT playlist_id, check_interval_minutes, last_checked_at FROM managed_playlists")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlists := make(map[int]ManagedPlaylist)
+	for rows.Next() {
+		var playlist ManagedPlaylist
+		if err := rows.Scan(&playlist.PlaylistID, &playlist.CheckIntervalMinutes, &playlist.LastCheckedAt); err != nil {
+			return nil, err
+		}
+		playlists[playlist.PlaylistID] = playlist
+	}
+	return playlists, rows.Err()
+}
+
+// GetManagedPlaylist returns nil if the playlist is not managed
+func (m *ManagedConfigDB) GetManagedPlaylist(playlistId int) (*ManagedPlaylist, error) {
+	var playlist ManagedPlaylist
+	err := m.db.QueryRow(
+		"SELECT playlist_id, check_interval_minutes, last_checked_at FROM managed_playlists WHERE playlist_id = ?",
+		playlistId,
+	).Scan(&playlist.PlaylistID, &playlist.CheckIntervalMinutes, &playlist.LastCheckedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+// UpsertManagedPlaylist marks a playlist as managed with its schedule, keeping when it was last checked
+func (m *ManagedConfigDB) UpsertManagedPlaylist(playlistId, checkIntervalMinutes int) error {
+	_, err := m.db.Exec(`
+		INSERT INTO managed_playlists (playlist_id, check_interval_minutes) VALUES (?, ?)
+		ON CONFLICT(playlist_id) DO UPDATE SET check_interval_minutes = excluded.check_interval_minutes`,
+		playlistId, checkIntervalMinutes,
+	)
+	return err
+}
+
+func (m *ManagedConfigDB) DeleteManagedPlaylist(playlistId int) error {
+	_, err := m.db.Exec("DELETE FROM managed_playlists WHERE playlist_id = ?", playlistId)
+	return err
+}
+
+func (m *ManagedConfigDB) SetLastCheckedAt(playlistId int, checkedAt int64) error {
+	_, err := m.db.Exec("UPDATE managed_playlists SET last_checked_at = ? WHERE playlist_id = ?", checkedAt, playlistId)
+	return err
+}
+
+func (m *ManagedConfigDB) IsSettingManaged(key string) (bool, error) {
+	var count int
+	err := m.db.QueryRow("SELECT COUNT(*) FROM managed_settings WHERE setting_key = ?", key).Scan(&count)
+	return count > 0, err
+}
+
+// ReplaceManagedSettings replaces the set of settings that come from the config file
+func (m *ManagedConfigDB) ReplaceManagedSettings(keys []string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM managed_settings"); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, err := tx.Exec("INSERT INTO managed_settings (setting_key) VALUES (?)", key); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClearManaged releases every playlist and setting, used when no config file is configured
+func (m *ManagedConfigDB) ClearManaged() error {
+	if _, err := m.db.Exec("DELETE FROM managed_playlists"); err != nil {
+		return err
+	}
+	_, err := m.db.Exec("DELETE FROM managed_settings")
+	return err
+}
+
+func (m *ManagedConfigDB) CreateRun(configPath string) (int, error) {
+	result, err := m.db.Exec("INSERT INTO config_sync_runs (config_path, status) VALUES (?, ?)", configPath, RunRunning)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (m *ManagedConfigDB) FinishRun(runId int, status string, driftCount int, errorMessage string) error {
+	_, err := m.db.Exec(
+		"UPDATE config_sync_runs SET status = ?, drift_count = ?, error_message = NULLIF(?, ''), finished_at = strftime('%s', 'now') WHERE id = ?",
+		status, driftCount, errorMessage, runId,
+	)
+	return err
+}
+
+func (m *ManagedConfigDB) InsertDrift(drift *Drift) error {
+	_, err := m.db.Exec(
+		"INSERT INTO config_drift (run_id, kind, subject, expected, actual, action) VALUES (?, ?, ?, ?, ?, ?)",
+		drift.RunID, drift.Kind, drift.Subject, drift.Expected, drift.Actual, drift.Action,
+	)
+	return err
+}
+
+// GetLatestRun returns nil if there are no runs
+func (m *ManagedConfigDB) GetLatestRun() (*SyncRun, error) {
+	var run SyncRun
+	err := m.db.QueryRow(
+		"SELECT id, config_path, status, started_at, finished_at, drift_count, error_message FROM config_sync_runs ORDER BY id DESC LIMIT 1",
+	).Scan(&run.ID, &run.ConfigPath, &run.Status, &run.StartedAt, &run.FinishedAt, &run.DriftCount, &run.ErrorMessage)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (m *ManagedConfigDB) GetDriftForRun(runId int) ([]Drift, error) {
+	rows, err := m.db.Query(
+		"SELECT id, run_id, kind, subject, expected, actual, action FROM config_drift WHERE run_id = ? ORDER BY id",
+		runId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drift := make([]Drift, 0)
+	for rows.Next() {
+		var d Drift
+		if err := rows.Scan(&d.ID, &d.RunID, &d.Kind, &d.Subject, &d.Expected, &d.Actual, &d.Action); err != nil {
+			return nil, err
+		}
+		drift = append(drift, d)
+	}
+	return drift, rows.Err()
+}
@@ -0,0 +1,138 @@
+package managedconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"videoarchiver/backend/domains/postprocess"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/transfer"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats a playlist can download as
+var outputFormats = map[string]bool{"mp3": true, "mp4": true}
+
+// LoadConfigFile reads a YAML or TOML config file, chosen by extension. Unknown fields are rejected.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseConfigFile(data, strings.ToLower(filepath.Ext(path)))
+}
+
+func parseConfigFile(data []byte, extension string) (*ConfigFile, error) {
+	file := &ConfigFile{}
+	switch extension {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// An empty file is an empty config, not an error
+		if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid YAML config file: %w", err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(data), file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOML config file: %w", err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("invalid TOML config file: unknown field %s", undecoded[0])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, use .yaml, .yml or .toml", extension)
+	}
+	return file, nil
+}
+
+// validateConfigFile checks the whole file against this installation, returning every problem at once
+func validateConfigFile(file *ConfigFile, storedSettings map[string]string) error {
+	var errs []error
+	keys := make([]string, 0, len(file.Settings))
+	for key := range file.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, exists := storedSettings[key]; !exists {
+			errs = append(errs, fmt.Errorf("unknown setting: %s", key))
+		} else if transfer.IsInstallationSetting(key) {
+			errs = append(errs, fmt.Errorf("setting %s belongs to an installation and cannot be managed", key))
+		} else if err := settings.ValidateSettingValue(key, string(file.Settings[key])); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	seen := make(map[string]bool, len(file.Playlists))
+	for i, playlist := range file.Playlists {
+		label := fmt.Sprintf("playlist %d (%s)", i+1, playlist.URL)
+		if playlist.URL == "" {
+			errs = append(errs, fmt.Errorf("%s: url is required", label))
+		}
+		if !outputFormats[playlist.Format] {
+			errs = append(errs, fmt.Errorf("%s: unsupported format %q", label, playlist.Format))
+		}
+		if !filepath.IsAbs(playlist.Directory) {
+			errs = append(errs, fmt.Errorf("%s: directory %q must be an absolute path", label, playlist.Directory))
+		}
+		if _, err := playlist.checkInterval(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", label, err))
+		}
+		key := playlist.identity()
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("%s: listed more than once with the same directory and format", label))
+		}
+		seen[key] = true
+		for j, step := range playlist.steps() {
+			if err := postprocess.ValidateStep(step); err != nil {
+				errs = append(errs, fmt.Errorf("%s: step %d: %w", label, j+1, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkInterval parses the schedule, 0 if the playlist is checked on every daemon run
+func (p PlaylistConfig) checkInterval() (time.Duration, error) {
+	if p.CheckInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(p.CheckInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid check_interval %q: %w", p.CheckInterval, err)
+	}
+	if interval < MinCheckInterval {
+		return 0, fmt.Errorf("check_interval %s is shorter than the minimum of %s", p.CheckInterval, MinCheckInterval)
+	}
+	return interval, nil
+}
+
+// identity is the combination that identifies a playlist
+func (p PlaylistConfig) identity() string {
+	return p.URL + "\x00" + filepath.Clean(p.Directory) + "\x00" + p.Format
+}
+
+func (p PlaylistConfig) steps() []postprocess.PostProcessStep {
+	steps := make([]postprocess.PostProcessStep, 0, len(p.PostProcess))
+	for _, step := range p.PostProcess {
+		options := make(map[string]string, len(step.Options))
+		for key, value := range step.Options {
+			options[key] = string(value)
+		}
+		steps = append(steps, postprocess.PostProcessStep{
+			Kind:      step.Kind,
+			Options:   options,
+			IsEnabled: step.Enabled == nil || *step.Enabled,
+		})
+	}
+	return steps
+}
@@ -0,0 +1,115 @@
+package managedconfig
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MinCheckInterval is the shortest playlist schedule, the daemon checks playlists every 30 minutes
+const MinCheckInterval = 30 * time.Minute
+
+// Statuses of a sync run
+const (
+	RunRunning = "running"
+	RunSuccess = "success"
+	RunFailed  = "failed"
+)
+
+// Kinds of drift between the config file and the database
+const (
+	DriftSetting         = "setting"          // Setting value differs from the file
+	DriftPlaylistMissing = "playlist_missing" // Playlist in the file is not configured
+	DriftPlaylistRemoved = "playlist_removed" // Managed playlist is no longer in the file
+	DriftName            = "playlist_name"
+	DriftDirectory       = "playlist_directory"
+	DriftSchedule        = "playlist_schedule"
+	DriftPostProcess     = "playlist_post_process"
+	DriftUnmanaged       = "playlist_unmanaged" // Playlist added through the UI that the file does not list
+)
+
+// What a sync run did about drift
+const (
+	ActionCorrected = "corrected"
+	ActionReported  = "reported"
+)
+
+// ConfigFile is the declarative config file, in YAML or TOML
+type ConfigFile struct {
+	Settings  map[string]scalar `yaml:"settings" toml:"settings"`
+	Playlists []PlaylistConfig  `yaml:"playlists" toml:"playlists"`
+}
+
+// PlaylistConfig is a playlist as declared in the config file.
+// A playlist is identified by its URL, directory and format, like playlists added through the UI.
+type PlaylistConfig struct {
+	URL           string       `yaml:"url" toml:"url"`
+	Directory     string       `yaml:"directory" toml:"directory"`
+	Format        string       `yaml:"format" toml:"format"`
+	Name          string       `yaml:"name" toml:"name"`                     // Defaults to the URL for new playlists
+	CheckInterval string       `yaml:"check_interval" toml:"check_interval"` // Go duration such as 6h, empty checks on every daemon run
+	PostProcess   []StepConfig `yaml:"post_process" toml:"post_process"`
+}
+
+// StepConfig is a post-processing step, in pipeline order
+type StepConfig struct {
+	Kind    string            `yaml:"kind" toml:"kind"`
+	Options map[string]scalar `yaml:"options" toml:"options"`
+	Enabled *bool             `yaml:"enabled" toml:"enabled"` // Enabled when omitted
+}
+
+// scalar accepts any TOML value that reads naturally as a setting, so `allow_duplicates = true` works like YAML
+type scalar string
+
+func (s *scalar) UnmarshalTOML(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*s = scalar(v)
+	case bool:
+		*s = scalar(strconv.FormatBool(v))
+	case int64:
+		*s = scalar(strconv.FormatInt(v, 10))
+	case float64:
+		*s = scalar(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("unsupported value %v, expected a string, number or boolean", value)
+	}
+	return nil
+}
+
+// ManagedPlaylist is a playlist whose configuration comes from the config file
+type ManagedPlaylist struct {
+	PlaylistID           int   `json:"playlist_id" db:"playlist_id"`
+	CheckIntervalMinutes int   `json:"check_interval_minutes" db:"check_interval_minutes"` // 0 checks on every daemon run
+	LastCheckedAt        int64 `json:"last_checked_at" db:"last_checked_at"`
+}
+
+// SyncRun is a reconciliation of the database against the config file
+type SyncRun struct {
+	ID           int            `json:"id" db:"id"`
+	ConfigPath   string         `json:"config_path" db:"config_path"`
+	Status       string         `json:"status" db:"status"`
+	StartedAt    int64          `json:"started_at" db:"started_at"`
+	FinishedAt   sql.NullInt64  `json:"finished_at,omitempty" db:"finished_at"`
+	DriftCount   int            `json:"drift_count" db:"drift_count"`
+	ErrorMessage sql.NullString `json:"error_message,omitempty" db:"error_message"`
+}
+
+// Drift is a difference between the config file and the database found during a run
+type Drift struct {
+	ID       int            `json:"id" db:"id"`
+	RunID    int            `json:"run_id" db:"run_id"`
+	Kind     string         `json:"kind" db:"kind"`
+	Subject  string         `json:"subject" db:"subject"` // Setting key or playlist URL
+	Expected sql.NullString `json:"expected,omitempty" db:"expected"`
+	Actual   sql.NullString `json:"actual,omitempty" db:"actual"`
+	Action   string         `json:"action" db:"action"`
+}
+
+// SyncStatus is the latest run with the drift it found
+type SyncStatus struct {
+	ConfigPath string   `json:"config_path"` // Empty if no config file is configured
+	Run        *SyncRun `json:"run"`         // Nil if the file was never reconciled
+	Drift      []Drift  `json:"drift"`
+}
@@ -0,0 +1,456 @@
+package managedconfig
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"videoarchiver/backend/daemonsignal"
+	"videoarchiver/backend/domains/config"
+	"videoarchiver/backend/domains/playlist"
+	"videoarchiver/backend/domains/postprocess"
+	"videoarchiver/backend/domains/settings"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+type ManagedConfigService struct {
+	db                  *ManagedConfigDB
+	configService       *config.ConfigService
+	settingsService     *settings.SettingsService
+	playlistDB          *playlist.PlaylistDB
+	postProcessService  *postprocess.PostProcessService
+	daemonSignalService *daemonsignal.DaemonSignalService
+	logService          LogServiceInterface
+	reconcileMutex      sync.Mutex
+}
+
+func NewManagedConfigService(
+	db *ManagedConfigDB,
+	configService *config.ConfigService,
+	settingsService *settings.SettingsService,
+	playlistDB *playlist.PlaylistDB,
+	postProcessService *postprocess.PostProcessService,
+	daemonSignalService *daemonsignal.DaemonSignalService,
+	logService LogServiceInterface,
+) *ManagedConfigService {
+	return &ManagedConfigService{
+		db:                  db,
+		configService:       configService,
+		settingsService:     settingsService,
+		playlistDB:          playlistDB,
+		postProcessService:  postProcessService,
+		daemonSignalService: daemonSignalService,
+		logService:          logService,
+	}
+}
+
+// GetConfigPath returns the configured config file, empty if there is none
+func (m *ManagedConfigService) GetConfigPath() (string, error) {
+	return m.configService.GetManagedConfigPath()
+}
+
+// Reconcile makes the database match the config file and records the drift it found.
+// Without a config file every playlist and setting is released for editing in the UI.
+// An invalid file changes nothing, the previous state stays managed until the file is fixed.
+func (m *ManagedConfigService) Reconcile() (*SyncStatus, error) {
+	m.reconcileMutex.Lock()
+	defer m.reconcileMutex.Unlock()
+
+	configPath, err := m.GetConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config file path: %w", err)
+	}
+	if configPath == "" {
+		if err := m.db.ClearManaged(); err != nil {
+			return nil, fmt.Errorf("failed to release managed playlists and settings: %w", err)
+		}
+		return &SyncStatus{}, nil
+	}
+
+	runId, err := m.db.CreateRun(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config sync run: %w", err)
+	}
+	recorder := &driftRecorder{db: m.db, runId: runId}
+
+	err = m.reconcileFile(configPath, recorder)
+	status := RunSuccess
+	errorMessage := ""
+	if err != nil {
+		status = RunFailed
+		errorMessage = err.Error()
+		m.logService.Error(fmt.Sprintf("Failed to reconcile config file %s: %v", configPath, err))
+	} else if recorder.count > 0 {
+		m.logService.Info(fmt.Sprintf("Reconciled config file %s: %d differences found", configPath, recorder.count))
+	}
+	if finishErr := m.db.FinishRun(runId, status, recorder.count, errorMessage); finishErr != nil {
+		m.logService.Error(fmt.Sprintf("Failed to finish config sync run: %v", finishErr))
+	}
+
+	if recorder.corrected && m.daemonSignalService != nil {
+		if err := m.daemonSignalService.TriggerChange(); err != nil {
+			m.logService.Warn(fmt.Sprintf("Failed to signal daemon after config sync: %v", err))
+		}
+	}
+
+	syncStatus, statusErr := m.GetStatus()
+	if err != nil {
+		return syncStatus, err
+	}
+	return syncStatus, statusErr
+}
+
+// GetStatus returns the latest sync run and its drift
+func (m *ManagedConfigService) GetStatus() (*SyncStatus, error) {
+	configPath, err := m.GetConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config file path: %w", err)
+	}
+	status := &SyncStatus{ConfigPath: configPath, Drift: []Drift{}}
+	if configPath == "" {
+		return status, nil
+	}
+
+	status.Run, err = m.db.GetLatestRun()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest config sync run: %w", err)
+	}
+	if status.Run != nil {
+		status.Drift, err = m.db.GetDriftForRun(status.Run.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config drift: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// CheckPlaylistEditable returns an error if the config file manages the playlist
+func (m *ManagedConfigService) CheckPlaylistEditable(playlistId int) error {
+	managed, err := m.IsPlaylistManaged(playlistId)
+	if err != nil {
+		return err
+	}
+	if managed {
+		return m.managedError("playlist")
+	}
+	return nil
+}
+
+// CheckSettingEditable returns an error if the config file manages the setting
+func (m *ManagedConfigService) CheckSettingEditable(key string) error {
+	managed, err := m.IsSettingManaged(key)
+	if err != nil {
+		return err
+	}
+	if managed {
+		return m.managedError("setting " + key)
+	}
+	return nil
+}
+
+// IsPlaylistManaged returns true if the config file manages the playlist
+func (m *ManagedConfigService) IsPlaylistManaged(playlistId int) (bool, error) {
+	managed, err := m.db.GetManagedPlaylist(playlistId)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if playlist is managed: %w", err)
+	}
+	return managed != nil, nil
+}
+
+// IsSettingManaged returns true if the config file manages the setting
+func (m *ManagedConfigService) IsSettingManaged(key string) (bool, error) {
+	managed, err := m.db.IsSettingManaged(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if setting is managed: %w", err)
+	}
+	return managed, nil
+}
+
+// GetManagedPlaylistIds returns the IDs of playlists the config file manages
+func (m *ManagedConfigService) GetManagedPlaylistIds() ([]int, error) {
+	managed, err := m.db.GetManagedPlaylists()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(managed))
+	for id := range managed {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// IsPlaylistDue returns false while a scheduled playlist's check interval has not elapsed
+func (m *ManagedConfigService) IsPlaylistDue(playlistId int) (bool, error) {
+	managed, err := m.db.GetManagedPlaylist(playlistId)
+	if err != nil {
+		return false, err
+	}
+	if managed == nil || managed.CheckIntervalMinutes == 0 {
+		return true, nil
+	}
+	nextCheck := time.Unix(managed.LastCheckedAt, 0).Add(time.Duration(managed.CheckIntervalMinutes) * time.Minute)
+	return !time.Now().Before(nextCheck), nil
+}
+
+// MarkPlaylistChecked records that a playlist was checked, for its schedule
+func (m *ManagedConfigService) MarkPlaylistChecked(playlistId int) error {
+	return m.db.SetLastCheckedAt(playlistId, time.Now().Unix())
+}
+
+func (m *ManagedConfigService) managedError(subject string) error {
+	configPath, _ := m.GetConfigPath()
+	return fmt.Errorf("%s is managed by the config file %s, edit the file instead", subject, configPath)
+}
+
+func (m *ManagedConfigService) reconcileFile(configPath string, recorder *driftRecorder) error {
+	file, err := LoadConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	storedSettings, err := m.settingsService.GetAllSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	if err := validateConfigFile(file, storedSettings); err != nil {
+		return err
+	}
+
+	if err := m.reconcileSettings(file, storedSettings, recorder); err != nil {
+		return err
+	}
+	return m.reconcilePlaylists(file, recorder)
+}
+
+func (m *ManagedConfigService) reconcileSettings(file *ConfigFile, storedSettings map[string]string, recorder *driftRecorder) error {
+	keys := make([]string, 0, len(file.Settings))
+	for key := range file.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		expected := string(file.Settings[key])
+		if storedSettings[key] == expected {
+			continue
+		}
+		if err := m.settingsService.SetPreparsed(key, expected); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		if err := recorder.record(DriftSetting, key, expected, storedSettings[key], ActionCorrected); err != nil {
+			return err
+		}
+	}
+	return m.db.ReplaceManagedSettings(keys)
+}
+
+func (m *ManagedConfigService) reconcilePlaylists(file *ConfigFile, recorder *driftRecorder) error {
+	active, err := m.playlistDB.GetActivePlaylists()
+	if err != nil {
+		return fmt.Errorf("failed to get playlists: %w", err)
+	}
+	managed, err := m.db.GetManagedPlaylists()
+	if err != nil {
+		return fmt.Errorf("failed to get managed playlists: %w", err)
+	}
+
+	byIdentity := make(map[string]playlist.Playlist, len(active))
+	for _, pl := range active {
+		byIdentity[PlaylistConfig{URL: pl.URL, Directory: pl.SaveDirectory, Format: pl.OutputFormat}.identity()] = pl
+	}
+	claimed := make(map[int]bool, len(file.Playlists))
+
+	// Exact matches first, so a moved directory can only claim a playlist nothing else matches
+	matches := make([]*playlist.Playlist, len(file.Playlists))
+	for i, entry := range file.Playlists {
+		if pl, ok := byIdentity[entry.identity()]; ok && !claimed[pl.ID] {
+			matches[i] = &pl
+			claimed[pl.ID] = true
+		}
+	}
+	for i, entry := range file.Playlists {
+		if matches[i] != nil {
+			continue
+		}
+		// A managed playlist with the same URL and format whose directory changed in the file
+		for _, pl := range active {
+			if _, isManaged := managed[pl.ID]; isManaged && !claimed[pl.ID] && pl.URL == entry.URL && pl.OutputFormat == entry.Format {
+				if err := m.playlistDB.UpdatePlaylistDirectory(pl.ID, filepath.Clean(entry.Directory)); err != nil {
+					return fmt.Errorf("failed to update directory of %s: %w", entry.URL, err)
+				}
+				if err := recorder.record(DriftDirectory, entry.URL, entry.Directory, pl.SaveDirectory, ActionCorrected); err != nil {
+					return err
+				}
+				pl.SaveDirectory = entry.Directory
+				matches[i] = &pl
+				claimed[pl.ID] = true
+				break
+			}
+		}
+	}
+
+	for i, entry := range file.Playlists {
+		if err := m.reconcilePlaylist(entry, matches[i], managed, recorder); err != nil {
+			return fmt.Errorf("failed to reconcile %s: %w", entry.URL, err)
+		}
+	}
+
+	for _, pl := range active {
+		if claimed[pl.ID] {
+			continue
+		}
+		if _, isManaged := managed[pl.ID]; isManaged {
+			// The file is the source of truth, removing a playlist from it removes the playlist
+			if err := m.playlistDB.DeletePlaylist(pl.ID); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", pl.URL, err)
+			}
+			if err := m.playlistDB.MarkDeletedPlaylistDownloads(pl.ID); err != nil {
+				return fmt.Errorf("failed to mark downloads of %s: %w", pl.URL, err)
+			}
+			if err := m.db.DeleteManagedPlaylist(pl.ID); err != nil {
+				return err
+			}
+			if err := recorder.record(DriftPlaylistRemoved, pl.URL, "", pl.SaveDirectory, ActionCorrected); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := recorder.record(DriftUnmanaged, pl.URL, "", pl.SaveDirectory, ActionReported); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcilePlaylist adds a playlist from the file, or corrects the name, schedule and steps of an existing one
+func (m *ManagedConfigService) reconcilePlaylist(entry PlaylistConfig, existing *playlist.Playlist, managed map[int]ManagedPlaylist, recorder *driftRecorder) error {
+	interval, _ := entry.checkInterval()
+	intervalMinutes := int(interval / time.Minute)
+	steps := entry.steps()
+
+	if existing == nil {
+		name := entry.Name
+		if name == "" {
+			name = entry.URL
+		}
+		if err := os.MkdirAll(entry.Directory, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		playlistId, err := m.playlistDB.AddPlaylist(name, entry.URL, filepath.Clean(entry.Directory), entry.Format, "")
+		if err != nil {
+			return err
+		}
+		if err := m.postProcessService.SetSteps(playlistId, steps); err != nil {
+			return err
+		}
+		if err := m.db.UpsertManagedPlaylist(playlistId, intervalMinutes); err != nil {
+			return err
+		}
+		return recorder.record(DriftPlaylistMissing, entry.URL, entry.Directory, "", ActionCorrected)
+	}
+
+	if entry.Name != "" && entry.Name != existing.Name {
+		if err := m.playlistDB.UpdatePlaylistName(existing.ID, entry.Name); err != nil {
+			return err
+		}
+		if err := recorder.record(DriftName, entry.URL, entry.Name, existing.Name, ActionCorrected); err != nil {
+			return err
+		}
+	}
+
+	if current, isManaged := managed[existing.ID]; isManaged && current.CheckIntervalMinutes != intervalMinutes {
+		if err := recorder.record(DriftSchedule, entry.URL, formatMinutes(intervalMinutes), formatMinutes(current.CheckIntervalMinutes), ActionCorrected); err != nil {
+			return err
+		}
+	}
+	if err := m.db.UpsertManagedPlaylist(existing.ID, intervalMinutes); err != nil {
+		return err
+	}
+
+	currentSteps, err := m.postProcessService.GetSteps(existing.ID)
+	if err != nil {
+		return err
+	}
+	if !sameSteps(steps, currentSteps) {
+		if err := m.postProcessService.SetSteps(existing.ID, steps); err != nil {
+			return err
+		}
+		if err := recorder.record(DriftPostProcess, entry.URL, describeSteps(steps), describeSteps(currentSteps), ActionCorrected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driftRecorder stores the drift of a run as it is found
+type driftRecorder struct {
+	db        *ManagedConfigDB
+	runId     int
+	count     int
+	corrected bool
+}
+
+func (r *driftRecorder) record(kind, subject, expected, actual, action string) error {
+	r.count++
+	if action == ActionCorrected {
+		r.corrected = true
+	}
+	return r.db.InsertDrift(&Drift{
+		RunID:    r.runId,
+		Kind:     kind,
+		Subject:  subject,
+		Expected: sql.NullString{String: expected, Valid: expected != ""},
+		Actual:   sql.NullString{String: actual, Valid: actual != ""},
+		Action:   action,
+	})
+}
+
+func sameSteps(expected, actual []postprocess.PostProcessStep) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if expected[i].Kind != actual[i].Kind || expected[i].IsEnabled != actual[i].IsEnabled {
+			return false
+		}
+		if len(expected[i].Options) != len(actual[i].Options) {
+			return false
+		}
+		if len(expected[i].Options) > 0 && !reflect.DeepEqual(expected[i].Options, actual[i].Options) {
+			return false
+		}
+	}
+	return true
+}
+
+func describeSteps(steps []postprocess.PostProcessStep) string {
+	kinds := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if step.IsEnabled {
+			kinds = append(kinds, step.Kind)
+		} else {
+			kinds = append(kinds, step.Kind+" (disabled)")
+		}
+	}
+	return strings.Join(kinds, ", ")
+}
+
+func formatMinutes(minutes int) string {
+	if minutes == 0 {
+		return "every run"
+	}
+	return (time.Duration(minutes) * time.Minute).String()
+}
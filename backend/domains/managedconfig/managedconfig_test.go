@@ -0,0 +1,143 @@
+package managedconfig
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"videoarchiver/backend/domains/postprocess"
+)
+
+func TestParseConfigFileFormats(t *testing.T) {
+	yamlData := `
+settings:
+  allow_duplicates: true
+  registry_scan_interval_hours: 12
+playlists:
+  - url: https://www.youtube.com/playlist?list=PL1
+    directory: /srv/music/Mixes
+    format: mp3
+    check_interval: 6h
+    post_process:
+      - kind: loudnorm
+        options:
+          target_lufs: -14
+      - kind: tag
+        enabled: false
+`
+	tomlData := `
+[settings]
+allow_duplicates = true
+registry_scan_interval_hours = 12
+
+[[playlists]]
+url = "https://www.youtube.com/playlist?list=PL1"
+directory = "/srv/music/Mixes"
+format = "mp3"
+check_interval = "6h"
+
+[[playlists.post_process]]
+kind = "loudnorm"
+options = { target_lufs = -14 }
+
+[[playlists.post_process]]
+kind = "tag"
+enabled = false
+`
+	for extension, data := range map[string]string{".yaml": yamlData, ".toml": tomlData} {
+		file, err := parseConfigFile([]byte(data), extension)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", extension, err)
+		}
+		if file.Settings["allow_duplicates"] != "true" || file.Settings["registry_scan_interval_hours"] != "12" {
+			t.Errorf("%s: unexpected settings: %v", extension, file.Settings)
+		}
+		if len(file.Playlists) != 1 {
+			t.Fatalf("%s: expected 1 playlist, got %d", extension, len(file.Playlists))
+		}
+		steps := file.Playlists[0].steps()
+		if len(steps) != 2 || steps[0].Options["target_lufs"] != "-14" || !steps[0].IsEnabled || steps[1].IsEnabled {
+			t.Errorf("%s: unexpected steps: %+v", extension, steps)
+		}
+	}
+}
+
+func TestParseConfigFileRejectsUnknownFields(t *testing.T) {
+	if _, err := parseConfigFile([]byte("playlists:\n  - url: x\n    folder: /srv\n"), ".yaml"); err == nil {
+		t.Error("expected an error for an unknown YAML field")
+	}
+	if _, err := parseConfigFile([]byte("[[playlists]]\nurl = \"x\"\nfolder = \"/srv\"\n"), ".toml"); err == nil {
+		t.Error("expected an error for an unknown TOML field")
+	}
+	if _, err := parseConfigFile([]byte("{}"), ".json"); err == nil {
+		t.Error("expected an error for an unsupported extension")
+	}
+	if file, err := parseConfigFile([]byte(""), ".yml"); err != nil || len(file.Playlists) != 0 {
+		t.Errorf("expected an empty file to be an empty config, got %+v, %v", file, err)
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	directory, _ := filepath.Abs("archive")
+	valid := PlaylistConfig{URL: "https://www.youtube.com/playlist?list=PL1", Directory: directory, Format: "mp4"}
+	stored := map[string]string{"allow_duplicates": "false", "daemon_signal": "0"}
+
+	if err := validateConfigFile(&ConfigFile{
+		Settings:  map[string]scalar{"allow_duplicates": "true"},
+		Playlists: []PlaylistConfig{valid},
+	}, stored); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tooOften := valid
+	tooOften.CheckInterval = "5m"
+	relative := valid
+	relative.Directory = "archive"
+	badStep := valid
+	badStep.Directory = filepath.Join(directory, "other")
+	badStep.PostProcess = []StepConfig{{Kind: postprocess.StepHardlink}}
+
+	err := validateConfigFile(&ConfigFile{
+		Settings:  map[string]scalar{"no_such_setting": "1", "daemon_signal": "1"},
+		Playlists: []PlaylistConfig{valid, valid, tooOften, relative, badStep, {Format: "flac"}},
+	}, stored)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, expected := range []string{
+		"unknown setting: no_such_setting",
+		"daemon_signal belongs to an installation",
+		"listed more than once",
+		"shorter than the minimum",
+		"must be an absolute path",
+		"requires the",
+		"url is required",
+		`unsupported format "flac"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to mention %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestSameSteps(t *testing.T) {
+	steps := []postprocess.PostProcessStep{
+		{Kind: postprocess.StepLoudnorm, Options: map[string]string{"target_lufs": "-14"}, IsEnabled: true},
+		{Kind: postprocess.StepTag, IsEnabled: true},
+	}
+	// Stored steps carry IDs and an empty options map, neither counts as drift
+	stored := []postprocess.PostProcessStep{
+		{ID: 4, Kind: postprocess.StepLoudnorm, Options: map[string]string{"target_lufs": "-14"}, IsEnabled: true},
+		{ID: 5, Kind: postprocess.StepTag, Options: map[string]string{}, IsEnabled: true},
+	}
+	if !sameSteps(steps, stored) {
+		t.Error("expected steps to match")
+	}
+
+	stored[1].IsEnabled = false
+	if sameSteps(steps, stored) {
+		t.Error("expected a disabled step to differ")
+	}
+	if sameSteps(steps, []postprocess.PostProcessStep{stored[1], stored[0]}) {
+		t.Error("expected reordered steps to differ")
+	}
+}
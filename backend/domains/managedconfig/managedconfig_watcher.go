@@ -0,0 +1,64 @@
+package managedconfig
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Time the config file must be quiet before it is reconciled, editors write files in several steps
+const watcherDebounceDelay = 2 * time.Second
+
+// Watch reconciles the config file whenever it changes, until the context is cancelled.
+// The directory is watched rather than the file, because editors often replace the file on save.
+func (m *ManagedConfigService) Watch(ctx context.Context) error {
+	configPath, err := m.GetConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to get config file path: %w", err)
+	}
+	if configPath == "" {
+		return nil
+	}
+	configPath = filepath.Clean(configPath)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(configPath)); err != nil {
+		return fmt.Errorf("failed to watch config file directory: %w", err)
+	}
+	m.logService.Info(fmt.Sprintf("Watching config file %s for changes", configPath))
+
+	debounce := time.NewTimer(watcherDebounceDelay)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == configPath {
+				debounce.Reset(watcherDebounceDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			m.logService.Warn(fmt.Sprintf("Config file watcher error: %v", err))
+		case <-debounce.C:
+			m.logService.Info("Config file changed, reconciling")
+			if _, err := m.Reconcile(); err != nil {
+				m.logService.Error(fmt.Sprintf("Config file sync failed: %v", err))
+			}
+		}
+	}
+}
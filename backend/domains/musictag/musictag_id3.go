@@ -0,0 +1,208 @@
+package musictag
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	id3HeaderSize  = 10
+	id3Padding     = 1024 // Free space after the frames so tag editors can update the tag in place
+	id3MaxSyncsafe = 1<<28 - 1
+
+	id3FlagFooter = 0x10
+
+	encodingUTF8          = 0x03
+	pictureTypeFrontCover = 0x03
+	musicBrainzUFID       = "http://musicbrainz.org"
+)
+
+// WriteID3v24 replaces any ID3v2 tag at the start of an mp3 file with a new ID3v2.4 tag
+func WriteID3v24(filePath string, tags Tags) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	// Keep the cover yt-dlp embedded when no new cover art is available
+	var keptCover []byte
+	if len(tags.Cover) == 0 {
+		for _, frame := range readID3v2Frames(data) {
+			if frame.id == "APIC" {
+				keptCover = frame.body
+				break
+			}
+		}
+	}
+	tag, err := encodeID3v24(tags, keptCover)
+	if err != nil {
+		return err
+	}
+
+	// Write next to the file and swap, so a failed write never leaves a truncated mp3
+	tmpPath := filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tagging")
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	_, err = file.Write(tag)
+	if err == nil {
+		_, err = file.Write(stripID3v2(data))
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write tagged file: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace file with tagged version: %w", err)
+	}
+	return nil
+}
+
+// encodeID3v24 builds a complete ID3v2.4 tag with UTF-8 text frames.
+// apicBody is written as the picture frame as-is when tags has no cover.
+func encodeID3v24(tags Tags, apicBody []byte) ([]byte, error) {
+	var frames bytes.Buffer
+	writeTextFrame(&frames, "TIT2", tags.Title)
+	writeTextFrame(&frames, "TPE1", tags.Artist)
+	writeTextFrame(&frames, "TALB", tags.Album)
+	if tags.Track > 0 {
+		writeTextFrame(&frames, "TRCK", strconv.Itoa(tags.Track))
+	}
+	writeTextFrame(&frames, "TDRC", tags.Year)
+	if tags.SourceURL != "" {
+		// URL frames are always ISO-8859-1 without an encoding byte
+		writeFrame(&frames, "WOAS", []byte(tags.SourceURL))
+	}
+	if tags.RecordingMBID != "" {
+		writeFrame(&frames, "UFID", append([]byte(musicBrainzUFID+"\x00"), tags.RecordingMBID...))
+	}
+	if len(tags.Cover) > 0 {
+		var body bytes.Buffer
+		body.WriteByte(encodingUTF8)
+		body.WriteString("image/jpeg\x00")
+		body.WriteByte(pictureTypeFrontCover)
+		body.WriteByte(0x00) // Empty description
+		body.Write(tags.Cover)
+		writeFrame(&frames, "APIC", body.Bytes())
+	} else if len(apicBody) > 0 {
+		writeFrame(&frames, "APIC", apicBody)
+	}
+
+	size := frames.Len() + id3Padding
+	if size > id3MaxSyncsafe {
+		return nil, fmt.Errorf("tag is too large: %d bytes", size)
+	}
+	var tag bytes.Buffer
+	tag.WriteString("ID3")
+	tag.Write([]byte{0x04, 0x00, 0x00}) // Version 2.4.0, no flags
+	tag.Write(syncsafe(size))
+	tag.Write(frames.Bytes())
+	tag.Write(make([]byte, id3Padding))
+	return tag.Bytes(), nil
+}
+
+func writeTextFrame(frames *bytes.Buffer, id, value string) {
+	if value == "" {
+		return
+	}
+	writeFrame(frames, id, append([]byte{encodingUTF8}, value...))
+}
+
+func writeFrame(frames *bytes.Buffer, id string, body []byte) {
+	frames.WriteString(id)
+	frames.Write(syncsafe(len(body)))
+	frames.Write([]byte{0x00, 0x00}) // No frame flags
+	frames.Write(body)
+}
+
+// stripID3v2 returns the data after any ID3v2 tags at the start of a file
+func stripID3v2(data []byte) []byte {
+	for len(data) >= id3HeaderSize && bytes.Equal(data[:3], []byte("ID3")) {
+		size, ok := readSyncsafe(data[6:10])
+		if !ok {
+			break
+		}
+		size += id3HeaderSize
+		if data[5]&id3FlagFooter != 0 {
+			size += id3HeaderSize
+		}
+		if size > len(data) {
+			break
+		}
+		data = data[size:]
+	}
+	return data
+}
+
+// id3Frame is a raw frame of an existing tag
+type id3Frame struct {
+	id   string
+	body []byte
+}
+
+// readID3v2Frames returns the frames of an ID3v2.3 or ID3v2.4 tag at the start of a file.
+// Tags that use unsynchronisation or cannot be parsed return what was read so far.
+func readID3v2Frames(data []byte) []id3Frame {
+	frames := make([]id3Frame, 0)
+	if len(data) < id3HeaderSize || !bytes.Equal(data[:3], []byte("ID3")) {
+		return frames
+	}
+	version, flags := data[3], data[5]
+	size, ok := readSyncsafe(data[6:10])
+	if !ok || (version != 3 && version != 4) || flags&0x80 != 0 || id3HeaderSize+size > len(data) {
+		return frames
+	}
+	tag := data[id3HeaderSize : id3HeaderSize+size]
+
+	// Skip the extended header, its size includes itself in v2.4 but not in v2.3
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		extendedSize := int(tag[0])<<24 | int(tag[1])<<16 | int(tag[2])<<8 | int(tag[3])
+		if version == 4 {
+			extendedSize, _ = readSyncsafe(tag[:4])
+		} else {
+			extendedSize += 4
+		}
+		if extendedSize > len(tag) {
+			return frames
+		}
+		tag = tag[extendedSize:]
+	}
+
+	for len(tag) >= id3HeaderSize && tag[0] != 0x00 {
+		frameSize := int(tag[4])<<24 | int(tag[5])<<16 | int(tag[6])<<8 | int(tag[7])
+		if version == 4 {
+			if frameSize, ok = readSyncsafe(tag[4:8]); !ok {
+				break
+			}
+		}
+		if id3HeaderSize+frameSize > len(tag) {
+			break
+		}
+		frames = append(frames, id3Frame{id: string(tag[:4]), body: tag[id3HeaderSize : id3HeaderSize+frameSize]})
+		tag = tag[id3HeaderSize+frameSize:]
+	}
+	return frames
+}
+
+// syncsafe encodes a size as four bytes of seven bits each, as ID3v2 requires
+func syncsafe(n int) []byte {
+	return []byte{byte(n>>21) & 0x7f, byte(n>>14) & 0x7f, byte(n>>7) & 0x7f, byte(n) & 0x7f}
+}
+
+func readSyncsafe(b []byte) (int, bool) {
+	n := 0
+	for _, part := range b {
+		if part&0x80 != 0 {
+			return 0, false
+		}
+		n = n<<7 | int(part)
+	}
+	return n, true
+}
@@ -0,0 +1,28 @@
+package musictag
+
+// SourceInfo is what is known about a downloaded item before tagging
+type SourceInfo struct {
+	URL           string
+	Title         string
+	Description   string
+	Artist        string // Reported by the site, such as YouTube Music, empty for most uploads
+	Track         string // Song title reported by the site
+	Album         string
+	Uploader      string
+	ReleaseYear   string
+	ThumbnailURL  string
+	PlaylistName  string
+	PlaylistIndex int // 1-based position in the playlist, 0 if unknown
+}
+
+// Tags are the resolved tags written to a file
+type Tags struct {
+	Title         string `json:"title"`
+	Artist        string `json:"artist"`
+	Album         string `json:"album"`
+	Track         int    `json:"track"` // 0 to omit
+	Year          string `json:"year"`
+	SourceURL     string `json:"source_url"`
+	RecordingMBID string `json:"recording_mbid,omitempty"` // MusicBrainz recording ID when enriched
+	Cover         []byte `json:"-"`                        // Square JPEG
+}
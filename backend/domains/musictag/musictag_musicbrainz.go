@@ -0,0 +1,83 @@
+package musictag
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Lowest search score accepted as the same recording, MusicBrainz scores range from 0 to 100
+	minMusicBrainzScore = 90
+	musicBrainzTimeout  = 10 * time.Second
+	// MusicBrainz compatible servers reject requests without an identifying user agent
+	musicBrainzUserAgent = "videoarchiver ( https://github.com/NotCoffee418/videoarchiver )"
+)
+
+// musicBrainzRecording is the part of a recording search result used for tagging
+type musicBrainzRecording struct {
+	ID           string `json:"id"`
+	Score        int    `json:"score"`
+	Title        string `json:"title"`
+	ArtistCredit []struct {
+		Name       string `json:"name"`
+		JoinPhrase string `json:"joinphrase"`
+	} `json:"artist-credit"`
+	Releases []struct {
+		Title string `json:"title"`
+		Date  string `json:"date"`
+	} `json:"releases"`
+}
+
+// artist joins the credited artists the way MusicBrainz displays them
+func (r *musicBrainzRecording) artist() string {
+	var artist strings.Builder
+	for _, credit := range r.ArtistCredit {
+		artist.WriteString(credit.Name)
+		artist.WriteString(credit.JoinPhrase)
+	}
+	return strings.TrimSpace(artist.String())
+}
+
+// lookupRecording searches a MusicBrainz compatible endpoint, such as http://localhost:5000/ws/2,
+// for the best matching recording. Returns nil if no result scores high enough.
+func lookupRecording(endpoint, artist, title string) (*musicBrainzRecording, error) {
+	query := fmt.Sprintf(`recording:"%s" AND artist:"%s"`, escapeLucene(title), escapeLucene(artist))
+	requestUrl := strings.TrimRight(endpoint, "/") + "/recording?fmt=json&limit=1&query=" + url.QueryEscape(query)
+
+	req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MusicBrainz endpoint: %w", err)
+	}
+	req.Header.Set("User-Agent", musicBrainzUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: musicBrainzTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query MusicBrainz: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query MusicBrainz: status code %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Recordings []musicBrainzRecording `json:"recordings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode MusicBrainz response: %w", err)
+	}
+	if len(result.Recordings) == 0 || result.Recordings[0].Score < minMusicBrainzScore {
+		return nil, nil
+	}
+	return &result.Recordings[0], nil
+}
+
+// escapeLucene escapes a value for use inside a quoted Lucene phrase
+func escapeLucene(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,187 @@
+package musictag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Fields a pattern can capture, {ignore} matches text that is thrown away
+var patternFields = []string{"artist", "title", "album", "track", "year", "ignore"}
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+	// Bracketed suffixes such as "(Official Music Video)" or "[HD]" that are not part of the song title
+	noisePattern = regexp.MustCompile(`(?i)\s*[\(\[][^\)\]]*\b(official|music video|video|audio|lyrics?|visuali[sz]er|hd|hq|4k|mv)\b[^\)\]]*[\)\]]`)
+	// Separator between artists in YouTube's auto-generated descriptions
+	artistSeparator = regexp.MustCompile(`\s+·\s+`)
+)
+
+// Pattern matches a title or description and captures tag fields
+type Pattern struct {
+	Template string
+	regex    *regexp.Regexp
+}
+
+// CompilePattern turns a template such as "{artist} - {title}" into a pattern.
+// A literal \n in the template matches a line break, so description patterns can span lines.
+func CompilePattern(template string) (*Pattern, error) {
+	template = strings.TrimSpace(template)
+	if template == "" {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+
+	var expr strings.Builder
+	expr.WriteString(`(?im)^\s*`)
+	seen := make(map[string]bool)
+	last := 0
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		expr.WriteString(quoteLiteral(template[last:match[0]]))
+		field := template[match[2]:match[3]]
+		if !isPatternField(field) {
+			return nil, fmt.Errorf("unknown field {%s} in pattern %q", field, template)
+		}
+		if seen[field] && field != "ignore" {
+			return nil, fmt.Errorf("field {%s} is used twice in pattern %q", field, template)
+		}
+		seen[field] = true
+		if field == "ignore" {
+			expr.WriteString(`.*?`)
+		} else {
+			expr.WriteString(`(?P<` + field + `>.+?)`)
+		}
+		last = match[1]
+	}
+	expr.WriteString(quoteLiteral(template[last:]))
+	expr.WriteString(`\s*$`)
+
+	if !seen["title"] && !seen["artist"] && !seen["album"] {
+		return nil, fmt.Errorf("pattern %q captures no artist, title or album", template)
+	}
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", template, err)
+	}
+	return &Pattern{Template: template, regex: regex}, nil
+}
+
+// ParsePatterns compiles one pattern per line of a setting, blank lines are skipped
+func ParsePatterns(value string) ([]*Pattern, error) {
+	patterns := make([]*Pattern, 0)
+	for _, line := range strings.Split(value, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		pattern, err := CompilePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Match returns the captured fields, or nil if the text does not match
+func (p *Pattern) Match(text string) map[string]string {
+	match := p.regex.FindStringSubmatch(text)
+	if match == nil {
+		return nil
+	}
+	fields := make(map[string]string)
+	for i, name := range p.regex.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		value := cleanValue(match[i])
+		if name == "artist" {
+			value = artistSeparator.ReplaceAllString(value, ", ")
+		}
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// matchFirst returns the fields of the first pattern that matches the text
+func matchFirst(patterns []*Pattern, text string) map[string]string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	for _, pattern := range patterns {
+		if fields := pattern.Match(text); fields != nil {
+			return fields
+		}
+	}
+	return nil
+}
+
+// CleanTitle removes bracketed noise such as "(Official Video)" from a video title
+func CleanTitle(title string) string {
+	return strings.TrimSpace(noisePattern.ReplaceAllString(title, ""))
+}
+
+// cleanValue trims whitespace and wrapping quotes from a captured value
+func cleanValue(value string) string {
+	value = strings.TrimSpace(value)
+	for _, quote := range []string{`"`, `'`, "“”", "‘’"} {
+		open, close := quote, quote
+		if runes := []rune(quote); len(runes) == 2 {
+			open, close = string(runes[0]), string(runes[1])
+		}
+		if len(value) > len(open)+len(close) && strings.HasPrefix(value, open) && strings.HasSuffix(value, close) {
+			value = strings.TrimSpace(value[len(open) : len(value)-len(close)])
+		}
+	}
+	return value
+}
+
+// parseTrackNumber reads "3" or "3/12" as 3
+func parseTrackNumber(value string) int {
+	value, _, _ = strings.Cut(value, "/")
+	track, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || track < 0 {
+		return 0
+	}
+	return track
+}
+
+func isPatternField(field string) bool {
+	for _, known := range patternFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteLiteral escapes template text for a regex. Whitespace matches any amount of whitespace,
+// and the two characters \n match a line break.
+func quoteLiteral(text string) string {
+	lines := strings.Split(text, `\n`)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			if line != "" {
+				lines[i] = `\s+`
+			}
+			continue
+		}
+
+		words := strings.Fields(trimmed)
+		for j, word := range words {
+			words[j] = regexp.QuoteMeta(word)
+		}
+		quoted := strings.Join(words, `\s+`)
+		// Whitespace around separators is required, so "AC-DC - Song" splits at the spaced dash
+		if strings.TrimLeft(line, " \t") != line {
+			quoted = `\s+` + quoted
+		}
+		if strings.TrimRight(line, " \t") != line {
+			quoted += `\s+`
+		}
+		lines[i] = quoted
+	}
+	return strings.Join(lines, `[ \t]*\r?\n\s*`)
+}
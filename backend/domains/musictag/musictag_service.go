@@ -0,0 +1,203 @@
+package musictag
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+	"videoarchiver/backend/imaging"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// Largest width and height of embedded cover art
+const coverSize = 600
+
+type MusicTagService struct {
+	settingsService *settings.SettingsService
+	logService      LogServiceInterface
+}
+
+func NewMusicTagService(settingsService *settings.SettingsService, logService LogServiceInterface) *MusicTagService {
+	return &MusicTagService{
+		settingsService: settingsService,
+		logService:      logService,
+	}
+}
+
+// IsEnabled returns true if mp3 downloads should be tagged
+func (m *MusicTagService) IsEnabled() bool {
+	enabled, err := m.settingsService.GetSettingBool("music_tagging_enabled")
+	if err != nil {
+		m.logService.Warn(fmt.Sprintf("Failed to get music_tagging_enabled setting: %v", err))
+		return false
+	}
+	return enabled
+}
+
+// SourceInfoFromYtdlp reads the fields used for tagging from yt-dlp's JSON output
+func SourceInfoFromYtdlp(infoJson string) SourceInfo {
+	info := SourceInfo{}
+	info.Title, _ = ytdlp.GetString(infoJson, "fulltitle")
+	info.Description, _ = ytdlp.GetString(infoJson, "description")
+	info.Artist, _ = ytdlp.GetString(infoJson, "artist")
+	info.Track, _ = ytdlp.GetString(infoJson, "track")
+	info.Album, _ = ytdlp.GetString(infoJson, "album")
+	info.Uploader, _ = ytdlp.GetString(infoJson, "uploader")
+	info.ThumbnailURL, _ = ytdlp.GetString(infoJson, "thumbnail")
+	info.URL, _ = ytdlp.GetString(infoJson, "webpage_url")
+	if year, err := ytdlp.GetFloat(infoJson, "release_year"); err == nil && year > 0 {
+		info.ReleaseYear = strconv.Itoa(int(year))
+	}
+	return info
+}
+
+// TagFile resolves tags for an mp3 file and writes them as an ID3v2.4 tag.
+// Cover art and MusicBrainz enrichment are optional, failing to get them only logs a warning.
+func (m *MusicTagService) TagFile(filePath string, info SourceInfo) (*Tags, error) {
+	if !strings.EqualFold(filepath.Ext(filePath), ".mp3") {
+		return nil, fmt.Errorf("only mp3 files can be tagged: %s", filePath)
+	}
+
+	titlePatterns, descriptionPatterns, err := m.getPatterns()
+	if err != nil {
+		return nil, err
+	}
+	tags := resolveTags(info, titlePatterns, descriptionPatterns)
+
+	if endpoint := m.getMusicBrainzEndpoint(); endpoint != "" && tags.Artist != "" && tags.Title != "" {
+		recording, err := lookupRecording(endpoint, tags.Artist, tags.Title)
+		if err != nil {
+			m.logService.Warn(fmt.Sprintf("MusicBrainz lookup failed for %s - %s: %v", tags.Artist, tags.Title, err))
+		} else if recording != nil {
+			applyRecording(&tags, recording)
+		}
+	}
+
+	// Playlists of songs are usually albums or mixtapes, which makes the playlist name a fair album
+	if tags.Album == "" {
+		tags.Album = info.PlaylistName
+	}
+
+	if info.ThumbnailURL != "" {
+		cover, err := imaging.GetSquareCoverJPEG(info.ThumbnailURL, coverSize)
+		if err != nil {
+			m.logService.Warn(fmt.Sprintf("Failed to get cover art for %s: %v", info.URL, err))
+		} else {
+			tags.Cover = cover
+		}
+	}
+
+	if err := WriteID3v24(filePath, tags); err != nil {
+		return nil, err
+	}
+	m.logService.Debug(fmt.Sprintf("Tagged %s as %s - %s (%s, track %d)", filePath, tags.Artist, tags.Title, tags.Album, tags.Track))
+	return &tags, nil
+}
+
+// PreviewTags returns the tags a title and description would get, without cover art or enrichment.
+// Used to try out patterns.
+func (m *MusicTagService) PreviewTags(title, description string) (*Tags, error) {
+	titlePatterns, descriptionPatterns, err := m.getPatterns()
+	if err != nil {
+		return nil, err
+	}
+	tags := resolveTags(SourceInfo{Title: title, Description: description}, titlePatterns, descriptionPatterns)
+	return &tags, nil
+}
+
+// resolveTags combines the site's metadata with what the patterns find in the description and title.
+// Earlier sources win: site metadata, then description patterns, then title patterns.
+func resolveTags(info SourceInfo, titlePatterns, descriptionPatterns []*Pattern) Tags {
+	tags := Tags{
+		Title:     info.Track,
+		Artist:    info.Artist,
+		Album:     info.Album,
+		Track:     info.PlaylistIndex,
+		Year:      info.ReleaseYear,
+		SourceURL: info.URL,
+	}
+
+	cleanTitle := CleanTitle(info.Title)
+	for _, fields := range []map[string]string{
+		matchFirst(descriptionPatterns, info.Description),
+		matchFirst(titlePatterns, cleanTitle),
+	} {
+		tags.Title = firstNonEmpty(tags.Title, fields["title"])
+		tags.Artist = firstNonEmpty(tags.Artist, fields["artist"])
+		tags.Album = firstNonEmpty(tags.Album, fields["album"])
+		tags.Year = firstNonEmpty(tags.Year, fields["year"])
+		if tags.Track == 0 {
+			tags.Track = parseTrackNumber(fields["track"])
+		}
+	}
+
+	// Without a pattern match the whole title is the song, by whoever uploaded it.
+	// Auto-generated YouTube channels are named "<artist> - Topic".
+	tags.Title = firstNonEmpty(tags.Title, cleanTitle)
+	tags.Artist = firstNonEmpty(tags.Artist, strings.TrimSuffix(info.Uploader, " - Topic"))
+	return tags
+}
+
+// applyRecording replaces the guessed title and artist with MusicBrainz' and fills in what is missing
+func applyRecording(tags *Tags, recording *musicBrainzRecording) {
+	tags.RecordingMBID = recording.ID
+	tags.Title = firstNonEmpty(recording.Title, tags.Title)
+	tags.Artist = firstNonEmpty(recording.artist(), tags.Artist)
+	if len(recording.Releases) > 0 {
+		release := recording.Releases[0]
+		tags.Album = firstNonEmpty(tags.Album, release.Title)
+		if len(release.Date) >= 4 {
+			tags.Year = firstNonEmpty(tags.Year, release.Date[:4])
+		}
+	}
+}
+
+// getPatterns reads the configured title and description patterns
+func (m *MusicTagService) getPatterns() ([]*Pattern, []*Pattern, error) {
+	titleSetting, err := m.settingsService.GetSettingString("music_title_patterns")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get music_title_patterns setting: %w", err)
+	}
+	titlePatterns, err := ParsePatterns(titleSetting)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid music_title_patterns setting: %w", err)
+	}
+
+	descriptionSetting, err := m.settingsService.GetSettingString("music_description_patterns")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get music_description_patterns setting: %w", err)
+	}
+	descriptionPatterns, err := ParsePatterns(descriptionSetting)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid music_description_patterns setting: %w", err)
+	}
+	return titlePatterns, descriptionPatterns, nil
+}
+
+func (m *MusicTagService) getMusicBrainzEndpoint() string {
+	endpoint, err := m.settingsService.GetSettingString("musicbrainz_endpoint")
+	if err != nil {
+		m.logService.Warn(fmt.Sprintf("Failed to get musicbrainz_endpoint setting: %v", err))
+		return ""
+	}
+	return strings.TrimSpace(endpoint)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,207 @@
+package musictag
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const autoGeneratedDescription = `Provided to YouTube by Example Records
+
+Midnight City · M83 · Anthony Gonzalez
+
+Hurry Up, We're Dreaming
+
+℗ 2011 Naive
+
+Released on: 2011-10-18`
+
+func defaultPatterns(t *testing.T) ([]*Pattern, []*Pattern) {
+	t.Helper()
+	titlePatterns, err := ParsePatterns("{artist} - {title}\n{artist} – {title}\n\n{artist} — {title}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptionPatterns, err := ParsePatterns(`Provided to YouTube by {ignore}\n{title} · {artist}\n{album}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return titlePatterns, descriptionPatterns
+}
+
+func TestPatternMatch(t *testing.T) {
+	titlePatterns, descriptionPatterns := defaultPatterns(t)
+	tests := []struct {
+		name     string
+		patterns []*Pattern
+		text     string
+		expected map[string]string
+	}{
+		{"dash", titlePatterns, "Daft Punk - One More Time", map[string]string{"artist": "Daft Punk", "title": "One More Time"}},
+		{"hyphen in artist", titlePatterns, "AC-DC - Thunderstruck", map[string]string{"artist": "AC-DC", "title": "Thunderstruck"}},
+		{"quoted title", titlePatterns, `Queen – "Bohemian Rhapsody"`, map[string]string{"artist": "Queen", "title": "Bohemian Rhapsody"}},
+		{"no match", titlePatterns, "My holiday vlog", nil},
+		{"description", descriptionPatterns, autoGeneratedDescription, map[string]string{
+			"title": "Midnight City", "artist": "M83, Anthony Gonzalez", "album": "Hurry Up, We're Dreaming",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := matchFirst(tt.patterns, tt.text)
+			if tt.expected == nil {
+				if fields != nil {
+					t.Errorf("Expected no match, got %+v", fields)
+				}
+				return
+			}
+			for key, value := range tt.expected {
+				if fields[key] != value {
+					t.Errorf("Expected %s %q, got %q", key, value, fields[key])
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for _, template := range []string{"", "{artist} - {genre}", "{artist} - {artist}", "{ignore} - {track}"} {
+		if _, err := CompilePattern(template); err == nil {
+			t.Errorf("Expected %q to be rejected", template)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := map[string]string{
+		"Artist - Song (Official Music Video)": "Artist - Song",
+		"Artist - Song [HD] (Lyrics)":          "Artist - Song",
+		"Artist - Song (Live at Wembley)":      "Artist - Song (Live at Wembley)",
+		"Artist - Song (Remix)":                "Artist - Song (Remix)",
+	}
+	for title, expected := range tests {
+		if cleaned := CleanTitle(title); cleaned != expected {
+			t.Errorf("CleanTitle(%q) = %q, expected %q", title, cleaned, expected)
+		}
+	}
+}
+
+func TestResolveTags(t *testing.T) {
+	titlePatterns, descriptionPatterns := defaultPatterns(t)
+
+	// Site metadata wins over patterns, the playlist index is the track number
+	tags := resolveTags(SourceInfo{
+		Title:         "Wrong Artist - Wrong Title (Official Video)",
+		Track:         "Right Title",
+		Artist:        "Right Artist",
+		PlaylistIndex: 4,
+	}, titlePatterns, descriptionPatterns)
+	if tags.Title != "Right Title" || tags.Artist != "Right Artist" || tags.Track != 4 {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+
+	// The description of auto-generated uploads is used before the title
+	tags = resolveTags(SourceInfo{
+		Title:       "Midnight City",
+		Description: autoGeneratedDescription,
+		Uploader:    "M83 - Topic",
+	}, titlePatterns, descriptionPatterns)
+	if tags.Title != "Midnight City" || tags.Artist != "M83, Anthony Gonzalez" || tags.Album != "Hurry Up, We're Dreaming" {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+
+	// Without a match the uploader is the artist
+	tags = resolveTags(SourceInfo{Title: "Untitled jam (Official Audio)", Uploader: "Some Band - Topic"}, titlePatterns, descriptionPatterns)
+	if tags.Title != "Untitled jam" || tags.Artist != "Some Band" {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+}
+
+func TestWriteID3v24(t *testing.T) {
+	audio := []byte("\xff\xfbfake mpeg audio frames")
+	oldCover := append([]byte{0x00}, "image/jpeg\x00\x03\x00old cover"...)
+
+	// Existing ID3v2.3 tag as written by other tools, with plain frame sizes
+	var oldFrames bytes.Buffer
+	oldFrames.WriteString("APIC")
+	oldFrames.Write([]byte{0, 0, 0, byte(len(oldCover)), 0, 0})
+	oldFrames.Write(oldCover)
+	var file bytes.Buffer
+	file.WriteString("ID3\x03\x00\x00")
+	file.Write(syncsafe(oldFrames.Len()))
+	file.Write(oldFrames.Bytes())
+	file.Write(audio)
+
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(path, file.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tags := Tags{Title: "Título", Artist: "Artist", Album: "Album", Track: 7, Year: "2011", SourceURL: "https://example.com/v", RecordingMBID: "mbid"}
+	if err := WriteID3v24(path, tags); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[3] != 4 {
+		t.Fatalf("Expected an ID3v2.4 tag, got version %d", data[3])
+	}
+	if !bytes.Equal(stripID3v2(data), audio) {
+		t.Error("Expected audio data to be preserved after the tag")
+	}
+
+	frames := make(map[string]string)
+	for _, frame := range readID3v2Frames(data) {
+		frames[frame.id] = string(frame.body)
+	}
+	expected := map[string]string{
+		"TIT2": "\x03Título",
+		"TPE1": "\x03Artist",
+		"TALB": "\x03Album",
+		"TRCK": "\x037",
+		"TDRC": "\x032011",
+		"WOAS": "https://example.com/v",
+		"UFID": "http://musicbrainz.org\x00mbid",
+		"APIC": string(oldCover),
+	}
+	for id, body := range expected {
+		if frames[id] != body {
+			t.Errorf("Frame %s: expected %q, got %q", id, body, frames[id])
+		}
+	}
+}
+
+func TestLookupRecording(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "videoarchiver") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"recordings":[{"id":"abc","score":95,"title":"Midnight City",
+			"artist-credit":[{"name":"M83","joinphrase":" feat. "},{"name":"Someone"}],
+			"releases":[{"title":"Hurry Up, We're Dreaming","date":"2011-10-18"}]}]}`))
+	}))
+	defer server.Close()
+
+	recording, err := lookupRecording(server.URL+"/ws/2/", `M83`, `Midnight "City"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if query != `recording:"Midnight \"City\"" AND artist:"M83"` {
+		t.Errorf("Unexpected query: %s", query)
+	}
+
+	tags := Tags{Title: "midnight city", Artist: "m83"}
+	applyRecording(&tags, recording)
+	if tags.Title != "Midnight City" || tags.Artist != "M83 feat. Someone" || tags.Album != "Hurry Up, We're Dreaming" ||
+		tags.Year != "2011" || tags.RecordingMBID != "abc" {
+		t.Errorf("Unexpected tags: %+v", tags)
+	}
+}
@@ -0,0 +1,77 @@
+package overrides
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables that override config and settings values
+const EnvPrefix = "VIDEOARCHIVER_"
+
+// Override is a value that takes precedence over the stored value
+type Override struct {
+	Value  string `json:"value"`
+	Source string `json:"source"` // Flag or environment variable the value comes from
+}
+
+// Values from --config and --setting flags, set once at startup
+var (
+	configFlags  = map[string]string{}
+	settingFlags = map[string]string{}
+)
+
+// SetConfigFlags sets the config values given with --config flags
+func SetConfigFlags(values map[string]string) {
+	configFlags = values
+}
+
+// SetSettingFlags sets the settings values given with --setting flags
+func SetSettingFlags(values map[string]string) {
+	settingFlags = values
+}
+
+// GetSettingFlags returns the settings values given with --setting flags
+func GetSettingFlags() map[string]string {
+	return settingFlags
+}
+
+// GetConfigFlags returns the config values given with --config flags
+func GetConfigFlags() map[string]string {
+	return configFlags
+}
+
+// LookupConfig returns the override of a config.json field. Flags take precedence over environment variables.
+func LookupConfig(key string) (Override, bool) {
+	return lookup(configFlags, "--config", key)
+}
+
+// LookupSetting returns the override of a setting. Flags take precedence over environment variables.
+func LookupSetting(key string) (Override, bool) {
+	return lookup(settingFlags, "--setting", key)
+}
+
+// EnvName returns the environment variable that overrides a key, such as VIDEOARCHIVER_ALLOW_DUPLICATES
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+func lookup(flags map[string]string, flagName, key string) (Override, bool) {
+	if value, ok := flags[key]; ok {
+		return Override{Value: value, Source: flagName + " " + key}, true
+	}
+	if value, ok := os.LookupEnv(EnvName(key)); ok {
+		return Override{Value: value, Source: EnvName(key)}, true
+	}
+	return Override{}, false
+}
+
+// ParseKeyValue parses a KEY=VALUE flag value, the value may be empty
+func ParseKeyValue(value string) (string, string, error) {
+	key, val, found := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", fmt.Errorf("invalid override %q, expected KEY=VALUE", value)
+	}
+	return key, val, nil
+}
@@ -7,6 +7,22 @@ import (
 	"videoarchiver/data"
 )
 
+// DirKind is the kind of files a directory holds.
+// On Linux each kind follows the XDG base directory specification, elsewhere they share one directory.
+type DirKind int
+
+const (
+	DirData    DirKind = iota // Database and quarantined files, $XDG_DATA_HOME
+	DirConfig                 // config.json, $XDG_CONFIG_HOME
+	DirState                  // Logs, $XDG_STATE_HOME
+	DirCache                  // Downloaded yt-dlp and ffmpeg binaries, $XDG_CACHE_HOME
+	DirRuntime                // Lock file and exported cookies, $XDG_RUNTIME_DIR
+)
+
+// DataDirEnv is the environment variable that relocates the working directory
+const DataDirEnv = "VIDEOARCHIVER_DATA_DIR"
+
+// GetWorkingFile returns the path of a file in the data directory
 func GetWorkingFile(fileName string, parts ...string) (string, error) {
 	workingDir, err := GetWorkingDir(parts...)
 	if err != nil {
@@ -15,31 +31,118 @@ func GetWorkingFile(fileName string, parts ...string) (string, error) {
 	return filepath.Join(append([]string{workingDir}, fileName)...), nil
 }
 
+// GetWorkingDir returns the data directory, or a directory inside it, creating it if needed
 func GetWorkingDir(parts ...string) (string, error) {
-	var baseDir string
+	return GetDir(DirData, parts...)
+}
+
+// GetFile returns the path of a file in the directory for its kind
+func GetFile(kind DirKind, fileName string) (string, error) {
+	dir, err := GetDir(kind)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// GetDir returns the directory for a kind of files, or a directory inside it, creating it if needed
+func GetDir(kind DirKind, parts ...string) (string, error) {
+	baseDir, err := getBaseDir(kind)
+	if err != nil {
+		return "", err
+	}
+
+	targetDir := filepath.Join(append([]string{baseDir}, parts...)...)
+
+	// Runtime files can include credentials
+	perm := os.ModePerm
+	if kind == DirRuntime {
+		perm = 0700
+	}
+	if err := os.MkdirAll(targetDir, perm); err != nil {
+		return "", err
+	}
+
+	return targetDir, nil
+}
+
+// SetDataDir relocates the working directory, for the --data-dir flag.
+// The environment variable is set as well, so processes started from this one use the same directory.
+func SetDataDir(dir string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return os.Setenv(DataDirEnv, absDir)
+}
+
+// GetDataDirOverride returns the relocated working directory, empty if the default is used
+func GetDataDirOverride() string {
+	dir := os.Getenv(DataDirEnv)
+	if dir == "" {
+		return ""
+	}
+	if absDir, err := filepath.Abs(dir); err == nil {
+		return absDir
+	}
+	return dir
+}
+
+// getBaseDir returns the directory for a kind of files.
+// A relocated working directory holds every kind, as does the per-user application directory on Windows.
+func getBaseDir(kind DirKind) (string, error) {
+	if dir := GetDataDirOverride(); dir != "" {
+		return dir, nil
+	}
 
 	if isWindows() {
-		baseDir = os.Getenv("LOCALAPPDATA")
+		baseDir := os.Getenv("LOCALAPPDATA")
 		if baseDir == "" {
 			return "", errors.New("LOCALAPPDATA environment variable is not set")
 		}
-	} else if isLinux() {
-		home := os.Getenv("HOME")
-		if home == "" {
-			return "", errors.New("HOME environment variable is not set")
-		}
-		baseDir = filepath.Join(home, ".local", "share")
-	} else {
+		return filepath.Join(baseDir, data.AppShortName), nil
+	}
+	if !isLinux() {
 		return "", errors.New("unsupported platform")
 	}
 
-	targetDir := filepath.Join(append([]string{baseDir, data.AppShortName}, parts...)...)
+	switch kind {
+	case DirConfig:
+		return xdgDir("XDG_CONFIG_HOME", ".config")
+	case DirState:
+		return xdgDir("XDG_STATE_HOME", ".local", "state")
+	case DirCache:
+		return xdgDir("XDG_CACHE_HOME", ".cache")
+	case DirRuntime:
+		// The specification leaves the fallback to the application, state survives like the runtime directory would not
+		if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(runtimeDir) {
+			return filepath.Join(runtimeDir, data.AppShortName), nil
+		}
+		return xdgDir("XDG_STATE_HOME", ".local", "state")
+	}
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
 
-	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
-		return "", err
+// xdgDir returns the application directory inside an XDG base directory.
+// Relative values are invalid according to the specification and ignored.
+func xdgDir(env string, defaultParts ...string) (string, error) {
+	if baseDir := os.Getenv(env); filepath.IsAbs(baseDir) {
+		return filepath.Join(baseDir, data.AppShortName), nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("HOME environment variable is not set")
 	}
+	return filepath.Join(append(append([]string{home}, defaultParts...), data.AppShortName)...), nil
+}
 
-	return targetDir, nil
+// legacyDir returns the directory that held every file before XDG base directories were used
+func legacyDir() (string, error) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("HOME environment variable is not set")
+	}
+	return filepath.Join(home, ".local", "share", data.AppShortName), nil
 }
 
 func isWindows() bool {
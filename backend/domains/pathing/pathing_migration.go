@@ -0,0 +1,111 @@
+package pathing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Written to the legacy directory once its files were moved, so the move runs once
+const legacyMigrationMarker = ".xdg-migrated"
+
+// LegacyFile is a file that moves from the legacy directory to the directory for its kind
+type LegacyFile struct {
+	Name string
+	Kind DirKind
+}
+
+// MigrateLegacyDirectory moves files from ~/.local/share/videoarchiver to their XDG base directories, once.
+// Files that already exist at their new location are left in place. The database stays where config.json points to.
+// Returns the files that were moved.
+func MigrateLegacyDirectory(files []LegacyFile) ([]string, error) {
+	if !isLinux() || GetDataDirOverride() != "" {
+		return nil, nil
+	}
+
+	legacy, err := legacyDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(legacy); os.IsNotExist(err) {
+		return nil, nil
+	}
+	markerPath := filepath.Join(legacy, legacyMigrationMarker)
+	if _, err := os.Stat(markerPath); err == nil {
+		return nil, nil
+	}
+
+	var moved []string
+	var errs []error
+	for _, file := range files {
+		source := filepath.Join(legacy, file.Name)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			continue
+		}
+		target, err := GetFile(file.Kind, file.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get new location of %s: %w", file.Name, err))
+			continue
+		}
+		if target == source {
+			continue
+		}
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := moveFile(source, target); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move %s to %s: %w", source, target, err))
+			continue
+		}
+		moved = append(moved, target)
+	}
+
+	// Retry on the next start if anything failed
+	if len(errs) > 0 {
+		return moved, errors.Join(errs...)
+	}
+	if err := os.WriteFile(markerPath, []byte("Files were moved to XDG base directories\n"), 0644); err != nil {
+		return moved, fmt.Errorf("failed to write migration marker: %w", err)
+	}
+	return moved, nil
+}
+
+// moveFile renames a file, copying it when the target is on another file system
+func moveFile(source, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	partial := target + ".partial"
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	if err := os.Rename(partial, target); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	in.Close()
+	return os.Remove(source)
+}
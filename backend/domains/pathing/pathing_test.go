@@ -0,0 +1,110 @@
+package pathing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupHome(t *testing.T) string {
+	if !isLinux() {
+		t.Skip("XDG base directories are only used on Linux")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(DataDirEnv, "")
+	for _, env := range []string{"XDG_DATA_HOME", "XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "XDG_RUNTIME_DIR"} {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
+func TestGetDirFollowsXDG(t *testing.T) {
+	home := setupHome(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "cache"))
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("XDG_STATE_HOME", "relative/state") // Invalid, ignored
+
+	tests := map[DirKind]string{
+		DirData:    filepath.Join(home, ".local", "share", "videoarchiver"),
+		DirConfig:  filepath.Join(home, ".config", "videoarchiver"),
+		DirState:   filepath.Join(home, ".local", "state", "videoarchiver"),
+		DirCache:   filepath.Join(home, "cache", "videoarchiver"),
+		DirRuntime: filepath.Join(runtimeDir, "videoarchiver"),
+	}
+	for kind, expected := range tests {
+		dir, err := GetDir(kind)
+		if err != nil {
+			t.Fatalf("kind %d: unexpected error: %v", kind, err)
+		}
+		if dir != expected {
+			t.Errorf("kind %d: got %s, expected %s", kind, dir, expected)
+		}
+	}
+
+	// A relocated working directory holds everything
+	dataDir := t.TempDir()
+	t.Setenv(DataDirEnv, dataDir)
+	for kind := range tests {
+		if dir, _ := GetDir(kind); dir != dataDir {
+			t.Errorf("kind %d: got %s, expected the data directory %s", kind, dir, dataDir)
+		}
+	}
+}
+
+func TestMigrateLegacyDirectory(t *testing.T) {
+	home := setupHome(t)
+	legacy := filepath.Join(home, ".local", "share", "videoarchiver")
+	if err := os.MkdirAll(legacy, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"config.json", "daemon.log", "ui.log", "db.sqlite"} {
+		if err := os.WriteFile(filepath.Join(legacy, name), []byte("legacy "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A log already written at the new location is kept
+	stateDir := filepath.Join(home, ".local", "state", "videoarchiver")
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, "ui.log"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []LegacyFile{
+		{Name: "config.json", Kind: DirConfig},
+		{Name: "daemon.log", Kind: DirState},
+		{Name: "ui.log", Kind: DirState},
+		{Name: "yt-dlp_linux", Kind: DirCache},
+	}
+	moved, err := MigrateLegacyDirectory(files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(moved) != 2 {
+		t.Errorf("expected 2 moved files, got %v", moved)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(home, ".config", "videoarchiver", "config.json")); string(content) != "legacy config.json" {
+		t.Errorf("config.json was not moved, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(stateDir, "ui.log")); string(content) != "new" {
+		t.Errorf("existing ui.log was overwritten, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "db.sqlite")); err != nil {
+		t.Errorf("database should stay in the data directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(legacy, legacyMigrationMarker)); err != nil {
+		t.Errorf("migration marker was not written: %v", err)
+	}
+
+	// Runs once
+	if err := os.WriteFile(filepath.Join(legacy, "daemon.log"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if moved, err := MigrateLegacyDirectory(files); err != nil || len(moved) != 0 {
+		t.Errorf("expected nothing to move after the marker, got %v, %v", moved, err)
+	}
+}
@@ -92,12 +92,28 @@ func (p *PlaylistDB) AddPlaylist(
 	directory,
 	format,
 	thumbnail string,
-) error {
+) (int, error) {
 	// Add new playlist
-	_, err := p.db.Exec(
+	result, err := p.db.Exec(
 		`INSERT INTO playlists (name, url, output_format, save_directory, thumbnail_base64, is_enabled)
 		VALUES (?, ?, ?, ?, ?, 1)`,
 		name, webpageUrl, format, directory, thumbnail,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// GetActivePlaylistID returns the ID of the active playlist with this configuration, 0 if there is none
+func (p *PlaylistDB) GetActivePlaylistID(webpageUrl, directory, format string) (int, error) {
+	var id int
+	err := p.db.QueryRow(
+		"SELECT id FROM playlists WHERE url = ? AND save_directory = ? AND output_format = ? AND is_enabled = 1",
+		webpageUrl, directory, format).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
 }
@@ -65,7 +65,7 @@ func (p *PlaylistService) TryAddNewPlaylist(url, directory, format string) error
 	}
 
 	// Add playlist to database
-	err = p.db.AddPlaylist(
+	_, err = p.db.AddPlaylist(
 		plInfo.Title,
 		plInfo.CleanUrl,
 		directory,
@@ -0,0 +1,77 @@
+package playlistfile
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+)
+
+// playlistItem is an archived file in the position it has in the source playlist
+type playlistItem struct {
+	Title        string
+	RelativePath string // Slash separated, relative to the playlist file
+	Position     int    // 1-based position in the source playlist
+}
+
+// renderM3U8 writes an extended M3U playlist in UTF-8
+func renderM3U8(name string, items []playlistItem) string {
+	var m3u strings.Builder
+	m3u.WriteString("#EXTM3U\n")
+	m3u.WriteString("#PLAYLIST:" + singleLine(name) + "\n")
+	for _, item := range items {
+		// Duration is unknown, -1 lets players read it from the file
+		m3u.WriteString("#EXTINF:-1," + singleLine(item.Title) + "\n")
+		m3u.WriteString(item.RelativePath + "\n")
+	}
+	return m3u.String()
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	TrackNum int    `xml:"trackNum"`
+}
+
+// renderXSPF writes an XSPF playlist, locations are relative URIs
+func renderXSPF(name string, items []playlistItem) (string, error) {
+	playlist := xspfPlaylist{
+		Version: "1",
+		Xmlns:   "http://xspf.org/ns/0/",
+		Title:   name,
+		Tracks:  make([]xspfTrack, 0, len(items)),
+	}
+	for _, item := range items {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location: escapePath(item.RelativePath),
+			Title:    item.Title,
+			TrackNum: item.Position,
+		})
+	}
+
+	content, err := xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(content) + "\n", nil
+}
+
+// escapePath percent-encodes every segment of a relative path for use as a URI
+func escapePath(relativePath string) string {
+	segments := strings.Split(relativePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,130 @@
+package playlistfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/playlist"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+type PlaylistFileService struct {
+	settingsService *settings.SettingsService
+	downloadDB      *download.DownloadDB
+	logService      LogServiceInterface
+}
+
+func NewPlaylistFileService(
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	logService LogServiceInterface,
+) *PlaylistFileService {
+	return &PlaylistFileService{
+		settingsService: settingsService,
+		downloadDB:      downloadDB,
+		logService:      logService,
+	}
+}
+
+// WritePlaylistFiles writes the archived files of a playlist to playlist files in its save directory,
+// in the order of the source playlist. Files are only rewritten when their content changes.
+func (s *PlaylistFileService) WritePlaylistFiles(pl *playlist.Playlist, entries []ytdlp.YtdlpEntry) error {
+	enabled, err := s.settingsService.GetSettingBool("playlist_files_enabled")
+	if err != nil {
+		return fmt.Errorf("failed to get playlist_files_enabled setting: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+	writeXspf, err := s.settingsService.GetSettingBool("playlist_files_xspf")
+	if err != nil {
+		return fmt.Errorf("failed to get playlist_files_xspf setting: %w", err)
+	}
+
+	downloads, err := s.downloadDB.GetDownloadsForPlaylist(pl.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get downloads for playlist: %w", err)
+	}
+	items := collectItems(pl.SaveDirectory, entries, downloads)
+
+	basePath := filepath.Join(pl.SaveDirectory, fileutils.SanitizeFilename(pl.Name))
+	changed, err := writeIfChanged(basePath+".m3u8", renderM3U8(pl.Name, items))
+	if err != nil {
+		return err
+	}
+	if writeXspf {
+		content, err := renderXSPF(pl.Name, items)
+		if err != nil {
+			return fmt.Errorf("failed to render XSPF playlist: %w", err)
+		}
+		xspfChanged, err := writeIfChanged(basePath+".xspf", content)
+		if err != nil {
+			return err
+		}
+		changed = changed || xspfChanged
+	}
+
+	if changed {
+		s.logService.Info(fmt.Sprintf("Updated playlist files for %s: %d of %d items archived", pl.Name, len(items), len(entries)))
+	}
+	return nil
+}
+
+// collectItems returns the archived file of every entry that has one in the save directory, in remote order
+func collectItems(saveDirectory string, entries []ytdlp.YtdlpEntry, downloads []download.Download) []playlistItem {
+	filenames := make(map[string]string, len(downloads))
+	for _, dl := range downloads {
+		if dl.OutputFilename.Valid && dl.OutputFilename.String != "" {
+			filenames[dl.Url] = dl.OutputFilename.String
+		}
+	}
+
+	items := make([]playlistItem, 0, len(entries))
+	for _, entry := range entries {
+		filename, ok := filenames[entry.URL]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(saveDirectory, filename)); err != nil {
+			continue
+		}
+		items = append(items, playlistItem{
+			Title:        entry.Title,
+			RelativePath: filepath.ToSlash(filename),
+			Position:     entry.Index,
+		})
+	}
+	return items
+}
+
+// writeIfChanged replaces a file through a temporary file when its content differs.
+// Returns true if the file was written.
+func writeIfChanged(path, content string) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, []byte(content)) {
+		return false, nil
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write playlist file %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to replace playlist file %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,82 @@
+package playlistfile
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+func TestCollectItems(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.mp3", "a.mp3"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := []ytdlp.YtdlpEntry{
+		{Title: "Second upload", URL: "https://example.com/b", Index: 1},
+		{Title: "Not archived", URL: "https://example.com/c", Index: 2},
+		{Title: "Missing file", URL: "https://example.com/d", Index: 3},
+		{Title: "First upload", URL: "https://example.com/a", Index: 4},
+	}
+	downloads := []download.Download{
+		{Url: "https://example.com/a", OutputFilename: sql.NullString{String: "a.mp3", Valid: true}},
+		{Url: "https://example.com/b", OutputFilename: sql.NullString{String: "b.mp3", Valid: true}},
+		{Url: "https://example.com/d", OutputFilename: sql.NullString{String: "d.mp3", Valid: true}},
+	}
+
+	items := collectItems(dir, entries, downloads)
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %+v", items)
+	}
+	if items[0].RelativePath != "b.mp3" || items[0].Position != 1 || items[1].RelativePath != "a.mp3" || items[1].Position != 4 {
+		t.Errorf("Expected items in remote order, got %+v", items)
+	}
+}
+
+func TestRenderPlaylists(t *testing.T) {
+	items := []playlistItem{
+		{Title: "Song\nwith newline", RelativePath: "Song #1 & more.mp3", Position: 1},
+		{Title: "Über <Track>", RelativePath: "sub/Über.mp3", Position: 2},
+	}
+
+	expectedM3U := "#EXTM3U\n#PLAYLIST:Mix\n#EXTINF:-1,Song with newline\nSong #1 & more.mp3\n#EXTINF:-1,Über <Track>\nsub/Über.mp3\n"
+	if m3u := renderM3U8("Mix", items); m3u != expectedM3U {
+		t.Errorf("Unexpected M3U8 output:\n%s", m3u)
+	}
+
+	xspf, err := renderXSPF("Mix", items)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"<location>Song%20%231%20&amp;%20more.mp3</location>",
+		"<location>sub/%C3%9Cber.mp3</location>",
+		"<title>Über &lt;Track&gt;</title>",
+		"<trackNum>2</trackNum>",
+	} {
+		if !strings.Contains(xspf, expected) {
+			t.Errorf("Expected XSPF output to contain %s:\n%s", expected, xspf)
+		}
+	}
+}
+
+func TestWriteIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Mix.m3u8")
+	if changed, err := writeIfChanged(path, "#EXTM3U\n"); err != nil || !changed {
+		t.Fatalf("Expected first write to change the file, got %v, %v", changed, err)
+	}
+	if changed, err := writeIfChanged(path, "#EXTM3U\n"); err != nil || changed {
+		t.Errorf("Expected identical content to be left alone, got %v, %v", changed, err)
+	}
+	if changed, err := writeIfChanged(path, "#EXTM3U\n#PLAYLIST:Mix\n"); err != nil || !changed {
+		t.Errorf("Expected new content to be written, got %v, %v", changed, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Expected the temporary file to be gone")
+	}
+}
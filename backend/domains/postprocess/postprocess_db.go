@@ -0,0 +1,114 @@
+package postprocess
+
+import (
+	"database/sql"
+	"encoding/json"
+	"videoarchiver/backend/domains/db"
+)
+
+type PostProcessDB struct {
+	db *sql.DB
+}
+
+func NewPostProcessDB(dbService *db.DatabaseService) *PostProcessDB {
+	return &PostProcessDB{db: dbService.GetDB()}
+}
+
+// GetSteps returns the steps of a playlist in the order they run
+func (p *PostProcessDB) GetSteps(playlistId int) ([]PostProcessStep, error) {
+	rows, err := p.db.Query(
+		"SELECT id, playlist_id, position, kind, options, is_enabled FROM postprocess_steps WHERE playlist_id = ? ORDER BY position",
+		playlistId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := make([]PostProcessStep, 0)
+	for rows.Next() {
+		var step PostProcessStep
+		var options string
+		if err := rows.Scan(&step.ID, &step.PlaylistID, &step.Position, &step.Kind, &options, &step.IsEnabled); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(options), &step.Options); err != nil {
+			return nil, err
+		}
+		if step.Options == nil {
+			step.Options = make(map[string]string)
+		}
+		steps = append(steps, step)
+	}
+	return steps, rows.Err()
+}
+
+// ReplaceSteps replaces every step of a playlist, positions follow the order of the given steps
+func (p *PostProcessDB) ReplaceSteps(playlistId int, steps []PostProcessStep) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM postprocess_steps WHERE playlist_id = ?", playlistId); err != nil {
+		return err
+	}
+	for position, step := range steps {
+		options, err := json.Marshal(step.Options)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO postprocess_steps (playlist_id, position, kind, options, is_enabled) VALUES (?, ?, ?, ?, ?)",
+			playlistId, position, step.Kind, string(options), step.IsEnabled,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// InsertResult records the outcome of a step
+func (p *PostProcessDB) InsertResult(result *PostProcessResult) error {
+	res, err := p.db.Exec(
+		`INSERT INTO postprocess_results (download_id, step_id, position, kind, status, detail, started_at, finished_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		result.DownloadID, result.StepID, result.Position, result.Kind, result.Status, result.Detail,
+		result.StartedAt, result.FinishedAt,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	result.ID = int(id)
+	return err
+}
+
+// GetResultsForDownload returns every step result of a download, oldest first
+func (p *PostProcessDB) GetResultsForDownload(downloadId int) ([]PostProcessResult, error) {
+	rows, err := p.db.Query(
+		`SELECT id, download_id, step_id, position, kind, status, detail, started_at, finished_at
+		 FROM postprocess_results WHERE download_id = ? ORDER BY id`,
+		downloadId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]PostProcessResult, 0)
+	for rows.Next() {
+		var result PostProcessResult
+		err := rows.Scan(
+			&result.ID, &result.DownloadID, &result.StepID, &result.Position, &result.Kind,
+			&result.Status, &result.Detail, &result.StartedAt, &result.FinishedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,54 @@
+package postprocess
+
+import "database/sql"
+
+// Kinds of post-processing steps
+const (
+	StepLoudnorm = "loudnorm" // Normalize audio loudness with ffmpeg loudnorm
+	StepH265     = "h265"     // Re-encode video to H.265, audio is copied
+	StepTag      = "tag"      // Write title, source URL and playlist name into the file's metadata
+	StepHardlink = "hardlink" // Hardlink the file into another directory, such as a media server library
+	StepShell    = "shell"    // Run a user command with the file's details in environment variables
+)
+
+// Outcome of a single step
+const (
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+	ResultSkipped = "skipped" // Step does not apply to the file
+)
+
+// PostProcessStep is a configured step of a playlist's pipeline, run in order of Position
+type PostProcessStep struct {
+	ID         int               `json:"id" db:"id"`
+	PlaylistID int               `json:"playlist_id" db:"playlist_id"`
+	Position   int               `json:"position" db:"position"`
+	Kind       string            `json:"kind" db:"kind"`
+	Options    map[string]string `json:"options" db:"options"`
+	IsEnabled  bool              `json:"is_enabled" db:"is_enabled"`
+}
+
+// PostProcessResult is the outcome of a step run against a download
+type PostProcessResult struct {
+	ID         int            `json:"id" db:"id"`
+	DownloadID int            `json:"download_id" db:"download_id"`
+	StepID     sql.NullInt64  `json:"step_id" db:"step_id"` // Steps are replaced when the pipeline is edited
+	Position   int            `json:"position" db:"position"`
+	Kind       string         `json:"kind" db:"kind"`
+	Status     string         `json:"status" db:"status"`
+	Detail     sql.NullString `json:"detail,omitempty" db:"detail"`
+	StartedAt  int64          `json:"started_at" db:"started_at"`
+	FinishedAt int64          `json:"finished_at" db:"finished_at"`
+}
+
+// StepInput describes the archived file a pipeline runs against
+type StepInput struct {
+	DownloadID   int
+	PlaylistID   int
+	PlaylistName string
+	FilePath     string
+	URL          string
+	Title        string
+	Format       string
+	MusicTagged  bool // Music tagging wrote the file's ID3v2.4 tags
+}
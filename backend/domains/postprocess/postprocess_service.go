@@ -0,0 +1,125 @@
+package postprocess
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+type PostProcessService struct {
+	db         *PostProcessDB
+	logService LogServiceInterface
+}
+
+func NewPostProcessService(db *PostProcessDB, logService LogServiceInterface) *PostProcessService {
+	return &PostProcessService{
+		db:         db,
+		logService: logService,
+	}
+}
+
+// GetSteps returns the post-processing pipeline of a playlist
+func (p *PostProcessService) GetSteps(playlistId int) ([]PostProcessStep, error) {
+	steps, err := p.db.GetSteps(playlistId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post-processing steps: %w", err)
+	}
+	return steps, nil
+}
+
+// SetSteps replaces the post-processing pipeline of a playlist, steps run in the given order
+func (p *PostProcessService) SetSteps(playlistId int, steps []PostProcessStep) error {
+	for i, step := range steps {
+		if err := ValidateStep(step); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	if err := p.db.ReplaceSteps(playlistId, steps); err != nil {
+		return fmt.Errorf("failed to save post-processing steps: %w", err)
+	}
+	p.logService.Info(fmt.Sprintf("Saved %d post-processing steps for playlist %d", len(steps), playlistId))
+	return nil
+}
+
+// GetStepDefaults returns the options of every kind of step with their default values
+func (p *PostProcessService) GetStepDefaults() map[string]map[string]string {
+	defaults := make(map[string]map[string]string, len(stepDefinitions))
+	for kind := range stepDefinitions {
+		defaults[kind] = resolveOptions(kind, nil)
+	}
+	return defaults
+}
+
+// GetResultsForDownload returns the step results recorded for a download
+func (p *PostProcessService) GetResultsForDownload(downloadId int) ([]PostProcessResult, error) {
+	results, err := p.db.GetResultsForDownload(downloadId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post-processing results: %w", err)
+	}
+	return results, nil
+}
+
+// Run executes the enabled steps of the playlist against an archived file and records each result.
+// A failed step leaves the file as it was and does not stop the following steps.
+// Returns true if any step rewrote the file, so its stored hash must be recalculated.
+func (p *PostProcessService) Run(ctx context.Context, input StepInput) bool {
+	steps, err := p.db.GetSteps(input.PlaylistID)
+	if err != nil {
+		p.logService.Error(fmt.Sprintf("Failed to get post-processing steps for playlist %d: %v", input.PlaylistID, err))
+		return false
+	}
+
+	modified := false
+	for _, step := range steps {
+		if !step.IsEnabled {
+			continue
+		}
+		if ctx.Err() != nil {
+			p.logService.Warn(fmt.Sprintf("Post-processing of %s cancelled", input.FilePath))
+			break
+		}
+
+		result := PostProcessResult{
+			DownloadID: input.DownloadID,
+			StepID:     sql.NullInt64{Int64: int64(step.ID), Valid: true},
+			Position:   step.Position,
+			Kind:       step.Kind,
+			StartedAt:  time.Now().Unix(),
+		}
+		outcome, err := p.runStep(ctx, step, input)
+		result.FinishedAt = time.Now().Unix()
+		if err != nil {
+			result.Status = ResultFailed
+			result.Detail = sql.NullString{String: err.Error(), Valid: true}
+			p.logService.Warn(fmt.Sprintf("Post-processing step %s failed for %s: %v", step.Kind, input.FilePath, err))
+		} else {
+			result.Status = outcome.status
+			result.Detail = sql.NullString{String: outcome.detail, Valid: outcome.detail != ""}
+			modified = modified || outcome.modified
+			p.logService.Info(fmt.Sprintf("Post-processing step %s %s for %s", step.Kind, outcome.status, input.FilePath))
+		}
+
+		if err := p.db.InsertResult(&result); err != nil {
+			p.logService.Error(fmt.Sprintf("Failed to record post-processing result for download %d: %v", input.DownloadID, err))
+		}
+	}
+	return modified
+}
+
+func (p *PostProcessService) runStep(ctx context.Context, step PostProcessStep, input StepInput) (stepOutcome, error) {
+	// Steps are validated when saved, this only guards against rows edited by hand
+	if err := ValidateStep(step); err != nil {
+		return stepOutcome{}, err
+	}
+	return stepDefinitions[step.Kind].run(ctx, resolveOptions(step.Kind, step.Options), input)
+}
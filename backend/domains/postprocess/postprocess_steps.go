@@ -0,0 +1,265 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"videoarchiver/backend/domains/fileutils"
+	"videoarchiver/backend/domains/runner"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+// Longest command output stored as a step result detail
+const maxDetailLength = 2000
+
+// stepOutcome is what a step reports back to the pipeline
+type stepOutcome struct {
+	status   string
+	detail   string
+	modified bool // File content was rewritten
+}
+
+// stepDefinition describes the options and behaviour of a kind of step
+type stepDefinition struct {
+	defaults map[string]string // Every option the step accepts, with its default value
+	required []string          // Options that must not be empty
+	numeric  []string          // Options that must be numbers
+	run      func(ctx context.Context, options map[string]string, input StepInput) (stepOutcome, error)
+}
+
+var stepDefinitions = map[string]stepDefinition{
+	StepLoudnorm: {
+		defaults: map[string]string{"target_lufs": "-16", "true_peak": "-1.5", "loudness_range": "11", "audio_bitrate": "192k"},
+		numeric:  []string{"target_lufs", "true_peak", "loudness_range"},
+		run:      runLoudnorm,
+	},
+	StepH265: {
+		defaults: map[string]string{"crf": "28", "preset": "medium"},
+		required: []string{"preset"},
+		numeric:  []string{"crf"},
+		run:      runH265,
+	},
+	StepTag: {
+		defaults: map[string]string{"album": ""}, // Empty uses the playlist name
+		run:      runTag,
+	},
+	StepHardlink: {
+		defaults: map[string]string{"directory": ""},
+		required: []string{"directory"},
+		run:      runHardlink,
+	},
+	StepShell: {
+		defaults: map[string]string{"command": "", "timeout_seconds": "600"},
+		required: []string{"command"},
+		numeric:  []string{"timeout_seconds"},
+		run:      runShell,
+	},
+}
+
+// Audio encoder used when re-encoding audio into a container, by extension
+var audioEncoders = map[string]string{
+	".mp3": "libmp3lame", ".m4a": "aac", ".aac": "aac", ".mp4": "aac", ".m4v": "aac", ".mov": "aac", ".mkv": "aac",
+	".ogg": "libvorbis", ".opus": "libopus", ".webm": "libopus", ".flac": "flac", ".wav": "pcm_s16le",
+}
+
+// Containers that can hold H.265 video, mapped to whether they need the hvc1 tag for Apple players
+var h265Containers = map[string]bool{
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": false, ".ts": false,
+}
+
+// resolveOptions fills in defaults for options the step does not set and drops unknown options
+func resolveOptions(kind string, options map[string]string) map[string]string {
+	resolved := make(map[string]string)
+	for key, value := range stepDefinitions[kind].defaults {
+		resolved[key] = value
+		if configured, ok := options[key]; ok && strings.TrimSpace(configured) != "" {
+			resolved[key] = strings.TrimSpace(configured)
+		}
+	}
+	return resolved
+}
+
+// ValidateStep checks that a step has a known kind and usable options
+func ValidateStep(step PostProcessStep) error {
+	definition, ok := stepDefinitions[step.Kind]
+	if !ok {
+		return fmt.Errorf("unknown post-processing step: %s", step.Kind)
+	}
+	options := resolveOptions(step.Kind, step.Options)
+	for _, key := range definition.required {
+		if options[key] == "" {
+			return fmt.Errorf("%s step requires the %s option", step.Kind, key)
+		}
+	}
+	for _, key := range definition.numeric {
+		if _, err := strconv.ParseFloat(options[key], 64); err != nil {
+			return fmt.Errorf("%s step option %s must be a number: %s", step.Kind, key, options[key])
+		}
+	}
+	return nil
+}
+
+// runLoudnorm normalizes the loudness of the audio, other streams are copied
+func runLoudnorm(ctx context.Context, options map[string]string, input StepInput) (stepOutcome, error) {
+	extension := strings.ToLower(filepath.Ext(input.FilePath))
+	encoder, ok := audioEncoders[extension]
+	if !ok {
+		return stepOutcome{status: ResultSkipped, detail: fmt.Sprintf("no audio encoder for %s files", extension)}, nil
+	}
+
+	filter := fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s", options["target_lufs"], options["true_peak"], options["loudness_range"])
+	// loudnorm upsamples to 192kHz, which most encoders do not accept
+	args := []string{"-map", "0", "-c", "copy", "-af", filter, "-ar", "48000", "-c:a", encoder}
+	if encoder != "flac" && encoder != "pcm_s16le" {
+		args = append(args, "-b:a", options["audio_bitrate"])
+	}
+	if err := rewriteWithFfmpeg(ctx, input.FilePath, args...); err != nil {
+		return stepOutcome{}, err
+	}
+	return stepOutcome{status: ResultSuccess, detail: filter, modified: true}, nil
+}
+
+// runH265 re-encodes the video stream to H.265, audio and subtitles are copied
+func runH265(ctx context.Context, options map[string]string, input StepInput) (stepOutcome, error) {
+	if fileutils.IsAudioFile(input.FilePath) {
+		return stepOutcome{status: ResultSkipped, detail: "audio-only file"}, nil
+	}
+	extension := strings.ToLower(filepath.Ext(input.FilePath))
+	needsTag, ok := h265Containers[extension]
+	if !ok {
+		return stepOutcome{status: ResultSkipped, detail: fmt.Sprintf("%s files cannot hold H.265 video", extension)}, nil
+	}
+
+	args := []string{"-map", "0", "-c", "copy", "-c:v", "libx265", "-crf", options["crf"], "-preset", options["preset"]}
+	if needsTag {
+		args = append(args, "-tag:v", "hvc1")
+	}
+	if err := rewriteWithFfmpeg(ctx, input.FilePath, args...); err != nil {
+		return stepOutcome{}, err
+	}
+	return stepOutcome{status: ResultSuccess, detail: fmt.Sprintf("crf %s, preset %s", options["crf"], options["preset"]), modified: true}, nil
+}
+
+// runTag writes the title, source URL and album into the file's metadata without re-encoding
+func runTag(ctx context.Context, options map[string]string, input StepInput) (stepOutcome, error) {
+	// Rewriting would replace the parsed title and downgrade the tags to ID3v2.3
+	if input.MusicTagged {
+		return stepOutcome{status: ResultSkipped, detail: "already tagged by music tagging"}, nil
+	}
+	album := options["album"]
+	if album == "" {
+		album = input.PlaylistName
+	}
+
+	args := []string{"-map", "0", "-c", "copy", "-metadata", "title=" + input.Title, "-metadata", "comment=" + input.URL}
+	if album != "" {
+		args = append(args, "-metadata", "album="+album)
+	}
+	if strings.EqualFold(filepath.Ext(input.FilePath), ".mp3") {
+		// ID3v2.3 is the version most players read
+		args = append(args, "-id3v2_version", "3")
+	}
+	if err := rewriteWithFfmpeg(ctx, input.FilePath, args...); err != nil {
+		return stepOutcome{}, err
+	}
+	return stepOutcome{status: ResultSuccess, modified: true}, nil
+}
+
+// runHardlink links the file into the configured directory under the same name
+func runHardlink(ctx context.Context, options map[string]string, input StepInput) (stepOutcome, error) {
+	directory := options["directory"]
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return stepOutcome{}, fmt.Errorf("failed to create directory %s: %w", directory, err)
+	}
+
+	linkPath := filepath.Join(directory, filepath.Base(input.FilePath))
+	if existing, err := os.Stat(linkPath); err == nil {
+		source, err := os.Stat(input.FilePath)
+		if err == nil && os.SameFile(source, existing) {
+			return stepOutcome{status: ResultSkipped, detail: "already linked at " + linkPath}, nil
+		}
+		return stepOutcome{}, fmt.Errorf("a different file already exists at %s", linkPath)
+	}
+
+	// Hardlinks cannot cross filesystems, the library must be on the same drive as the archive
+	if err := os.Link(input.FilePath, linkPath); err != nil {
+		return stepOutcome{}, fmt.Errorf("failed to create hardlink: %w", err)
+	}
+	return stepOutcome{status: ResultSuccess, detail: linkPath}, nil
+}
+
+// runShell runs the configured command in the system shell, the file is described in environment variables
+func runShell(ctx context.Context, options map[string]string, input StepInput) (stepOutcome, error) {
+	timeoutSeconds, _ := strconv.ParseFloat(options["timeout_seconds"], 64)
+	if timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds*float64(time.Second)))
+		defer cancel()
+	}
+
+	before, _ := os.Stat(input.FilePath)
+	name, args := runner.ShellCommand(options["command"])
+	output, err := runner.RunCombinedOutputWithEnv(ctx, shellEnv(input), name, args...)
+	detail := truncateDetail(strings.TrimSpace(string(output)))
+	if ctx.Err() == context.DeadlineExceeded {
+		return stepOutcome{}, fmt.Errorf("command timed out after %s seconds: %s", options["timeout_seconds"], detail)
+	}
+	if err != nil {
+		return stepOutcome{}, fmt.Errorf("command failed: %v: %s", err, detail)
+	}
+
+	// The command may have rewritten the file in place
+	after, err := os.Stat(input.FilePath)
+	modified := err == nil && before != nil && (after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()))
+	return stepOutcome{status: ResultSuccess, detail: detail, modified: modified}, nil
+}
+
+// shellEnv describes the archived file to shell commands
+func shellEnv(input StepInput) []string {
+	return []string{
+		"VIDEOARCHIVER_PATH=" + input.FilePath,
+		"VIDEOARCHIVER_URL=" + input.URL,
+		"VIDEOARCHIVER_TITLE=" + input.Title,
+		"VIDEOARCHIVER_FORMAT=" + input.Format,
+		"VIDEOARCHIVER_PLAYLIST=" + input.PlaylistName,
+		"VIDEOARCHIVER_DOWNLOAD_ID=" + strconv.Itoa(input.DownloadID),
+		"VIDEOARCHIVER_PLAYLIST_ID=" + strconv.Itoa(input.PlaylistID),
+	}
+}
+
+// rewriteWithFfmpeg writes a processed copy next to the file and replaces the file with it
+func rewriteWithFfmpeg(ctx context.Context, filePath string, args ...string) error {
+	ffmpegPath, err := ytdlp.GetFfmpegPath()
+	if err != nil {
+		return fmt.Errorf("failed to get ffmpeg path: %w", err)
+	}
+
+	// Keep the extension so ffmpeg picks the same container
+	outputPath := filePath + ".postprocess" + filepath.Ext(filePath)
+	defer os.Remove(outputPath)
+
+	fullArgs := append([]string{"-nostdin", "-v", "error", "-i", filePath}, args...)
+	fullArgs = append(fullArgs, "-y", outputPath)
+	output, err := runner.RunCombinedOutputWithEnv(ctx, nil, ffmpegPath, fullArgs...)
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, truncateDetail(strings.TrimSpace(string(output))))
+	}
+	if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+		return fmt.Errorf("ffmpeg produced no output")
+	}
+	if err := os.Rename(outputPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace file with processed version: %w", err)
+	}
+	return nil
+}
+
+func truncateDetail(detail string) string {
+	if len(detail) > maxDetailLength {
+		return detail[:maxDetailLength] + "..."
+	}
+	return detail
+}
@@ -0,0 +1,116 @@
+package postprocess
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestValidateStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    PostProcessStep
+		wantErr bool
+	}{
+		{"defaults", PostProcessStep{Kind: StepLoudnorm}, false},
+		{"unknown kind", PostProcessStep{Kind: "transcode"}, true},
+		{"missing directory", PostProcessStep{Kind: StepHardlink}, true},
+		{"blank command", PostProcessStep{Kind: StepShell, Options: map[string]string{"command": "  "}}, true},
+		{"non-numeric crf", PostProcessStep{Kind: StepH265, Options: map[string]string{"crf": "high"}}, true},
+		{"configured", PostProcessStep{Kind: StepH265, Options: map[string]string{"crf": "23", "preset": "slow"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateStep(tt.step); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	options := resolveOptions(StepH265, map[string]string{"crf": " 23 ", "preset": "", "unknown": "x"})
+	if options["crf"] != "23" || options["preset"] != "medium" {
+		t.Errorf("Unexpected options: %+v", options)
+	}
+	if _, ok := options["unknown"]; ok {
+		t.Error("Expected unknown options to be dropped")
+	}
+}
+
+func TestRunHardlink(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "archive", "video.mp4")
+	library := filepath.Join(dir, "library")
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := StepInput{FilePath: source}
+	options := map[string]string{"directory": library}
+	outcome, err := runHardlink(context.Background(), options, input)
+	if err != nil {
+		t.Skipf("Hardlinks not supported: %v", err)
+	}
+	if outcome.status != ResultSuccess || outcome.modified {
+		t.Errorf("Unexpected outcome: %+v", outcome)
+	}
+
+	// Running again finds the existing link
+	outcome, err = runHardlink(context.Background(), options, input)
+	if err != nil || outcome.status != ResultSkipped {
+		t.Errorf("Expected existing link to be skipped, got %+v (%v)", outcome, err)
+	}
+}
+
+func TestRunTagSkipsMusicTagged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(path, []byte("ID3 content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := StepInput{FilePath: path, Title: "Artist - Song (Official Video)", MusicTagged: true}
+	outcome, err := runTag(context.Background(), resolveOptions(StepTag, nil), input)
+	if err != nil || outcome.status != ResultSkipped || outcome.modified {
+		t.Errorf("Expected music tagged files to be skipped, got %+v (%v)", outcome, err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "ID3 content" {
+		t.Errorf("Expected the tags to be left untouched, got %q (%v)", content, err)
+	}
+}
+
+func TestRunShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell syntax differs on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := StepInput{FilePath: path, URL: "https://example.com/watch?v=1", Title: "Song"}
+
+	options := resolveOptions(StepShell, map[string]string{"command": `echo "$VIDEOARCHIVER_TITLE $VIDEOARCHIVER_URL"`})
+	outcome, err := runShell(context.Background(), options, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if outcome.detail != "Song https://example.com/watch?v=1" || outcome.modified {
+		t.Errorf("Unexpected outcome: %+v", outcome)
+	}
+
+	options = resolveOptions(StepShell, map[string]string{"command": `printf more >> "$VIDEOARCHIVER_PATH"`})
+	if outcome, err = runShell(context.Background(), options, input); err != nil || !outcome.modified {
+		t.Errorf("Expected file change to be detected, got %+v (%v)", outcome, err)
+	}
+
+	options = resolveOptions(StepShell, map[string]string{"command": "echo broken >&2; exit 3"})
+	if _, err = runShell(context.Background(), options, input); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected failure with command output, got %v", err)
+	}
+}
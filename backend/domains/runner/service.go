@@ -2,10 +2,15 @@ package runner
 
 import (
 	"bytes"
+	"context"
+	"os"
 	"os/exec"
 	"time"
 )
 
+// How long a cancelled command may keep its output open, after which it is no longer waited on
+const cancelWaitDelay = 5 * time.Second
+
 // StartDetached starts a command and immediately returns without waiting.
 // Used for operations like opening directories or starting daemon processes.
 // OS-specific implementations handle console window hiding on Windows.
@@ -57,6 +62,18 @@ func RunCombinedOutput(name string, args ...string) ([]byte, error) {
 	return cmd.CombinedOutput()
 }
 
+// RunCombinedOutputWithEnv executes a command with extra environment variables and returns the combined output.
+// Used for user supplied commands, the command and the processes it started are killed when the context is cancelled.
+func RunCombinedOutputWithEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	configureProcessAttributes(cmd)
+	configureCancel(cmd)
+	// A child that outlives the killed command would otherwise hold the output pipe open indefinitely
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.CombinedOutput()
+}
+
 // RunCombinedOutputWithTimeout executes a command with a timeout and returns the combined output.
 // This is specifically used for corruption checks to prevent hanging on corrupted binaries.
 func RunCombinedOutputWithTimeout(timeout time.Duration, name string, args ...string) ([]byte, error) {
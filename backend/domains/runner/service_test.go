@@ -0,0 +1,26 @@
+package runner
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunCombinedOutputWithEnvKillsChildren(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a Unix shell")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The background sleep inherits the output pipe and would keep it open after the shell is killed
+	name, args := ShellCommand("sleep 30 & sleep 30")
+	start := time.Now()
+	if _, err := RunCombinedOutputWithEnv(ctx, nil, name, args...); err == nil {
+		t.Fatal("Expected the cancelled command to fail")
+	}
+	if elapsed := time.Since(start); elapsed >= cancelWaitDelay {
+		t.Errorf("Expected the command and its children to be killed on cancellation, took %v", elapsed)
+	}
+}
@@ -19,6 +19,14 @@ func configureProcessAttributes(cmd *exec.Cmd) {
 	}
 }
 
+// configureCancel kills the whole process group of a command on cancellation, including shells' children.
+// The command must have been configured with configureProcessAttributes.
+func configureCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
 // configureProcessAttributesWithFlags sets up process isolation with flags on Unix systems.
 func configureProcessAttributesWithFlags(cmd *exec.Cmd, creationFlags uint32) {
 	// On Unix, we ignore creationFlags and use standard isolation
@@ -54,3 +62,8 @@ func runWithTimeoutCheck(timeout time.Duration, name string, args ...string) err
 	}
 	return nil
 }
+
+// ShellCommand returns the program and arguments that run a command line in the system shell.
+func ShellCommand(command string) (string, []string) {
+	return "/bin/sh", []string{"-c", command}
+}
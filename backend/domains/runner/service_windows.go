@@ -15,6 +15,10 @@ func configureProcessAttributes(cmd *exec.Cmd) {
 	}
 }
 
+// configureCancel keeps the default cancellation on Windows, which kills the command itself.
+// Children that keep the output open are released by the command's WaitDelay.
+func configureCancel(cmd *exec.Cmd) {}
+
 // configureProcessAttributesWithFlags sets Windows-specific process attributes with custom creation flags.
 func configureProcessAttributesWithFlags(cmd *exec.Cmd, creationFlags uint32) {
 	cmd.SysProcAttr = &syscall.SysProcAttr{
@@ -29,3 +33,8 @@ func runWithTimeoutCheck(timeout time.Duration, name string, args ...string) err
 	configureProcessAttributes(cmd)
 	return cmd.Run()
 }
+
+// ShellCommand returns the program and arguments that run a command line in the system shell.
+func ShellCommand(command string) (string, []string) {
+	return "cmd", []string{"/C", command}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"runtime"
 	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/pathing"
 	"videoarchiver/backend/domains/runner"
 )
 
@@ -47,6 +48,10 @@ func (h *AutostartServiceHandler) handleWindowsAutostart(enable bool, logger *lo
 	registryKey := `HKCU\Software\Microsoft\Windows\CurrentVersion\Run`
 	valueName := "Video Archiver Daemon"
 	command := fmt.Sprintf(`"%s" --mode daemon`, execPath)
+	if dataDir := pathing.GetDataDirOverride(); dataDir != "" {
+		// Autostarted daemon must use the same relocated working directory
+		command += fmt.Sprintf(` --data-dir "%s"`, dataDir)
+	}
 
 	if enable {
 		// Add registry key
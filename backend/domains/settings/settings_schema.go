@@ -0,0 +1,310 @@
+package settings
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Types of setting values, matching the setting types the UI renders
+const (
+	TypeBool        = "bool"
+	TypeInt         = "int"
+	TypeFloat       = "float"
+	TypeString      = "string"
+	TypeSelect      = "select"      // One of AllowedValues
+	TypeMultiSelect = "multiselect" // Comma separated list of AllowedValues, may be empty
+)
+
+// SettingDefinition describes a setting, its default and which values it accepts
+type SettingDefinition struct {
+	Key           string   `json:"key"`
+	Type          string   `json:"type"`
+	Default       string   `json:"default"`
+	AllowedValues []string `json:"allowed_values,omitempty"` // For select and multiselect settings
+	Min           *float64 `json:"min,omitempty"`            // For int and float settings
+	Max           *float64 `json:"max,omitempty"`
+	Label         string   `json:"label"`
+	Description   string   `json:"description"`
+	Internal      bool     `json:"internal"` // Managed by the application itself, not shown in settings
+
+	validate func(value string) error // Extra validation beyond the type
+}
+
+var sponsorblockCategories = []string{
+	"sponsor", "intro", "outro", "selfpromo", "interaction", "music_offtopic", "preview", "filler", "chapter",
+}
+
+// schema lists every setting in the order the UI shows them.
+// New settings only need an entry here, missing rows are filled in with the default on startup.
+var schema = []SettingDefinition{
+	{Key: "autostart_service", Type: TypeBool, Default: "true",
+		Label: "Autostart Downloader Service", Description: "Start the downloader service when the computer starts"},
+	{Key: "autoupdate_ytdlp", Type: TypeBool, Default: "true",
+		Label: "Autoupdate yt-dlp", Description: "Update yt-dlp when a new version is available"},
+	{Key: "ytdlp_update_interval_hours", Type: TypeInt, Default: "24", Min: bound(0),
+		Label: "yt-dlp Update Interval (hours)", Description: "Hours between update checks while the downloader service runs, 0 only checks on start"},
+	{Key: "ytdlp_update_failure_threshold", Type: TypeInt, Default: "3", Min: bound(0),
+		Label: "yt-dlp Update After Failures", Description: "Failed downloads that point to an outdated yt-dlp before it is updated right away, 0 disables this"},
+	{Key: "ytdlp_version", Type: TypeString, Default: "", validate: validateReleaseTag,
+		Label: "yt-dlp Version", Description: "yt-dlp release to install, such as 2025.01.26, empty follows the latest release. Applied on the next start"},
+	{Key: "ffmpeg_version", Type: TypeString, Default: "", validate: validateReleaseTag,
+		Label: "FFmpeg Version", Description: "FFmpeg build to install, a BtbN release tag on Linux or a gyan.dev version such as 7.1 on Windows, empty uses the latest build. Applied on the next start"},
+	{Key: "tool_source", Type: TypeSelect, Default: "managed", AllowedValues: []string{"managed", "system", "custom"},
+		Label: "Tool Source", Description: "Where yt-dlp, ffmpeg and ffprobe come from: downloaded by the application, found on PATH, or the paths below. Applied on the next start"},
+	{Key: "ytdlp_path", Type: TypeString, Default: "",
+		Label: "yt-dlp Path", Description: "yt-dlp executable used when the tool source is custom, empty downloads it"},
+	{Key: "ffmpeg_path", Type: TypeString, Default: "",
+		Label: "FFmpeg Path", Description: "ffmpeg executable used when the tool source is custom, set together with the ffprobe path, empty downloads both"},
+	{Key: "ffprobe_path", Type: TypeString, Default: "",
+		Label: "FFprobe Path", Description: "ffprobe executable used when the tool source is custom, set together with the ffmpeg path"},
+	{Key: "tool_bundle_path", Type: TypeString, Default: "",
+		Label: "Offline Tool Bundle", Description: "Directory or archive with yt-dlp, ffmpeg and ffprobe to install instead of downloading them, verified against a SHA2-256SUMS file in it if there is one"},
+	{Key: "ytdlp_extractor_args", Type: TypeString, Default: "", validate: validateExtractorArgs,
+		Label: "yt-dlp Extractor Arguments", Description: "Passed to every yt-dlp call with --extractor-args, one EXTRACTOR:ARGS per line, such as youtube:player_client=default,mweb"},
+	{Key: "ytdlp_plugins", Type: TypeString, Default: "", validate: validatePlugins,
+		Label: "yt-dlp Plugins", Description: "Plugins installed into the plugins directory, one NAME=URL per line. The URL points to a .zip or .whl and can end in #sha256=CHECKSUM to verify it. Applied on the next start"},
+	{Key: "browser_credentials_source", Type: TypeSelect, Default: "none",
+		AllowedValues: []string{"none", "chrome", "firefox", "edge", "opera", "brave", "safari"},
+		Label:         "Browser Credentials Source", Description: "Export browser cookies for authenticated downloads"},
+	{Key: "allow_duplicates", Type: TypeBool, Default: "false",
+		Label: "Allow Duplicate Downloads", Description: "Re-download videos even if they already exist in your directories"},
+	{Key: "sponsorblock_video", Type: TypeMultiSelect, Default: "sponsor,intro,outro,selfpromo,interaction,preview,filler",
+		AllowedValues: sponsorblockCategories,
+		Label:         "SponsorBlock Video", Description: "Segments SponsorBlock removes from videos"},
+	{Key: "sponsorblock_audio", Type: TypeMultiSelect, Default: "sponsor,selfpromo,interaction,preview,filler",
+		AllowedValues: sponsorblockCategories,
+		Label:         "SponsorBlock Music", Description: "Segments SponsorBlock removes from music"},
+	{Key: "hash_algorithm", Type: TypeSelect, Default: "xxh3",
+		AllowedValues: []string{"xxh3", "sha256", "md5"},
+		Label:         "Hash Algorithm", Description: "Algorithm used to hash archived and registered files, existing hashes are migrated a few minutes per daemon run after a change"},
+	{Key: "fingerprinting_enabled", Type: TypeBool, Default: "false",
+		Label: "Audio Fingerprinting", Description: "Detect duplicates that differ in encoding by their audio fingerprint"},
+	{Key: "fingerprint_similarity_threshold", Type: TypeFloat, Default: "0.80", Min: bound(0), Max: bound(1),
+		Label: "Fingerprint Similarity Threshold", Description: "Similarity from 0 to 1 at which two fingerprints count as the same recording"},
+	{Key: "integrity_check_interval_days", Type: TypeInt, Default: "30", Min: bound(0),
+		Label: "Integrity Check Interval (days)", Description: "Days between archive integrity checks, 0 disables them"},
+	{Key: "integrity_check_corruption", Type: TypeBool, Default: "false",
+		Label: "Check For Corruption", Description: "Decode files during integrity checks to find corruption, this is slow"},
+	{Key: "integrity_requeue_missing", Type: TypeBool, Default: "false",
+		Label: "Requeue Missing Files", Description: "Download files again when an integrity check finds them missing"},
+	{Key: "registry_reconcile_interval_hours", Type: TypeInt, Default: "24", Min: bound(1),
+		Label: "Registry Reconcile Interval (hours)", Description: "Hours between full rescans of watched directories"},
+	{Key: "registry_scan_workers", Type: TypeInt, Default: "4", Min: bound(1), Max: bound(32),
+		Label: "Registry Scan Workers", Description: "Files hashed in parallel when scanning directories"},
+	{Key: "duplicate_quarantine_directory", Type: TypeString, Default: "",
+		Label: "Duplicate Quarantine Directory", Description: "Directory duplicates are moved to when quarantined, empty disables quarantine"},
+	{Key: "corruption_scan_concurrency", Type: TypeInt, Default: "2", Min: bound(1), Max: bound(16),
+		Label: "Corruption Scan Concurrency", Description: "Files checked in parallel by a corruption scan"},
+	{Key: "corruption_tolerated_classes", Type: TypeMultiSelect, Default: "",
+		AllowedValues: []string{"timestamps", "decode", "truncated", "io", "unreadable", "unknown"},
+		Label:         "Tolerated Corruption", Description: "Kinds of corruption that are reported but not repaired"},
+	{Key: "corruption_repair_strategies", Type: TypeString, Default: "remux,reencode_cfr",
+		Label: "Repair Strategies", Description: "Comma separated repair strategies, tried in order"},
+	{Key: "corruption_max_repair_attempts", Type: TypeInt, Default: "2", Min: bound(0),
+		Label: "Max Repair Attempts", Description: "Repair attempts per file before it is downloaded again"},
+	{Key: "music_tagging_enabled", Type: TypeBool, Default: "true",
+		Label: "Music Tagging", Description: "Tag mp3 downloads with artist, title and album parsed from the video"},
+	{Key: "music_title_patterns", Type: TypeString, Default: "{artist} - {title}\n{artist} – {title}\n{artist} — {title}",
+		Label: "Title Patterns", Description: "Patterns matched against video titles, one per line"},
+	{Key: "music_description_patterns", Type: TypeString, Default: `Provided to YouTube by {ignore}\n{title} · {artist}\n{album}`,
+		Label: "Description Patterns", Description: `Patterns matched against video descriptions, one per line, \n matches a line break`},
+	{Key: "musicbrainz_endpoint", Type: TypeString, Default: "", validate: validateURL,
+		Label: "MusicBrainz Endpoint", Description: "MusicBrainz server used to complete tags, empty disables lookups"},
+	{Key: "playlist_files_enabled", Type: TypeBool, Default: "true",
+		Label: "Playlist Files", Description: "Write an M3U8 playlist in source order next to every playlist's files"},
+	{Key: "playlist_files_xspf", Type: TypeBool, Default: "false",
+		Label: "XSPF Playlist Files", Description: "Also write an XSPF playlist"},
+	{Key: "backup_enabled", Type: TypeBool, Default: "true",
+		Label: "Scheduled Backups", Description: "Back up the database on a schedule"},
+	{Key: "backup_interval_hours", Type: TypeInt, Default: "24", Min: bound(1),
+		Label: "Backup Interval (hours)", Description: "Hours between database backups"},
+	{Key: "backup_keep_count", Type: TypeInt, Default: "7", Min: bound(1),
+		Label: "Backups To Keep", Description: "Older backups are removed"},
+	{Key: "backup_directory", Type: TypeString, Default: "",
+		Label: "Backup Directory", Description: "Directory backups are written to, empty uses a backups directory next to the database"},
+
+	{Key: "daemon_signal", Type: TypeInt, Default: "0", Internal: true,
+		Label: "Daemon Signal", Description: "Set by the UI to make the daemon run an iteration"},
+	{Key: "legal_disclaimer_accepted", Type: TypeBool, Default: "false", Internal: true,
+		Label: "Legal Disclaimer Accepted", Description: "Whether the legal disclaimer was accepted"},
+	{Key: "direct_download_last_path", Type: TypeString, Default: "", Internal: true,
+		Label: "Last Direct Download Directory", Description: "Directory of the last direct download"},
+	{Key: "direct_download_last_format", Type: TypeSelect, Default: "mp4", AllowedValues: []string{"mp4", "mp3"}, Internal: true,
+		Label: "Last Direct Download Format", Description: "Format of the last direct download"},
+}
+
+var definitions = indexSchema()
+
+var (
+	releaseTagPattern     = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	extractorArgsPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+:\S.*$`)
+	pluginChecksumPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+func indexSchema() map[string]SettingDefinition {
+	index := make(map[string]SettingDefinition, len(schema))
+	for _, definition := range schema {
+		index[definition.Key] = definition
+	}
+	return index
+}
+
+func bound(value float64) *float64 {
+	return &value
+}
+
+// GetSchema returns the definition of every setting in display order
+func GetSchema() []SettingDefinition {
+	result := make([]SettingDefinition, len(schema))
+	copy(result, schema)
+	return result
+}
+
+// GetDefinition returns the definition of a setting, false if the key is unknown
+func GetDefinition(key string) (SettingDefinition, bool) {
+	definition, ok := definitions[key]
+	return definition, ok
+}
+
+// ValidateSettingValue returns an error if the key is unknown or the value does not fit its definition
+func ValidateSettingValue(key, value string) error {
+	definition, ok := definitions[key]
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+	if err := definition.validateValue(value); err != nil {
+		return fmt.Errorf("invalid value for setting %s: %w", key, err)
+	}
+	return nil
+}
+
+func (d SettingDefinition) validateValue(value string) error {
+	switch d.Type {
+	case TypeBool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q must be true or false", value)
+		}
+	case TypeInt:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		if err := d.checkRange(float64(number)); err != nil {
+			return err
+		}
+	case TypeFloat:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		if err := d.checkRange(number); err != nil {
+			return err
+		}
+	case TypeSelect:
+		if !d.isAllowed(value) {
+			return fmt.Errorf("%q must be one of %s", value, strings.Join(d.AllowedValues, ", "))
+		}
+	case TypeMultiSelect:
+		if value != "" {
+			for _, item := range strings.Split(value, ",") {
+				if !d.isAllowed(item) {
+					return fmt.Errorf("%q must be one of %s", item, strings.Join(d.AllowedValues, ", "))
+				}
+			}
+		}
+	}
+	if d.validate != nil {
+		return d.validate(value)
+	}
+	return nil
+}
+
+func (d SettingDefinition) checkRange(number float64) error {
+	if d.Min != nil && number < *d.Min {
+		return fmt.Errorf("%v is below the minimum of %v", number, *d.Min)
+	}
+	if d.Max != nil && number > *d.Max {
+		return fmt.Errorf("%v is above the maximum of %v", number, *d.Max)
+	}
+	return nil
+}
+
+func (d SettingDefinition) isAllowed(value string) bool {
+	for _, allowed := range d.AllowedValues {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// validateReleaseTag accepts an empty value or a release tag that is safe to put in a download URL
+func validateReleaseTag(value string) error {
+	if value == "" || releaseTagPattern.MatchString(value) {
+		return nil
+	}
+	return fmt.Errorf("%q is not a release tag", value)
+}
+
+// validateExtractorArgs accepts lines of an extractor key and its arguments, empty lines are ignored
+func validateExtractorArgs(value string) error {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !extractorArgsPattern.MatchString(line) {
+			return fmt.Errorf("%q is not EXTRACTOR:ARGS", line)
+		}
+	}
+	return nil
+}
+
+// validatePlugins accepts lines of a plugin name and the URL of its .zip or .whl, empty lines are ignored
+func validatePlugins(value string) error {
+	names := map[string]bool{}
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, pluginUrl, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || !releaseTagPattern.MatchString(name) {
+			return fmt.Errorf("%q is not NAME=URL", line)
+		}
+		if names[name] {
+			return fmt.Errorf("plugin %s is listed twice", name)
+		}
+		names[name] = true
+
+		pluginUrl, checksum, hasChecksum := strings.Cut(strings.TrimSpace(pluginUrl), "#sha256=")
+		if hasChecksum && !pluginChecksumPattern.MatchString(checksum) {
+			return fmt.Errorf("plugin %s: %q is not a SHA-256 checksum", name, checksum)
+		}
+		if pluginUrl == "" {
+			return fmt.Errorf("plugin %s has no URL", name)
+		}
+		if err := validateURL(pluginUrl); err != nil {
+			return fmt.Errorf("plugin %s: %w", name, err)
+		}
+		extension := strings.ToLower(path.Ext(strings.SplitN(pluginUrl, "?", 2)[0]))
+		if extension != ".zip" && extension != ".whl" {
+			return fmt.Errorf("plugin %s: %s is not a .zip or .whl", name, pluginUrl)
+		}
+	}
+	return nil
+}
+
+// validateURL accepts an empty value or an absolute http(s) URL
+func validateURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", value)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package settings
+
+import "testing"
+
+func TestSchemaDefaultsAreValid(t *testing.T) {
+	seen := make(map[string]bool, len(schema))
+	for _, definition := range schema {
+		if seen[definition.Key] {
+			t.Errorf("setting %s is defined more than once", definition.Key)
+		}
+		seen[definition.Key] = true
+		if err := ValidateSettingValue(definition.Key, definition.Default); err != nil {
+			t.Errorf("default of %s is invalid: %v", definition.Key, err)
+		}
+	}
+}
+
+func TestValidateSettingValue(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{"allow_duplicates", "true", true},
+		{"allow_duplicates", "false", true},
+		{"allow_duplicates", "1", false},
+		{"allow_duplicates", "True", false},
+		{"registry_scan_workers", "8", true},
+		{"registry_scan_workers", "0", false},
+		{"registry_scan_workers", "64", false},
+		{"registry_scan_workers", "4.5", false},
+		{"fingerprint_similarity_threshold", "0.95", true},
+		{"fingerprint_similarity_threshold", "1.5", false},
+		{"hash_algorithm", "sha256", true},
+		{"hash_algorithm", "crc32", false},
+		{"sponsorblock_video", "", true},
+		{"sponsorblock_video", "sponsor,intro", true},
+		{"sponsorblock_video", "sponsor,ads", false},
+		{"musicbrainz_endpoint", "", true},
+		{"musicbrainz_endpoint", "https://musicbrainz.org", true},
+		{"musicbrainz_endpoint", "musicbrainz.org", false},
+		{"ytdlp_extractor_args", "youtube:player_client=default,mweb\n\ngeneric:impersonate", true},
+		{"ytdlp_extractor_args", "player_client=default", false},
+		{"ytdlp_plugins", "", true},
+		{"ytdlp_plugins", "pot=https://example.com/pot.zip\nextra=https://example.com/extra-1.0-py3-none-any.whl#sha256=e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", true},
+		{"ytdlp_plugins", "pot=https://example.com/pot.tar.gz", false},
+		{"ytdlp_plugins", "pot=https://example.com/pot.zip#sha256=abc", false},
+		{"ytdlp_plugins", "pot=https://example.com/a.zip\npot=https://example.com/b.zip", false},
+		{"ytdlp_plugins", "../pot=https://example.com/pot.zip", false},
+		{"no_such_setting", "true", false},
+	}
+	for _, test := range tests {
+		err := ValidateSettingValue(test.key, test.value)
+		if test.valid && err != nil {
+			t.Errorf("%s=%q: unexpected error: %v", test.key, test.value, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s=%q: expected an error", test.key, test.value)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package settings
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"sort"
 	"videoarchiver/backend/domains/db"
 	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/overrides"
 )
 
-// Settings must be created in a migration.
+// Settings must be defined in the schema, see settings_schema.go.
 type SettingsService struct {
 	db       *sql.DB
 	handlers map[string]SettingHandler
@@ -22,34 +25,104 @@ func NewSettingsService(db *db.DatabaseService, logger *logging.LogService) *Set
 	}
 }
 
-// GetSettingString gets the raw value from the database
+// EnsureDefaults inserts the default of every setting in the schema that has no row yet
+func (s *SettingsService) EnsureDefaults() error {
+	for _, definition := range schema {
+		_, err := s.db.Exec(
+			"INSERT OR IGNORE INTO settings (setting_key, setting_value) VALUES (?, ?)",
+			definition.Key, definition.Default,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert default for setting %s: %w", definition.Key, err)
+		}
+	}
+	return nil
+}
+
+// GetSettingString gets the effective value: flag > environment > database > default
 func (s *SettingsService) GetSettingString(key string) (string, error) {
+	definition, ok := definitions[key]
+	if !ok {
+		return "", fmt.Errorf("unknown setting: %s", key)
+	}
+	if override, ok := s.GetSettingOverride(key); ok {
+		return override.Value, nil
+	}
 	row := s.db.QueryRow("SELECT setting_value FROM settings WHERE setting_key = ?", key)
 	var value string
 	err := row.Scan(&value)
 	if err == sql.ErrNoRows {
-		return "", errors.New("setting not found")
+		return definition.Default, nil
 	}
 	return value, err
 }
 
-// GetSettingBool gets a boolean setting from the database
+// GetSettingBool gets a boolean setting, values other than true or false are an error
 func (s *SettingsService) GetSettingBool(key string) (bool, error) {
 	value, err := s.GetSettingString(key)
 	if err != nil {
 		return false, err
 	}
-	return value == "true", nil
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("setting %s is not a boolean: %q", key, value)
+}
+
+// GetSettingOverride returns the flag or environment variable overriding a setting, if any.
+// Internal settings cannot be overridden.
+func (s *SettingsService) GetSettingOverride(key string) (overrides.Override, bool) {
+	definition, ok := definitions[key]
+	if !ok || definition.Internal {
+		return overrides.Override{}, false
+	}
+	return overrides.LookupSetting(key)
+}
+
+// ValidateOverrides checks every setting override from flags and environment variables, returning every problem at once
+func (s *SettingsService) ValidateOverrides() error {
+	var errs []error
+	flagKeys := make([]string, 0, len(overrides.GetSettingFlags()))
+	for key := range overrides.GetSettingFlags() {
+		flagKeys = append(flagKeys, key)
+	}
+	sort.Strings(flagKeys)
+	for _, key := range flagKeys {
+		if definition, ok := definitions[key]; ok && definition.Internal {
+			errs = append(errs, fmt.Errorf("setting %s is managed by the application and cannot be overridden", key))
+		} else if !ok {
+			errs = append(errs, fmt.Errorf("unknown setting in --setting flag: %s", key))
+		}
+	}
+
+	for _, definition := range schema {
+		override, ok := s.GetSettingOverride(definition.Key)
+		if !ok {
+			continue
+		}
+		if err := ValidateSettingValue(definition.Key, override.Value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", override.Source, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// Set inserts or updates the setting value and triggers handlers
+// Set validates and stores the setting value and triggers handlers
 func (s *SettingsService) SetPreparsed(key string, value string) error {
+	if err := ValidateSettingValue(key, value); err != nil {
+		return err
+	}
+
 	// Get old value for handler
-	oldValue, _ := s.GetSettingString(key) // Ignore error - may not exist
+	oldValue, _ := s.GetSettingString(key)
 
 	_, err := s.db.Exec(`
-		UPDATE settings SET setting_value = ? WHERE setting_key = ?
-	`, value, key)
+		INSERT INTO settings (setting_key, setting_value) VALUES (?, ?)
+		ON CONFLICT(setting_key) DO UPDATE SET setting_value = excluded.setting_value
+	`, key, value)
 	if err != nil {
 		return err
 	}
@@ -66,3 +139,22 @@ func (s *SettingsService) SetPreparsed(key string, value string) error {
 
 	return nil
 }
+
+// GetAllSettings returns every stored setting with its raw value
+func (s *SettingsService) GetAllSettings() (map[string]string, error) {
+	rows, err := s.db.Query("SELECT setting_key, setting_value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, rows.Err()
+}
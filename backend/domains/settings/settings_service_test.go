@@ -0,0 +1,45 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+	"videoarchiver/backend/domains/overrides"
+)
+
+func TestSettingOverrides(t *testing.T) {
+	s := &SettingsService{}
+	defer overrides.SetSettingFlags(map[string]string{})
+
+	t.Setenv(overrides.EnvName("allow_duplicates"), "true")
+	override, ok := s.GetSettingOverride("allow_duplicates")
+	if !ok || override.Value != "true" || override.Source != "VIDEOARCHIVER_ALLOW_DUPLICATES" {
+		t.Errorf("Expected environment override, got %+v, %t", override, ok)
+	}
+
+	// Flags take precedence over the environment
+	overrides.SetSettingFlags(map[string]string{"allow_duplicates": "false"})
+	override, ok = s.GetSettingOverride("allow_duplicates")
+	if !ok || override.Value != "false" || override.Source != "--setting allow_duplicates" {
+		t.Errorf("Expected flag override, got %+v, %t", override, ok)
+	}
+	if err := s.ValidateOverrides(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Internal settings ignore the environment and reject flags
+	t.Setenv(overrides.EnvName("daemon_signal"), "1")
+	if _, ok := s.GetSettingOverride("daemon_signal"); ok {
+		t.Error("Expected internal setting not to be overridden")
+	}
+	overrides.SetSettingFlags(map[string]string{"daemon_signal": "1", "no_such_setting": "1"})
+	t.Setenv(overrides.EnvName("registry_scan_workers"), "many")
+	err := s.ValidateOverrides()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, expected := range []string{"daemon_signal", "no_such_setting", "VIDEOARCHIVER_REGISTRY_SCAN_WORKERS"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %s, got: %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package toolupdate
+
+import (
+	"database/sql"
+	"time"
+	"videoarchiver/backend/domains/db"
+)
+
+type ToolUpdateDB struct {
+	db *sql.DB
+}
+
+func NewToolUpdateDB(dbService *db.DatabaseService) *ToolUpdateDB {
+	return &ToolUpdateDB{db: dbService.GetDB()}
+}
+
+// CreateUpdate inserts a running update check and returns its ID
+func (t *ToolUpdateDB) CreateUpdate(tool, trigger, fromVersion string, failureCount int) (int, error) {
+	res, err := t.db.Exec(
+		`INSERT INTO tool_update_history (tool, trigger, status, from_version, failure_count, started_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		tool, trigger, StatusRunning, nullString(fromVersion), failureCount, time.Now().Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// FinishUpdate stores the outcome of an update check
+func (t *ToolUpdateDB) FinishUpdate(updateId int, status, toVersion, errorMessage string, requeuedCount int) error {
+	_, err := t.db.Exec(
+		`UPDATE tool_update_history SET status = ?, to_version = ?, error_message = ?, requeued_count = ?, finished_at = ?
+		 WHERE id = ?`,
+		status, nullString(toVersion), nullString(errorMessage), requeuedCount, time.Now().Unix(), updateId,
+	)
+	return err
+}
+
+// GetLatestUpdate returns the most recent update check of a tool, or nil if it was never checked
+func (t *ToolUpdateDB) GetLatestUpdate(tool string) (*ToolUpdate, error) {
+	rows, err := t.db.Query(
+		`SELECT id, tool, trigger, status, from_version, to_version, error_message, failure_count, requeued_count, started_at, finished_at
+		 FROM tool_update_history WHERE tool = ? ORDER BY started_at DESC, id DESC LIMIT 1`,
+		tool,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	updates, err := scanUpdates(rows)
+	if err != nil || len(updates) == 0 {
+		return nil, err
+	}
+	return &updates[0], nil
+}
+
+// GetHistory returns the most recent update checks of every tool, newest first
+func (t *ToolUpdateDB) GetHistory(limit int) ([]ToolUpdate, error) {
+	rows, err := t.db.Query(
+		`SELECT id, tool, trigger, status, from_version, to_version, error_message, failure_count, requeued_count, started_at, finished_at
+		 FROM tool_update_history ORDER BY started_at DESC, id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanUpdates(rows)
+}
+
+func scanUpdates(rows *sql.Rows) ([]ToolUpdate, error) {
+	updates := []ToolUpdate{}
+	for rows.Next() {
+		var update ToolUpdate
+		if err := rows.Scan(
+			&update.ID, &update.Tool, &update.Trigger, &update.Status, &update.FromVersion, &update.ToVersion,
+			&update.ErrorMessage, &update.FailureCount, &update.RequeuedCount, &update.StartedAt, &update.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+	return updates, rows.Err()
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,32 @@
+package toolupdate
+
+import "database/sql"
+
+// What started an update check
+const (
+	TriggerScheduled = "scheduled" // The update interval elapsed
+	TriggerFailures  = "failures"  // Downloads failed in a way an update may fix
+)
+
+// Outcomes of an update check
+const (
+	StatusRunning  = "running"
+	StatusUpdated  = "updated"
+	StatusUpToDate = "up_to_date"
+	StatusFailed   = "failed"
+)
+
+// ToolUpdate is an update check of a tool run by the daemon
+type ToolUpdate struct {
+	ID            int            `json:"id" db:"id"`
+	Tool          string         `json:"tool" db:"tool"`
+	Trigger       string         `json:"trigger" db:"trigger"`
+	Status        string         `json:"status" db:"status"`
+	FromVersion   sql.NullString `json:"from_version,omitempty" db:"from_version"`
+	ToVersion     sql.NullString `json:"to_version,omitempty" db:"to_version"`
+	ErrorMessage  sql.NullString `json:"error_message,omitempty" db:"error_message"`
+	FailureCount  int            `json:"failure_count" db:"failure_count"`   // Failed downloads that pointed to an outdated tool
+	RequeuedCount int            `json:"requeued_count" db:"requeued_count"` // Failed downloads queued for a retry after updating
+	StartedAt     int64          `json:"started_at" db:"started_at"`
+	FinishedAt    sql.NullInt64  `json:"finished_at,omitempty" db:"finished_at"`
+}
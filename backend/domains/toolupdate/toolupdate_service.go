@@ -0,0 +1,230 @@
+package toolupdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"videoarchiver/backend/daemonsignal"
+	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/settings"
+	"videoarchiver/backend/domains/ytdlp"
+)
+
+const (
+	failureWindow   = 6 * time.Hour // Only recent failures point to a broken extractor
+	failureCooldown = time.Hour     // Failures start at most one update check per hour
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// ToolUpdateService updates yt-dlp while the daemon runs, on a schedule and when downloads fail
+// in a way an update may fix. Downloads that failed that way are retried after an update.
+type ToolUpdateService struct {
+	toolUpdateDB        *ToolUpdateDB
+	settingsService     *settings.SettingsService
+	downloadDB          *download.DownloadDB
+	daemonSignalService *daemonsignal.DaemonSignalService
+	logService          LogServiceInterface
+	startedAt           time.Time // yt-dlp is updated on startup, the schedule starts then
+}
+
+func NewToolUpdateService(
+	toolUpdateDB *ToolUpdateDB,
+	settingsService *settings.SettingsService,
+	downloadDB *download.DownloadDB,
+	daemonSignalService *daemonsignal.DaemonSignalService,
+	logService LogServiceInterface,
+) *ToolUpdateService {
+	return &ToolUpdateService{
+		toolUpdateDB:        toolUpdateDB,
+		settingsService:     settingsService,
+		downloadDB:          downloadDB,
+		daemonSignalService: daemonSignalService,
+		logService:          logService,
+		startedAt:           time.Now(),
+	}
+}
+
+// CheckDue returns the trigger of a yt-dlp update check that is due, false if none is.
+// Nothing is due when autoupdate is off, a release is pinned or yt-dlp is not managed by the application.
+func (s *ToolUpdateService) CheckDue() (string, bool, error) {
+	enabled, err := s.isEnabled()
+	if err != nil || !enabled {
+		return "", false, err
+	}
+	lastCheck, err := s.getLastCheck()
+	if err != nil {
+		return "", false, err
+	}
+	sinceLastCheck := time.Since(lastCheck)
+
+	intervalHours, err := s.getIntSetting("ytdlp_update_interval_hours")
+	if err != nil {
+		return "", false, err
+	}
+	if intervalHours > 0 && sinceLastCheck >= time.Duration(intervalHours)*time.Hour {
+		return TriggerScheduled, true, nil
+	}
+
+	threshold, err := s.getIntSetting("ytdlp_update_failure_threshold")
+	if err != nil {
+		return "", false, err
+	}
+	if threshold == 0 || sinceLastCheck < failureCooldown {
+		return "", false, nil
+	}
+	since := lastCheck
+	if windowStart := time.Now().Add(-failureWindow); windowStart.After(since) {
+		since = windowStart
+	}
+	failures, err := s.getExtractorFailures(since)
+	if err != nil {
+		return "", false, err
+	}
+	return TriggerFailures, len(failures) >= threshold, nil
+}
+
+// RunUpdate updates yt-dlp and records the check. After an update, downloads that failed
+// in a way the update may fix since the last check are queued for a retry.
+func (s *ToolUpdateService) RunUpdate(trigger string) (*ToolUpdate, error) {
+	lastCheck, err := s.getLastCheck()
+	if err != nil {
+		return nil, err
+	}
+	failures, err := s.getExtractorFailures(lastCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	fromVersion, err := ytdlp.GetYtdlpVersion()
+	if err != nil {
+		s.logService.Warn(fmt.Sprintf("Failed to get yt-dlp version before updating: %v", err))
+	}
+	fromSha256, _ := ytdlp.GetYtdlpSha256()
+	updateId, err := s.toolUpdateDB.CreateUpdate(ytdlp.ToolYtdlp, trigger, fromVersion, len(failures))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record yt-dlp update: %w", err)
+	}
+
+	s.logService.Info(fmt.Sprintf("Checking for yt-dlp updates (%s, %d recent extractor failures)...", trigger, len(failures)))
+	updateErr := ytdlp.UpdateYtdlp(s.settingsService, nil)
+	toVersion, versionErr := ytdlp.GetYtdlpVersion()
+	toSha256, _ := ytdlp.GetYtdlpSha256()
+	if updateErr == nil {
+		updateErr = versionErr
+	}
+	status, errorMessage := updateStatus(updateErr, fromVersion, toVersion, fromSha256, toSha256)
+
+	requeued := 0
+	if status == StatusUpdated {
+		for _, failure := range failures {
+			if err := s.downloadDB.SetManualRetry(failure.ID); err != nil {
+				s.logService.Error(fmt.Sprintf("Failed to queue %s for a retry: %v", failure.Url, err))
+				continue
+			}
+			requeued++
+		}
+		// Restart the iteration so the requeued downloads are retried right away
+		if requeued > 0 {
+			if err := s.daemonSignalService.TriggerChange(); err != nil {
+				s.logService.Warn(fmt.Sprintf("Failed to signal the daemon to retry downloads: %v", err))
+			}
+		}
+	}
+
+	if err := s.toolUpdateDB.FinishUpdate(updateId, status, toVersion, errorMessage, requeued); err != nil {
+		return nil, fmt.Errorf("failed to record yt-dlp update: %w", err)
+	}
+	switch status {
+	case StatusUpdated:
+		s.logService.Info(fmt.Sprintf("Updated yt-dlp from %s to %s, %d failed downloads queued for a retry", fromVersion, toVersion, requeued))
+	case StatusUpToDate:
+		s.logService.Info(fmt.Sprintf("yt-dlp %s is up to date", toVersion))
+	case StatusFailed:
+		s.logService.Error(fmt.Sprintf("Failed to update yt-dlp: %s", errorMessage))
+	}
+	return s.toolUpdateDB.GetLatestUpdate(ytdlp.ToolYtdlp)
+}
+
+// updateStatus decides the outcome of an update check. The versions are compared when both are known,
+// otherwise the checksums of the executable before and after tell if it was replaced.
+func updateStatus(updateErr error, fromVersion, toVersion, fromSha256, toSha256 string) (status, errorMessage string) {
+	switch {
+	case updateErr != nil:
+		return StatusFailed, updateErr.Error()
+	case fromVersion != "" && toVersion != "":
+		if fromVersion != toVersion {
+			return StatusUpdated, ""
+		}
+	case fromSha256 != "" && toSha256 != "" && fromSha256 != toSha256:
+		return StatusUpdated, ""
+	}
+	return StatusUpToDate, ""
+}
+
+// GetHistory returns the most recent update checks, newest first
+func (s *ToolUpdateService) GetHistory(limit int) ([]ToolUpdate, error) {
+	return s.toolUpdateDB.GetHistory(limit)
+}
+
+func (s *ToolUpdateService) isEnabled() (bool, error) {
+	if !ytdlp.IsManaged(ytdlp.ToolYtdlp) {
+		return false, nil
+	}
+	autoupdate, err := s.settingsService.GetSettingBool("autoupdate_ytdlp")
+	if err != nil {
+		return false, fmt.Errorf("failed to get autoupdate_ytdlp setting: %w", err)
+	}
+	pinned, err := s.settingsService.GetSettingString("ytdlp_version")
+	if err != nil {
+		return false, fmt.Errorf("failed to get ytdlp_version setting: %w", err)
+	}
+	return autoupdate && strings.TrimSpace(pinned) == "", nil
+}
+
+// getLastCheck returns when yt-dlp was last checked for updates, at startup or by the daemon
+func (s *ToolUpdateService) getLastCheck() (time.Time, error) {
+	latest, err := s.toolUpdateDB.GetLatestUpdate(ytdlp.ToolYtdlp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get latest yt-dlp update: %w", err)
+	}
+	if latest != nil && time.Unix(latest.StartedAt, 0).After(s.startedAt) {
+		return time.Unix(latest.StartedAt, 0), nil
+	}
+	return s.startedAt, nil
+}
+
+// getExtractorFailures returns downloads that failed since a time in a way a yt-dlp update may fix
+func (s *ToolUpdateService) getExtractorFailures(since time.Time) ([]download.Download, error) {
+	failures, err := s.downloadDB.GetFailuresSince(since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed downloads: %w", err)
+	}
+	extractorFailures := make([]download.Download, 0, len(failures))
+	for _, failure := range failures {
+		if failure.FailMessage.Valid && ytdlp.IsExtractorFailure(failure.FailMessage.String) {
+			extractorFailures = append(extractorFailures, failure)
+		}
+	}
+	return extractorFailures, nil
+}
+
+func (s *ToolUpdateService) getIntSetting(key string) (int, error) {
+	value, err := s.settingsService.GetSettingString(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s setting: %w", key, err)
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s setting: %s", key, value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,90 @@
+package toolupdate
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func createTestDB(t *testing.T) *ToolUpdateDB {
+	t.Helper()
+	database, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	migration, err := os.ReadFile("../../../migrations/0021_create_tool_update_history.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	up, _, _ := strings.Cut(string(migration), "-- +down")
+	if _, err := database.Exec(up); err != nil {
+		t.Fatal(err)
+	}
+	return &ToolUpdateDB{db: database}
+}
+
+func TestToolUpdateHistory(t *testing.T) {
+	toolUpdateDB := createTestDB(t)
+
+	if latest, err := toolUpdateDB.GetLatestUpdate("yt-dlp"); err != nil || latest != nil {
+		t.Fatalf("expected no update yet, got %+v, %v", latest, err)
+	}
+
+	firstId, err := toolUpdateDB.CreateUpdate("yt-dlp", TriggerScheduled, "2025.01.15", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := toolUpdateDB.FinishUpdate(firstId, StatusFailed, "2025.01.15", "no network", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secondId, err := toolUpdateDB.CreateUpdate("yt-dlp", TriggerFailures, "", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := toolUpdateDB.FinishUpdate(secondId, StatusUpdated, "2025.01.26", "", 4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	latest, err := toolUpdateDB.GetLatestUpdate("yt-dlp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if latest.ID != secondId || latest.Status != StatusUpdated || latest.FromVersion.Valid || latest.ToVersion.String != "2025.01.26" ||
+		latest.FailureCount != 4 || latest.RequeuedCount != 4 || !latest.FinishedAt.Valid {
+		t.Errorf("unexpected latest update: %+v", latest)
+	}
+
+	history, err := toolUpdateDB.GetHistory(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 2 || history[1].ErrorMessage.String != "no network" {
+		t.Errorf("unexpected history: %+v", history)
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	cases := []struct {
+		name                   string
+		updateErr              error
+		fromVersion, toVersion string
+		fromSha256, toSha256   string
+		expected               string
+	}{
+		{"failed", errors.New("no network"), "2025.01.15", "2025.01.15", "a", "a", StatusFailed},
+		{"new version", nil, "2025.01.15", "2025.01.26", "a", "b", StatusUpdated},
+		{"same version", nil, "2025.01.15", "2025.01.15", "a", "a", StatusUpToDate},
+		{"unknown version, same binary", nil, "", "2025.01.26", "a", "a", StatusUpToDate},
+		{"unknown version, replaced binary", nil, "", "2025.01.26", "a", "b", StatusUpdated},
+		{"unknown version, no binary before", nil, "", "2025.01.26", "", "b", StatusUpToDate},
+	}
+	for _, c := range cases {
+		if status, _ := updateStatus(c.updateErr, c.fromVersion, c.toVersion, c.fromSha256, c.toSha256); status != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, status)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatFromPath returns the document format for a file name, JSON unless it has a YAML extension
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// Marshal encodes a document as JSON or YAML
+func Marshal(doc *Document, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported document format: %s", format)
+	}
+}
+
+// Unmarshal decodes a JSON or YAML document. Unknown fields are rejected so typos don't go unnoticed.
+func Unmarshal(data []byte, format string) (*Document, error) {
+	doc := &Document{}
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON document: %w", err)
+		}
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML document: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported document format: %s", format)
+	}
+	return doc, nil
+}
+
+// ParsePathRemap parses a FROM=TO command line remap
+func ParsePathRemap(value string) (PathRemap, error) {
+	from, to, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+		return PathRemap{}, fmt.Errorf("invalid path remap %q, expected FROM=TO", value)
+	}
+	return PathRemap{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}, nil
+}
+
+// remapPath applies the longest matching remap to a path.
+// Paths are compared with forward slashes, so documents exported on Windows can be remapped on Linux and back.
+func remapPath(path string, remaps []PathRemap) string {
+	sorted := make([]PathRemap, len(remaps))
+	copy(sorted, remaps)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].From) > len(sorted[j].From) })
+
+	normalized := slashPath(path)
+	for _, remap := range sorted {
+		from := strings.TrimSuffix(slashPath(remap.From), "/")
+		if from == "" {
+			continue
+		}
+		if normalized != from && !strings.HasPrefix(normalized, from+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(normalized, from)
+		return filepath.Clean(filepath.FromSlash(strings.TrimSuffix(slashPath(remap.To), "/") + rest))
+	}
+	return path
+}
+
+// slashPath replaces both kinds of separators with forward slashes, regardless of the current OS
+func slashPath(path string) string {
+	return strings.ReplaceAll(path, `\`, "/")
+}
@@ -0,0 +1,68 @@
+package transfer
+
+// DocumentVersion is the version of export documents written by this build.
+// Older versions are still imported, newer ones are rejected.
+const DocumentVersion = 1
+
+// Document formats, chosen by file extension
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// How an import treats playlists that are already configured
+const (
+	ConflictMerge = "merge" // Imported name, post-processing steps and settings replace the existing ones
+	ConflictSkip  = "skip"  // Existing playlists and settings are left as they are
+)
+
+// Document is a portable copy of an installation's playlists and settings
+type Document struct {
+	Version    int               `json:"version" yaml:"version"`
+	AppVersion string            `json:"app_version,omitempty" yaml:"app_version,omitempty"`
+	ExportedAt string            `json:"exported_at,omitempty" yaml:"exported_at,omitempty"` // RFC 3339
+	Settings   map[string]string `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Playlists  []PlaylistEntry   `json:"playlists,omitempty" yaml:"playlists,omitempty"`
+}
+
+// PlaylistEntry is an active playlist with its per-playlist options.
+// The output format is the playlist's quality choice, there are no separate quality profiles.
+type PlaylistEntry struct {
+	Name             string      `json:"name" yaml:"name"`
+	URL              string      `json:"url" yaml:"url"`
+	OutputFormat     string      `json:"output_format" yaml:"output_format"`
+	SaveDirectory    string      `json:"save_directory" yaml:"save_directory"`
+	ThumbnailBase64  string      `json:"thumbnail_base64,omitempty" yaml:"thumbnail_base64,omitempty"`
+	PostProcessSteps []StepEntry `json:"post_process_steps,omitempty" yaml:"post_process_steps,omitempty"`
+}
+
+// StepEntry is a post-processing step, in pipeline order
+type StepEntry struct {
+	Kind    string            `json:"kind" yaml:"kind"`
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+	Enabled *bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"` // Enabled when omitted
+}
+
+// PathRemap replaces the From prefix of save directories and path settings with To
+type PathRemap struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ImportOptions struct {
+	ConflictMode string      `json:"conflict_mode"` // ConflictMerge or ConflictSkip, merge if empty
+	PathRemaps   []PathRemap `json:"path_remaps"`
+	DryRun       bool        `json:"dry_run"` // Report what would change without changing anything
+}
+
+// ImportReport lists what an import changed, by playlist name and setting key
+type ImportReport struct {
+	DryRun           bool     `json:"dry_run"`
+	PlaylistsAdded   []string `json:"playlists_added"`
+	PlaylistsMerged  []string `json:"playlists_merged"`
+	PlaylistsSkipped []string `json:"playlists_skipped"`
+	SettingsChanged  []string `json:"settings_changed"`
+	SettingsSkipped  []string `json:"settings_skipped"`
+	PlaylistsManaged []string `json:"playlists_managed"` // Left alone because the declarative config file manages them
+	SettingsManaged  []string `json:"settings_managed"`
+}
@@ -0,0 +1,363 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"videoarchiver/backend/daemonsignal"
+	"videoarchiver/backend/domains/playlist"
+	"videoarchiver/backend/domains/postprocess"
+	"videoarchiver/backend/domains/settings"
+)
+
+// LogServiceInterface defines the logging interface to avoid circular imports
+type LogServiceInterface interface {
+	Debug(message string)
+	Info(message string)
+	Warn(message string)
+	Error(message string)
+	Fatal(message string)
+}
+
+// Settings that describe this installation rather than the user's preferences. They are never exported or imported.
+var installationSettings = map[string]bool{
+	"daemon_signal":               true,
+	"legal_disclaimer_accepted":   true, // Must be accepted on every installation
+	"direct_download_last_path":   true,
+	"direct_download_last_format": true,
+}
+
+// IsInstallationSetting returns true for settings that describe an installation and are never exported or imported
+func IsInstallationSetting(key string) bool {
+	return installationSettings[key]
+}
+
+// Settings holding a path, remapped like save directories on import
+var pathSettings = map[string]bool{
+	"duplicate_quarantine_directory": true,
+	"backup_directory":               true,
+}
+
+// Output formats a playlist can download as
+var outputFormats = map[string]bool{"mp3": true, "mp4": true}
+
+// ManagedChecker reports playlists and settings the declarative config file manages, an import leaves them alone
+type ManagedChecker interface {
+	IsPlaylistManaged(playlistId int) (bool, error)
+	IsSettingManaged(key string) (bool, error)
+}
+
+type TransferService struct {
+	settingsService     *settings.SettingsService
+	playlistDB          *playlist.PlaylistDB
+	postProcessService  *postprocess.PostProcessService
+	daemonSignalService *daemonsignal.DaemonSignalService
+	managedChecker      ManagedChecker
+	logService          LogServiceInterface
+}
+
+func NewTransferService(
+	settingsService *settings.SettingsService,
+	playlistDB *playlist.PlaylistDB,
+	postProcessService *postprocess.PostProcessService,
+	daemonSignalService *daemonsignal.DaemonSignalService,
+	managedChecker ManagedChecker,
+	logService LogServiceInterface,
+) *TransferService {
+	return &TransferService{
+		settingsService:     settingsService,
+		playlistDB:          playlistDB,
+		postProcessService:  postProcessService,
+		daemonSignalService: daemonSignalService,
+		managedChecker:      managedChecker,
+		logService:          logService,
+	}
+}
+
+// Export creates a document of the active playlists, their post-processing steps and the settings
+func (t *TransferService) Export(appVersion string) (*Document, error) {
+	storedSettings, err := t.settingsService.GetAllSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	doc := &Document{
+		Version:    DocumentVersion,
+		AppVersion: appVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Settings:   make(map[string]string, len(storedSettings)),
+	}
+	for key, value := range storedSettings {
+		if !installationSettings[key] {
+			doc.Settings[key] = value
+		}
+	}
+
+	playlists, err := t.playlistDB.GetActivePlaylists()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlists: %w", err)
+	}
+	// Oldest first, so an import adds them in their original order
+	sort.SliceStable(playlists, func(i, j int) bool { return playlists[i].AddedAt < playlists[j].AddedAt })
+	for _, pl := range playlists {
+		steps, err := t.postProcessService.GetSteps(pl.ID)
+		if err != nil {
+			return nil, err
+		}
+		entry := PlaylistEntry{
+			Name:            pl.Name,
+			URL:             pl.URL,
+			OutputFormat:    pl.OutputFormat,
+			SaveDirectory:   pl.SaveDirectory,
+			ThumbnailBase64: pl.ThumbnailBase64.String,
+		}
+		for _, step := range steps {
+			enabled := step.IsEnabled
+			entry.PostProcessSteps = append(entry.PostProcessSteps, StepEntry{
+				Kind:    step.Kind,
+				Options: step.Options,
+				Enabled: &enabled,
+			})
+		}
+		doc.Playlists = append(doc.Playlists, entry)
+	}
+	return doc, nil
+}
+
+// ExportToFile writes an export document, as YAML if the file has a .yaml or .yml extension and JSON otherwise
+func (t *TransferService) ExportToFile(path, appVersion string) error {
+	doc, err := t.Export(appVersion)
+	if err != nil {
+		return err
+	}
+	data, err := Marshal(doc, FormatFromPath(path))
+	if err != nil {
+		return fmt.Errorf("failed to encode export document: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export document: %w", err)
+	}
+	t.logService.Info(fmt.Sprintf("Exported %d playlists and %d settings to %s", len(doc.Playlists), len(doc.Settings), path))
+	return nil
+}
+
+// ImportFromFile reads a JSON or YAML export document and imports it
+func (t *TransferService) ImportFromFile(path string, options ImportOptions) (*ImportReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import document: %w", err)
+	}
+	doc, err := Unmarshal(data, FormatFromPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return t.Import(doc, options)
+}
+
+// Import validates a document as a whole and then adds or merges its playlists and settings.
+// Nothing is changed if the document is invalid. Playlists and settings the config file manages are left alone.
+func (t *TransferService) Import(doc *Document, options ImportOptions) (*ImportReport, error) {
+	if options.ConflictMode == "" {
+		options.ConflictMode = ConflictMerge
+	}
+	if options.ConflictMode != ConflictMerge && options.ConflictMode != ConflictSkip {
+		return nil, fmt.Errorf("invalid conflict mode: %s", options.ConflictMode)
+	}
+
+	storedSettings, err := t.settingsService.GetAllSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	playlists := remapPlaylists(doc.Playlists, options.PathRemaps)
+	if err := validateDocument(doc, playlists, storedSettings); err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: options.DryRun}
+	if err := t.importSettings(doc.Settings, storedSettings, options, report); err != nil {
+		return report, err
+	}
+	for _, entry := range playlists {
+		if err := t.importPlaylist(entry, options, report); err != nil {
+			return report, fmt.Errorf("failed to import playlist %s: %w", entry.Name, err)
+		}
+	}
+
+	if !options.DryRun {
+		t.logService.Info(fmt.Sprintf("Imported configuration: %d playlists added, %d merged, %d skipped, %d settings changed, %d managed by the config file",
+			len(report.PlaylistsAdded), len(report.PlaylistsMerged), len(report.PlaylistsSkipped), len(report.SettingsChanged),
+			len(report.PlaylistsManaged)+len(report.SettingsManaged)))
+		if err := t.daemonSignalService.TriggerChange(); err != nil {
+			t.logService.Warn(fmt.Sprintf("Failed to signal daemon after import: %v", err))
+		}
+	}
+	return report, nil
+}
+
+func (t *TransferService) importSettings(values, storedSettings map[string]string, options ImportOptions, report *ImportReport) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+		if pathSettings[key] && value != "" {
+			value = remapPath(value, options.PathRemaps)
+		}
+		if storedSettings[key] == value {
+			continue
+		}
+		managed, err := t.managedChecker.IsSettingManaged(key)
+		if err != nil {
+			return err
+		}
+		if managed {
+			report.SettingsManaged = append(report.SettingsManaged, key)
+			continue
+		}
+		if options.ConflictMode == ConflictSkip {
+			report.SettingsSkipped = append(report.SettingsSkipped, key)
+			continue
+		}
+		report.SettingsChanged = append(report.SettingsChanged, key)
+		if options.DryRun {
+			continue
+		}
+		if err := t.settingsService.SetPreparsed(key, value); err != nil {
+			return fmt.Errorf("failed to import setting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (t *TransferService) importPlaylist(entry PlaylistEntry, options ImportOptions, report *ImportReport) error {
+	existingId, err := t.playlistDB.GetActivePlaylistID(entry.URL, entry.SaveDirectory, entry.OutputFormat)
+	if err != nil {
+		return err
+	}
+	if existingId != 0 {
+		managed, err := t.managedChecker.IsPlaylistManaged(existingId)
+		if err != nil {
+			return err
+		}
+		if managed {
+			report.PlaylistsManaged = append(report.PlaylistsManaged, entry.Name)
+			return nil
+		}
+	}
+	if existingId != 0 && options.ConflictMode == ConflictSkip {
+		report.PlaylistsSkipped = append(report.PlaylistsSkipped, entry.Name)
+		return nil
+	}
+	if existingId != 0 {
+		report.PlaylistsMerged = append(report.PlaylistsMerged, entry.Name)
+	} else {
+		report.PlaylistsAdded = append(report.PlaylistsAdded, entry.Name)
+	}
+	if options.DryRun {
+		return nil
+	}
+
+	playlistId := existingId
+	if existingId != 0 {
+		if err := t.playlistDB.UpdatePlaylistName(existingId, entry.Name); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(entry.SaveDirectory, 0755); err != nil {
+			return fmt.Errorf("failed to create save directory: %w", err)
+		}
+		playlistId, err = t.playlistDB.AddPlaylist(entry.Name, entry.URL, entry.SaveDirectory, entry.OutputFormat, entry.ThumbnailBase64)
+		if err != nil {
+			return err
+		}
+	}
+	return t.postProcessService.SetSteps(playlistId, toSteps(entry.PostProcessSteps))
+}
+
+// remapPlaylists returns a copy of the playlists with their save directories and hardlink directories remapped
+func remapPlaylists(entries []PlaylistEntry, remaps []PathRemap) []PlaylistEntry {
+	remapped := make([]PlaylistEntry, len(entries))
+	for i, entry := range entries {
+		entry.SaveDirectory = remapPath(entry.SaveDirectory, remaps)
+		steps := make([]StepEntry, len(entry.PostProcessSteps))
+		for j, step := range entry.PostProcessSteps {
+			if step.Kind == postprocess.StepHardlink && step.Options["directory"] != "" {
+				options := make(map[string]string, len(step.Options))
+				for key, value := range step.Options {
+					options[key] = value
+				}
+				options["directory"] = remapPath(options["directory"], remaps)
+				step.Options = options
+			}
+			steps[j] = step
+		}
+		entry.PostProcessSteps = steps
+		remapped[i] = entry
+	}
+	return remapped
+}
+
+// validateDocument checks the whole document against this installation, returning every problem at once
+func validateDocument(doc *Document, playlists []PlaylistEntry, storedSettings map[string]string) error {
+	if doc.Version < 1 || doc.Version > DocumentVersion {
+		return fmt.Errorf("unsupported document version %d, this version of videoarchiver reads up to version %d", doc.Version, DocumentVersion)
+	}
+
+	var errs []error
+	keys := make([]string, 0, len(doc.Settings))
+	for key := range doc.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, exists := storedSettings[key]; !exists {
+			errs = append(errs, fmt.Errorf("unknown setting: %s", key))
+		} else if installationSettings[key] {
+			errs = append(errs, fmt.Errorf("setting %s belongs to an installation and cannot be imported", key))
+		} else if err := settings.ValidateSettingValue(key, doc.Settings[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	seen := make(map[string]bool, len(playlists))
+	for i, entry := range playlists {
+		label := fmt.Sprintf("playlist %d (%s)", i+1, entry.Name)
+		if entry.Name == "" || entry.URL == "" {
+			errs = append(errs, fmt.Errorf("%s: name and url are required", label))
+		}
+		if !outputFormats[entry.OutputFormat] {
+			errs = append(errs, fmt.Errorf("%s: unsupported output format %q", label, entry.OutputFormat))
+		}
+		if !filepath.IsAbs(entry.SaveDirectory) {
+			errs = append(errs, fmt.Errorf("%s: save directory %q is not an absolute path on this system, add a path remap", label, entry.SaveDirectory))
+		}
+		key := entry.URL + "\x00" + entry.SaveDirectory + "\x00" + entry.OutputFormat
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("%s: listed more than once with the same directory and format", label))
+		}
+		seen[key] = true
+		for j, step := range toSteps(entry.PostProcessSteps) {
+			if err := postprocess.ValidateStep(step); err != nil {
+				errs = append(errs, fmt.Errorf("%s: step %d: %w", label, j+1, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func toSteps(entries []StepEntry) []postprocess.PostProcessStep {
+	steps := make([]postprocess.PostProcessStep, 0, len(entries))
+	for _, entry := range entries {
+		steps = append(steps, postprocess.PostProcessStep{
+			Kind:      entry.Kind,
+			Options:   entry.Options,
+			IsEnabled: entry.Enabled == nil || *entry.Enabled,
+		})
+	}
+	return steps
+}
@@ -0,0 +1,124 @@
+package transfer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemapPath(t *testing.T) {
+	remaps := []PathRemap{
+		{From: `D:\Media`, To: "/srv/media"},
+		{From: `D:\Media\Music`, To: "/srv/music"},
+	}
+	tests := map[string]string{
+		`D:\Media\Videos\Talks`: filepath.FromSlash("/srv/media/Videos/Talks"),
+		`D:\Media\Music\Mixes`:  filepath.FromSlash("/srv/music/Mixes"),
+		`D:\Media`:              filepath.FromSlash("/srv/media"),
+		`D:\MediaOld\Talks`:     `D:\MediaOld\Talks`,
+		"/home/me/videos":       "/home/me/videos",
+	}
+	for path, expected := range tests {
+		if remapped := remapPath(path, remaps); remapped != expected {
+			t.Errorf("remapPath(%q) = %q, expected %q", path, remapped, expected)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	disabled := false
+	doc := &Document{
+		Version:  DocumentVersion,
+		Settings: map[string]string{"allow_duplicates": "false"},
+		Playlists: []PlaylistEntry{{
+			Name:          "Mixes",
+			URL:           "https://www.youtube.com/playlist?list=PL1",
+			OutputFormat:  "mp3",
+			SaveDirectory: "/srv/music/Mixes",
+			PostProcessSteps: []StepEntry{
+				{Kind: "loudnorm", Options: map[string]string{"target_lufs": "-14"}},
+				{Kind: "tag", Enabled: &disabled},
+			},
+		}},
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		data, err := Marshal(doc, format)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		decoded, err := Unmarshal(data, format)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		if len(decoded.Playlists) != 1 || decoded.Settings["allow_duplicates"] != "false" {
+			t.Fatalf("%s: unexpected document: %+v", format, decoded)
+		}
+		steps := toSteps(decoded.Playlists[0].PostProcessSteps)
+		if len(steps) != 2 || !steps[0].IsEnabled || steps[1].IsEnabled || steps[0].Options["target_lufs"] != "-14" {
+			t.Errorf("%s: unexpected steps: %+v", format, steps)
+		}
+	}
+
+	if _, err := Unmarshal([]byte("version: 1\nplaylist: []\n"), FormatYAML); err == nil {
+		t.Error("Expected unknown YAML fields to be rejected")
+	}
+	if _, err := Unmarshal([]byte(`{"version": 1, "setting": {}}`), FormatJSON); err == nil {
+		t.Error("Expected unknown JSON fields to be rejected")
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	stored := map[string]string{"allow_duplicates": "false", "legal_disclaimer_accepted": "true"}
+	doc := &Document{
+		Version:  DocumentVersion,
+		Settings: map[string]string{"allow_duplicates": "true", "no_such_setting": "1", "legal_disclaimer_accepted": "true"},
+		Playlists: []PlaylistEntry{
+			{Name: "Talks", URL: "https://example.com/a", OutputFormat: "mkv", SaveDirectory: `D:\Talks`},
+			{Name: "Mixes", URL: "https://example.com/b", OutputFormat: "mp3", SaveDirectory: "/srv/music",
+				PostProcessSteps: []StepEntry{{Kind: "hardlink"}}},
+		},
+	}
+
+	err := validateDocument(doc, doc.Playlists, stored)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, expected := range []string{
+		"unknown setting: no_such_setting",
+		"legal_disclaimer_accepted belongs to an installation",
+		`unsupported output format "mkv"`,
+		`save directory "D:\\Talks" is not an absolute path`,
+		"step 1: hardlink step requires the directory option",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to contain %q, got:\n%v", expected, err)
+		}
+	}
+
+	if err := validateDocument(&Document{Version: DocumentVersion + 1}, nil, stored); err == nil {
+		t.Error("Expected newer document versions to be rejected")
+	}
+}
+
+type managedSettings map[string]bool
+
+func (m managedSettings) IsPlaylistManaged(playlistId int) (bool, error) { return false, nil }
+func (m managedSettings) IsSettingManaged(key string) (bool, error)      { return m[key], nil }
+
+func TestImportSettingsLeavesManagedSettings(t *testing.T) {
+	service := &TransferService{managedChecker: managedSettings{"ytdlp_update_interval_hours": true}}
+	stored := map[string]string{"ytdlp_update_interval_hours": "2", "allow_duplicates": "false"}
+	values := map[string]string{"ytdlp_update_interval_hours": "8", "allow_duplicates": "true"}
+
+	report := &ImportReport{DryRun: true}
+	if err := service.importSettings(values, stored, ImportOptions{ConflictMode: ConflictMerge, DryRun: true}, report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.SettingsManaged) != 1 || report.SettingsManaged[0] != "ytdlp_update_interval_hours" {
+		t.Errorf("Expected the managed setting to be reported, got %v", report.SettingsManaged)
+	}
+	if len(report.SettingsChanged) != 1 || report.SettingsChanged[0] != "allow_duplicates" {
+		t.Errorf("Expected only the unmanaged setting to change, got %v", report.SettingsChanged)
+	}
+}
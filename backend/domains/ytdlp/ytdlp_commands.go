@@ -80,7 +80,7 @@ func GetPlaylistInfoFlat(url string) (*YtdlpPlaylistInfo, error) {
 	}
 
 	// Iterate over entries and add to result
-	for _, entry := range entries {
+	for i, entry := range entries {
 		entryMap, ok := entry.(map[string]interface{})
 		if !ok {
 			continue
@@ -98,16 +98,51 @@ func GetPlaylistInfoFlat(url string) (*YtdlpPlaylistInfo, error) {
 			continue
 		}
 
+		// Get video ID and extractor, used to detect duplicates before downloading
+		videoId, _ := entryMap["id"].(string)
+		extractor, _ := entryMap["ie_key"].(string)
+		if videoId == "" || extractor == "" {
+			extractor, videoId, _ = ParseVideoURL(url)
+		}
+
 		// Add entry to result
 		result.Entries = append(result.Entries, YtdlpEntry{
-			Title: title,
-			URL:   url,
+			Title:     title,
+			URL:       url,
+			ID:        videoId,
+			Extractor: NormalizeExtractor(extractor),
+			Index:     i + 1,
 		})
 	}
 
 	return result, nil
 }
 
+// ResolveVideoURL asks yt-dlp for the extractor and video ID of a URL of any site it supports.
+// Unlike ParseVideoURL this runs yt-dlp, which usually contacts the site.
+func ResolveVideoURL(url string) (extractor string, videoId string, err error) {
+	raw, err := runCommand("--no-warnings", "--no-playlist", "--skip-download", "--socket-timeout", "15",
+		"--print", "%(extractor_key)s\t%(id)s", url)
+	if err != nil {
+		return "", "", err
+	}
+	extractor, videoId, ok := parseResolvedIdentity(raw)
+	if !ok {
+		return "", "", fmt.Errorf("yt-dlp reported no video ID for %s", url)
+	}
+	return extractor, videoId, nil
+}
+
+// parseResolvedIdentity reads the extractor and video ID printed by ResolveVideoURL, yt-dlp prints NA for missing fields
+func parseResolvedIdentity(output string) (string, string, bool) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	extractor, videoId, found := strings.Cut(strings.TrimSpace(lines[len(lines)-1]), "\t")
+	if !found || extractor == "" || extractor == "NA" || videoId == "" || videoId == "NA" {
+		return "", "", false
+	}
+	return NormalizeExtractor(extractor), videoId, true
+}
+
 func DownloadFile(
 	settingsService *settings.SettingsService,
 	url,
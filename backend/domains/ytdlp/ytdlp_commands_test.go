@@ -0,0 +1,26 @@
+package ytdlp
+
+import "testing"
+
+func TestParseResolvedIdentity(t *testing.T) {
+	tests := []struct {
+		output    string
+		extractor string
+		videoId   string
+		ok        bool
+	}{
+		{"Youtube\tdQw4w9WgXcQ\n", ExtractorYoutube, "dQw4w9WgXcQ", true},
+		{"Soundcloud\t123456789", "soundcloud", "123456789", true},
+		{"[generic] Falling back on generic information extractor\nGeneric\tclip", "generic", "clip", true},
+		{"NA\tNA\n", "", "", false},
+		{"Youtube\tNA", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		extractor, videoId, ok := parseResolvedIdentity(tt.output)
+		if extractor != tt.extractor || videoId != tt.videoId || ok != tt.ok {
+			t.Errorf("parseResolvedIdentity(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.output, extractor, videoId, ok, tt.extractor, tt.videoId, tt.ok)
+		}
+	}
+}
@@ -70,7 +70,7 @@ func CleanupCredentialsFile(logService LogServiceInterface) error {
 
 // GetCredentialsFilePath returns the path where credentials file will be stored
 func GetCredentialsFilePath() (string, error) {
-	return pathing.GetWorkingFile("cookies.txt")
+	return pathing.GetFile(pathing.DirRuntime, "cookies.txt")
 }
 
 // GetCredentialsFilePathForDownload returns the path to use in download commands
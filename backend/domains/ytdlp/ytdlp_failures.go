@@ -0,0 +1,27 @@
+package ytdlp
+
+import "strings"
+
+// Parts of yt-dlp errors that point to an outdated extractor rather than the video itself
+var extractorFailurePatterns = []string{
+	"confirm you are on the latest version",
+	"unable to extract",
+	"signature extraction failed",
+	"nsig extraction failed",
+	"n challenge",
+	"no video formats found",
+	"requested format is not available",
+	"http error 403: forbidden",
+}
+
+// IsExtractorFailure returns true if a download failed in a way a yt-dlp update may fix.
+// Unavailable, private and age restricted videos are not extractor failures.
+func IsExtractorFailure(message string) bool {
+	message = strings.ToLower(message)
+	for _, pattern := range extractorFailurePatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
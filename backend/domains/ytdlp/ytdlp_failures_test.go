@@ -0,0 +1,20 @@
+package ytdlp
+
+import "testing"
+
+func TestIsExtractorFailure(t *testing.T) {
+	tests := map[string]bool{
+		"[youtube] dQw4w9WgXcQ: Unable to extract uploader id; please report this issue on https://github.com/yt-dlp/yt-dlp/issues": true,
+		"[youtube] dQw4w9WgXcQ: Signature extraction failed: Some formats may be missing":                                           true,
+		"[youtube] dQw4w9WgXcQ: Requested format is not available. Use --list-formats for a list of available formats":              true,
+		"unable to download video data: HTTP Error 403: Forbidden":                                                                  true,
+		"[youtube] dQw4w9WgXcQ: Video unavailable. This video has been removed by the uploader":                                     false,
+		"[youtube] dQw4w9WgXcQ: Private video. Sign in if you've been granted access to this video":                                 false,
+		"file corruption detected: truncated":                                                                                       false,
+	}
+	for message, expected := range tests {
+		if IsExtractorFailure(message) != expected {
+			t.Errorf("IsExtractorFailure(%q) should be %v", message, expected)
+		}
+	}
+}
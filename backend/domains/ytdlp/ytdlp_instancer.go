@@ -2,6 +2,7 @@ package ytdlp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -37,19 +38,42 @@ func InstallOrUpdate(forceReinstall bool, settingsChecker SettingsChecker, logge
 		log = logger[0]
 	}
 
-	// Install or update ytdlp
-	err := installUpdateYtdlp(settingsChecker, log)
-	if err != nil {
-		return err
+	// Use tools from PATH or explicit paths if configured
+	if err := ConfigureToolSource(settingsChecker); err != nil {
+		return fmt.Errorf("ytdlp instancer: %w", err)
+	}
+
+	// Install or update ytdlp, ffmpeg is still installed if this fails so startup can continue with what works
+	var errs []error
+	if ytdlpPath, external := getExternalTool(ToolYtdlp); external {
+		if err := checkExternalTool(ytdlpPath, ytdlpCorruptionCheck); err != nil {
+			errs = append(errs, err)
+		}
+	} else if err := installUpdateYtdlp(settingsChecker, log); err != nil {
+		errs = append(errs, err)
 	}
 
 	// Install or update ffmpeg
-	err = installUpdateFfmpeg(false, log)
-	if err != nil {
-		return err
+	ffmpegPath, external := getExternalTool(ToolFfmpeg)
+	if external {
+		ffprobePath, _ := getExternalTool(ToolFfprobe)
+		if err := checkExternalTool(ffmpegPath, ffmpegCorruptionCheck); err != nil {
+			errs = append(errs, err)
+		}
+		if err := checkExternalTool(ffprobePath, ffprobeCorruptionCheck); err != nil {
+			errs = append(errs, err)
+		}
+	} else if err := installUpdateFfmpeg(false, settingsChecker, log); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	// Install plugins and pass extractor arguments to every yt-dlp call
+	useArgsSettings(settingsChecker)
+	if err := installPlugins(settingsChecker, log); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
 }
 
 // GetFfmpegPath returns the path to the ffmpeg executable
@@ -57,7 +81,13 @@ func GetFfmpegPath() (string, error) {
 	return getFfmpegPath()
 }
 
-// Runs a ytdlp command and returns the stdout and stderr
+// GetFfprobePath returns the path to the ffprobe executable
+func GetFfprobePath() (string, error) {
+	return getFfprobePath()
+}
+
+// Runs a ytdlp command and returns the stdout and stderr.
+// Installed plugins and the ytdlp_extractor_args setting apply to every command.
 func runCommand(args ...string) (string, error) {
 	// Note: This function doesn't use logger to avoid changing all call sites
 	// The command execution details are not critical for logging
@@ -66,7 +96,7 @@ func runCommand(args ...string) (string, error) {
 		return "", err
 	}
 
-	stdout, stderr, err := runner.RunWithOutput(ytdlpPath, args...)
+	stdout, stderr, err := runner.RunWithOutput(ytdlpPath, append(getExtraArgs(), args...)...)
 	if err != nil {
 		return stdout, fmt.Errorf("%s: %s", err, stderr)
 	}
@@ -81,7 +111,8 @@ func runCommand(args ...string) (string, error) {
 	return stdoutStr, nil
 }
 
-// Install or update ytdlp
+// Install or update ytdlp.
+// Follows the latest release unless ytdlp_version pins one. Downloads are verified against the published checksums.
 func installUpdateYtdlp(settingsChecker SettingsChecker, logger *logging.LogService) error {
 	ytdlpPath, err := getYtdlpPath()
 	if err != nil {
@@ -104,65 +135,90 @@ func installUpdateYtdlp(settingsChecker SettingsChecker, logger *logging.LogServ
 		}
 	}
 
-	// Download ytdlp if it doesn't exist
-	if !fileExists(ytdlpPath) {
-		if logger != nil {
-			logger.Info("Downloading ytdlp...")
+	// An offline bundle replaces downloads
+	if bundlePath := getToolSetting(settingsChecker, "tool_bundle_path"); bundlePath != "" {
+		installed, err := installFromBundle(bundlePath, []bundleTool{
+			{name: ToolYtdlp, target: ytdlpPath, fileNames: []string{getYtdlpExecutableFileName(), "yt-dlp", "yt-dlp.exe"}},
+		}, func() error {
+			return ytdlpCorruptionCheck(ytdlpPath)
+		})
+		if err != nil {
+			return fmt.Errorf("ytdlp instancer: failed to install ytdlp from bundle: %w", err)
+		}
+		if installed && logger != nil {
+			logger.Info(fmt.Sprintf("ytdlp installed from bundle %s", bundlePath))
 		}
+		return nil
+	}
 
-		// Download
-		downloadUrl := baseYtdlpDownloadUrl + getYtdlpExecutableFileName()
-		err := downloadFileHttp(downloadUrl, ytdlpPath)
+	pinned := getToolSetting(settingsChecker, "ytdlp_version")
+	if fileExists(ytdlpPath) {
+		manifest, err := readToolManifest()
 		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to download ytdlp: %w", err)
+			return fmt.Errorf("ytdlp instancer: %w", err)
 		}
 
-		if logger != nil {
-			logger.Info("ytdlp downloaded successfully")
+		// A pinned release does not change, no need to check for updates
+		if pinned != "" && manifest[ToolYtdlp].Release == pinned {
+			if logger != nil {
+				logger.Debug(fmt.Sprintf("ytdlp is pinned to installed release %s", pinned))
+			}
+			return nil
 		}
 
-		// Make executable on unix
-		if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
-			err := os.Chmod(ytdlpPath, 0755)
-			if err != nil {
-				return fmt.Errorf("ytdlp instancer: failed to make executable: %w", err)
+		// Check autoupdate setting, a changed pin is installed regardless
+		if pinned == "" && getToolSetting(settingsChecker, "autoupdate_ytdlp") == "false" {
+			if logger != nil {
+				logger.Debug("Skipping ytdlp update - autoupdate_ytdlp is disabled")
 			}
+			return nil
 		}
+	}
 
-		// No need to check for update if we just installed it
-		return nil
+	if logger != nil {
+		logger.Debug(fmt.Sprintf("Checking ytdlp release %s...", releaseName(pinned)))
+	}
+	release, err := resolveYtdlpRelease(pinned)
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: %w", err)
 	}
 
-	// Update ytdlp (check autoupdate setting first)
-	shouldUpdate := true
-	if settingsChecker != nil {
-		autoupdate, err := settingsChecker.GetSettingString("autoupdate_ytdlp")
-		if err == nil && autoupdate == "false" {
-			shouldUpdate = false
+	// Up to date when the installed binary is the release binary
+	if fileExists(ytdlpPath) {
+		if installedSha256, err := fileSha256(ytdlpPath); err == nil && installedSha256 == release.Sha256 {
 			if logger != nil {
-				logger.Debug("Skipping ytdlp update - autoupdate_ytdlp is disabled")
+				logger.Debug("ytdlp is up to date")
 			}
+			return recordToolRelease(release, ToolYtdlp)
 		}
 	}
 
-	if shouldUpdate {
-		if logger != nil {
-			logger.Debug("Checking for ytdlp updates...")
-		}
-		_, err = runCommand("-U")
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to update ytdlp: %w", err)
-		}
-
-		if logger != nil {
-			logger.Debug("ytdlp update check completed")
-		}
+	if logger != nil {
+		logger.Info(fmt.Sprintf("Downloading ytdlp release %s...", release.Release))
+	}
+	downloadPath := ytdlpPath + downloadSuffix
+	if err := downloadVerified(release, downloadPath); err != nil {
+		return fmt.Errorf("ytdlp instancer: failed to download ytdlp: %w", err)
+	}
+	err = swapInTools(map[string]string{ytdlpPath: downloadPath}, func() error {
+		return ytdlpCorruptionCheck(ytdlpPath)
+	})
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: failed to install ytdlp release %s: %w", release.Release, err)
+	}
+	if err := recordToolRelease(release, ToolYtdlp); err != nil {
+		return fmt.Errorf("ytdlp instancer: %w", err)
 	}
 
+	if logger != nil {
+		logger.Info(fmt.Sprintf("ytdlp release %s installed successfully", release.Release))
+	}
 	return nil
 }
 
-func installUpdateFfmpeg(forceReinstall bool, logger *logging.LogService) error {
+// Install ffmpeg and ffprobe when they are missing, corrupt or ffmpeg_version pins another release.
+// Unpinned installs are not updated, they only need to read and convert what ytdlp downloads.
+func installUpdateFfmpeg(forceReinstall bool, settingsChecker SettingsChecker, logger *logging.LogService) error {
 	if logger != nil {
 		logger.Info("Installing or updating ffmpeg")
 	}
@@ -179,143 +235,126 @@ func installUpdateFfmpeg(forceReinstall bool, logger *logging.LogService) error
 		return err
 	}
 
-	// Delete existing ffmpeg if any, if forceUpdate is true
-	if forceReinstall || ffmpegCorruptionCheck(ffmpegPath) != nil {
-		if fileExists(ffmpegPath) {
-			err := os.Remove(ffmpegPath)
-			if err != nil {
-				return fmt.Errorf("ytdlp instancer: failed to delete old ffmpeg: %w", err)
+	// Delete corrupt ffmpeg and ffprobe, there is nothing to roll back to
+	for _, check := range []struct {
+		path      string
+		corrupted func(string) error
+	}{{ffmpegPath, ffmpegCorruptionCheck}, {ffprobePath, ffprobeCorruptionCheck}} {
+		if fileExists(check.path) && check.corrupted(check.path) != nil {
+			if err := os.Remove(check.path); err != nil {
+				return fmt.Errorf("ytdlp instancer: failed to delete corrupt %s: %w", check.path, err)
 			}
 		}
-		forceReinstall = true
 	}
 
-	// Delete existing ffprobe if any, if forceUpdate is true
-	if forceReinstall || ffprobeCorruptionCheck(ffprobePath) != nil {
-		if fileExists(ffprobePath) {
-			err := os.Remove(ffprobePath)
-			if err != nil {
-				return fmt.Errorf("ytdlp instancer: failed to delete old ffprobe: %w", err)
+	// An offline bundle replaces downloads
+	if bundlePath := getToolSetting(settingsChecker, "tool_bundle_path"); bundlePath != "" {
+		installed, err := installFromBundle(bundlePath, []bundleTool{
+			{name: ToolFfmpeg, target: ffmpegPath, fileNames: []string{getFfmpegExecutableFileName()}},
+			{name: ToolFfprobe, target: ffprobePath, fileNames: []string{getFfprobeExecutableFileName()}},
+		}, func() error {
+			if err := ffmpegCorruptionCheck(ffmpegPath); err != nil {
+				return err
 			}
+			return ffprobeCorruptionCheck(ffprobePath)
+		})
+		if err != nil {
+			return fmt.Errorf("ytdlp instancer: failed to install ffmpeg from bundle: %w", err)
+		}
+		if installed && logger != nil {
+			logger.Info(fmt.Sprintf("ffmpeg and ffprobe installed from bundle %s", bundlePath))
 		}
-		forceReinstall = true
+		return nil
 	}
 
+	manifest, err := readToolManifest()
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: %w", err)
+	}
+	pinned := getToolSetting(settingsChecker, "ffmpeg_version")
+	installedRelease := manifest[ToolFfmpeg].Release
+	pinChanged := (pinned != "" && installedRelease != pinned) ||
+		(pinned == "" && installedRelease != "" && installedRelease != latestRelease)
+
 	// Already exists, no update needed
-	if !forceReinstall && fileExists(ffmpegPath) && fileExists(ffprobePath) {
+	if !forceReinstall && !pinChanged && fileExists(ffmpegPath) && fileExists(ffprobePath) {
 		if logger != nil {
 			logger.Debug("ffmpeg and ffprobe already exist, no update needed")
 		}
 		return nil
 	}
 
-	switch runtime.GOOS {
-	case "windows":
-		if logger != nil {
-			logger.Debug("Downloading ffmpeg for Windows...")
-		}
-
-		// Download ffmpeg to temp file
-		downloadUrl := "https://www.gyan.dev/ffmpeg/builds/ffmpeg-release-essentials.7z"
-		tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("videoarchiver-ffmpeg-%d.7z", time.Now().UnixNano()))
-		defer os.Remove(tmpFile)
-
-		// Download archive to temp file
-		err = downloadFileHttp(downloadUrl, tmpFile)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to download ffmpeg: %w", err)
-		}
-
-		if logger != nil {
-			logger.Debug("Extracting ffmpeg and ffprobe...")
-		}
-
-		// Extract ffmpeg.exe
-		err = extractFile(tmpFile, "bin/ffmpeg.exe", ffmpegPath)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to extract ffmpeg: %w", err)
-		}
-
-		// Extract ffprobe.exe
-		err = extractFile(tmpFile, "bin/ffprobe.exe", ffprobePath)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to extract ffprobe: %w", err)
-		}
-	case "linux":
-		if logger != nil {
-			logger.Debug("Downloading ffmpeg for Linux...")
-		}
-
-		// Identify download for architecture
-		var archStr string
-		switch runtime.GOARCH {
-		case "amd64":
-			archStr = "linux64"
-		case "arm64":
-			archStr = "linuxarm64"
-		default:
-			return fmt.Errorf("ytdlp instancer: unsupported architecture: %s", runtime.GOARCH)
-		}
-
-		tarName := fmt.Sprintf("ffmpeg-master-latest-%s-lgpl", archStr)
-		downloadUrl := fmt.Sprintf("https://github.com/BtbN/FFmpeg-Builds/releases/download/latest/%s.tar.xz", tarName)
-
-		// Download ffmpeg to temp file
-		tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("videoarchiver-ffmpeg-%d.tar.xz", time.Now().UnixNano()))
-		defer os.Remove(tmpFile)
-
-		// Download ffmpeg to temp file
-		err = downloadFileHttp(downloadUrl, tmpFile)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to download ffmpeg: %w", err)
-		}
+	release, err := resolveFfmpegRelease(pinned)
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: %w", err)
+	}
 
-		if logger != nil {
-			logger.Debug("Extracting ffmpeg and ffprobe...")
-		}
+	if logger != nil {
+		logger.Debug(fmt.Sprintf("Downloading ffmpeg release %s...", release.Release))
+	}
 
-		// Extract ffmpeg
-		err = extractFile(tmpFile, "bin/ffmpeg", ffmpegPath)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to extract ffmpeg: %w", err)
-		}
+	// Download the archive to a temp file and verify it before extracting
+	extension := ".tar.xz"
+	if runtime.GOOS == "windows" {
+		extension = ".7z"
+	}
+	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("videoarchiver-ffmpeg-%d%s", time.Now().UnixNano(), extension))
+	defer os.Remove(tmpFile)
+	if err := downloadVerified(release, tmpFile); err != nil {
+		return fmt.Errorf("ytdlp instancer: failed to download ffmpeg: %w", err)
+	}
 
-		// Extract ffprobe
-		err = extractFile(tmpFile, "bin/ffprobe", ffprobePath)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to extract ffprobe: %w", err)
-		}
+	if logger != nil {
+		logger.Debug("Extracting ffmpeg and ffprobe...")
+	}
 
-		// Make executable on unix
-		err = os.Chmod(ffmpegPath, 0755)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to make ffmpeg executable: %w", err)
+	downloads := map[string]string{
+		ffmpegPath:  ffmpegPath + downloadSuffix,
+		ffprobePath: ffprobePath + downloadSuffix,
+	}
+	for target, download := range downloads {
+		if err := extractFile(tmpFile, "bin/"+filepath.Base(target), download); err != nil {
+			for _, download := range downloads {
+				os.Remove(download)
+			}
+			return fmt.Errorf("ytdlp instancer: failed to extract %s: %w", filepath.Base(target), err)
 		}
+	}
 
-		// Make executable on unix
-		err = os.Chmod(ffprobePath, 0755)
-		if err != nil {
-			return fmt.Errorf("ytdlp instancer: failed to make ffprobe executable: %w", err)
+	err = swapInTools(downloads, func() error {
+		if err := ffmpegCorruptionCheck(ffmpegPath); err != nil {
+			return err
 		}
-	default:
-		return fmt.Errorf("ytdlp instancer: unsupported OS: %s", runtime.GOOS)
+		return ffprobeCorruptionCheck(ffprobePath)
+	})
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: failed to install ffmpeg release %s: %w", release.Release, err)
+	}
+	if err := recordToolRelease(release, ToolFfmpeg, ToolFfprobe); err != nil {
+		return fmt.Errorf("ytdlp instancer: %w", err)
 	}
 
 	if logger != nil {
-		logger.Info("ffmpeg and ffprobe installation completed successfully")
+		logger.Info(fmt.Sprintf("ffmpeg and ffprobe release %s installed successfully", release.Release))
 	}
 	return nil
 }
 
 // Get full path to the ytdlp executable
 func getYtdlpPath() (string, error) {
-	return pathing.GetWorkingFile(getYtdlpExecutableFileName())
+	if ytdlpPath, external := getExternalTool(ToolYtdlp); external {
+		return ytdlpPath, nil
+	}
+	return pathing.GetFile(pathing.DirCache, getYtdlpExecutableFileName())
 }
 
 // Get full path to the ffmpeg executable
 func getFfmpegPath() (string, error) {
+	if ffmpegPath, external := getExternalTool(ToolFfmpeg); external {
+		return ffmpegPath, nil
+	}
 	if ffmpegExecutableFullPath == "" {
-		p, err := pathing.GetWorkingFile(getFfmpegExecutableFileName())
+		p, err := pathing.GetFile(pathing.DirCache, getFfmpegExecutableFileName())
 		if err != nil {
 			return "", err
 		}
@@ -326,8 +365,11 @@ func getFfmpegPath() (string, error) {
 }
 
 func getFfprobePath() (string, error) {
+	if ffprobePath, external := getExternalTool(ToolFfprobe); external {
+		return ffprobePath, nil
+	}
 	if ffprobeExecutableFullPath == "" {
-		p, err := pathing.GetWorkingFile(getFfprobeExecutableFileName())
+		p, err := pathing.GetFile(pathing.DirCache, getFfprobeExecutableFileName())
 		if err != nil {
 			return "", err
 		}
@@ -345,6 +387,11 @@ func getFfmpegDir() (string, error) {
 	return filepath.Dir(ffmpegPath), nil
 }
 
+// ToolFileNames returns the file names of the downloaded yt-dlp, ffmpeg and ffprobe executables
+func ToolFileNames() []string {
+	return []string{getYtdlpExecutableFileName(), getFfmpegExecutableFileName(), getFfprobeExecutableFileName()}
+}
+
 // Get the name of the ytdlp executable on the current OS
 func getYtdlpExecutableFileName() string {
 	if ytdlpExecutableFileName != "" {
@@ -441,15 +488,30 @@ func ffprobeCorruptionCheck(ffprobePath string) error {
 }
 
 func ytdlpCorruptionCheck(ytdlpPath string) error {
-	_, err := runCommand("--version")
+	// The first start of a onefile build unpacks itself, which can take a while
+	_, err := runner.RunCombinedOutputWithTimeout(60*time.Second, ytdlpPath, "--version")
 	if err != nil {
 		return fmt.Errorf("ytdlp corruption check failed, reinstalling: %v", err)
 	}
 	return nil
 }
 
+// errNotInArchive is returned when an archive has no matching file
+var errNotInArchive = errors.New("not found in archive")
+
 // Extract a file from an archive
 func extractFile(archivePath, fileToExtract, outputFile string) error {
+	err := extractMatchingFile(archivePath, func(nameInArchive string) bool {
+		return strings.HasSuffix(nameInArchive, fileToExtract)
+	}, outputFile)
+	if errors.Is(err, errNotInArchive) {
+		return fmt.Errorf("file %s %w", fileToExtract, err)
+	}
+	return err
+}
+
+// Extract the first file an archive has that matches
+func extractMatchingFile(archivePath string, matches func(nameInArchive string) bool, outputFile string) error {
 	file, err := os.Open(archivePath)
 	if err != nil {
 		return err
@@ -466,7 +528,7 @@ func extractFile(archivePath, fileToExtract, outputFile string) error {
 
 		file.Seek(0, 0)
 		err := ex.Extract(context.Background(), file, func(ctx context.Context, f archives.FileInfo) error {
-			if strings.HasSuffix(f.NameInArchive, fileToExtract) {
+			if !found && !f.IsDir() && matches(f.NameInArchive) {
 				found = true
 				outFile, err := os.Create(outputFile)
 				if err != nil {
@@ -489,7 +551,7 @@ func extractFile(archivePath, fileToExtract, outputFile string) error {
 			return err
 		}
 		if !found {
-			return fmt.Errorf("file %s not found in archive", fileToExtract)
+			return errNotInArchive
 		}
 		return nil
 	}
@@ -8,6 +8,9 @@ type YtdlpPlaylistInfo struct {
 }
 
 type YtdlpEntry struct {
-	Title string
-	URL   string
+	Title     string
+	URL       string
+	ID        string // Video ID on the site, empty if unknown
+	Extractor string // Normalized extractor key, empty if unknown
+	Index     int    // 1-based position in the playlist
 }
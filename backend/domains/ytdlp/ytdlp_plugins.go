@@ -0,0 +1,270 @@
+package ytdlp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/pathing"
+)
+
+const (
+	pluginsDirName         = "yt-dlp-plugins" // Passed to yt-dlp with --plugin-dirs
+	pluginManifestFileName = "plugins.json"   // Installed plugins, kept in the plugins directory
+)
+
+// Plugin is a yt-dlp plugin installed into the plugins directory
+type Plugin struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	SourceURL   string `json:"source_url"`
+	Sha256      string `json:"sha256"`
+	Verified    bool   `json:"verified"` // The download matched a checksum from the ytdlp_plugins setting
+	InstalledAt int64  `json:"installed_at"`
+}
+
+// pluginSpec is a plugin listed in the ytdlp_plugins setting
+type pluginSpec struct {
+	name   string
+	url    string
+	sha256 string // Empty if the setting has no checksum for it
+}
+
+var (
+	argsSettingsMutex sync.RWMutex
+	argsSettings      SettingsChecker // Read on every yt-dlp call for the extractor arguments
+)
+
+// useArgsSettings sets the settings every yt-dlp call reads its extractor arguments from
+func useArgsSettings(settingsChecker SettingsChecker) {
+	argsSettingsMutex.Lock()
+	defer argsSettingsMutex.Unlock()
+	argsSettings = settingsChecker
+}
+
+// getExtraArgs returns the arguments every yt-dlp call starts with:
+// the plugins directory if plugins are installed and the extractor arguments from the settings
+func getExtraArgs() []string {
+	argsSettingsMutex.RLock()
+	settingsChecker := argsSettings
+	argsSettingsMutex.RUnlock()
+
+	var args []string
+	if plugins, err := GetInstalledPlugins(); err == nil && len(plugins) > 0 {
+		if pluginsDir, err := getPluginsDir(); err == nil {
+			args = append(args, "--plugin-dirs", pluginsDir)
+		}
+	}
+	return append(args, buildExtractorArgs(getToolSetting(settingsChecker, "ytdlp_extractor_args"))...)
+}
+
+// buildExtractorArgs turns lines of EXTRACTOR:ARGS into --extractor-args flags, empty lines are ignored
+func buildExtractorArgs(value string) []string {
+	var args []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			args = append(args, "--extractor-args", line)
+		}
+	}
+	return args
+}
+
+// parsePluginSpecs parses lines of NAME=URL with an optional #sha256=CHECKSUM, empty lines are ignored
+func parsePluginSpecs(value string) ([]pluginSpec, error) {
+	var specs []pluginSpec
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, pluginUrl, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("%q is not NAME=URL", line)
+		}
+		pluginUrl, checksum, _ := strings.Cut(strings.TrimSpace(pluginUrl), "#sha256=")
+		if pluginUrl == "" {
+			return nil, fmt.Errorf("plugin %s has no URL", name)
+		}
+		specs = append(specs, pluginSpec{name: name, url: pluginUrl, sha256: strings.ToLower(checksum)})
+	}
+	return specs, nil
+}
+
+// pluginFileName returns the file a plugin is installed as, yt-dlp loads .zip and .whl packages
+func pluginFileName(spec pluginSpec) (string, error) {
+	extension := strings.ToLower(path.Ext(strings.SplitN(spec.url, "?", 2)[0]))
+	if extension != ".zip" && extension != ".whl" {
+		return "", fmt.Errorf("plugin %s: %s is not a .zip or .whl", spec.name, spec.url)
+	}
+	return spec.name + extension, nil
+}
+
+// installPlugins installs the plugins from the ytdlp_plugins setting and removes plugins no longer listed.
+// Plugins are downloaded again when their URL or checksum changes.
+func installPlugins(settingsChecker SettingsChecker, logger *logging.LogService) error {
+	specs, err := parsePluginSpecs(getToolSetting(settingsChecker, "ytdlp_plugins"))
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: invalid ytdlp_plugins setting: %w", err)
+	}
+	return syncPlugins(specs, downloadFileHttp, logger)
+}
+
+// syncPlugins makes the plugins directory match the listed plugins, download fetches a URL to a file
+func syncPlugins(specs []pluginSpec, download func(url, filePath string) error, logger *logging.LogService) error {
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return fmt.Errorf("ytdlp instancer: failed to create plugins directory: %w", err)
+	}
+	manifest, err := readPluginManifest()
+	if err != nil {
+		// A corrupt manifest is rewritten, the plugins it listed are downloaded again
+		manifest = map[string]Plugin{}
+	}
+
+	var errs []error
+	listed := map[string]bool{}
+	for _, spec := range specs {
+		listed[spec.name] = true
+		fileName, err := pluginFileName(spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		filePath := filepath.Join(pluginsDir, fileName)
+
+		installed, ok := manifest[spec.name]
+		if ok && installed.File == fileName && installed.SourceURL == spec.url && fileExists(filePath) &&
+			(spec.sha256 == "" || strings.EqualFold(installed.Sha256, spec.sha256)) {
+			installed.Verified = installed.Verified || spec.sha256 != ""
+			manifest[spec.name] = installed
+			continue
+		}
+
+		if logger != nil {
+			logger.Info(fmt.Sprintf("Installing yt-dlp plugin %s from %s...", spec.name, spec.url))
+		}
+		plugin, err := installPlugin(spec, pluginsDir, fileName, download)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ytdlp instancer: failed to install plugin %s: %w", spec.name, err))
+			continue
+		}
+		if !plugin.Verified && logger != nil {
+			logger.Warn(fmt.Sprintf("yt-dlp plugin %s has no checksum in ytdlp_plugins, it was not verified", spec.name))
+		}
+		// A plugin that changed between .zip and .whl leaves the old file behind
+		if ok && installed.File != fileName {
+			os.Remove(filepath.Join(pluginsDir, installed.File))
+		}
+		manifest[spec.name] = plugin
+	}
+
+	// Only files the manifest lists are removed, the directory may hold plugins added by hand
+	for name, plugin := range manifest {
+		if listed[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(pluginsDir, plugin.File)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("ytdlp instancer: failed to remove plugin %s: %w", name, err))
+			continue
+		}
+		if logger != nil {
+			logger.Info(fmt.Sprintf("Removed yt-dlp plugin %s", name))
+		}
+		delete(manifest, name)
+	}
+
+	if err := writePluginManifest(manifest); err != nil {
+		errs = append(errs, fmt.Errorf("ytdlp instancer: failed to write plugin manifest: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// installPlugin downloads a plugin next to its file and moves it in once it matches the checksum
+func installPlugin(spec pluginSpec, pluginsDir, fileName string, download func(url, filePath string) error) (Plugin, error) {
+	filePath := filepath.Join(pluginsDir, fileName)
+	downloadPath := filePath + downloadSuffix
+	if err := download(spec.url, downloadPath); err != nil {
+		os.Remove(downloadPath)
+		return Plugin{}, err
+	}
+	defer os.Remove(downloadPath)
+
+	if spec.sha256 != "" {
+		if err := verifyChecksum(downloadPath, spec.sha256); err != nil {
+			return Plugin{}, err
+		}
+	}
+	checksum, err := fileSha256(downloadPath)
+	if err != nil {
+		return Plugin{}, fmt.Errorf("failed to hash %s: %w", downloadPath, err)
+	}
+	if err := os.Rename(downloadPath, filePath); err != nil {
+		return Plugin{}, err
+	}
+	return Plugin{
+		Name:        spec.name,
+		File:        fileName,
+		SourceURL:   spec.url,
+		Sha256:      checksum,
+		Verified:    spec.sha256 != "",
+		InstalledAt: time.Now().Unix(),
+	}, nil
+}
+
+// GetInstalledPlugins returns the plugins installed into the plugins directory, sorted by name
+func GetInstalledPlugins() ([]Plugin, error) {
+	manifest, err := readPluginManifest()
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]Plugin, 0, len(manifest))
+	for _, plugin := range manifest {
+		plugins = append(plugins, plugin)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+func getPluginsDir() (string, error) {
+	return pathing.GetWorkingDir(pluginsDirName)
+}
+
+// readPluginManifest returns the installed plugins by name, empty if none were installed yet
+func readPluginManifest() (map[string]Plugin, error) {
+	manifest := map[string]Plugin{}
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(pluginsDir, pluginManifestFileName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func writePluginManifest(manifest map[string]Plugin) error {
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(pluginsDir, pluginManifestFileName), data, 0644)
+}
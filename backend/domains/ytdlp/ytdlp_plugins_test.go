@@ -0,0 +1,99 @@
+package ytdlp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"videoarchiver/backend/domains/pathing"
+)
+
+func TestBuildExtractorArgs(t *testing.T) {
+	args := buildExtractorArgs("youtube:player_client=default,mweb\n\n  generic:impersonate  \n")
+	expected := []string{"--extractor-args", "youtube:player_client=default,mweb", "--extractor-args", "generic:impersonate"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("got %v, expected %v", args, expected)
+	}
+	if args := buildExtractorArgs(""); len(args) != 0 {
+		t.Errorf("expected no arguments, got %v", args)
+	}
+}
+
+func TestParsePluginSpecs(t *testing.T) {
+	specs, err := parsePluginSpecs("pot = https://example.com/pot.zip#sha256=ABC\n\nextra=https://example.com/extra.whl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []pluginSpec{
+		{name: "pot", url: "https://example.com/pot.zip", sha256: "abc"},
+		{name: "extra", url: "https://example.com/extra.whl"},
+	}
+	if !reflect.DeepEqual(specs, expected) {
+		t.Errorf("got %+v, expected %+v", specs, expected)
+	}
+	if _, err := parsePluginSpecs("https://example.com/pot.zip"); err == nil {
+		t.Error("expected a line without a name to be rejected")
+	}
+}
+
+func TestSyncPlugins(t *testing.T) {
+	t.Setenv(pathing.DataDirEnv, t.TempDir())
+	downloads := 0
+	content := "plugin"
+	download := func(url, filePath string) error {
+		downloads++
+		return os.WriteFile(filePath, []byte(content), 0644)
+	}
+	checksum := "5e689e2b01672bf33996e75d5e372ff60c536ce1599a1458e867cd8f4bef5160" // sha256 of "plugin"
+
+	specs := []pluginSpec{{name: "pot", url: "https://example.com/pot.zip"}}
+	if err := syncPlugins(specs, download, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pluginsDir, _ := getPluginsDir()
+	if content := readFile(t, filepath.Join(pluginsDir, "pot.zip")); content != "plugin" {
+		t.Errorf("expected the plugin to be installed, got %q", content)
+	}
+	plugins, _ := GetInstalledPlugins()
+	if len(plugins) != 1 || plugins[0].Verified || plugins[0].Sha256 != checksum {
+		t.Fatalf("unexpected plugins: %+v", plugins)
+	}
+
+	// Nothing is downloaded when the plugin is up to date, also when the setting adds its checksum
+	specs[0].sha256 = checksum
+	if err := syncPlugins(specs, download, nil); err != nil || downloads != 1 {
+		t.Errorf("expected no download, got %d downloads, %v", downloads, err)
+	}
+	if plugins, _ := GetInstalledPlugins(); len(plugins) != 1 || !plugins[0].Verified {
+		t.Errorf("expected the plugin to be verified, got %+v", plugins)
+	}
+
+	// A checksum that does not match keeps the installed plugin
+	content = "tampered"
+	specs[0].sha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if err := syncPlugins(specs, download, nil); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if content := readFile(t, filepath.Join(pluginsDir, "pot.zip")); content != "plugin" {
+		t.Errorf("expected the installed plugin to stay, got %q", content)
+	}
+	if fileExists(filepath.Join(pluginsDir, "pot.zip"+downloadSuffix)) {
+		t.Error("expected the rejected download to be removed")
+	}
+
+	// Plugins no longer listed are removed, files added by hand stay
+	writeFile(t, filepath.Join(pluginsDir, "manual.zip"), "manual")
+	if err := syncPlugins(nil, download, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fileExists(filepath.Join(pluginsDir, "pot.zip")) {
+		t.Error("expected the plugin to be removed")
+	}
+	if !fileExists(filepath.Join(pluginsDir, "manual.zip")) {
+		t.Error("expected the plugin added by hand to stay")
+	}
+	if plugins, _ := GetInstalledPlugins(); len(plugins) != 0 {
+		t.Errorf("expected no plugins, got %+v", plugins)
+	}
+}
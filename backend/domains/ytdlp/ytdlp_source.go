@@ -0,0 +1,293 @@
+package ytdlp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Sources of the yt-dlp, ffmpeg and ffprobe binaries, from the tool_source setting
+const (
+	ToolSourceManaged = "managed" // Downloaded and updated by the application, or installed from tool_bundle_path
+	ToolSourceSystem  = "system"  // Found on PATH, updated by the system
+	ToolSourceCustom  = "custom"  // Paths from ytdlp_path, ffmpeg_path and ffprobe_path, empty paths are managed
+)
+
+// Release recorded for tools installed from an offline bundle
+const bundleRelease = "bundle"
+
+// Checksum files an offline bundle may contain, tools are verified against the first one found
+var bundleChecksumFiles = []string{"SHA2-256SUMS", "SHA256SUMS", "checksums.sha256"}
+
+var (
+	toolSourceMutex sync.RWMutex
+	toolSource      = ToolSourceManaged
+	externalTools   = map[string]string{} // Paths of tools the application does not manage, by tool name
+)
+
+// ConfigureToolSource applies the tool_source setting. Tools from PATH or explicit paths must exist.
+func ConfigureToolSource(settingsChecker SettingsChecker) error {
+	source := getToolSetting(settingsChecker, "tool_source")
+	if source == "" {
+		source = ToolSourceManaged
+	}
+
+	external := map[string]string{}
+	switch source {
+	case ToolSourceManaged:
+	case ToolSourceSystem:
+		for _, tool := range []string{ToolYtdlp, ToolFfmpeg, ToolFfprobe} {
+			toolPath, err := exec.LookPath(tool)
+			if err != nil {
+				return fmt.Errorf("%s was not found on PATH: %w", tool, err)
+			}
+			external[tool] = toolPath
+		}
+	case ToolSourceCustom:
+		for tool, key := range map[string]string{ToolYtdlp: "ytdlp_path", ToolFfmpeg: "ffmpeg_path", ToolFfprobe: "ffprobe_path"} {
+			toolPath := getToolSetting(settingsChecker, key)
+			if toolPath == "" {
+				continue
+			}
+			if info, err := os.Stat(toolPath); err != nil || info.IsDir() {
+				return fmt.Errorf("%s %s is not a file", key, toolPath)
+			}
+			external[tool] = toolPath
+		}
+		// Both come from the same ffmpeg archive when managed
+		if (external[ToolFfmpeg] == "") != (external[ToolFfprobe] == "") {
+			return fmt.Errorf("ffmpeg_path and ffprobe_path must be set together")
+		}
+	default:
+		return fmt.Errorf("unknown tool source: %s", source)
+	}
+
+	toolSourceMutex.Lock()
+	defer toolSourceMutex.Unlock()
+	toolSource = source
+	externalTools = external
+	return nil
+}
+
+// getExternalTool returns the path of a tool the application does not manage, false if it is managed
+func getExternalTool(tool string) (string, bool) {
+	toolSourceMutex.RLock()
+	defer toolSourceMutex.RUnlock()
+	toolPath, ok := externalTools[tool]
+	return toolPath, ok
+}
+
+// getToolSource returns where a tool comes from
+func getToolSource(tool string) string {
+	toolSourceMutex.RLock()
+	defer toolSourceMutex.RUnlock()
+	if _, ok := externalTools[tool]; ok {
+		return toolSource
+	}
+	return ToolSourceManaged
+}
+
+// CheckTools returns an error unless yt-dlp, ffmpeg and ffprobe are installed and run.
+// Startup continues with working binaries when updating them failed.
+func CheckTools() error {
+	ytdlpPath, err := getYtdlpPath()
+	if err != nil {
+		return err
+	}
+	ffmpegPath, err := getFfmpegPath()
+	if err != nil {
+		return err
+	}
+	ffprobePath, err := getFfprobePath()
+	if err != nil {
+		return err
+	}
+
+	for _, check := range []struct {
+		path      string
+		corrupted func(string) error
+	}{{ytdlpPath, ytdlpCorruptionCheck}, {ffmpegPath, ffmpegCorruptionCheck}, {ffprobePath, ffprobeCorruptionCheck}} {
+		if !fileExists(check.path) {
+			return fmt.Errorf("%s is not installed", check.path)
+		}
+		if err := check.corrupted(check.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkExternalTool returns an error if a tool that is not managed does not run
+func checkExternalTool(path string, corrupted func(string) error) error {
+	if err := corrupted(path); err != nil {
+		return fmt.Errorf("ytdlp instancer: %s does not run: %w", path, err)
+	}
+	return nil
+}
+
+// bundleTool is a tool installed from an offline bundle
+type bundleTool struct {
+	name      string
+	target    string   // Installed binary
+	fileNames []string // Names the binary may have in the bundle
+}
+
+// installFromBundle installs tools from a directory or archive unless the installed binaries already match.
+// If the bundle has a checksum file, the binaries are verified against it.
+func installFromBundle(bundlePath string, tools []bundleTool, smokeTest func() error) (bool, error) {
+	wanted := append([]string{}, bundleChecksumFiles...)
+	for _, tool := range tools {
+		wanted = append(wanted, tool.fileNames...)
+	}
+	bundleDir, cleanup, err := openBundle(bundlePath, wanted)
+	if err != nil {
+		return false, fmt.Errorf("failed to open bundle %s: %w", bundlePath, err)
+	}
+	defer cleanup()
+
+	files := findBundleFiles(bundleDir, wanted)
+	var checksums map[string]string
+	for _, name := range bundleChecksumFiles {
+		if checksumPath, ok := files[name]; ok {
+			data, err := os.ReadFile(checksumPath)
+			if err != nil {
+				return false, fmt.Errorf("failed to read %s: %w", checksumPath, err)
+			}
+			checksums = parseChecksums(string(data))
+			break
+		}
+	}
+
+	downloads := map[string]string{}
+	releases := map[string]toolRelease{}
+	upToDate := true
+	for _, tool := range tools {
+		source, fileName := "", ""
+		for _, name := range tool.fileNames {
+			if filePath, ok := files[name]; ok {
+				source, fileName = filePath, name
+				break
+			}
+		}
+		if source == "" {
+			return false, fmt.Errorf("bundle %s has no %s", bundlePath, tool.name)
+		}
+
+		checksum, err := fileSha256(source)
+		if err != nil {
+			return false, fmt.Errorf("failed to hash %s: %w", source, err)
+		}
+		if checksums != nil {
+			expected, ok := checksums[fileName]
+			if !ok {
+				return false, fmt.Errorf("bundle %s has no checksum for %s", bundlePath, fileName)
+			}
+			if err := verifyChecksum(source, expected); err != nil {
+				return false, err
+			}
+		}
+		releases[tool.name] = toolRelease{Release: bundleRelease, URL: source, Sha256: checksum}
+		downloads[tool.target] = tool.target + downloadSuffix
+
+		if installed, err := fileSha256(tool.target); err != nil || installed != checksum {
+			upToDate = false
+		}
+	}
+	if upToDate {
+		return false, nil
+	}
+
+	for _, tool := range tools {
+		if err := copyFile(releases[tool.name].URL, downloads[tool.target]); err != nil {
+			for _, download := range downloads {
+				os.Remove(download)
+			}
+			return false, fmt.Errorf("failed to copy %s from bundle: %w", tool.name, err)
+		}
+	}
+	if err := swapInTools(downloads, smokeTest); err != nil {
+		return false, err
+	}
+
+	// Recorded with the bundle itself, files extracted from an archive are temporary
+	for _, tool := range tools {
+		release := releases[tool.name]
+		release.URL = bundlePath
+		if err := recordToolRelease(release, tool.name); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// openBundle returns a directory with the bundle's files. Archives are extracted to a temporary directory.
+func openBundle(bundlePath string, wanted []string) (string, func(), error) {
+	info, err := os.Stat(bundlePath)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return bundlePath, func() {}, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "videoarchiver-bundle-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+	for _, name := range wanted {
+		err := extractMatchingFile(bundlePath, func(nameInArchive string) bool {
+			return path.Base(nameInArchive) == name
+		}, filepath.Join(tmpDir, name))
+		if err != nil && !errors.Is(err, errNotInArchive) {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	return tmpDir, cleanup, nil
+}
+
+// findBundleFiles finds wanted files anywhere in a directory by name, the first match wins
+func findBundleFiles(dir string, wanted []string) map[string]string {
+	isWanted := map[string]bool{}
+	for _, name := range wanted {
+		isWanted[name] = true
+	}
+	files := map[string]string{}
+	filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if isWanted[entry.Name()] {
+			if _, found := files[entry.Name()]; !found {
+				files[entry.Name()] = filePath
+			}
+		}
+		return nil
+	})
+	return files
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
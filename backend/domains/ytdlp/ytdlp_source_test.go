@@ -0,0 +1,118 @@
+package ytdlp
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"videoarchiver/backend/domains/pathing"
+)
+
+type fakeSettings map[string]string
+
+func (f fakeSettings) GetSettingString(key string) (string, error) {
+	return f[key], nil
+}
+
+func TestConfigureToolSourceCustom(t *testing.T) {
+	t.Cleanup(func() { ConfigureToolSource(nil) })
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	ffprobe := filepath.Join(dir, "ffprobe")
+	writeFile(t, ffmpeg, "ffmpeg")
+	writeFile(t, ffprobe, "ffprobe")
+
+	err := ConfigureToolSource(fakeSettings{"tool_source": ToolSourceCustom, "ffmpeg_path": ffmpeg})
+	if err == nil || !strings.Contains(err.Error(), "set together") {
+		t.Errorf("expected ffmpeg without ffprobe to be rejected, got %v", err)
+	}
+	err = ConfigureToolSource(fakeSettings{"tool_source": ToolSourceCustom, "ytdlp_path": filepath.Join(dir, "missing")})
+	if err == nil {
+		t.Error("expected a missing yt-dlp path to be rejected")
+	}
+
+	err = ConfigureToolSource(fakeSettings{"tool_source": ToolSourceCustom, "ffmpeg_path": ffmpeg, "ffprobe_path": ffprobe})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path, _ := getFfmpegPath(); path != ffmpeg {
+		t.Errorf("expected the custom ffmpeg, got %s", path)
+	}
+	if getToolSource(ToolFfprobe) != ToolSourceCustom || getToolSource(ToolYtdlp) != ToolSourceManaged {
+		t.Error("expected ffmpeg and ffprobe to be custom and yt-dlp to be managed")
+	}
+}
+
+func TestInstallFromBundle(t *testing.T) {
+	t.Setenv(pathing.DataDirEnv, t.TempDir())
+	bundle := t.TempDir()
+	writeFile(t, filepath.Join(bundle, "yt-dlp"), "bundled")
+	target := filepath.Join(t.TempDir(), "yt-dlp")
+	tools := []bundleTool{{name: ToolYtdlp, target: target, fileNames: []string{"yt-dlp"}}}
+	passes := func() error { return nil }
+
+	installed, err := installFromBundle(bundle, tools, passes)
+	if err != nil || !installed {
+		t.Fatalf("expected an install, got %v, %v", installed, err)
+	}
+	if content := readFile(t, target); content != "bundled" {
+		t.Errorf("expected the bundled binary, got %q", content)
+	}
+	manifest, _ := readToolManifest()
+	if manifest[ToolYtdlp].Release != bundleRelease || manifest[ToolYtdlp].SourceURL != bundle {
+		t.Errorf("unexpected manifest entry: %+v", manifest[ToolYtdlp])
+	}
+
+	// Nothing to do when the installed binary matches
+	if installed, err := installFromBundle(bundle, tools, passes); err != nil || installed {
+		t.Errorf("expected no install, got %v, %v", installed, err)
+	}
+
+	// A checksum file in the bundle must match
+	writeFile(t, filepath.Join(bundle, "yt-dlp"), "changed")
+	writeFile(t, filepath.Join(bundle, "SHA2-256SUMS"), "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  yt-dlp\n")
+	if _, err := installFromBundle(bundle, tools, passes); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if content := readFile(t, target); content != "bundled" {
+		t.Errorf("expected the installed binary to stay, got %q", content)
+	}
+}
+
+func TestInstallFromBundleArchive(t *testing.T) {
+	t.Setenv(pathing.DataDirEnv, t.TempDir())
+	archivePath := filepath.Join(t.TempDir(), "tools.zip")
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(archive)
+	for name, content := range map[string]string{"bin/ffmpeg": "ffmpeg", "bin/ffprobe": "ffprobe", "doc/ffmpeg.txt": "docs"} {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(file, content)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	dir := t.TempDir()
+	tools := []bundleTool{
+		{name: ToolFfmpeg, target: filepath.Join(dir, "ffmpeg"), fileNames: []string{"ffmpeg"}},
+		{name: ToolFfprobe, target: filepath.Join(dir, "ffprobe"), fileNames: []string{"ffprobe"}},
+	}
+	if _, err := installFromBundle(archivePath, tools, func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content := readFile(t, filepath.Join(dir, "ffmpeg")); content != "ffmpeg" {
+		t.Errorf("expected ffmpeg from the archive, got %q", content)
+	}
+	if content := readFile(t, filepath.Join(dir, "ffprobe")); content != "ffprobe" {
+		t.Errorf("expected ffprobe from the archive, got %q", content)
+	}
+}
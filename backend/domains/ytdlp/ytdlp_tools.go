@@ -0,0 +1,484 @@
+package ytdlp
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/pathing"
+	"videoarchiver/backend/domains/runner"
+)
+
+// Names of the managed tools
+const (
+	ToolYtdlp   = "yt-dlp"
+	ToolFfmpeg  = "ffmpeg"
+	ToolFfprobe = "ffprobe"
+)
+
+const (
+	toolManifestFileName = "tools.json"
+	previousSuffix       = ".previous" // The replaced binary, restored when a new one fails its smoke test
+	downloadSuffix       = ".download" // A downloaded binary that is not verified yet
+	latestRelease        = "latest"
+)
+
+// ToolVersion describes an installed tool
+type ToolVersion struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"` // ToolSourceManaged, ToolSourceSystem or ToolSourceCustom
+	Path        string `json:"path"`
+	Version     string `json:"version"`      // Reported by the binary, empty if it does not run
+	Release     string `json:"release"`      // Release it was installed from, empty if installed before releases were recorded
+	Pinned      string `json:"pinned"`       // Pinned release from settings, empty follows the latest release
+	Sha256      string `json:"sha256"`       // Checksum of the verified download
+	SourceURL   string `json:"source_url"`   // Where the verified download came from
+	InstalledAt int64  `json:"installed_at"` // 0 if unknown
+	HasPrevious bool   `json:"has_previous"` // The replaced binary is kept to roll back to
+}
+
+// toolManifestEntry records where an installed tool came from, in tools.json next to the binaries
+type toolManifestEntry struct {
+	Release     string `json:"release"`
+	Sha256      string `json:"sha256"`
+	SourceURL   string `json:"source_url"`
+	InstalledAt int64  `json:"installed_at"`
+}
+
+// toolRelease is a download with the checksum it is verified against
+type toolRelease struct {
+	Release string
+	URL     string
+	Sha256  string
+}
+
+// GetInstalledToolVersions returns the installed yt-dlp, ffmpeg and ffprobe with the release they came from
+func GetInstalledToolVersions(settingsChecker SettingsChecker) ([]ToolVersion, error) {
+	manifest, err := readToolManifest()
+	if err != nil {
+		return nil, err
+	}
+	ytdlpPath, err := getYtdlpPath()
+	if err != nil {
+		return nil, err
+	}
+	ffmpegPath, err := getFfmpegPath()
+	if err != nil {
+		return nil, err
+	}
+	ffprobePath, err := getFfprobePath()
+	if err != nil {
+		return nil, err
+	}
+
+	tools := []struct {
+		name, path, pinSetting string
+		versionArg             string
+	}{
+		{ToolYtdlp, ytdlpPath, "ytdlp_version", "--version"},
+		{ToolFfmpeg, ffmpegPath, "ffmpeg_version", "-version"},
+		{ToolFfprobe, ffprobePath, "ffmpeg_version", "-version"},
+	}
+
+	versions := make([]ToolVersion, 0, len(tools))
+	for _, tool := range tools {
+		// The manifest only describes binaries the application installed
+		entry := manifest[tool.name]
+		if getToolSource(tool.name) != ToolSourceManaged {
+			entry = toolManifestEntry{}
+		}
+		version := ToolVersion{
+			Name:        tool.name,
+			Source:      getToolSource(tool.name),
+			Path:        tool.path,
+			Release:     entry.Release,
+			Pinned:      getToolSetting(settingsChecker, tool.pinSetting),
+			Sha256:      entry.Sha256,
+			SourceURL:   entry.SourceURL,
+			InstalledAt: entry.InstalledAt,
+			HasPrevious: fileExists(tool.path + previousSuffix),
+		}
+		if fileExists(tool.path) {
+			if output, err := runner.RunCombinedOutputWithTimeout(30*time.Second, tool.path, tool.versionArg); err == nil {
+				version.Version = parseToolVersion(string(output))
+			}
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// GetYtdlpVersion returns the version the installed yt-dlp reports
+func GetYtdlpVersion() (string, error) {
+	ytdlpPath, err := getYtdlpPath()
+	if err != nil {
+		return "", err
+	}
+	output, err := runner.RunCombinedOutputWithTimeout(60*time.Second, ytdlpPath, "--version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get yt-dlp version: %w", err)
+	}
+	return parseToolVersion(string(output)), nil
+}
+
+// GetYtdlpSha256 returns the checksum of the yt-dlp executable, to detect a replaced binary that does not report its version
+func GetYtdlpSha256() (string, error) {
+	ytdlpPath, err := getYtdlpPath()
+	if err != nil {
+		return "", err
+	}
+	return fileSha256(ytdlpPath)
+}
+
+// UpdateYtdlp updates yt-dlp between downloads, the same way it is updated at startup.
+// Does nothing if yt-dlp comes from PATH or an explicit path.
+func UpdateYtdlp(settingsChecker SettingsChecker, logger *logging.LogService) error {
+	if !IsManaged(ToolYtdlp) {
+		return nil
+	}
+	return installUpdateYtdlp(settingsChecker, logger)
+}
+
+// IsManaged returns true if the application installs and updates a tool
+func IsManaged(tool string) bool {
+	_, external := getExternalTool(tool)
+	return !external
+}
+
+// parseToolVersion returns the version from `yt-dlp --version` or `ffmpeg -version` output
+func parseToolVersion(output string) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(firstLine)
+	// ffmpeg version N-118448-g4f3f1c5f2a Copyright ...
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+	return strings.TrimSpace(firstLine)
+}
+
+// getToolSetting returns a setting, empty if there is no settings checker or the setting can't be read
+func getToolSetting(settingsChecker SettingsChecker, key string) string {
+	if settingsChecker == nil {
+		return ""
+	}
+	value, err := settingsChecker.GetSettingString(key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// releaseName returns the name a pinned version is recorded under
+func releaseName(pinned string) string {
+	if pinned == "" {
+		return latestRelease
+	}
+	return pinned
+}
+
+// resolveYtdlpRelease finds the yt-dlp download for a release and its published SHA-256 checksum
+func resolveYtdlpRelease(version string) (toolRelease, error) {
+	baseUrl := baseYtdlpDownloadUrl
+	if version != "" {
+		baseUrl = fmt.Sprintf("https://github.com/yt-dlp/yt-dlp/releases/download/%s/", version)
+	}
+
+	fileName := getYtdlpExecutableFileName()
+	sums, err := fetchText(baseUrl + "SHA2-256SUMS")
+	if err != nil {
+		return toolRelease{}, fmt.Errorf("failed to get yt-dlp checksums for release %s: %w", releaseName(version), err)
+	}
+	checksum, ok := parseChecksums(sums)[fileName]
+	if !ok {
+		return toolRelease{}, fmt.Errorf("release %s of yt-dlp has no checksum for %s", releaseName(version), fileName)
+	}
+	return toolRelease{Release: releaseName(version), URL: baseUrl + fileName, Sha256: checksum}, nil
+}
+
+// resolveFfmpegRelease finds the ffmpeg archive for a release and its published SHA-256 checksum.
+// Linux builds come from BtbN/FFmpeg-Builds, pinned by release tag. Windows builds come from gyan.dev, pinned by FFmpeg version.
+func resolveFfmpegRelease(version string) (toolRelease, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if version == "" {
+			downloadUrl := "https://www.gyan.dev/ffmpeg/builds/ffmpeg-release-essentials.7z"
+			sum, err := fetchText(downloadUrl + ".sha256")
+			if err != nil {
+				return toolRelease{}, fmt.Errorf("failed to get ffmpeg checksum: %w", err)
+			}
+			fields := strings.Fields(sum)
+			if len(fields) == 0 {
+				return toolRelease{}, errors.New("ffmpeg checksum is empty")
+			}
+			return toolRelease{Release: latestRelease, URL: downloadUrl, Sha256: strings.ToLower(fields[0])}, nil
+		}
+		// Versioned gyan.dev builds are published on GitHub, which records the checksum of every asset
+		return resolveGithubAsset("GyanD/codexffmpeg", version, fmt.Sprintf("ffmpeg-%s-essentials_build.7z", version))
+	case "linux":
+		archStr, err := getBtbnArch()
+		if err != nil {
+			return toolRelease{}, err
+		}
+		tag := version
+		if tag == "" {
+			tag = latestRelease
+		}
+		baseUrl := fmt.Sprintf("https://github.com/BtbN/FFmpeg-Builds/releases/download/%s/", tag)
+		sums, err := fetchText(baseUrl + "checksums.sha256")
+		if err != nil {
+			return toolRelease{}, fmt.Errorf("failed to get ffmpeg checksums for release %s: %w", tag, err)
+		}
+		fileName, checksum, err := findBtbnAsset(parseChecksums(sums), archStr)
+		if err != nil {
+			return toolRelease{}, fmt.Errorf("release %s of ffmpeg: %w", tag, err)
+		}
+		return toolRelease{Release: releaseName(version), URL: baseUrl + fileName, Sha256: checksum}, nil
+	}
+	return toolRelease{}, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+}
+
+// getBtbnArch returns the architecture name used in BtbN build names
+func getBtbnArch() (string, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "linux64", nil
+	case "arm64":
+		return "linuxarm64", nil
+	}
+	return "", fmt.Errorf("unsupported architecture: %s", runtime.GOARCH)
+}
+
+// findBtbnAsset picks the static LGPL master build from a BtbN release.
+// Archive names include the git revision in tagged releases, so they are matched by their prefix and suffix.
+func findBtbnAsset(checksums map[string]string, archStr string) (string, string, error) {
+	suffix := "-" + archStr + "-lgpl.tar.xz"
+	for _, prefix := range []string{"ffmpeg-master-latest", "ffmpeg-N-"} {
+		for name, checksum := range checksums {
+			if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix) {
+				return name, checksum, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("no %s LGPL build found", archStr)
+}
+
+// resolveGithubAsset finds a release asset and the checksum GitHub recorded for it
+func resolveGithubAsset(repository, tag, assetName string) (toolRelease, error) {
+	body, err := fetchText(fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repository, tag))
+	if err != nil {
+		return toolRelease{}, fmt.Errorf("failed to get release %s of %s: %w", tag, repository, err)
+	}
+	var release struct {
+		Assets []struct {
+			Name        string `json:"name"`
+			DownloadUrl string `json:"browser_download_url"`
+			Digest      string `json:"digest"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal([]byte(body), &release); err != nil {
+		return toolRelease{}, fmt.Errorf("failed to parse release %s of %s: %w", tag, repository, err)
+	}
+	for _, asset := range release.Assets {
+		if asset.Name != assetName {
+			continue
+		}
+		checksum, found := strings.CutPrefix(asset.Digest, "sha256:")
+		if !found {
+			return toolRelease{}, fmt.Errorf("release %s of %s has no checksum for %s", tag, repository, assetName)
+		}
+		return toolRelease{Release: tag, URL: asset.DownloadUrl, Sha256: strings.ToLower(checksum)}, nil
+	}
+	return toolRelease{}, fmt.Errorf("release %s of %s has no asset %s", tag, repository, assetName)
+}
+
+// parseChecksums parses sha256sum output, lines of a checksum and a file name
+func parseChecksums(text string) map[string]string {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			continue
+		}
+		// Binary mode marks the file name with an asterisk
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return checksums
+}
+
+func fetchText(url string) (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	// Checksum files and release descriptions are small
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// fileSha256 returns the hex SHA-256 checksum of a file
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyChecksum returns an error if a file does not match the expected checksum
+func verifyChecksum(path, expected string) error {
+	actual, err := fileSha256(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+	}
+	return nil
+}
+
+// downloadVerified downloads a release to a file and removes it again if it does not match the checksum
+func downloadVerified(release toolRelease, filePath string) error {
+	if err := downloadFileHttp(release.URL, filePath); err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	if err := verifyChecksum(filePath, release.Sha256); err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+// swapInTools moves downloaded binaries over the installed ones and runs a smoke test.
+// Replaced binaries are kept with a .previous suffix. When the smoke test fails they are restored.
+// downloads maps the path of each installed binary to the path of its replacement.
+func swapInTools(downloads map[string]string, smokeTest func() error) error {
+	defer func() {
+		for _, download := range downloads {
+			os.Remove(download)
+		}
+	}()
+
+	var installed []string
+	for target, download := range downloads {
+		if runtime.GOOS != "windows" {
+			if err := os.Chmod(download, 0755); err != nil {
+				rollbackTools(installed)
+				return fmt.Errorf("failed to make %s executable: %w", download, err)
+			}
+		}
+		if fileExists(target) {
+			os.Remove(target + previousSuffix)
+			if err := os.Rename(target, target+previousSuffix); err != nil {
+				rollbackTools(installed)
+				return fmt.Errorf("failed to keep previous %s: %w", target, err)
+			}
+		}
+		installed = append(installed, target)
+		if err := os.Rename(download, target); err != nil {
+			rollbackTools(installed)
+			return fmt.Errorf("failed to install %s: %w", target, err)
+		}
+	}
+
+	if err := smokeTest(); err != nil {
+		if rollbackErr := rollbackTools(installed); rollbackErr != nil {
+			return fmt.Errorf("smoke test failed: %w, and rolling back failed: %v", err, rollbackErr)
+		}
+		return fmt.Errorf("smoke test failed, rolled back to the previous version: %w", err)
+	}
+	return nil
+}
+
+// rollbackTools restores the previous binaries of a failed install.
+// Without a previous binary the new one is removed, so the next start installs it again.
+func rollbackTools(targets []string) error {
+	var errs []error
+	for _, target := range targets {
+		os.Remove(target)
+		if !fileExists(target + previousSuffix) {
+			continue
+		}
+		if err := os.Rename(target+previousSuffix, target); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func getToolManifestPath() (string, error) {
+	return pathing.GetFile(pathing.DirCache, toolManifestFileName)
+}
+
+// readToolManifest returns the recorded releases by tool name, empty if nothing was recorded yet
+func readToolManifest() (map[string]toolManifestEntry, error) {
+	manifest := map[string]toolManifestEntry{}
+	manifestPath, err := getToolManifestPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse tool manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// recordToolRelease stores the release tools were installed from
+func recordToolRelease(release toolRelease, tools ...string) error {
+	manifest, err := readToolManifest()
+	if err != nil {
+		// A corrupt manifest is rewritten
+		manifest = map[string]toolManifestEntry{}
+	}
+	for _, tool := range tools {
+		manifest[tool] = toolManifestEntry{
+			Release:     release.Release,
+			Sha256:      release.Sha256,
+			SourceURL:   release.URL,
+			InstalledAt: time.Now().Unix(),
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestPath, err := getToolManifestPath()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write tool manifest: %w", err)
+	}
+	return os.Rename(manifestPath+".tmp", manifestPath)
+}
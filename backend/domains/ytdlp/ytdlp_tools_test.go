@@ -0,0 +1,154 @@
+package ytdlp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"videoarchiver/backend/domains/pathing"
+)
+
+func TestParseChecksums(t *testing.T) {
+	sums := "" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  yt-dlp_linux\n" +
+		"2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824 *yt-dlp.exe\n" +
+		"not a checksum  yt-dlp_macos\n"
+
+	checksums := parseChecksums(sums)
+	if len(checksums) != 2 {
+		t.Fatalf("expected 2 checksums, got %v", checksums)
+	}
+	if checksums["yt-dlp_linux"] != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("unexpected checksum for yt-dlp_linux: %s", checksums["yt-dlp_linux"])
+	}
+	if checksums["yt-dlp.exe"] != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("binary mode checksum should be lower case without the asterisk, got %v", checksums)
+	}
+}
+
+func TestFindBtbnAsset(t *testing.T) {
+	checksums := map[string]string{
+		"ffmpeg-N-118448-g4f3f1c5f2a-linux64-gpl.tar.xz":            "a",
+		"ffmpeg-N-118448-g4f3f1c5f2a-linux64-lgpl-shared.tar.xz":    "b",
+		"ffmpeg-N-118448-g4f3f1c5f2a-linux64-lgpl.tar.xz":           "c",
+		"ffmpeg-n7.1-latest-linux64-lgpl-7.1.tar.xz":                "d",
+		"ffmpeg-N-118448-g4f3f1c5f2a-linuxarm64-lgpl.tar.xz":        "e",
+		"ffmpeg-N-118448-g4f3f1c5f2a-win64-lgpl.zip":                "f",
+		"ffmpeg-master-latest-linuxarm64-lgpl-godot.tar.xz":         "g",
+		"ffmpeg-N-118448-g4f3f1c5f2a-linuxarm64-lgpl-shared.tar.xz": "h",
+	}
+
+	name, checksum, err := findBtbnAsset(checksums, "linux64")
+	if err != nil || checksum != "c" {
+		t.Errorf("expected the static linux64 LGPL build, got %s %s %v", name, checksum, err)
+	}
+	if _, checksum, _ := findBtbnAsset(checksums, "linuxarm64"); checksum != "e" {
+		t.Errorf("expected the static linuxarm64 LGPL build, got %s", checksum)
+	}
+
+	// Latest releases use a fixed name
+	checksums["ffmpeg-master-latest-linux64-lgpl.tar.xz"] = "latest"
+	if _, checksum, _ := findBtbnAsset(checksums, "linux64"); checksum != "latest" {
+		t.Errorf("expected the master build, got %s", checksum)
+	}
+
+	if _, _, err := findBtbnAsset(map[string]string{}, "linux64"); err == nil {
+		t.Error("expected an error for a release without builds")
+	}
+}
+
+func TestParseToolVersion(t *testing.T) {
+	tests := map[string]string{
+		"2025.01.26\n": "2025.01.26",
+		"ffmpeg version N-118448-g4f3f1c5f2a-20250131 Copyright (c) 2000-2025 the FFmpeg developers\nbuilt with gcc": "N-118448-g4f3f1c5f2a-20250131",
+		"ffprobe version 7.1-essentials_build-www.gyan.dev Copyright":                                                "7.1-essentials_build-www.gyan.dev",
+	}
+	for output, expected := range tests {
+		if version := parseToolVersion(output); version != expected {
+			t.Errorf("got %q, expected %q", version, expected)
+		}
+	}
+}
+
+func TestSwapInToolsRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "tool")
+	download := target + downloadSuffix
+	writeFile(t, target, "old")
+	writeFile(t, download, "new")
+
+	err := swapInTools(map[string]string{target: download}, func() error {
+		return errors.New("crashed")
+	})
+	if err == nil {
+		t.Fatal("expected the failed smoke test to be reported")
+	}
+	if content := readFile(t, target); content != "old" {
+		t.Errorf("expected the previous binary to be restored, got %q", content)
+	}
+	if fileExists(download) || fileExists(target+previousSuffix) {
+		t.Error("expected the download and the previous copy to be gone after rolling back")
+	}
+
+	// A passing smoke test keeps the previous binary around
+	writeFile(t, download, "new")
+	if err := swapInTools(map[string]string{target: download}, func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content := readFile(t, target); content != "new" {
+		t.Errorf("expected the new binary, got %q", content)
+	}
+	if content := readFile(t, target+previousSuffix); content != "old" {
+		t.Errorf("expected the previous binary to be kept, got %q", content)
+	}
+}
+
+func TestToolManifest(t *testing.T) {
+	t.Setenv(pathing.DataDirEnv, t.TempDir())
+
+	manifest, err := readToolManifest()
+	if err != nil || len(manifest) != 0 {
+		t.Fatalf("expected an empty manifest, got %v, %v", manifest, err)
+	}
+
+	release := toolRelease{Release: "2025.01.26", URL: "https://example.com/yt-dlp", Sha256: "abc"}
+	if err := recordToolRelease(release, ToolYtdlp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifest, err = readToolManifest()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry := manifest[ToolYtdlp]
+	if entry.Release != "2025.01.26" || entry.Sha256 != "abc" || entry.InstalledAt == 0 {
+		t.Errorf("unexpected manifest entry: %+v", entry)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	writeFile(t, path, "hello")
+
+	if err := verifyChecksum(path, "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := verifyChecksum(path, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"); err == nil {
+		t.Error("expected a checksum mismatch")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
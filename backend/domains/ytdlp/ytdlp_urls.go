@@ -0,0 +1,119 @@
+package ytdlp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Extractor keys as normalized by NormalizeExtractor
+const (
+	ExtractorYoutube    = "youtube"
+	ExtractorSoundcloud = "soundcloud"
+	ExtractorVimeo      = "vimeo"
+	ExtractorBandcamp   = "bandcamp"
+)
+
+// videoSite describes how to recognize video URLs of a site supported by yt-dlp
+type videoSite struct {
+	extractor string
+	pattern   *regexp.Regexp // First capture group is the identifier used in canonicalUrl
+	// Format of the canonical URL, %s is replaced by the captured identifier
+	canonicalUrl string
+	// True if the captured identifier is the video ID yt-dlp reports for this extractor.
+	// SoundCloud and Bandcamp report numeric IDs that are not part of the URL.
+	isVideoId bool
+}
+
+var videoSites = []videoSite{
+	{
+		extractor:    ExtractorYoutube,
+		pattern:      regexp.MustCompile(`^https?://(?:(?:www|m|music)\.)?(?:youtube\.com/(?:watch\?(?:.*&)?v=|shorts/|live/|embed/)|youtu\.be/)([\w-]{11})(?:[?&#/].*)?$`),
+		canonicalUrl: "https://www.youtube.com/watch?v=%s",
+		isVideoId:    true,
+	},
+	{
+		extractor:    ExtractorVimeo,
+		pattern:      regexp.MustCompile(`^https?://(?:www\.|player\.)?vimeo\.com/(?:video/)?(\d+)(?:[?#/].*)?$`),
+		canonicalUrl: "https://vimeo.com/%s",
+		isVideoId:    true,
+	},
+	{
+		extractor:    ExtractorSoundcloud,
+		pattern:      regexp.MustCompile(`^https?://(?:www\.|m\.)?soundcloud\.com/([\w-]+/[\w-]+)/?(?:[?#].*)?$`),
+		canonicalUrl: "https://soundcloud.com/%s",
+	},
+	{
+		extractor:    ExtractorBandcamp,
+		pattern:      regexp.MustCompile(`^https?://([\w-]+\.bandcamp\.com/track/[\w-]+)/?(?:[?#].*)?$`),
+		canonicalUrl: "https://%s",
+	},
+}
+
+// Matches any http(s) URL in free text such as descriptions or comments
+var urlInTextRegex = regexp.MustCompile(`https?://[^\s"'<>()\[\]{}]+`)
+
+// VideoURL is a recognized video URL
+type VideoURL struct {
+	Extractor    string
+	VideoID      string // Empty if the URL does not contain the ID yt-dlp reports
+	CanonicalURL string
+}
+
+// NormalizeExtractor converts an extractor name as reported by yt-dlp (ie_key or extractor_key)
+// to the form stored in the database
+func NormalizeExtractor(extractor string) string {
+	return strings.ToLower(strings.TrimSpace(extractor))
+}
+
+// RecognizeVideoURL matches a URL against the known video sites.
+// Returns nil if the URL does not belong to a recognized site.
+func RecognizeVideoURL(url string) *VideoURL {
+	url = strings.TrimSpace(url)
+	for _, site := range videoSites {
+		match := site.pattern.FindStringSubmatch(url)
+		if match == nil {
+			continue
+		}
+		result := &VideoURL{
+			Extractor:    site.extractor,
+			CanonicalURL: fmt.Sprintf(site.canonicalUrl, match[1]),
+		}
+		if site.isVideoId {
+			result.VideoID = match[1]
+		}
+		return result
+	}
+	return nil
+}
+
+// ParseVideoURL extracts the extractor and video ID from a known video URL without invoking yt-dlp.
+// Returns ok false for URLs of sites that are not recognized or don't contain the video ID.
+func ParseVideoURL(url string) (extractor string, videoId string, ok bool) {
+	recognized := RecognizeVideoURL(url)
+	if recognized == nil || recognized.VideoID == "" {
+		return "", "", false
+	}
+	return recognized.Extractor, recognized.VideoID, true
+}
+
+// CanonicalizeURL returns the canonical form of a recognized video URL,
+// unrecognized URLs are returned trimmed but otherwise unchanged
+func CanonicalizeURL(url string) string {
+	if recognized := RecognizeVideoURL(url); recognized != nil {
+		return recognized.CanonicalURL
+	}
+	return strings.TrimSpace(url)
+}
+
+// FindVideoURL returns the first recognized video URL in free text, or nil if there is none
+func FindVideoURL(text string) *VideoURL {
+	for _, candidate := range urlInTextRegex.FindAllString(text, -1) {
+		// Trailing punctuation is rarely part of the URL
+		candidate = strings.TrimRight(candidate, ".,;:!")
+		if recognized := RecognizeVideoURL(candidate); recognized != nil {
+			return recognized
+		}
+	}
+	return nil
+}
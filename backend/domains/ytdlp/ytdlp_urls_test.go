@@ -0,0 +1,51 @@
+package ytdlp
+
+import "testing"
+
+func TestRecognizeVideoURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		extractor    string
+		videoId      string
+		canonicalUrl string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", ExtractorYoutube, "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", ExtractorYoutube, "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://music.youtube.com/watch?list=PL123&v=dQw4w9WgXcQ", ExtractorYoutube, "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", ExtractorYoutube, "dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://player.vimeo.com/video/76979871", ExtractorVimeo, "76979871", "https://vimeo.com/76979871"},
+		{"https://soundcloud.com/artist-name/track-name/", ExtractorSoundcloud, "", "https://soundcloud.com/artist-name/track-name"},
+		{"https://artist.bandcamp.com/track/some-song", ExtractorBandcamp, "", "https://artist.bandcamp.com/track/some-song"},
+	}
+
+	for _, test := range tests {
+		recognized := RecognizeVideoURL(test.url)
+		if recognized == nil {
+			t.Errorf("Expected %s to be recognized", test.url)
+			continue
+		}
+		if recognized.Extractor != test.extractor || recognized.VideoID != test.videoId || recognized.CanonicalURL != test.canonicalUrl {
+			t.Errorf("For %s expected (%s, %s, %s), got (%s, %s, %s)", test.url,
+				test.extractor, test.videoId, test.canonicalUrl,
+				recognized.Extractor, recognized.VideoID, recognized.CanonicalURL)
+		}
+	}
+
+	for _, url := range []string{"https://example.com/watch?v=dQw4w9WgXcQ", "https://www.youtube.com/playlist?list=PL123", "not a url"} {
+		if recognized := RecognizeVideoURL(url); recognized != nil {
+			t.Errorf("Expected %s not to be recognized, got %+v", url, recognized)
+		}
+	}
+}
+
+func TestFindVideoURL(t *testing.T) {
+	text := "Original upload: https://example.com/page, mirror at https://vimeo.com/76979871. Enjoy!"
+	recognized := FindVideoURL(text)
+	if recognized == nil || recognized.VideoID != "76979871" {
+		t.Errorf("Expected vimeo video 76979871, got %+v", recognized)
+	}
+
+	if recognized := FindVideoURL("no links here"); recognized != nil {
+		t.Errorf("Expected nil, got %+v", recognized)
+	}
+}
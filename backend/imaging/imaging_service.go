@@ -6,27 +6,37 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	_ "image/png"
 	"net/http"
 
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // YouTube serves most thumbnails as WebP
 )
 
-func GetBase64Thumb(url string) (string, error) {
-	// Step 1: Fetch the image
+// fetchImage downloads and decodes an image
+func fetchImage(url string) (image.Image, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return "", fmt.Errorf("failed to download image: %w", err)
+		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
 	}
 
-	// Step 2: Decode the image
 	img, _, err := image.Decode(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+func GetBase64Thumb(url string) (string, error) {
+	// Step 1 & 2: Fetch and decode the image
+	img, err := fetchImage(url)
+	if err != nil {
+		return "", err
 	}
 
 	// Step 3: Calculate new dimensions while maintaining aspect ratio
@@ -64,3 +74,37 @@ func GetBase64Thumb(url string) (string, error) {
 
 	return base64Str, nil
 }
+
+// GetSquareCoverJPEG fetches an image, crops it to a centered square and scales it down to at most maxSize pixels.
+// Used as cover art, video thumbnails are usually 16:9 with the artwork in the middle.
+func GetSquareCoverJPEG(url string, maxSize int) ([]byte, error) {
+	img, err := fetchImage(url)
+	if err != nil {
+		return nil, err
+	}
+
+	cropped := SquareCrop(img)
+	size := cropped.Bounds().Dx()
+	if size > maxSize {
+		size = maxSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), cropped, cropped.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("failed to encode image to JPEG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SquareCrop returns the largest centered square of an image
+func SquareCrop(img image.Image) image.Image {
+	bounds := img.Bounds()
+	size := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-size)/2
+	y := bounds.Min.Y + (bounds.Dy()-size)/2
+	square := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(square, square.Bounds(), img, image.Point{X: x, Y: y}, draw.Src)
+	return square
+}
@@ -0,0 +1,220 @@
+// Command line modes that run a single task against the database and exit
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"videoarchiver/backend/domains/backup"
+	"videoarchiver/backend/domains/config"
+	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/overrides"
+	"videoarchiver/backend/domains/transfer"
+)
+
+// pathRemapFlag collects repeated -remap FROM=TO flags
+type pathRemapFlag []transfer.PathRemap
+
+func (p *pathRemapFlag) String() string {
+	values := make([]string, 0, len(*p))
+	for _, remap := range *p {
+		values = append(values, remap.From+"="+remap.To)
+	}
+	return strings.Join(values, ", ")
+}
+
+func (p *pathRemapFlag) Set(value string) error {
+	remap, err := transfer.ParsePathRemap(value)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, remap)
+	return nil
+}
+
+// keyValueFlag collects repeated KEY=VALUE flags, later values replace earlier ones
+type keyValueFlag map[string]string
+
+func (k keyValueFlag) String() string {
+	values := make([]string, 0, len(k))
+	for key, value := range k {
+		values = append(values, key+"="+value)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
+
+func (k keyValueFlag) Set(value string) error {
+	key, val, err := overrides.ParseKeyValue(value)
+	if err != nil {
+		return err
+	}
+	k[key] = val
+	return nil
+}
+
+// newCLIApp initializes the services and database without the UI, daemon lock or dependency installation
+func newCLIApp(mode string) *App {
+	app := NewApp(false, mode)
+	app.ctx = context.Background()
+	app.LogService = logging.NewLogService(mode)
+	app.initCore(app.ctx)
+	return app
+}
+
+func runExport(file string) int {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "Export requires -file")
+		return 1
+	}
+	app := newCLIApp("export")
+	defer app.LogService.Close()
+
+	if err := app.TransferService.ExportToFile(file, GetVersionInfo()); err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Exported playlists and settings to %s\n", file)
+	return 0
+}
+
+func runImport(file string, options transfer.ImportOptions) int {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "Import requires -file")
+		return 1
+	}
+	app := newCLIApp("import")
+	defer app.LogService.Close()
+
+	report, err := app.TransferService.ImportFromFile(file, options)
+	if report != nil {
+		printImportReport(report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printImportReport(report *transfer.ImportReport) {
+	if report.DryRun {
+		fmt.Println("Dry run, nothing was changed.")
+	}
+	for _, section := range []struct {
+		label string
+		names []string
+	}{
+		{"Playlists added", report.PlaylistsAdded},
+		{"Playlists merged", report.PlaylistsMerged},
+		{"Playlists skipped", report.PlaylistsSkipped},
+		{"Settings changed", report.SettingsChanged},
+		{"Settings skipped", report.SettingsSkipped},
+		{"Playlists managed by the config file", report.PlaylistsManaged},
+		{"Settings managed by the config file", report.SettingsManaged},
+	} {
+		fmt.Printf("%s: %d\n", section.label, len(section.names))
+		for _, name := range section.names {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+}
+
+func runBackup() int {
+	app := newCLIApp("backup")
+	defer app.LogService.Close()
+
+	backupFile, err := app.BackupService.CreateBackup(app.ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Backup failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Backed up database to %s\n", backupFile.Path)
+	return 0
+}
+
+// runRestore replaces the database with a backup. The database is not opened, so no process may be using it.
+func runRestore(file string) int {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "Restore requires -file")
+		return 1
+	}
+	app := NewApp(false, "restore")
+	if app.isDaemonRunning {
+		fmt.Fprintln(os.Stderr, "Stop the daemon before restoring a backup")
+		return 1
+	}
+
+	configService, err := config.NewConfigService()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	dbPath, err := configService.GetDatabasePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get database path: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("Make sure the application is closed while restoring.")
+	keptPath, err := backup.RestoreDatabase(file, dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Restore failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Restored %s to %s\n", file, dbPath)
+	fmt.Printf("The previous database was kept at %s\n", keptPath)
+	return 0
+}
+
+// runExportHistory exports the download history, statuses is a comma separated list of success, failed and duplicate
+func runExportHistory(file, statuses string) int {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "Export requires -file")
+		return 1
+	}
+	var showSuccess, showFailed, showDuplicate bool
+	for _, status := range strings.Split(statuses, ",") {
+		switch strings.TrimSpace(status) {
+		case "success":
+			showSuccess = true
+		case "failed":
+			showFailed = true
+		case "duplicate":
+			showDuplicate = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown status %q, expected success, failed or duplicate\n", status)
+			return 1
+		}
+	}
+
+	app := newCLIApp("export-history")
+	defer app.LogService.Close()
+
+	count, err := app.BackupService.ExportDownloadHistory(file, showSuccess, showFailed, showDuplicate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Exported %d downloads to %s\n", count, file)
+	return 0
+}
+
+func runExportRegistry(file string) int {
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "Export requires -file")
+		return 1
+	}
+	app := newCLIApp("export-registry")
+	defer app.LogService.Close()
+
+	count, err := app.BackupService.ExportFileRegistry(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Exported %d registered files to %s\n", count, file)
+	return 0
+}
@@ -0,0 +1,211 @@
+// Foreground daemon for containers, with a heartbeat for health checks
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"videoarchiver/backend/domains/pathing"
+)
+
+const (
+	acceptDisclaimerEnv = "VIDEOARCHIVER_ACCEPT_LEGAL_DISCLAIMER"
+	heartbeatFileName   = "heartbeat.json"
+	heartbeatInterval   = 30 * time.Second
+	heartbeatMaxAge     = 2 * time.Minute // Unhealthy when the heartbeat is older than this
+	// Unhealthy when the daemon loop made no progress for this long. Background jobs stop after
+	// daemonJobTimeLimit and every download or job step counts as progress, so only a stuck loop gets here.
+	iterationMaxAge = 8 * daemonPlaylistCheckInterval
+)
+
+// Statuses reported by the heartbeat
+const (
+	healthStarting = "starting" // Installing dependencies
+	healthRunning  = "running"
+)
+
+// heartbeat is written to the state directory and served by the health endpoint
+type heartbeat struct {
+	Status          string `json:"status"`
+	Pid             int    `json:"pid"`
+	UpdatedAt       int64  `json:"updated_at"`
+	LastIterationAt int64  `json:"last_iteration_at"` // 0 until the daemon finished its first iteration
+	LastProgressAt  int64  `json:"last_progress_at"`  // Last download or background job step, 0 if none yet
+}
+
+var (
+	healthMutex     sync.Mutex
+	healthStatus    = healthStarting
+	lastIterationAt time.Time
+	lastProgressAt  time.Time
+	healthChanged   = make(chan struct{}, 1) // Writes the heartbeat right away when the status changes
+)
+
+// recordIteration notes that the daemon loop finished an iteration
+func recordIteration() {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+	lastIterationAt = time.Now()
+}
+
+// recordProgress notes that the daemon loop moved on to a new download or background job step
+func recordProgress() {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+	lastProgressAt = time.Now()
+}
+
+func setHealthStatus(status string) {
+	healthMutex.Lock()
+	healthStatus = status
+	healthMutex.Unlock()
+
+	select {
+	case healthChanged <- struct{}{}:
+	default:
+	}
+}
+
+func currentHeartbeat() heartbeat {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+	h := heartbeat{Status: healthStatus, Pid: os.Getpid(), UpdatedAt: time.Now().Unix()}
+	if !lastIterationAt.IsZero() {
+		h.LastIterationAt = lastIterationAt.Unix()
+	}
+	if !lastProgressAt.IsZero() {
+		h.LastProgressAt = lastProgressAt.Unix()
+	}
+	return h
+}
+
+// checkHeartbeat returns an error until the daemon runs, if the heartbeat is too old to trust,
+// or if the daemon loop stopped making progress while the process kept writing heartbeats
+func checkHeartbeat(h heartbeat, now time.Time) error {
+	if h.Status != healthRunning {
+		return fmt.Errorf("daemon is %s", h.Status)
+	}
+	age := now.Sub(time.Unix(h.UpdatedAt, 0))
+	if age > heartbeatMaxAge {
+		return fmt.Errorf("last heartbeat was %s ago", age.Round(time.Second))
+	}
+	if h.LastIterationAt != 0 {
+		lastActivity := max(h.LastIterationAt, h.LastProgressAt)
+		if idle := now.Sub(time.Unix(lastActivity, 0)); idle > iterationMaxAge {
+			return fmt.Errorf("daemon loop made no progress for %s", idle.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// runContainer runs the daemon in the foreground until SIGTERM, without the lock that coordinates with a UI
+func runContainer(acceptDisclaimer bool, healthAddr string) int {
+	if err := applyContainerUser(); err != nil {
+		fmt.Printf("Failed to apply PUID, PGID or UMASK: %v\n", err)
+		return 1
+	}
+
+	app := NewApp(false, "daemon")
+	app.containerMode = true
+	app.acceptDisclaimer = acceptDisclaimer || os.Getenv(acceptDisclaimerEnv) == "true"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	heartbeatPath, err := pathing.GetFile(pathing.DirState, heartbeatFileName)
+	if err != nil {
+		fmt.Printf("Failed to get heartbeat file path: %v\n", err)
+		return 1
+	}
+	go writeHeartbeats(ctx, heartbeatPath)
+	if healthAddr != "" {
+		go serveHealth(ctx, healthAddr)
+	}
+
+	app.startup(context.Background())
+	setHealthStatus(healthRunning)
+	app.LogService.Info("Daemon running in the foreground")
+	startDaemonLoop(app)
+
+	cancel()
+	if err := os.Remove(heartbeatPath); err != nil && !os.IsNotExist(err) {
+		app.LogService.Warn(fmt.Sprintf("Failed to remove heartbeat file: %v", err))
+	}
+	app.LogService.Info("Daemon stopped")
+	return 0
+}
+
+// writeHeartbeats keeps the heartbeat file fresh until the context is cancelled
+func writeHeartbeats(ctx context.Context, path string) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		data, err := json.Marshal(currentHeartbeat())
+		if err == nil {
+			// Written next to the file and renamed, a health check never reads half a heartbeat
+			if err = os.WriteFile(path+".tmp", data, 0644); err == nil {
+				err = os.Rename(path+".tmp", path)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Failed to write heartbeat: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-healthChanged:
+		}
+	}
+}
+
+// serveHealth answers GET /healthz with the heartbeat, 503 until the daemon runs
+func serveHealth(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		h := currentHeartbeat()
+		w.Header().Set("Content-Type", "application/json")
+		if checkHeartbeat(h, time.Now()) != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(h)
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Health endpoint stopped: %v\n", err)
+	}
+}
+
+// runHealthcheck checks the heartbeat file of a container daemon, for HEALTHCHECK without an HTTP client
+func runHealthcheck() int {
+	heartbeatPath, err := pathing.GetFile(pathing.DirState, heartbeatFileName)
+	if err != nil {
+		fmt.Printf("unhealthy: %v\n", err)
+		return 1
+	}
+	data, err := os.ReadFile(heartbeatPath)
+	if err != nil {
+		fmt.Printf("unhealthy: no heartbeat: %v\n", err)
+		return 1
+	}
+	var h heartbeat
+	if err := json.Unmarshal(data, &h); err != nil {
+		fmt.Printf("unhealthy: invalid heartbeat: %v\n", err)
+		return 1
+	}
+	if err := checkHeartbeat(h, time.Now()); err != nil {
+		fmt.Printf("unhealthy: %v\n", err)
+		return 1
+	}
+	fmt.Printf("healthy: %s\n", h.Status)
+	return 0
+}
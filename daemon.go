@@ -3,24 +3,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 	"videoarchiver/backend/domains/download"
+	"videoarchiver/backend/domains/fileregistry"
+	"videoarchiver/backend/domains/integrity"
+	"videoarchiver/backend/domains/playlist"
 	"videoarchiver/backend/domains/ytdlp"
 )
 
 var (
 	app        *App
 	cancelFunc context.CancelFunc
-	lastRun    time.Time = time.Time{}
+	daemonCtx  context.Context = context.Background() // Cancelled on shutdown once the daemon loop runs
+	lastRun    time.Time       = time.Time{}
 )
 
 const (
 	daemonWorkCheckInterval     = 5 * time.Second
 	daemonPlaylistCheckInterval = 30 * time.Minute
+	// Longest a background job may hold up downloads per iteration, the rest is left to later iterations
+	daemonJobTimeLimit = 5 * time.Minute
 )
 
 func startDaemonLoop(_app *App) {
@@ -30,6 +37,7 @@ func startDaemonLoop(_app *App) {
 	// Create context and shutdown handling here
 	ctx, _cancelFunc := context.WithCancel(context.Background())
 	cancelFunc = _cancelFunc
+	daemonCtx = ctx
 
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
@@ -40,6 +48,13 @@ func startDaemonLoop(_app *App) {
 		cancelFunc()
 	}()
 
+	// Bring playlists and settings in line with the declarative config file, then follow its changes
+	syncManagedConfig()
+	go runManagedConfigWatcher(ctx)
+
+	// Keep watched registry roots up to date in the background
+	go runRegistryWatcher(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -75,7 +90,13 @@ func startDaemonLoop(_app *App) {
 			// Do work if needed
 			if doWork {
 				lastRun = time.Now()
+				runScheduledToolUpdate()
 				processActivePlaylists()
+				runHashMigration(ctx)
+				runRegistryReconciliation(ctx)
+				runScheduledIntegrityCheck(ctx)
+				runScheduledBackup(ctx)
+				recordIteration()
 			}
 
 			// Then wait 5s (or until cancelled)
@@ -112,6 +133,17 @@ func processActivePlaylists() {
 
 	// Loop over active playlists
 	for _, pl := range activePlaylists {
+		// Playlists scheduled in the config file are only checked once their interval has elapsed
+		isDue, err := app.ManagedConfig.IsPlaylistDue(pl.ID)
+		if err != nil {
+			app.LogService.Error(fmt.Sprintf("Failed to check schedule of playlist %s: %v", pl.Name, err))
+			continue
+		}
+		if !isDue {
+			app.LogService.Debug(fmt.Sprintf("Skipping playlist %s until its check interval has elapsed", pl.Name))
+			continue
+		}
+
 		app.LogService.Info(fmt.Sprintf("Processing playlist: %s", pl.Name))
 
 		// Get playlist items online
@@ -120,6 +152,9 @@ func processActivePlaylists() {
 			app.LogService.Error(fmt.Sprintf("Failed to get playlist info for %s: %v", pl.Name, err))
 			continue
 		}
+		if err := app.ManagedConfig.MarkPlaylistChecked(pl.ID); err != nil {
+			app.LogService.Warn(fmt.Sprintf("Failed to record check of playlist %s: %v", pl.Name, err))
+		}
 
 		// Check which playlist items are already processed
 		existingDls, err := app.DownloadDB.GetDownloadsForPlaylist(pl.ID)
@@ -128,43 +163,193 @@ func processActivePlaylists() {
 			continue
 		}
 
-		// Filter out already downloaded urls
-		retryables, undownloadedUrls := getDownloadables(plInfo, existingDls)
-		if len(undownloadedUrls) == 0 && len(retryables) == 0 {
+		// Filter out already downloaded entries
+		retryables, undownloadedEntries := getDownloadables(plInfo, existingDls)
+		if len(undownloadedEntries) == 0 && len(retryables) == 0 {
 			app.LogService.Debug(fmt.Sprintf("No new items or retryable to download for playlist: %s", pl.Name))
+			// The remote order may still have changed
+			writePlaylistFiles(&pl, plInfo)
 			continue
 		}
 		app.LogService.Info(fmt.Sprintf("Found %d new items and %d retryable items to download for playlist: %s",
-			len(undownloadedUrls), len(retryables), pl.Name))
+			len(undownloadedEntries), len(retryables), pl.Name))
+
+		// Position of every item in the playlist, used as track number when tagging
+		playlistIndexes := make(map[string]int, len(plInfo.Entries))
+		for _, entry := range plInfo.Entries {
+			playlistIndexes[entry.URL] = entry.Index
+		}
 
 		// Retry any retryable items
 		for _, dl := range retryables {
+			runScheduledToolUpdate()
 			if shouldStopIteration() {
 				return
 			}
+			dl.PlaylistIndex = playlistIndexes[dl.Url]
 			app.DownloadService.ArchiveDownloadFile(&dl, &pl)
 		}
 
 		// Download any new items
-		for _, url := range undownloadedUrls {
+		for _, entry := range undownloadedEntries {
+			runScheduledToolUpdate()
 			if shouldStopIteration() {
 				return
 			}
 
-			dl := download.NewDownload(pl.ID, url, pl.OutputFormat)
+			dl := download.NewDownload(pl.ID, entry.URL, pl.OutputFormat)
+			dl.SetVideoIdentity(entry.Extractor, entry.ID)
+			dl.PlaylistIndex = entry.Index
 			app.DownloadService.ArchiveDownloadFile(dl, &pl)
 		}
 
+		writePlaylistFiles(&pl, plInfo)
 	}
 
 	app.LogService.Info("Playlist processing complete.")
 }
 
+// Mirror the source playlist's order in playlist files next to the archived files
+func writePlaylistFiles(pl *playlist.Playlist, plInfo *ytdlp.YtdlpPlaylistInfo) {
+	if err := app.PlaylistFiles.WritePlaylistFiles(pl, plInfo.Entries); err != nil {
+		app.LogService.Error(fmt.Sprintf("Failed to write playlist files for %s: %v", pl.Name, err))
+	}
+}
+
+// Rehash downloads and registry rows that still use a previous hash algorithm, a few minutes per iteration
+func runHashMigration(ctx context.Context) {
+	migrated, err := app.HashMigration.MigrateAll(ctx, stopAfter(daemonJobTimeLimit))
+	if err != nil {
+		app.LogService.Error(fmt.Sprintf("Hash migration failed: %v", err))
+		return
+	}
+	if migrated > 0 {
+		app.LogService.Info(fmt.Sprintf("Rehashed %d files to the configured hash algorithm", migrated))
+	}
+}
+
+// Reconcile the database against the declarative config file, drift is recorded for the UI
+func syncManagedConfig() {
+	status, err := app.ManagedConfig.Reconcile()
+	if err != nil {
+		app.LogService.Error(fmt.Sprintf("Config file sync failed: %v", err))
+		return
+	}
+	if status.ConfigPath != "" && status.Run != nil {
+		app.LogService.Info(fmt.Sprintf("Config file %s synced, %d differences found", status.ConfigPath, status.Run.DriftCount))
+	}
+}
+
+// Reconcile the config file again whenever it changes until the daemon shuts down
+func runManagedConfigWatcher(ctx context.Context) {
+	if err := app.ManagedConfig.Watch(ctx); err != nil {
+		app.LogService.Error(fmt.Sprintf("Config file watcher stopped: %v", err))
+	}
+}
+
+// Watch registry roots for file changes until the daemon shuts down
+func runRegistryWatcher(ctx context.Context) {
+	watcher := fileregistry.NewRegistryWatcher(app.FileRegistryService, app.LogService)
+	if err := watcher.Run(ctx); err != nil {
+		app.LogService.Error(fmt.Sprintf("Registry watcher stopped: %v", err))
+	}
+}
+
+// Reconcile watched registry roots with the files on disk when their interval has elapsed.
+// Catches changes the watcher missed while the daemon was not running.
+func runRegistryReconciliation(ctx context.Context) {
+	// Finish scans that stopped when their process exited, before the periodic reconciliation
+	if err := app.FileRegistryService.ResumeInterruptedScans(ctx, app.LogService); err != nil {
+		app.LogService.Error(fmt.Sprintf("Failed to resume interrupted registry scans: %v", err))
+	}
+
+	roots, err := app.FileRegistryService.GetWatchedRoots()
+	if err != nil {
+		app.LogService.Error(fmt.Sprintf("Failed to get watched roots: %v", err))
+		return
+	}
+
+	for _, root := range roots {
+		if shouldStopIteration() {
+			return
+		}
+		isDue, err := app.FileRegistryService.IsReconcileDue(root)
+		if err != nil {
+			app.LogService.Error(fmt.Sprintf("Failed to check if reconciliation is due: %v", err))
+			return
+		}
+		if !isDue {
+			continue
+		}
+
+		app.LogService.Info(fmt.Sprintf("Reconciling registry root: %s", root.Path))
+		result, err := app.FileRegistryService.ReconcileRoot(ctx, root, app.LogService)
+		if err != nil {
+			app.LogService.Error(fmt.Sprintf("Failed to reconcile registry root %s: %v", root.Path, err))
+			continue
+		}
+		app.LogService.Info(fmt.Sprintf("Reconciled registry root %s: %d new, %d updated, %d unchanged, %d removed, %d errors",
+			root.Path, result.NewFiles, result.UpdatedFiles, result.SkippedFiles, result.RemovedFiles, result.ErrorCount))
+	}
+}
+
+// Run the archive integrity verification when the configured interval has elapsed
+func runScheduledIntegrityCheck(ctx context.Context) {
+	isDue, err := app.IntegrityService.IsVerificationDue()
+	if err != nil {
+		app.LogService.Error(fmt.Sprintf("Failed to check if integrity verification is due: %v", err))
+		return
+	}
+	if !isDue {
+		return
+	}
+
+	// Pauses after the time limit so downloads are not held up, the run resumes from its cursor on the next iteration
+	app.LogService.Info("Running scheduled integrity verification...")
+	_, err = app.IntegrityService.RunVerification(ctx, stopAfter(daemonJobTimeLimit), nil)
+	if err != nil && !errors.Is(err, integrity.ErrVerificationPaused) {
+		app.LogService.Error(fmt.Sprintf("Scheduled integrity verification failed: %v", err))
+	}
+}
+
+// Update yt-dlp between downloads when its interval has elapsed or downloads fail in a way an update may fix.
+// An update that requeues failed downloads signals a change, which restarts the iteration to retry them.
+func runScheduledToolUpdate() {
+	trigger, isDue, err := app.ToolUpdateService.CheckDue()
+	if err != nil {
+		app.LogService.Error(fmt.Sprintf("Failed to check if a yt-dlp update is due: %v", err))
+		return
+	}
+	if !isDue {
+		return
+	}
+	if _, err := app.ToolUpdateService.RunUpdate(trigger); err != nil {
+		app.LogService.Error(fmt.Sprintf("yt-dlp update failed: %v", err))
+	}
+}
+
+// Back up the database when the configured interval has elapsed
+func runScheduledBackup(ctx context.Context) {
+	isDue, err := app.BackupService.IsBackupDue()
+	if err != nil {
+		app.LogService.Error(fmt.Sprintf("Failed to check if a database backup is due: %v", err))
+		return
+	}
+	if !isDue {
+		return
+	}
+
+	app.LogService.Info("Running scheduled database backup...")
+	if _, err := app.BackupService.CreateBackup(ctx); err != nil {
+		app.LogService.Error(fmt.Sprintf("Scheduled database backup failed: %v", err))
+	}
+}
+
 // Get undownloaded and retryable items from playlist info and existing downloads
-func getDownloadables(plInfo *ytdlp.YtdlpPlaylistInfo, existingDls []download.Download) ([]download.Download, []string) {
+func getDownloadables(plInfo *ytdlp.YtdlpPlaylistInfo, existingDls []download.Download) ([]download.Download, []ytdlp.YtdlpEntry) {
 	// Prepare return values
 	retryables := make([]download.Download, 0)
-	undownloadedUrls := make([]string, 0)
+	undownloadedEntries := make([]ytdlp.YtdlpEntry, 0)
 
 	// Create map of existing entries for quick lookup
 	existingMap := make(map[string]bool)
@@ -181,18 +366,21 @@ func getDownloadables(plInfo *ytdlp.YtdlpPlaylistInfo, existingDls []download.Do
 	// Create download entries for new items
 	for _, item := range plInfo.Entries {
 		if _, exists := existingMap[item.URL]; !exists {
-			undownloadedUrls = append(undownloadedUrls, item.URL)
+			undownloadedEntries = append(undownloadedEntries, item)
 		}
 	}
 
 	// Return results
-	return retryables, undownloadedUrls
+	return retryables, undownloadedEntries
 }
 
 func shouldStopIteration() bool {
+	// Checked before every download and background job step, which shows the loop is not stuck
+	recordProgress()
+
 	// Check for shutdown signal
 	select {
-	case <-context.Background().Done():
+	case <-daemonCtx.Done():
 		app.LogService.Info("Shutdown signal received, stopping downloads")
 		return true
 	default:
@@ -209,3 +397,11 @@ func shouldStopIteration() bool {
 	}
 	return false
 }
+
+// stopAfter returns a stop check for background jobs that also stops them once the time limit has passed
+func stopAfter(limit time.Duration) func() bool {
+	deadline := time.Now().Add(limit)
+	return func() bool {
+		return time.Now().After(deadline) || shouldStopIteration()
+	}
+}
@@ -14,6 +14,7 @@ declare global {
           GetLegalDisclaimerAccepted: () => Promise<boolean>;
           GetConfirmCloseEnabled: () => Promise<boolean>;
           GetSettingString: (arg1: string) => Promise<string>;
+          GetSettingsSchema: () => Promise<Array<any>>;
           GetConfigString: (arg1: string) => Promise<string>;
           SetConfigString: (arg1: string, arg2: string) => Promise<void>;
           HandleFatalError: (arg1: string) => Promise<void>;
@@ -62,6 +63,44 @@ declare global {
           RegisterDirectory: (arg1: string) => Promise<void>;
           ClearAllRegisteredFiles: () => Promise<void>;
           GetVersion: () => Promise<string>;
+          RunIntegrityCheck: () => Promise<void>;
+          GetLatestIntegrityRun: () => Promise<any>;
+          GetIntegrityIssues: (arg1: number, arg2: number, arg3: number) => Promise<Array<any>>;
+          RequeueIntegrityIssue: (arg1: number) => Promise<void>;
+          GetProbableDuplicates: (arg1: number, arg2: number) => Promise<Array<any>>;
+          ResolveProbableDuplicate: (arg1: number, arg2: string) => Promise<void>;
+          GetDuplicateReport: () => Promise<any>;
+          ReconcileDuplicates: (arg1: any) => Promise<Array<any>>;
+          GetDuplicateActions: (arg1: number, arg2: number) => Promise<Array<any>>;
+          StartCorruptionScanDirectory: (arg1: string) => Promise<void>;
+          StartCorruptionScanPlaylist: (arg1: number) => Promise<void>;
+          CancelCorruptionScan: () => Promise<void>;
+          GetRecentCorruptionScans: (arg1: number) => Promise<Array<any>>;
+          GetCorruptionScanResults: (arg1: number, arg2: number, arg3: number) => Promise<Array<any>>;
+          RequeueCorruptFile: (arg1: number) => Promise<void>;
+          GetPostProcessSteps: (arg1: number) => Promise<Array<any>>;
+          SetPostProcessSteps: (arg1: number, arg2: Array<any>) => Promise<void>;
+          GetPostProcessStepDefaults: () => Promise<any>;
+          GetPostProcessResults: (arg1: number) => Promise<Array<any>>;
+          PreviewMusicTags: (arg1: string, arg2: string) => Promise<any>;
+          GetWatchedRoots: () => Promise<Array<any>>;
+          AddWatchedRoot: (arg1: string) => Promise<void>;
+          RemoveWatchedRoot: (arg1: number, arg2: boolean) => Promise<void>;
+          GetDefaultScanFilter: () => Promise<any>;
+          UpdateWatchedRootFilter: (arg1: number, arg2: any) => Promise<void>;
+          CancelDirectoryRegistration: () => Promise<void>;
+          GetRecentRegistryScans: (arg1: number) => Promise<Array<any>>;
+          ExportConfiguration: (arg1: string) => Promise<void>;
+          ImportConfiguration: (arg1: string, arg2: any) => Promise<any>;
+          CreateBackup: () => Promise<any>;
+          GetBackups: () => Promise<Array<any>>;
+          ExportDownloadHistory: (arg1: string, arg2: boolean, arg3: boolean, arg4: boolean) => Promise<number>;
+          ExportFileRegistry: (arg1: string) => Promise<number>;
+          GetConfigSyncStatus: () => Promise<any>;
+          GetManagedPlaylistIds: () => Promise<number[]>;
+          GetInstalledToolVersions: () => Promise<Array<any>>;
+          GetInstalledPlugins: () => Promise<Array<any>>;
+          GetToolUpdateHistory: (arg1: number) => Promise<Array<any>>;
         };
       };
     };
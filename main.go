@@ -8,6 +8,10 @@ import (
 	"os"
 	"videoarchiver/backend/domains/lockfile"
 	"videoarchiver/backend/domains/logging"
+	"videoarchiver/backend/domains/overrides"
+	"videoarchiver/backend/domains/pathing"
+	"videoarchiver/backend/domains/transfer"
+	"videoarchiver/backend/domains/ytdlp"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -18,9 +22,41 @@ import (
 var assets embed.FS
 
 func main() {
-	mode := flag.String("mode", "", "Startup mode: ui, daemon (defaults to ui)")
+	mode := flag.String("mode", "", "Startup mode: ui, daemon, container, healthcheck, export, import, backup, restore, export-history, export-registry (defaults to ui)")
+	file := flag.String("file", "", "File to export to, import from or restore from. The extension chooses the format.")
+	historyStatus := flag.String("status", "success,failed,duplicate", "Download statuses to include in export-history")
+	conflict := flag.String("conflict", transfer.ConflictMerge, "Import conflict mode for configured playlists and settings: merge, skip")
+	dryRun := flag.Bool("dry-run", false, "Report what an import would change without changing anything")
+	var remaps pathRemapFlag
+	flag.Var(&remaps, "remap", "Replace a save directory prefix on import, as FROM=TO (repeatable)")
+	healthAddr := flag.String("health-addr", "", "Address to serve GET /healthz on in container mode, such as :8080")
+	acceptDisclaimer := flag.Bool("accept-legal-disclaimer", false, "Accept the legal disclaimer in container mode (or set "+acceptDisclaimerEnv+"=true)")
+	dataDir := flag.String("data-dir", "", "Working directory for binaries, logs, lock, cookies, config and database (overrides "+pathing.DataDirEnv+")")
+	configOverrides := keyValueFlag{}
+	flag.Var(configOverrides, "config", "Override a config.json field, as KEY=VALUE (repeatable, overrides "+overrides.EnvPrefix+"<KEY>)")
+	settingOverrides := keyValueFlag{}
+	flag.Var(settingOverrides, "setting", "Override a setting, as KEY=VALUE (repeatable, overrides "+overrides.EnvPrefix+"<KEY>)")
 	flag.Parse()
 
+	// Apply overrides before anything touches the working directory, config or settings
+	if *dataDir != "" {
+		if err := pathing.SetDataDir(*dataDir); err != nil {
+			fmt.Printf("Invalid data directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	overrides.SetConfigFlags(configOverrides)
+	overrides.SetSettingFlags(settingOverrides)
+
+	// Move files of installs from before XDG base directories were used
+	moved, err := pathing.MigrateLegacyDirectory(legacyFiles())
+	for _, path := range moved {
+		fmt.Printf("Moved %s\n", path)
+	}
+	if err != nil {
+		fmt.Printf("Failed to move files to XDG base directories: %v\n", err)
+	}
+
 	// Early logging to track startup mode
 	fmt.Printf("Starting application in mode: %s\n", *mode)
 
@@ -30,18 +66,60 @@ func main() {
 		app := NewApp(false, "daemon")
 		runDaemon(app)
 
+	case "container":
+		fmt.Println("Initializing container mode...")
+		os.Exit(runContainer(*acceptDisclaimer, *healthAddr))
+
+	case "healthcheck":
+		os.Exit(runHealthcheck())
+
 	case "ui", "":
 		fmt.Println("Initializing UI mode...")
 		app := NewApp(true, "ui")
 		runUI(app)
 
+	case "export":
+		os.Exit(runExport(*file))
+
+	case "import":
+		os.Exit(runImport(*file, transfer.ImportOptions{
+			ConflictMode: *conflict,
+			PathRemaps:   remaps,
+			DryRun:       *dryRun,
+		}))
+
+	case "backup":
+		os.Exit(runBackup())
+
+	case "restore":
+		os.Exit(runRestore(*file))
+
+	case "export-history":
+		os.Exit(runExportHistory(*file, *historyStatus))
+
+	case "export-registry":
+		os.Exit(runExportRegistry(*file))
+
 	default:
 		fmt.Println("LOG: Application exiting due to invalid startup mode")
-		println("Invalid startup mode. Valid modes: ui, daemon")
+		println("Invalid startup mode. Valid modes: ui, daemon, container, healthcheck, export, import, backup, restore, export-history, export-registry")
 		os.Exit(1)
 	}
 }
 
+// legacyFiles lists the files that moved out of the data directory, the database stays where config.json points to
+func legacyFiles() []pathing.LegacyFile {
+	files := []pathing.LegacyFile{
+		{Name: "config.json", Kind: pathing.DirConfig},
+		{Name: "daemon.log", Kind: pathing.DirState},
+		{Name: "ui.log", Kind: pathing.DirState},
+	}
+	for _, name := range ytdlp.ToolFileNames() {
+		files = append(files, pathing.LegacyFile{Name: name, Kind: pathing.DirCache})
+	}
+	return files
+}
+
 func runDaemon(app *App) {
 	// Create early logger for daemon startup messages
 	earlyLogger := logging.NewLogService("daemon")
@@ -0,0 +1,36 @@
+-- +up
+CREATE TABLE IF NOT EXISTS "integrity_runs" (
+    "id" INTEGER NOT NULL,
+    "started_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "finished_at" BIGINT,
+    "files_checked" INTEGER NOT NULL DEFAULT 0,
+    "issues_found" INTEGER NOT NULL DEFAULT 0,
+    -- Last verified file, an unfinished run resumes after it
+    "cursor_source" VARCHAR,
+    "cursor_source_id" INTEGER,
+    PRIMARY KEY("id")
+);
+
+CREATE TABLE IF NOT EXISTS "integrity_issues" (
+    "id" INTEGER NOT NULL,
+    "run_id" INTEGER NOT NULL,
+    "source" VARCHAR NOT NULL,
+    "source_id" INTEGER NOT NULL,
+    "file_path" VARCHAR NOT NULL,
+    "issue" VARCHAR NOT NULL,
+    "expected_md5" VARCHAR,
+    "actual_md5" VARCHAR,
+    "detail" VARCHAR,
+    "requeued" BOOLEAN NOT NULL DEFAULT FALSE,
+    "detected_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY("id"),
+    FOREIGN KEY ("run_id") REFERENCES "integrity_runs"("id")
+    ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX "integrity_issues_run_id_index" ON "integrity_issues" ("run_id");
+
+-- +down
+DROP INDEX IF EXISTS "integrity_issues_run_id_index";
+DROP TABLE IF EXISTS "integrity_issues";
+DROP TABLE IF EXISTS "integrity_runs";
@@ -0,0 +1,23 @@
+-- +up
+-- Existing hashes were all calculated with MD5
+ALTER TABLE downloads ADD COLUMN hash_algorithm VARCHAR NOT NULL DEFAULT 'md5';
+ALTER TABLE file_registry ADD COLUMN hash_algorithm VARCHAR NOT NULL DEFAULT 'md5';
+
+-- Index for duplicate lookups and the background rehash migration
+CREATE INDEX "downloads_hash_index" ON "downloads" ("hash_algorithm", "md5");
+CREATE INDEX "file_registry_hash_index" ON "file_registry" ("hash_algorithm", "md5");
+
+-- Existing archives keep MD5, switching to xxh3 rehashes every file and is left to the user.
+-- New installations get the schema default.
+INSERT INTO "settings" (setting_key, setting_value)
+SELECT 'hash_algorithm', 'md5'
+WHERE EXISTS (SELECT 1 FROM downloads WHERE md5 IS NOT NULL) OR EXISTS (SELECT 1 FROM file_registry);
+
+-- +down
+DELETE FROM "settings" WHERE setting_key = 'hash_algorithm';
+
+DROP INDEX IF EXISTS "file_registry_hash_index";
+DROP INDEX IF EXISTS "downloads_hash_index";
+
+ALTER TABLE file_registry DROP COLUMN hash_algorithm;
+ALTER TABLE downloads DROP COLUMN hash_algorithm;
@@ -0,0 +1,37 @@
+-- +up
+CREATE TABLE IF NOT EXISTS "fingerprints" (
+    "id" INTEGER NOT NULL,
+    "source" VARCHAR NOT NULL,
+    "source_id" INTEGER NOT NULL,
+    "file_path" VARCHAR NOT NULL,
+    "duration" REAL NOT NULL DEFAULT 0,
+    "audio_fingerprint" BLOB,
+    "video_fingerprint" BLOB,
+    "created_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY("id"),
+    UNIQUE ("source", "source_id")
+);
+
+CREATE INDEX "fingerprints_duration_index" ON "fingerprints" ("duration");
+
+CREATE TABLE IF NOT EXISTS "probable_duplicates" (
+    "id" INTEGER NOT NULL,
+    "download_id" INTEGER NOT NULL,
+    "match_source" VARCHAR NOT NULL,
+    "match_source_id" INTEGER NOT NULL,
+    "match_path" VARCHAR NOT NULL,
+    "similarity" REAL NOT NULL,
+    "status" VARCHAR NOT NULL DEFAULT 'pending',
+    "created_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY("id"),
+    FOREIGN KEY ("download_id") REFERENCES "downloads"("id")
+    ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX "probable_duplicates_status_index" ON "probable_duplicates" ("status");
+
+-- +down
+DROP INDEX IF EXISTS "probable_duplicates_status_index";
+DROP TABLE IF EXISTS "probable_duplicates";
+DROP INDEX IF EXISTS "fingerprints_duration_index";
+DROP TABLE IF EXISTS "fingerprints";
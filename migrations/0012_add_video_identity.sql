@@ -0,0 +1,31 @@
+-- +up
+-- Extractor and site video ID, used to detect duplicates before downloading
+ALTER TABLE downloads ADD COLUMN extractor VARCHAR;
+ALTER TABLE downloads ADD COLUMN video_id VARCHAR;
+ALTER TABLE file_registry ADD COLUMN extractor VARCHAR;
+ALTER TABLE file_registry ADD COLUMN video_id VARCHAR;
+
+CREATE INDEX "downloads_video_identity_index" ON "downloads" ("extractor", "video_id");
+CREATE INDEX "file_registry_video_identity_index" ON "file_registry" ("extractor", "video_id");
+
+-- Backfill YouTube IDs from known URLs
+UPDATE downloads
+SET extractor = 'youtube', video_id = substr(url, instr(url, 'watch?v=') + 8, 11)
+WHERE url LIKE '%youtube.com/watch?v=%';
+
+UPDATE file_registry
+SET extractor = 'youtube', video_id = substr(known_url, instr(known_url, 'watch?v=') + 8, 11)
+WHERE known_url LIKE '%youtube.com/watch?v=%';
+
+UPDATE file_registry
+SET extractor = 'youtube', video_id = substr(known_url, instr(known_url, 'youtu.be/') + 9, 11)
+WHERE known_url LIKE '%youtu.be/%';
+
+-- +down
+DROP INDEX IF EXISTS "file_registry_video_identity_index";
+DROP INDEX IF EXISTS "downloads_video_identity_index";
+
+ALTER TABLE file_registry DROP COLUMN video_id;
+ALTER TABLE file_registry DROP COLUMN extractor;
+ALTER TABLE downloads DROP COLUMN video_id;
+ALTER TABLE downloads DROP COLUMN extractor;
@@ -0,0 +1,14 @@
+-- +up
+ALTER TABLE file_registry ADD COLUMN title VARCHAR;
+ALTER TABLE file_registry ADD COLUMN artist VARCHAR;
+ALTER TABLE file_registry ADD COLUMN duration REAL;
+
+-- Known URLs are now stored in canonical form
+UPDATE file_registry
+SET known_url = 'https://www.youtube.com/watch?v=' || video_id
+WHERE extractor = 'youtube' AND video_id IS NOT NULL;
+
+-- +down
+ALTER TABLE file_registry DROP COLUMN duration;
+ALTER TABLE file_registry DROP COLUMN artist;
+ALTER TABLE file_registry DROP COLUMN title;
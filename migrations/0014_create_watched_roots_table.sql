@@ -0,0 +1,14 @@
+-- +up
+CREATE TABLE IF NOT EXISTS "watched_roots" (
+    "id" INTEGER NOT NULL,
+    "path" VARCHAR NOT NULL UNIQUE,
+    "added_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "last_reconciled_at" BIGINT,
+    PRIMARY KEY("id")
+);
+
+CREATE INDEX "file_registry_file_path_index" ON "file_registry" ("file_path");
+
+-- +down
+DROP INDEX IF EXISTS "file_registry_file_path_index";
+DROP TABLE IF EXISTS "watched_roots";
@@ -0,0 +1,31 @@
+-- +up
+-- Size and modification time at the moment the file was hashed, unchanged files are skipped on rescans
+ALTER TABLE file_registry ADD COLUMN file_size INTEGER;
+ALTER TABLE file_registry ADD COLUMN file_mtime BIGINT;
+
+CREATE TABLE IF NOT EXISTS "registry_scans" (
+    "id" INTEGER NOT NULL,
+    "root_path" VARCHAR NOT NULL,
+    "status" VARCHAR NOT NULL DEFAULT 'running',
+    "started_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "updated_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "finished_at" BIGINT,
+    "total_files" INTEGER NOT NULL DEFAULT 0,
+    "new_files" INTEGER NOT NULL DEFAULT 0,
+    "updated_files" INTEGER NOT NULL DEFAULT 0,
+    "skipped_files" INTEGER NOT NULL DEFAULT 0,
+    "removed_files" INTEGER NOT NULL DEFAULT 0,
+    "error_count" INTEGER NOT NULL DEFAULT 0,
+    -- Process running the scan, a scan is only resumed by another process once its heartbeat stopped
+    "owner" VARCHAR,
+    PRIMARY KEY("id")
+);
+
+CREATE INDEX "registry_scans_status_index" ON "registry_scans" ("status");
+
+-- +down
+DROP INDEX IF EXISTS "registry_scans_status_index";
+DROP TABLE IF EXISTS "registry_scans";
+
+ALTER TABLE file_registry DROP COLUMN file_mtime;
+ALTER TABLE file_registry DROP COLUMN file_size;
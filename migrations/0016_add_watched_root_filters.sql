@@ -0,0 +1,41 @@
+-- +up
+-- Per-root scan filters, see fileregistry.ScanFilter. Lists are stored one entry per line.
+ALTER TABLE watched_roots ADD COLUMN include_extensions TEXT NOT NULL DEFAULT '.mp4
+.mkv
+.webm
+.mov
+.avi
+.m4v
+.flv
+.wmv
+.mpg
+.mpeg
+.ts
+.mp3
+.m4a
+.aac
+.ogg
+.opus
+.flac
+.wav
+.wma';
+ALTER TABLE watched_roots ADD COLUMN ignore_patterns TEXT NOT NULL DEFAULT '*.part
+*.ytdl
+*.tmp
+*.temp
+Thumbs.db
+desktop.ini
+.DS_Store
+$RECYCLE.BIN
+System Volume Information
+@eaDir';
+ALTER TABLE watched_roots ADD COLUMN min_file_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE watched_roots ADD COLUMN include_hidden BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE watched_roots ADD COLUMN follow_symlinks BOOLEAN NOT NULL DEFAULT 0;
+
+-- +down
+ALTER TABLE watched_roots DROP COLUMN follow_symlinks;
+ALTER TABLE watched_roots DROP COLUMN include_hidden;
+ALTER TABLE watched_roots DROP COLUMN min_file_size;
+ALTER TABLE watched_roots DROP COLUMN ignore_patterns;
+ALTER TABLE watched_roots DROP COLUMN include_extensions;
@@ -0,0 +1,25 @@
+-- +up
+-- Audit log of duplicate reconciliation, dry runs are recorded with status 'planned'
+CREATE TABLE IF NOT EXISTS "duplicate_actions" (
+    "id" INTEGER NOT NULL,
+    "batch_id" VARCHAR NOT NULL,
+    "performed_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "dry_run" BOOLEAN NOT NULL DEFAULT 0,
+    "group_kind" VARCHAR NOT NULL,
+    "group_key" VARCHAR NOT NULL,
+    "action" VARCHAR NOT NULL,
+    "keep_strategy" VARCHAR NOT NULL,
+    "kept_path" VARCHAR NOT NULL,
+    "file_path" VARCHAR NOT NULL,
+    "destination_path" VARCHAR,
+    "file_size" INTEGER NOT NULL DEFAULT 0,
+    "status" VARCHAR NOT NULL,
+    "detail" TEXT,
+    PRIMARY KEY("id")
+);
+
+CREATE INDEX "duplicate_actions_batch_id_index" ON "duplicate_actions" ("batch_id");
+
+-- +down
+DROP INDEX IF EXISTS "duplicate_actions_batch_id_index";
+DROP TABLE IF EXISTS "duplicate_actions";
@@ -0,0 +1,33 @@
+-- +up
+CREATE TABLE IF NOT EXISTS "corruption_scans" (
+    "id" INTEGER NOT NULL,
+    "target_kind" VARCHAR NOT NULL,
+    "target_path" VARCHAR NOT NULL,
+    "playlist_id" INTEGER,
+    "status" VARCHAR NOT NULL DEFAULT 'running',
+    "started_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "finished_at" BIGINT,
+    "files_total" INTEGER NOT NULL DEFAULT 0,
+    "files_checked" INTEGER NOT NULL DEFAULT 0,
+    "corrupt_found" INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY("id")
+);
+
+CREATE TABLE IF NOT EXISTS "corruption_results" (
+    "id" INTEGER NOT NULL,
+    "scan_id" INTEGER NOT NULL,
+    "file_path" VARCHAR NOT NULL,
+    "download_id" INTEGER,
+    "detail" TEXT NOT NULL,
+    "requeued" BOOLEAN NOT NULL DEFAULT 0,
+    "detected_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY("id"),
+    FOREIGN KEY ("scan_id") REFERENCES "corruption_scans"("id")
+);
+
+CREATE INDEX "corruption_results_scan_id_index" ON "corruption_results" ("scan_id");
+
+-- +down
+DROP INDEX IF EXISTS "corruption_results_scan_id_index";
+DROP TABLE IF EXISTS "corruption_results";
+DROP TABLE IF EXISTS "corruption_scans";
@@ -0,0 +1,37 @@
+-- +up
+-- Ordered post-processing steps per playlist, options are stored as a JSON object
+CREATE TABLE IF NOT EXISTS "postprocess_steps" (
+    "id" INTEGER NOT NULL,
+    "playlist_id" INTEGER NOT NULL,
+    "position" INTEGER NOT NULL,
+    "kind" VARCHAR NOT NULL,
+    "options" TEXT NOT NULL DEFAULT '{}',
+    "is_enabled" BOOLEAN NOT NULL DEFAULT 1,
+    PRIMARY KEY("id"),
+    FOREIGN KEY ("playlist_id") REFERENCES "playlists"("id")
+);
+
+CREATE INDEX "postprocess_steps_playlist_id_index" ON "postprocess_steps" ("playlist_id", "position");
+
+-- Outcome of every step run against a download, kept when the playlist's steps change
+CREATE TABLE IF NOT EXISTS "postprocess_results" (
+    "id" INTEGER NOT NULL,
+    "download_id" INTEGER NOT NULL,
+    "step_id" INTEGER,
+    "position" INTEGER NOT NULL,
+    "kind" VARCHAR NOT NULL,
+    "status" VARCHAR NOT NULL,
+    "detail" TEXT,
+    "started_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "finished_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    PRIMARY KEY("id"),
+    FOREIGN KEY ("download_id") REFERENCES "downloads"("id")
+);
+
+CREATE INDEX "postprocess_results_download_id_index" ON "postprocess_results" ("download_id");
+
+-- +down
+DROP INDEX IF EXISTS "postprocess_results_download_id_index";
+DROP TABLE IF EXISTS "postprocess_results";
+DROP INDEX IF EXISTS "postprocess_steps_playlist_id_index";
+DROP TABLE IF EXISTS "postprocess_steps";
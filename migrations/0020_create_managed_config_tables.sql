@@ -0,0 +1,47 @@
+-- +up
+CREATE TABLE IF NOT EXISTS "managed_playlists" (
+    "playlist_id" INTEGER NOT NULL,
+    "check_interval_minutes" INTEGER NOT NULL DEFAULT 0,
+    "last_checked_at" BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY("playlist_id"),
+    FOREIGN KEY ("playlist_id") REFERENCES "playlists"("id")
+    ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "managed_settings" (
+    "setting_key" VARCHAR NOT NULL,
+    PRIMARY KEY("setting_key")
+);
+
+CREATE TABLE IF NOT EXISTS "config_sync_runs" (
+    "id" INTEGER NOT NULL,
+    "config_path" VARCHAR NOT NULL,
+    "status" VARCHAR NOT NULL,
+    "started_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "finished_at" BIGINT,
+    "drift_count" INTEGER NOT NULL DEFAULT 0,
+    "error_message" VARCHAR,
+    PRIMARY KEY("id")
+);
+
+CREATE TABLE IF NOT EXISTS "config_drift" (
+    "id" INTEGER NOT NULL,
+    "run_id" INTEGER NOT NULL,
+    "kind" VARCHAR NOT NULL,
+    "subject" VARCHAR NOT NULL,
+    "expected" VARCHAR,
+    "actual" VARCHAR,
+    "action" VARCHAR NOT NULL,
+    PRIMARY KEY("id"),
+    FOREIGN KEY ("run_id") REFERENCES "config_sync_runs"("id")
+    ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX "config_drift_run_id_index" ON "config_drift" ("run_id");
+
+-- +down
+DROP INDEX IF EXISTS "config_drift_run_id_index";
+DROP TABLE IF EXISTS "config_drift";
+DROP TABLE IF EXISTS "config_sync_runs";
+DROP TABLE IF EXISTS "managed_settings";
+DROP TABLE IF EXISTS "managed_playlists";
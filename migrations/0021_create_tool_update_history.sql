@@ -0,0 +1,21 @@
+-- +up
+CREATE TABLE IF NOT EXISTS "tool_update_history" (
+    "id" INTEGER NOT NULL,
+    "tool" VARCHAR NOT NULL,
+    "trigger" VARCHAR NOT NULL,
+    "status" VARCHAR NOT NULL,
+    "from_version" VARCHAR,
+    "to_version" VARCHAR,
+    "error_message" VARCHAR,
+    "failure_count" INTEGER NOT NULL DEFAULT 0,
+    "requeued_count" INTEGER NOT NULL DEFAULT 0,
+    "started_at" BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+    "finished_at" BIGINT,
+    PRIMARY KEY("id")
+);
+
+CREATE INDEX "tool_update_history_started_at_index" ON "tool_update_history" ("started_at");
+
+-- +down
+DROP INDEX IF EXISTS "tool_update_history_started_at_index";
+DROP TABLE IF EXISTS "tool_update_history";
@@ -0,0 +1,86 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"videoarchiver/backend/domains/pathing"
+)
+
+// applyContainerUser applies the UMASK, PUID and PGID environment variables.
+// Started as root, the working directories are handed to PUID:PGID and the process drops to that user,
+// so archived files, the database and logs belong to the user that other containers read them as.
+func applyContainerUser() error {
+	if value := os.Getenv("UMASK"); value != "" {
+		umask, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid UMASK %q: %w", value, err)
+		}
+		syscall.Umask(int(umask))
+	}
+
+	puid, pgid := os.Getenv("PUID"), os.Getenv("PGID")
+	if puid == "" && pgid == "" {
+		return nil
+	}
+	uid, gid := os.Geteuid(), os.Getegid()
+	var err error
+	if puid != "" {
+		if uid, err = strconv.Atoi(puid); err != nil {
+			return fmt.Errorf("invalid PUID %q: %w", puid, err)
+		}
+	}
+	if pgid != "" {
+		if gid, err = strconv.Atoi(pgid); err != nil {
+			return fmt.Errorf("invalid PGID %q: %w", pgid, err)
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		if uid == os.Geteuid() && gid == os.Getegid() {
+			return nil
+		}
+		return fmt.Errorf("running as %d:%d, start as root to switch to PUID %d and PGID %d", os.Geteuid(), os.Getegid(), uid, gid)
+	}
+
+	seen := make(map[string]bool)
+	for _, kind := range []pathing.DirKind{pathing.DirData, pathing.DirConfig, pathing.DirState, pathing.DirCache, pathing.DirRuntime} {
+		dir, err := pathing.GetDir(kind)
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if err := chownTree(dir, uid, gid); err != nil {
+			return fmt.Errorf("failed to hand %s to %d:%d: %w", dir, uid, gid, err)
+		}
+	}
+
+	// Group first, the user change removes the permission to change it
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("failed to set group: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("failed to set user: %w", err)
+	}
+	return nil
+}
+
+func chownTree(root string, uid, gid int) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
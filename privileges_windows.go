@@ -0,0 +1,16 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// applyContainerUser rejects PUID and PGID, file ownership works differently on Windows
+func applyContainerUser() error {
+	if os.Getenv("PUID") != "" || os.Getenv("PGID") != "" {
+		return errors.New("PUID and PGID are not supported on Windows")
+	}
+	return nil
+}